@@ -0,0 +1,177 @@
+/*
+	Copyright © 2024-2026 Macaroni OS Linux
+	See AUTHORS and LICENSE for the license details and contributors.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/autogen"
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func autogenTestCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "test",
+		Short: "Test autogen specs with golden files.",
+		Long: `Executes the Autogen elaboration of a specfile without network and
+compares the ebuilds, the values and the Manifest of every package
+with the golden files stored next to the specfile.
+
+The HTTP requests are replayed from the cassette <spec>.cassette.yml
+and the golden files are stored under the directory <spec>.golden.
+
+$> mark-devkit autogen test --specfile myspec.yml -k merge.kit.d/test.yml
+
+$> mark-devkit autogen test --specfile myspec.yml -k merge.kit.d/test.yml --update
+`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			log := logger.GetDefaultLogger()
+			specfile, _ := cmd.Flags().GetString("specfile")
+			kitfile, _ := cmd.Flags().GetString("kitfile")
+
+			if specfile == "" {
+				log.Fatal("No specfile param defined.")
+			}
+
+			if kitfile == "" {
+				log.Fatal("No kitfile param defined.")
+			}
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			log := logger.GetDefaultLogger()
+			specfile, _ := cmd.Flags().GetString("specfile")
+			kitfile, _ := cmd.Flags().GetString("kitfile")
+			to, _ := cmd.Flags().GetString("to")
+			goldenDir, _ := cmd.Flags().GetString("golden-dir")
+			cassetteFile, _ := cmd.Flags().GetString("http-cassette")
+			cassetteMode, _ := cmd.Flags().GetString("http-cassette-mode")
+			update, _ := cmd.Flags().GetBool("update")
+			keepWorkdir, _ := cmd.Flags().GetBool("keep-workdir")
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			showValues, _ := cmd.Flags().GetBool("show-values")
+			atoms, _ := cmd.Flags().GetStringArray("pkg")
+
+			if goldenDir == "" {
+				goldenDir = autogen.GetGoldenDir(specfile)
+			}
+			if cassetteFile == "" {
+				cassetteFile = autogen.GetGoldenCassette(specfile)
+			}
+
+			if to == "" {
+				tmpdir, err := os.MkdirTemp("", "mark-devkit-autogen-test")
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+				to = tmpdir
+			} else if utils.Exists(to) {
+				// Never remove a directory not created by the command.
+				keepWorkdir = true
+			}
+
+			log.InfoC(log.Aurora.Bold(
+				fmt.Sprintf(":mask:Testing specfile %s with golden files %s",
+					specfile, goldenDir)),
+			)
+
+			// Without the cassette every HTTP request fails in
+			// order to avoid the use of the network.
+			var c *cassette.Cassette
+			var err error
+			if cassetteMode == cassette.ModeReplay && !utils.Exists(cassetteFile) {
+				log.InfoC(fmt.Sprintf(
+					":videocassette:Cassette %s not available. HTTP requests disabled.",
+					cassetteFile))
+				c = cassette.NewOfflineCassette(cassetteFile)
+			} else {
+				c, err = cassette.NewCassette(cassetteFile, cassetteMode)
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+				log.InfoC(fmt.Sprintf(
+					":videocassette:Using HTTP cassette %s in %s mode.",
+					cassetteFile, cassetteMode))
+			}
+			cassette.SetDefaultCassette(c)
+
+			autogenOpts := autogen.NewAutogenBotOpts()
+			autogenOpts.Concurrency = 1
+			autogenOpts.Verbose = verbose
+			autogenOpts.PullSources = false
+			autogenOpts.GenReposcan = false
+			autogenOpts.CleanWorkingDir = !keepWorkdir
+			autogenOpts.ShowGeneratedValues = showValues
+			autogenOpts.Atoms = atoms
+
+			autogenBot := autogen.NewAutogenBot(config)
+			autogenBot.SetWorkDir(to)
+			err = autogenBot.SetupFetcher("dir", map[string]string{})
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			results, err := autogenBot.Test(specfile, kitfile, goldenDir,
+				update, autogenOpts)
+			if cerr := c.Save(); cerr != nil {
+				log.Error(fmt.Sprintf("Error on save cassette %s: %s",
+					c.File, cerr.Error()))
+			}
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			failed := 0
+			for _, r := range results {
+				switch r.Status {
+				case autogen.GoldenStatusOk:
+					log.InfoC(fmt.Sprintf(":check_mark_button:[%s] OK", r.CatPkg))
+				case autogen.GoldenStatusUpdated:
+					log.InfoC(fmt.Sprintf(":pencil:[%s] Golden files updated:\n%s",
+						r.CatPkg, strings.Join(r.Messages, "\n")))
+				default:
+					failed++
+					log.InfoC(fmt.Sprintf(":fire:[%s] FAILED:\n%s",
+						r.CatPkg, strings.Join(r.Messages, "\n")))
+				}
+			}
+
+			if failed > 0 {
+				log.Fatal(fmt.Sprintf("%d of %d packages failed.",
+					failed, len(results)))
+			}
+
+			log.InfoC(log.Aurora.Bold(
+				fmt.Sprintf(":party_popper:All %d packages passed.", len(results))))
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.String("specfile", "", "The specfile with the rules of the packages to autogen.")
+	flags.StringP("kitfile", "k", "", "The YAML with the target kit definition.")
+	flags.String("to", "",
+		"Override the default temporary work directory. An existing directory is never removed.")
+	flags.String("golden-dir", "",
+		"Override the default <spec>.golden directory of the golden files.")
+	flags.Bool("update", false, "Update the golden files with the files generated.")
+	flags.Bool("keep-workdir", false, "Avoid to remove the working directory.")
+	flags.Bool("verbose", false, "Show additional informations.")
+	flags.Bool("show-values", false,
+		"For debug purpose print generated values for any elaborated package in YAML format.")
+	flags.StringArray("pkg", []string{}, "Test only specified packages.")
+	flags.String("http-cassette", "",
+		"Override the default <spec>.cassette.yml file of the HTTP requests.")
+	flags.String("http-cassette-mode", cassette.ModeReplay,
+		"Set the mode of the HTTP cassette: record|replay.")
+
+	return cmd
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"github.com/macaroni-os/mark-devkit/pkg/autogen"
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
 
@@ -66,17 +67,30 @@ func autogenThinCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			autogenOpts.ShowGeneratedValues = showValues
 			autogenOpts.Atoms = atoms
 
+			// The cassette must be available before the creation
+			// of the HTTP clients.
+			err := setupHttpCassette(cmd)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
 			autogenBot := autogen.NewAutogenBot(config)
 			autogenBot.SetWorkDir(to)
 			if downloadDir != "" {
 				autogenBot.SetDownloadDir(downloadDir)
 			}
-			err := autogenBot.SetupFetcher("dir", backendOpts)
+			err = autogenBot.SetupFetcher("dir", backendOpts)
 			if err != nil {
 				log.Fatal(err.Error())
 			}
 
 			err = autogenBot.Run(specfile, kitfile, autogenOpts)
+			if c := cassette.GetDefaultCassette(); c != nil {
+				if cerr := c.Save(); cerr != nil {
+					log.Error(fmt.Sprintf("Error on save cassette %s: %s",
+						c.File, cerr.Error()))
+				}
+			}
 			if err != nil {
 				log.Fatal(err.Error())
 			}
@@ -96,6 +110,10 @@ func autogenThinCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 	flags.Bool("show-values", false,
 		"For debug purpose print generated values for any elaborated package in YAML format.")
 	flags.StringArray("pkg", []string{}, "Elaborate only specified packages.")
+	flags.String("http-cassette", "",
+		"The file where record or replay the HTTP requests.")
+	flags.String("http-cassette-mode", cassette.ModeReplay,
+		"Set the mode of the HTTP cassette: record|replay.")
 
 	return cmd
 }
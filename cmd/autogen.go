@@ -10,6 +10,8 @@ import (
 	"os"
 
 	"github.com/macaroni-os/mark-devkit/pkg/autogen"
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
+	"github.com/macaroni-os/mark-devkit/pkg/kit"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
 
@@ -25,12 +27,17 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 		PreRun: func(cmd *cobra.Command, args []string) {
 			log := logger.GetDefaultLogger()
 			specfile, _ := cmd.Flags().GetString("specfile")
+			prMode, _ := cmd.Flags().GetString("pr-mode")
 			kitfile, _ := cmd.Flags().GetString("kitfile")
 
 			if specfile == "" {
 				log.Fatal("No specfile param defined.")
 			}
 
+			if err := kit.ValidatePullRequestMode(prMode); err != nil {
+				log.Fatal(err.Error())
+			}
+
 			if kitfile == "" {
 				log.Fatal("No kitfile param defined.")
 			}
@@ -54,6 +61,8 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			keepWorkdir, _ := cmd.Flags().GetBool("keep-workdir")
 			push, _ := cmd.Flags().GetBool("push")
 			githubUser, _ := cmd.Flags().GetString("github-user")
+			prLabels, _ := cmd.Flags().GetStringArray("pr-label")
+			prMode, _ := cmd.Flags().GetString("pr-mode")
 			pr, _ := cmd.Flags().GetBool("pr")
 			sync, _ := cmd.Flags().GetBool("sync")
 			showValues, _ := cmd.Flags().GetBool("show-values")
@@ -134,6 +143,8 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			if githubUser != "" {
 				autogenOpts.GithubUser = githubUser
 			}
+			autogenOpts.PullRequestLabels = prLabels
+			autogenOpts.PullRequestMode = prMode
 
 			if notifyDiscordUrl != "" {
 				hook := &specs.MarkDevkitHook{
@@ -149,17 +160,30 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 				}
 			}
 
+			// The cassette must be available before the creation
+			// of the HTTP clients.
+			err := setupHttpCassette(cmd)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
 			autogenBot := autogen.NewAutogenBot(config)
 			autogenBot.SetWorkDir(to)
 			if downloadDir != "" {
 				autogenBot.SetDownloadDir(downloadDir)
 			}
-			err := autogenBot.SetupFetcher(backend, backendOpts)
+			err = autogenBot.SetupFetcher(backend, backendOpts)
 			if err != nil {
 				log.Fatal(err.Error())
 			}
 
 			err = autogenBot.Run(specfile, kitfile, autogenOpts)
+			if c := cassette.GetDefaultCassette(); c != nil {
+				if cerr := c.Save(); cerr != nil {
+					log.Error(fmt.Sprintf("Error on save cassette %s: %s",
+						c.File, cerr.Error()))
+				}
+			}
 			if err != nil {
 				log.Fatal(err.Error())
 			}
@@ -168,6 +192,8 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(autogenTestCmdCommand(config))
+
 	flags := cmd.Flags()
 	flags.String("specfile", "", "The specfile with the rules of the packages to autogen.")
 	flags.StringP("kitfile", "k", "", "The YAML with the target kit definition.")
@@ -194,7 +220,12 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 
 	flags.String("signature-name", "", "Specify the name of the user for the commits.")
 	flags.String("signature-email", "", "Specify the email of the user for the commits.")
-	flags.String("github-user", "", "Override the default Github user used for PR.")
+	flags.String("github-user", "",
+		"Override the owner of the target repository used for PR.")
+	flags.StringArray("pr-label", []string{},
+		"Add the label to the created Pull Requests.")
+	flags.String("pr-mode", kit.PullRequestModePackage,
+		"Set how the Pull Requests are created: package|single|category.")
 
 	// Sync S3 / Minio backend flags
 	flags.String("backend", "dir", "Set the fetcher backend to use: dir|s3.")
@@ -210,6 +241,10 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 	flags.String("minio-prefix", "",
 		"Set the prefix path to use or set env MINIO_PREFIX. Note: The path is without initial /.")
 	flags.StringArray("pkg", []string{}, "Elaborate only specified packages.")
+	flags.String("http-cassette", "",
+		"The file where record or replay the HTTP requests.")
+	flags.String("http-cassette-mode", cassette.ModeReplay,
+		"Set the mode of the HTTP cassette: record|replay.")
 
 	// Discord notify url
 	flags.String("notify-discord-url", "",
@@ -217,3 +252,23 @@ func autogenCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 
 	return cmd
 }
+
+func setupHttpCassette(cmd *cobra.Command) error {
+	file, _ := cmd.Flags().GetString("http-cassette")
+	mode, _ := cmd.Flags().GetString("http-cassette-mode")
+
+	if file == "" {
+		return nil
+	}
+
+	c, err := cassette.NewCassette(file, mode)
+	if err != nil {
+		return err
+	}
+	cassette.SetDefaultCassette(c)
+
+	logger.GetDefaultLogger().InfoC(
+		fmt.Sprintf(":videocassette:Using HTTP cassette %s in %s mode.", file, mode))
+
+	return nil
+}
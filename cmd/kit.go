@@ -26,6 +26,8 @@ func kitCmdCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 		cmdkit.KitMergeCommand(config),
 		cmdkit.KitBumpReleaseCommand(config),
 		cmdkit.KitDistfilesSyncCommand(config),
+		cmdkit.KitCheckDepsCommand(config),
+		cmdkit.KitRdepsCommand(config),
 	)
 
 	return cmd
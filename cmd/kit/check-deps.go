@@ -0,0 +1,187 @@
+/*
+	Copyright © 2024-2026 Macaroni OS Linux
+	See AUTHORS and LICENSE for the license details and contributors.
+*/
+
+package cmdkit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+// getKitCacheFiles returns the kit-cache files of the kits defined
+// in the specfile or all the files of the kit-cache directory.
+func getKitCacheFiles(specfile, kitCacheDir string) ([]string, error) {
+	ans := []string{}
+
+	if specfile != "" {
+		ra, err := specs.NewReposcanAnalysis(specfile)
+		if err != nil {
+			return ans, err
+		}
+
+		for _, source := range ra.Kits {
+			ans = append(ans,
+				filepath.Join(kitCacheDir, source.Name+"-"+source.Branch))
+		}
+		return ans, nil
+	}
+
+	entries, err := os.ReadDir(kitCacheDir)
+	if err != nil {
+		return ans, fmt.Errorf("error on reading dir %s: %s",
+			kitCacheDir, err.Error())
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ans = append(ans, filepath.Join(kitCacheDir, entry.Name()))
+	}
+	sort.Strings(ans)
+
+	return ans, nil
+}
+
+func KitCheckDepsCommand(config *specs.MarkDevkitConfig) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:     "check-deps",
+		Aliases: []string{"cd", "check"},
+		Short:   "Check the dependencies of the packages between kits.",
+		Long: `Loads the kit-cache files of the kits and verifies that the dependencies
+of every package are satisfied by the packages available in the kits.
+
+The command exits with a non-zero value when unsatisfiable, ambiguous
+or blocker-conflicting dependencies are found.
+
+$> mark-devkit kit check-deps --kit-cache-dir kit-cache
+
+$> mark-devkit kit check-deps --specfile kits.yml --kit-cache-dir kit-cache --show-summary
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			log := logger.GetDefaultLogger()
+
+			specfile, _ := cmd.Flags().GetString("specfile")
+			kitCacheDir, _ := cmd.Flags().GetString("kit-cache-dir")
+			kitCacheFiles, _ := cmd.Flags().GetStringArray("kit-cache-file")
+			kinds, _ := cmd.Flags().GetStringArray("kind")
+			atoms, _ := cmd.Flags().GetStringArray("pkg")
+			verbose, _ := cmd.Flags().GetBool("verbose")
+			showSummary, _ := cmd.Flags().GetBool("show-summary")
+			writeSummaryFile, _ := cmd.Flags().GetString(
+				"write-summary-file")
+			summaryFormat, _ := cmd.Flags().GetString("summary-format")
+
+			if showSummary {
+				config.GetLogging().Level = "error"
+			}
+
+			if len(kitCacheFiles) == 0 {
+				files, err := getKitCacheFiles(specfile, kitCacheDir)
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+				kitCacheFiles = files
+			}
+
+			if len(kitCacheFiles) == 0 {
+				log.Fatal("No kit-cache files found.")
+			}
+
+			resolver := kit.NewRepoScanResolver(config)
+			resolver.JsonSources = kitCacheFiles
+
+			err := resolver.LoadJsonFiles(verbose)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			err = resolver.BuildMap()
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			opts := kit.NewDepsCheckOpts()
+			if len(kinds) > 0 {
+				opts.Kinds = kinds
+			}
+			opts.Atoms = atoms
+
+			report, err := resolver.CheckDependencies(opts)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			for _, issue := range report.Issues {
+				log.Warning(fmt.Sprintf(":warning:[%s::%s] %s %s %s: %s",
+					issue.Atom, issue.Kit, issue.Kind, issue.Dependency,
+					issue.Type, issue.Message))
+			}
+
+			if writeSummaryFile != "" {
+				if summaryFormat == "json" {
+					err = report.WriteJsonFile(writeSummaryFile)
+				} else {
+					err = report.WriteYamlFile(writeSummaryFile)
+				}
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+			}
+
+			if showSummary {
+				var data []byte
+
+				if summaryFormat == "json" {
+					data, err = report.Json()
+				} else {
+					data, err = report.Yaml()
+				}
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+
+				fmt.Println(string(data))
+			}
+
+			if len(report.Issues) > 0 {
+				if !showSummary {
+					log.Error(fmt.Sprintf("Found %d issues on %d dependencies of %d packages.",
+						report.Stats.TotIssues, report.Stats.TotDeps, report.Stats.TotAtoms))
+				}
+				os.Exit(1)
+			}
+
+			log.InfoC(log.Aurora.Bold(
+				fmt.Sprintf(":party_popper:All %d dependencies of %d packages are satisfied.",
+					report.Stats.TotDeps, report.Stats.TotAtoms)))
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.String("specfile", "", "The specfile with the kits to check.")
+	flags.String("kit-cache-dir", "kit-cache", "Directory with the kit-cache files.")
+	flags.StringArray("kit-cache-file", []string{},
+		"Check only the specified kit-cache files.")
+	flags.StringArray("kind", []string{},
+		"Check only the specified kind of dependencies (RDEPEND, DEPEND, BDEPEND, PDEPEND).")
+	flags.StringArray("pkg", []string{}, "Check only specified packages.")
+	flags.Bool("verbose", false, "Show additional informations.")
+	flags.Bool("show-summary", false, "Show YAML/JSON summary results")
+	flags.String("write-summary-file", "",
+		"Write the check summary to the specified file in YAML/JSON format.")
+	flags.String("summary-format", "yaml", "Specificy the summary format: json|yaml")
+
+	return cmd
+}
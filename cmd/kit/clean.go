@@ -6,15 +6,49 @@
 package cmdkit
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 
 	"github.com/macaroni-os/mark-devkit/pkg/kit"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+type CleanReport struct {
+	KeptAtoms []*kit.CleanKeptAtom `json:"kept_atoms,omitempty" yaml:"kept_atoms,omitempty"`
+}
+
+func (r *CleanReport) Yaml() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+func (r *CleanReport) Json() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *CleanReport) WriteJsonFile(f string) error {
+	data, err := r.Json()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, data, 0644)
+}
+
+func (r *CleanReport) WriteYamlFile(f string) error {
+	data, err := r.Yaml()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, data, 0644)
+}
+
 func KitCleanCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 
 	var cmd = &cobra.Command{
@@ -44,8 +78,21 @@ func KitCleanCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			keepWorkdir, _ := cmd.Flags().GetBool("keep-workdir")
 			push, _ := cmd.Flags().GetBool("push")
 			githubUser, _ := cmd.Flags().GetString("github-user")
+			prLabels, _ := cmd.Flags().GetStringArray("pr-label")
 			pr, _ := cmd.Flags().GetBool("pr")
 			atoms, _ := cmd.Flags().GetStringArray("pkg")
+			skipDepsCheck, _ := cmd.Flags().GetBool("skip-deps-check")
+			depsKitCacheDir, _ := cmd.Flags().GetString("deps-kit-cache-dir")
+			depsKitCacheFiles, _ := cmd.Flags().GetStringArray("deps-kit-cache-file")
+			skipDepsSourcesKits, _ := cmd.Flags().GetBool("deps-skip-sources-kits")
+			showSummary, _ := cmd.Flags().GetBool("show-summary")
+			writeSummaryFile, _ := cmd.Flags().GetString(
+				"write-summary-file")
+			summaryFormat, _ := cmd.Flags().GetString("summary-format")
+
+			if showSummary {
+				config.GetLogging().Level = "error"
+			}
 
 			log.InfoC(log.Aurora.Bold(
 				fmt.Sprintf(":mask:Loading specfile %s", specfile)),
@@ -63,10 +110,22 @@ func KitCleanCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			mergeOpts.SignatureEmail = signatureEmail
 			mergeOpts.CleanWorkingDir = !keepWorkdir
 			mergeOpts.Atoms = atoms
+			mergeOpts.CheckReverseDeps = !skipDepsCheck
+			mergeOpts.DepsKitCacheFiles = depsKitCacheFiles
+			mergeOpts.DepsSourcesKits = !skipDepsSourcesKits
+
+			if depsKitCacheDir != "" {
+				files, err := getKitCacheFiles("", depsKitCacheDir)
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+				mergeOpts.DepsKitCacheFiles = append(mergeOpts.DepsKitCacheFiles, files...)
+			}
 
 			if githubUser != "" {
 				mergeOpts.GithubUser = githubUser
 			}
+			mergeOpts.PullRequestLabels = prLabels
 
 			mergeBot := kit.NewMergeBot(config)
 			mergeBot.SetWorkDir(to)
@@ -76,7 +135,43 @@ func KitCleanCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 				log.Fatal(err.Error())
 			}
 
-			log.InfoC(log.Aurora.Bold(":party_popper:All done"))
+			report := &CleanReport{
+				KeptAtoms: []*kit.CleanKeptAtom{},
+			}
+			for _, kept := range mergeBot.GetKeptAtoms() {
+				report.KeptAtoms = append(report.KeptAtoms, kept)
+			}
+			sort.Slice(report.KeptAtoms, func(i, j int) bool {
+				return report.KeptAtoms[i].Atom < report.KeptAtoms[j].Atom
+			})
+
+			if writeSummaryFile != "" {
+				if summaryFormat == "json" {
+					err = report.WriteJsonFile(writeSummaryFile)
+				} else {
+					err = report.WriteYamlFile(writeSummaryFile)
+				}
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+			}
+
+			if showSummary {
+				var data []byte
+
+				if summaryFormat == "json" {
+					data, err = report.Json()
+				} else {
+					data, err = report.Yaml()
+				}
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+
+				fmt.Println(string(data))
+			} else {
+				log.InfoC(log.Aurora.Bold(":party_popper:All done"))
+			}
 		},
 	}
 
@@ -94,10 +189,25 @@ func KitCleanCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 	flags.Bool("keep-workdir", false, "Avoid to remove the working directory.")
 	flags.Bool("pr", false, "Push commit over specific branch and as Pull Request.")
 	flags.StringArray("pkg", []string{}, "Elaborate only specified packages.")
+	flags.Bool("skip-deps-check", false,
+		"Remove the versions without check if they are required by other packages.")
+	flags.String("deps-kit-cache-dir", "",
+		"Directory with the kit-cache files of the other kits to check.")
+	flags.StringArray("deps-kit-cache-file", []string{},
+		"The kit-cache file of another kit to check.")
+	flags.Bool("deps-skip-sources-kits", false,
+		"Check only the target kit and the kit-cache files defined with the deps-kit-cache options.")
+	flags.Bool("show-summary", false, "Show YAML/JSON summary results")
+	flags.String("write-summary-file", "",
+		"Write the clean summary to the specified file in YAML/JSON format.")
+	flags.String("summary-format", "yaml", "Specificy the summary format: json|yaml")
 
 	flags.String("signature-name", "", "Specify the name of the user for the commits.")
 	flags.String("signature-email", "", "Specify the email of the user for the commits.")
-	flags.String("github-user", "", "Override the default Github user used for PR.")
+	flags.String("github-user", "",
+		"Override the owner of the target repository used for PR.")
+	flags.StringArray("pr-label", []string{},
+		"Add the label to the created Pull Requests.")
 
 	return cmd
 }
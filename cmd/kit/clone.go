@@ -105,6 +105,7 @@ func KitCloneCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			}
 
 			opts := &kitops.CloneOptions{
+				Config: config,
 				GitCloneOptions: &git.CloneOptions{
 					SingleBranch: singleBranch,
 					RemoteName:   "origin",
@@ -6,15 +6,48 @@
 package cmdkit
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 
 	"github.com/macaroni-os/mark-devkit/pkg/kit"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+type MergeReport struct {
+	Eclasses *kit.MergeEclassesReport `json:"eclasses,omitempty" yaml:"eclasses,omitempty"`
+}
+
+func (r *MergeReport) Yaml() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+func (r *MergeReport) Json() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *MergeReport) WriteJsonFile(f string) error {
+	data, err := r.Json()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, data, 0644)
+}
+
+func (r *MergeReport) WriteYamlFile(f string) error {
+	data, err := r.Yaml()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, data, 0644)
+}
+
 func KitMergeCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 
 	var cmd = &cobra.Command{
@@ -24,11 +57,16 @@ func KitMergeCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 		PreRun: func(cmd *cobra.Command, args []string) {
 			log := logger.GetDefaultLogger()
 			specfile, _ := cmd.Flags().GetString("specfile")
+			prMode, _ := cmd.Flags().GetString("pr-mode")
 
 			if specfile == "" {
 				log.Fatal("No specfile param defined.")
 			}
 
+			if err := kit.ValidatePullRequestMode(prMode); err != nil {
+				log.Fatal(err.Error())
+			}
+
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			log := logger.GetDefaultLogger()
@@ -45,8 +83,18 @@ func KitMergeCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			keepWorkdir, _ := cmd.Flags().GetBool("keep-workdir")
 			push, _ := cmd.Flags().GetBool("push")
 			githubUser, _ := cmd.Flags().GetString("github-user")
+			prLabels, _ := cmd.Flags().GetStringArray("pr-label")
+			prMode, _ := cmd.Flags().GetString("pr-mode")
 			pr, _ := cmd.Flags().GetBool("pr")
 			atoms, _ := cmd.Flags().GetStringArray("pkg")
+			showSummary, _ := cmd.Flags().GetBool("show-summary")
+			writeSummaryFile, _ := cmd.Flags().GetString(
+				"write-summary-file")
+			summaryFormat, _ := cmd.Flags().GetString("summary-format")
+
+			if showSummary {
+				config.GetLogging().Level = "error"
+			}
 
 			log.InfoC(log.Aurora.Bold(
 				fmt.Sprintf(":mask:Loading specfile %s", specfile)),
@@ -69,6 +117,8 @@ func KitMergeCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 			if githubUser != "" {
 				mergeOpts.GithubUser = githubUser
 			}
+			mergeOpts.PullRequestLabels = prLabels
+			mergeOpts.PullRequestMode = prMode
 
 			mergeBot := kit.NewMergeBot(config)
 			mergeBot.SetWorkDir(to)
@@ -78,7 +128,37 @@ func KitMergeCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 				log.Fatal(err.Error())
 			}
 
-			log.InfoC(log.Aurora.Bold(":party_popper:All done"))
+			report := &MergeReport{
+				Eclasses: mergeBot.GetEclassesReport(),
+			}
+
+			if writeSummaryFile != "" {
+				if summaryFormat == "json" {
+					err = report.WriteJsonFile(writeSummaryFile)
+				} else {
+					err = report.WriteYamlFile(writeSummaryFile)
+				}
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+			}
+
+			if showSummary {
+				var data []byte
+
+				if summaryFormat == "json" {
+					data, err = report.Json()
+				} else {
+					data, err = report.Yaml()
+				}
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+
+				fmt.Println(string(data))
+			} else {
+				log.InfoC(log.Aurora.Bold(":party_popper:All done"))
+			}
 		},
 	}
 
@@ -97,10 +177,19 @@ func KitMergeCommand(config *specs.MarkDevkitConfig) *cobra.Command {
 	flags.Bool("keep-workdir", false, "Avoid to remove the working directory.")
 	flags.Bool("pr", false, "Push commit over specific branch and as Pull Request.")
 	flags.StringArray("pkg", []string{}, "Elaborate only specified packages.")
+	flags.Bool("show-summary", false, "Show YAML/JSON summary results")
+	flags.String("write-summary-file", "",
+		"Write the merge summary to the specified file in YAML/JSON format.")
+	flags.String("summary-format", "yaml", "Specificy the summary format: json|yaml")
 
 	flags.String("signature-name", "", "Specify the name of the user for the commits.")
 	flags.String("signature-email", "", "Specify the email of the user for the commits.")
-	flags.String("github-user", "", "Override the default Github user used for PR.")
+	flags.String("github-user", "",
+		"Override the owner of the target repository used for PR.")
+	flags.StringArray("pr-label", []string{},
+		"Add the label to the created Pull Requests.")
+	flags.String("pr-mode", kit.PullRequestModePackage,
+		"Set how the Pull Requests are created: package|single|category.")
 
 	return cmd
 }
@@ -0,0 +1,148 @@
+/*
+	Copyright © 2024-2026 Macaroni OS Linux
+	See AUTHORS and LICENSE for the license details and contributors.
+*/
+
+package cmdkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	specs "github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/spf13/cobra"
+)
+
+type RdepsResult struct {
+	Package     string                   `json:"package" yaml:"package"`
+	ReverseDeps []*kit.ReverseDependency `json:"reverse_deps" yaml:"reverse_deps"`
+}
+
+func printRdepsTree(res *RdepsResult) {
+	fmt.Println(res.Package)
+	for idx, rdep := range res.ReverseDeps {
+		prefix := "├── "
+		if idx == len(res.ReverseDeps)-1 {
+			prefix = "└── "
+		}
+
+		line := fmt.Sprintf("%s%s::%s (%s %s)", prefix,
+			rdep.Atom, rdep.Kit, rdep.Kind, rdep.Dependency)
+		if len(rdep.Versions) > 0 {
+			line += " -> " + strings.Join(rdep.Versions, ", ")
+		}
+		fmt.Println(line)
+	}
+}
+
+func KitRdepsCommand(config *specs.MarkDevkitConfig) *cobra.Command {
+
+	var cmd = &cobra.Command{
+		Use:     "rdeps <atom> ... <atomN>",
+		Aliases: []string{"rd", "reverse-deps"},
+		Short:   "Show the packages that require a package or a version.",
+		Long: `Loads the kit-cache files of the kits and shows the packages, and the
+kits, that require the packages or the versions in input.
+
+$> mark-devkit kit rdeps dev-libs/openssl --kit-cache-dir kit-cache
+
+$> mark-devkit kit rdeps "<dev-libs/openssl-3" --kind RDEPEND --output json
+`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			log := logger.GetDefaultLogger()
+
+			specfile, _ := cmd.Flags().GetString("specfile")
+			kitCacheDir, _ := cmd.Flags().GetString("kit-cache-dir")
+			kitCacheFiles, _ := cmd.Flags().GetStringArray("kit-cache-file")
+			kinds, _ := cmd.Flags().GetStringArray("kind")
+			output, _ := cmd.Flags().GetString("output")
+			verbose, _ := cmd.Flags().GetBool("verbose")
+
+			if output != "tree" && output != "json" {
+				log.Fatal(fmt.Sprintf("Invalid output %s.", output))
+			}
+
+			if output == "json" {
+				config.GetLogging().Level = "error"
+			}
+
+			if len(kitCacheFiles) == 0 {
+				files, err := getKitCacheFiles(specfile, kitCacheDir)
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+				kitCacheFiles = files
+			}
+
+			if len(kitCacheFiles) == 0 {
+				log.Fatal("No kit-cache files found.")
+			}
+
+			resolver := kit.NewRepoScanResolver(config)
+			resolver.JsonSources = kitCacheFiles
+
+			err := resolver.LoadJsonFiles(verbose)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			err = resolver.BuildMap()
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			err = resolver.BuildReverseIndex()
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+
+			opts := kit.NewReverseDepsOpts()
+			opts.Kinds = kinds
+
+			results := []*RdepsResult{}
+			for _, pkg := range args {
+				rdeps, err := resolver.GetReverseDependencies(pkg, opts)
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+				results = append(results, &RdepsResult{
+					Package:     pkg,
+					ReverseDeps: rdeps,
+				})
+			}
+
+			if output == "json" {
+				// The conditions of the packages are not escaped.
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetEscapeHTML(false)
+				encoder.SetIndent("", "  ")
+				err = encoder.Encode(results)
+				if err != nil {
+					log.Fatal(err.Error())
+				}
+			} else {
+				for _, res := range results {
+					printRdepsTree(res)
+				}
+			}
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.String("specfile", "", "The specfile with the kits to load.")
+	flags.String("kit-cache-dir", "kit-cache", "Directory with the kit-cache files.")
+	flags.StringArray("kit-cache-file", []string{},
+		"Load only the specified kit-cache files.")
+	flags.StringArray("kind", []string{},
+		"Filter for the kind of dependencies (RDEPEND, DEPEND, BDEPEND, PDEPEND).")
+	flags.StringP("output", "o", "tree", "Set the output format: tree|json")
+	flags.Bool("verbose", false, "Show additional informations.")
+
+	return cmd
+}
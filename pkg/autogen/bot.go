@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/autogen/generators"
 	"github.com/macaroni-os/mark-devkit/pkg/autogen/notifier"
 	tmpleng "github.com/macaroni-os/mark-devkit/pkg/autogen/tmpl-engines"
@@ -21,6 +22,7 @@ import (
 
 	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
 	"github.com/geaaru/rest-guard/pkg/guard"
+	git_http "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/google/go-github/v74/github"
 	"golang.org/x/oauth2"
 	"gopkg.in/yaml.v3"
@@ -41,6 +43,9 @@ type AutogenBot struct {
 	MergeOpts *kit.MergeBotOpts
 
 	Fetcher kit.Fetcher
+	// The files available on the fetcher backend.
+	backendFiles map[string]bool
+	backendMutex sync.Mutex
 
 	ElabAtoms []*specs.RepoScanAtom
 	mutex     sync.Mutex
@@ -59,6 +64,7 @@ type AutogenBotOpts struct {
 	MergeAutogen        bool
 	MergeForced         bool
 	ShowGeneratedValues bool
+	SaveGeneratedValues bool
 	StopOnError         bool
 	Atoms               []string
 
@@ -69,7 +75,9 @@ type AutogenBotOpts struct {
 	SignatureEmail string
 
 	// Pull Request data
-	GithubUser string
+	GithubUser        string
+	PullRequestLabels []string
+	PullRequestMode   string
 }
 
 func NewAutogenBotOpts() *AutogenBotOpts {
@@ -80,10 +88,13 @@ func NewAutogenBotOpts() *AutogenBotOpts {
 		SyncFiles:           true,
 		Concurrency:         10,
 		CleanWorkingDir:     true,
-		GithubUser:          "macaroni-os",
+		GithubUser:          "",
+		PullRequestLabels:   []string{},
+		PullRequestMode:     kit.PullRequestModePackage,
 		MergeAutogen:        true,
 		MergeForced:         true,
 		ShowGeneratedValues: false,
+		SaveGeneratedValues: false,
 		StopOnError:         false,
 		Atoms:               []string{},
 	}
@@ -106,6 +117,7 @@ func NewAutogenBot(c *specs.MarkDevkitConfig) *AutogenBot {
 	rg, _ := guard.NewRestGuard(c.GetRest())
 	// Overide the default check redirect
 	rg.Client.CheckRedirect = kit.CheckRedirect
+	cassette.WrapClient(rg.Client)
 
 	return &AutogenBot{
 		Config:       c,
@@ -133,28 +145,43 @@ func (a *AutogenBot) GetSourcesDir() string {
 	return filepath.Join(a.WorkDir, "sources")
 }
 
+func (a *AutogenBot) GetValuesDir() string {
+	return filepath.Join(a.WorkDir, "values")
+}
+
 func (a *AutogenBot) SetWorkDir(d string)     { a.WorkDir = d }
 func (a *AutogenBot) SetDownloadDir(d string) { a.DownloadDir = d }
 
 func (a *AutogenBot) SetupGithubClient(ctx context.Context) error {
 	if a.GithubClient == nil {
-		pushOpts := kit.NewPushOptions()
-
-		remote, available := a.Config.GetAuthentication().GetRemote("github.com")
-		if available {
-			pushOpts.Token = remote.Token
+		if c := cassette.GetDefaultCassette(); c != nil && c.IsReplay() {
+			// The token is not needed to replay the requests.
+			a.GithubClient = github.NewClient(cassette.NewClient())
+			return nil
 		}
 
-		auth, err := kit.GetGithubAuth(pushOpts)
+		// The token is retrieved from the credentials of the
+		// github.com remote or from the environment.
+		token := ""
+		auth, err := a.Config.GetAuthentication().GetGitAuth("https://github.com", "")
 		if err != nil {
 			return err
 		}
+		if basicAuth, ok := auth.(*git_http.BasicAuth); ok {
+			token = basicAuth.Password
+		}
+		if token == "" {
+			token = os.Getenv("GITHUB_TOKEN")
+		}
+		if token == "" {
+			return fmt.Errorf("Missing github token for the github.com remote!")
+		}
 
 		ts := oauth2.StaticTokenSource(&oauth2.Token{
-			AccessToken: auth.Password,
+			AccessToken: token,
 		})
 		tc := oauth2.NewClient(ctx, ts)
-		a.GithubClient = github.NewClient(tc)
+		a.GithubClient = github.NewClient(cassette.WrapClient(tc))
 	}
 
 	return nil
@@ -187,6 +214,8 @@ func (a *AutogenBot) setupMergeBot(mkit *specs.MergeKit, opts *AutogenBotOpts) e
 	a.MergeOpts.CleanWorkingDir = false
 	a.MergeOpts.Push = opts.Push
 	a.MergeOpts.GithubUser = opts.GithubUser
+	a.MergeOpts.PullRequestLabels = opts.PullRequestLabels
+	a.MergeOpts.PullRequestMode = opts.PullRequestMode
 	a.MergeOpts.SignatureEmail = opts.SignatureEmail
 	a.MergeOpts.SignatureName = opts.SignatureName
 	a.MergeOpts.GitDeepFetch = opts.GitDeepFetch
@@ -244,7 +273,7 @@ func (a *AutogenBot) UpdateTargetKit(mkit *specs.MergeKit, candidates []*specs.R
 	opts *AutogenBotOpts) error {
 
 	// Copy eclasses
-	err := a.MergeBot.MergeEclasses(mkit, a.MergeOpts)
+	err := a.MergeBot.MergeEclasses(mkit, candidates, a.MergeOpts)
 	if err != nil {
 		return err
 	}
@@ -319,7 +348,17 @@ func (a *AutogenBot) Run(specfile, kitFile string, opts *AutogenBotOpts) error {
 
 	// NOTE: This must be done only if there are
 	//       generator using github or for pull requests
-	if aspec.HasGithubGenerators() || opts.PullRequest {
+	//       on github.
+	prOnGithub := false
+	if opts.PullRequest {
+		forgeType, err := kit.GetKitForgeType(a.Config, targetKit.Url)
+		if err != nil {
+			return err
+		}
+		prOnGithub = forgeType == kit.ForgeGithub
+	}
+
+	if aspec.HasGithubGenerators() || prOnGithub {
 		err = a.SetupGithubClient(ctx)
 		if err != nil {
 			return err
@@ -451,6 +490,7 @@ func (a *AutogenBot) ProcessDefinition(mkit *specs.MergeKit,
 	if err != nil {
 		return err
 	}
+	templateEngine.SetLibraryDirs(aspec.GetTemplatesLibraryDirs(def))
 
 	atomFiltered := opts.HasAtoms()
 
@@ -751,6 +791,13 @@ func (a *AutogenBot) ProcessPackage(mkit *specs.MergeKit,
 	// Add reposcan to elab list
 	a.AddReposcanAtom(reposcanAtom)
 
+	if opts.SaveGeneratedValues {
+		err = a.saveGeneratedValues(atom, def, &values)
+		if err != nil {
+			return err
+		}
+	}
+
 	if a.Config.GetGeneral().Debug {
 		repoAtomRaw, _ := reposcanAtom.Yaml()
 		a.Logger.Debug(fmt.Sprintf(
@@ -760,3 +807,32 @@ func (a *AutogenBot) ProcessPackage(mkit *specs.MergeKit,
 
 	return nil
 }
+
+// saveGeneratedValues writes the values used to render the ebuild
+// under the values directory.
+func (a *AutogenBot) saveGeneratedValues(atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+	values := *mapref
+
+	pn, _ := values["pn"].(string)
+	version, _ := values["version"].(string)
+	valuesDir := filepath.Join(a.GetValuesDir(), atom.GetCategory(def), atom.Name)
+
+	err := helpers.EnsureDirWithoutIds(valuesDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	valuesFile := filepath.Join(valuesDir, fmt.Sprintf("%s-%s.values.yml", pn, version))
+	err = os.WriteFile(valuesFile, data, 0644)
+	if err != nil {
+		return fmt.Errorf("error on write file %s: %s", valuesFile, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,346 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ModeRecord saves all the HTTP requests and responses.
+	ModeRecord = "record"
+	// ModeReplay serves the responses saved without network.
+	ModeReplay = "replay"
+
+	// The responses with a body bigger than the threshold are stored
+	// in the bodies directory of the cassette, for example the tarballs.
+	DefaultBodyFileThreshold = 64 * 1024
+)
+
+// The request headers used by the clients that are stored in
+// the cassette. The authentication headers are never stored.
+var recordedRequestHeaders = []string{"Accept", "Range"}
+
+// Cassette contains the HTTP interactions recorded.
+type Cassette struct {
+	File         string         `json:"-" yaml:"-"`
+	Mode         string         `json:"-" yaml:"-"`
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+	// The max size of the bodies stored inside the cassette file.
+	BodyFileThreshold int64 `json:"-" yaml:"-"`
+
+	mutex sync.Mutex `json:"-" yaml:"-"`
+	// The next interaction to replay for every request key.
+	replayed map[string]int `json:"-" yaml:"-"`
+}
+
+type Interaction struct {
+	Request  *Request  `json:"request" yaml:"request"`
+	Response *Response `json:"response" yaml:"response"`
+}
+
+type Request struct {
+	Method  string            `json:"method" yaml:"method"`
+	Url     string            `json:"url" yaml:"url"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    string            `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int                 `json:"status_code" yaml:"status_code"`
+	Status     string              `json:"status" yaml:"status"`
+	Headers    map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       string              `json:"body,omitempty" yaml:"body,omitempty"`
+	// The binary bodies are stored encoded in base64.
+	BodyBase64 string `json:"body_base64,omitempty" yaml:"body_base64,omitempty"`
+	// The body could be read from a local file, for example a JSON
+	// or an HTML page. The path is relative to the cassette file.
+	BodyFile string `json:"body_file,omitempty" yaml:"body_file,omitempty"`
+}
+
+var defaultCassette *Cassette = nil
+
+func SetDefaultCassette(c *Cassette) { defaultCassette = c }
+func GetDefaultCassette() *Cassette  { return defaultCassette }
+
+// NewCassette returns the cassette of the file in input. In
+// replay mode the interactions are loaded from the file.
+func NewCassette(file, mode string) (*Cassette, error) {
+	ans := &Cassette{
+		File:              file,
+		Mode:              mode,
+		Interactions:      []*Interaction{},
+		BodyFileThreshold: DefaultBodyFileThreshold,
+		replayed:          make(map[string]int, 0),
+	}
+
+	switch mode {
+	case ModeRecord:
+	case ModeReplay:
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error on read cassette %s: %s", file, err.Error())
+		}
+		err = yaml.Unmarshal(content, ans)
+		if err != nil {
+			return nil, fmt.Errorf("error on parse cassette %s: %s", file, err.Error())
+		}
+		for idx, i := range ans.Interactions {
+			if i.Request == nil || i.Response == nil {
+				return nil, fmt.Errorf("invalid interaction %d on cassette %s",
+					idx, file)
+			}
+			// The fixtures written manually could define only the url.
+			if i.Request.Method == "" {
+				i.Request.Method = http.MethodGet
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid cassette mode %s", mode)
+	}
+
+	return ans, nil
+}
+
+// NewOfflineCassette returns a replay cassette without interactions.
+// All the HTTP requests fail.
+func NewOfflineCassette(file string) *Cassette {
+	return &Cassette{
+		File:              file,
+		Mode:              ModeReplay,
+		Interactions:      []*Interaction{},
+		BodyFileThreshold: DefaultBodyFileThreshold,
+		replayed:          make(map[string]int, 0),
+	}
+}
+
+func (c *Cassette) IsReplay() bool { return c.Mode == ModeReplay }
+
+// GetBodiesDir returns the directory where the big bodies are stored.
+// Ex: myspec.cassette.yml => myspec.cassette.bodies
+func (c *Cassette) GetBodiesDir() string {
+	return strings.TrimSuffix(c.File, filepath.Ext(c.File)) + ".bodies"
+}
+
+// Save writes the interactions recorded to the file of the cassette.
+func (c *Cassette) Save() error {
+	if c.Mode != ModeRecord {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// The requests done concurrently are sorted in order
+	// to have stable cassettes. The interactions with the
+	// same request keep the order of execution.
+	sort.SliceStable(c.Interactions, func(i, j int) bool {
+		return c.Interactions[i].Request.key() < c.Interactions[j].Request.key()
+	})
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(c.File); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	err = os.WriteFile(c.File, data, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Drop the bodies directory if there aren't big bodies.
+	os.Remove(c.GetBodiesDir())
+
+	return nil
+}
+
+func (r *Request) key() string {
+	ans := r.Method + " " + r.Url
+	if r.Body != "" {
+		ans += " " + r.Body
+	}
+	return ans
+}
+
+func newRequest(req *http.Request) (*Request, error) {
+	ans := &Request{
+		Method: req.Method,
+		Url:    req.URL.String(),
+	}
+
+	for _, h := range recordedRequestHeaders {
+		if v := req.Header.Get(h); v != "" {
+			if ans.Headers == nil {
+				ans.Headers = make(map[string]string, 0)
+			}
+			ans.Headers[h] = v
+		}
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		ans.Body = string(data)
+	}
+
+	return ans, nil
+}
+
+// newResponse returns the response to record. The body is copied in a
+// temporary file of the bodies directory in order to avoid to keep the big
+// artefacts in memory. The big bodies are stored in the bodies directory
+// with the sha256 of the content as name.
+func (c *Cassette) newResponse(resp *http.Response) (*Response, error) {
+	ans := &Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Headers:    make(map[string][]string, 0),
+	}
+
+	for k, v := range resp.Header {
+		if strings.EqualFold(k, "Set-Cookie") {
+			continue
+		}
+		ans.Headers[k] = v
+	}
+
+	bodiesDir := c.GetBodiesDir()
+	if err := os.MkdirAll(bodiesDir, 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(bodiesDir, ".body-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	resp.Body.Close()
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if size > c.BodyFileThreshold {
+		name := hex.EncodeToString(h.Sum(nil))
+		bodyFile := filepath.Join(bodiesDir, name)
+		if err := os.Rename(tmp.Name(), bodyFile); err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(bodyFile)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = f
+		ans.BodyFile = filepath.Join(filepath.Base(bodiesDir), name)
+		return ans, nil
+	}
+
+	data, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	if utf8.Valid(data) {
+		ans.Body = string(data)
+	} else {
+		ans.BodyBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+
+	return ans, nil
+}
+
+func (r *Response) toHttpResponse(req *http.Request, baseDir string) (*http.Response, error) {
+	var err error
+	var body io.ReadCloser
+	var size int64
+
+	if r.BodyFile != "" && r.BodyBase64 == "" {
+		bodyFile := r.BodyFile
+		if !filepath.IsAbs(bodyFile) {
+			bodyFile = filepath.Join(baseDir, bodyFile)
+		}
+		f, err := os.Open(bodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error on read body file %s: %s",
+				bodyFile, err.Error())
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		body = f
+		size = info.Size()
+	} else {
+		data := []byte(r.Body)
+		if r.BodyBase64 != "" {
+			data, err = base64.StdEncoding.DecodeString(r.BodyBase64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		body = io.NopCloser(bytes.NewReader(data))
+		size = int64(len(data))
+	}
+
+	statusCode := r.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	status := r.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+	}
+
+	header := make(http.Header, 0)
+	for k, v := range r.Headers {
+		header[k] = append([]string{}, v...)
+	}
+
+	return &http.Response{
+		StatusCode:    statusCode,
+		Status:        status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: size,
+		Request:       req,
+	}, nil
+}
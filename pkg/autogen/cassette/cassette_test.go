@@ -0,0 +1,208 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cassette_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cassette", func() {
+
+	binaryBody := []byte{0x1f, 0x8b, 0x00, 0xff, 0xfe}
+	bigBody := strings.Repeat("0123456789", 10)
+
+	var server *httptest.Server
+	var file string
+	var counter int
+
+	get := func(client *http.Client, url string) (int, string, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data), err
+	}
+
+	post := func(client *http.Client, url, body string) (string, error) {
+		resp, err := client.Post(url, "text/plain", strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	BeforeEach(func() {
+		counter = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/counter":
+				counter++
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+				fmt.Fprintf(w, "%d", counter)
+			case "/binary":
+				w.Write(binaryBody)
+			case "/big":
+				w.Header().Set("Content-Type", "application/x-tar")
+				fmt.Fprint(w, bigBody)
+			case "/echo":
+				data, _ := io.ReadAll(r.Body)
+				fmt.Fprintf(w, "echo %s", data)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		DeferCleanup(server.Close)
+		file = filepath.Join(GinkgoT().TempDir(), "spec.cassette.yml")
+	})
+
+	record := func() {
+		c, err := NewCassette(file, ModeRecord)
+		Expect(err).ToNot(HaveOccurred())
+		c.BodyFileThreshold = 64
+		client := &http.Client{Transport: c.Transport(nil)}
+
+		for _, expected := range []string{"1", "2"} {
+			_, body, err := get(client, server.URL+"/counter")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(Equal(expected))
+		}
+		for _, path := range []string{"/binary", "/big", "/missing"} {
+			_, _, err := get(client, server.URL+path)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/binary", nil)
+		req.Header.Set("Authorization", "Bearer mytoken")
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+
+		body, err := post(client, server.URL+"/echo", "hello")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(Equal("echo hello"))
+
+		Expect(c.Save()).To(Succeed())
+	}
+
+	replayClient := func() *http.Client {
+		// The server is closed to be sure that the network isn't used.
+		server.Close()
+		c, err := NewCassette(file, ModeReplay)
+		Expect(err).ToNot(HaveOccurred())
+		return &http.Client{Transport: c.Transport(nil)}
+	}
+
+	It("stores the big bodies in the bodies directory", func() {
+		record()
+
+		sum := sha256.Sum256([]byte(bigBody))
+		bodyFile := filepath.Join(strings.TrimSuffix(file, ".yml")+".bodies",
+			hex.EncodeToString(sum[:]))
+		Expect(bodyFile).To(BeAnExistingFile())
+
+		data, err := os.ReadFile(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(
+			"body_file: spec.cassette.bodies/" + hex.EncodeToString(sum[:])))
+		Expect(string(data)).ToNot(ContainSubstring("mytoken"))
+		Expect(string(data)).ToNot(ContainSubstring("session"))
+	})
+
+	It("replays the responses in the order of the record", func() {
+		record()
+		client := replayClient()
+
+		for _, expected := range []string{"1", "2", "2"} {
+			_, body, err := get(client, server.URL+"/counter")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(Equal(expected))
+		}
+	})
+
+	It("replays the bodies", func() {
+		record()
+		client := replayClient()
+
+		_, body, err := get(client, server.URL+"/binary")
+		Expect(err).ToNot(HaveOccurred())
+		Expect([]byte(body)).To(Equal(binaryBody))
+
+		resp, err := client.Get(server.URL + "/big")
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(bigBody))
+		Expect(resp.ContentLength).To(Equal(int64(len(bigBody))))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-tar"))
+
+		status, _, err := get(client, server.URL+"/missing")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(status).To(Equal(http.StatusNotFound))
+	})
+
+	It("matches the requests with the body", func() {
+		record()
+		client := replayClient()
+
+		body, err := post(client, server.URL+"/echo", "hello")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(Equal("echo hello"))
+
+		_, err = post(client, server.URL+"/echo", "other")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails with the requests not recorded", func() {
+		record()
+		client := replayClient()
+
+		_, _, err := get(client, server.URL+"/other")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no interaction recorded"))
+	})
+
+	It("replays the fixtures written manually", func() {
+		dir := filepath.Dir(file)
+		Expect(os.WriteFile(filepath.Join(dir, "index.json"), []byte(`{"a": 1}`), 0644)).To(Succeed())
+		Expect(os.WriteFile(file, []byte(`interactions:
+  - request:
+      url: https://example.org/index.json
+    response:
+      body_file: index.json
+`), 0644)).To(Succeed())
+
+		c, err := NewCassette(file, ModeReplay)
+		Expect(err).ToNot(HaveOccurred())
+		status, body, err := get(&http.Client{Transport: c.Transport(nil)},
+			"https://example.org/index.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(body).To(Equal(`{"a": 1}`))
+	})
+
+	It("fails all the requests offline", func() {
+		c := NewOfflineCassette(file)
+		_, _, err := get(&http.Client{Transport: c.Transport(nil)}, server.URL+"/counter")
+		Expect(err).To(HaveOccurred())
+		Expect(counter).To(Equal(0))
+	})
+})
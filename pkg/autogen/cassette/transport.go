@@ -0,0 +1,109 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package cassette
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+)
+
+// Transport is the http.RoundTripper that records or replays the
+// interactions of the cassette.
+type Transport struct {
+	Cassette *Cassette
+	Base     http.RoundTripper
+}
+
+func (c *Cassette) Transport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		Cassette: c,
+		Base:     base,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Cassette.IsReplay() {
+		return t.Cassette.replay(req, r)
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	recorded, err := t.Cassette.newResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Cassette.mutex.Lock()
+	t.Cassette.Interactions = append(t.Cassette.Interactions, &Interaction{
+		Request:  r,
+		Response: recorded,
+	})
+	t.Cassette.mutex.Unlock()
+
+	return resp, nil
+}
+
+// replay returns the response of the next interaction with the same
+// request. When all the interactions are been replayed the last one
+// is used again.
+func (c *Cassette) replay(req *http.Request, r *Request) (*http.Response, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := r.key()
+	matches := []*Interaction{}
+	for _, i := range c.Interactions {
+		if i.Request.key() == key {
+			matches = append(matches, i)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no interaction recorded on cassette %s for %s %s",
+			c.File, r.Method, r.Url)
+	}
+
+	idx := c.replayed[key]
+	if idx >= len(matches) {
+		idx = len(matches) - 1
+	} else {
+		c.replayed[key] = idx + 1
+	}
+
+	return matches[idx].Response.toHttpResponse(req, filepath.Dir(c.File))
+}
+
+// WrapClient replaces the transport of the client with the transport
+// of the default cassette if available.
+func WrapClient(client *http.Client) *http.Client {
+	if defaultCassette == nil || client == nil {
+		return client
+	}
+	if _, isCassette := client.Transport.(*Transport); !isCassette {
+		client.Transport = defaultCassette.Transport(client.Transport)
+	}
+	return client
+}
+
+// NewClient returns a new HTTP client that uses the default
+// cassette or nil if the cassette is not enabled.
+func NewClient() *http.Client {
+	if defaultCassette == nil {
+		return nil
+	}
+	return WrapClient(&http.Client{})
+}
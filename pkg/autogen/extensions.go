@@ -6,7 +6,9 @@ package autogen
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
@@ -38,23 +40,40 @@ func (a *AutogenBot) ConsumeExtensions(mkit *specs.MergeKit,
 		filesDirPath = filepath.Join(filepath.Dir(aspec.File), filesDirPath)
 	}
 
-	for _, atomExt := range atom.Extensions {
+	extensionsDefs, err := a.getExtensionsPipeline(atom, autogenDef)
+	if err != nil {
+		a.Logger.Error(fmt.Sprintf("[%s] %s", atom.Name, err.Error()))
+		return err
+	}
 
-		// Retrieve extension options
-		extOpts, err := autogenDef.GetExtensionOptions(atomExt)
-		if err != nil {
-			a.Logger.Error(fmt.Sprintf("[%s] %s", atom.Name, err.Error()))
-			return err
-		}
+	// The sources unpacked by the first extension are shared with
+	// the next extensions of the pipeline.
+	sourceDir, _ := filepath.Abs(
+		filepath.Join(a.WorkDir, "extensions-sources", atom.Name))
+	err = os.RemoveAll(sourceDir)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(sourceDir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	if !a.Logger.Config.GetGeneral().Debug {
+		defer os.RemoveAll(sourceDir)
+	}
+
+	for idx, atomExt := range extensionsDefs {
+		extOpts := atomExt.Ext
 
 		extOpts.Options["download_dir"] = a.GetDownloadDir()
 		extOpts.Options["workdir"] = a.WorkDir
 		extOpts.Options["specfile"] = aspec.File
 		extOpts.Options["files_dir"] = filesDirPath
+		extOpts.Options["source_dir"] = sourceDir
 
 		a.Logger.Info(
-			fmt.Sprintf(":brain:[%s] Elaborating extension %s...",
-				atom.Name, atomExt))
+			fmt.Sprintf(":brain:[%s] Elaborating extension %s (%d/%d)...",
+				atom.Name, atomExt.Id, idx+1, len(extensionsDefs)))
 
 		err = a.ConsumeExtension(mkit, aspec, atom, def,
 			mapref, extOpts,
@@ -67,6 +86,87 @@ func (a *AutogenBot) ConsumeExtensions(mkit *specs.MergeKit,
 	return nil
 }
 
+type extensionStep struct {
+	Id  string
+	Ext *specs.AutogenExtension
+}
+
+// getExtensionsPipeline returns the extensions of the atom sorted
+// by their requirements. The extensions without requirements
+// between them are executed in the order defined in the atom.
+func (a *AutogenBot) getExtensionsPipeline(atom *specs.AutogenAtom,
+	autogenDef *specs.AutogenDefinition) ([]*extensionStep, error) {
+
+	steps := []*extensionStep{}
+	stepsMap := make(map[string]*extensionStep, 0)
+
+	for _, atomExt := range atom.Extensions {
+		if _, present := stepsMap[atomExt]; present {
+			return nil, fmt.Errorf("extension %s defined multiple times", atomExt)
+		}
+
+		// Retrieve extension options
+		extOpts, err := autogenDef.GetExtensionOptions(atomExt)
+		if err != nil {
+			return nil, err
+		}
+
+		step := &extensionStep{Id: atomExt, Ext: extOpts}
+		steps = append(steps, step)
+		stepsMap[atomExt] = step
+	}
+
+	for _, step := range steps {
+		for _, req := range step.Ext.Requires {
+			if _, present := stepsMap[req]; !present {
+				return nil, fmt.Errorf(
+					"extension %s requires extension %s not enabled",
+					step.Id, req)
+			}
+		}
+	}
+
+	ans := []*extensionStep{}
+	done := make(map[string]bool, 0)
+
+	for len(ans) < len(steps) {
+		added := false
+		for _, step := range steps {
+			if _, isDone := done[step.Id]; isDone {
+				continue
+			}
+
+			ready := true
+			for _, req := range step.Ext.Requires {
+				if _, isDone := done[req]; !isDone {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				ans = append(ans, step)
+				done[step.Id] = true
+				added = true
+				break
+			}
+		}
+
+		if !added {
+			pending := []string{}
+			for _, step := range steps {
+				if _, isDone := done[step.Id]; !isDone {
+					pending = append(pending, step.Id)
+				}
+			}
+			return nil, fmt.Errorf("cycle on requires of the extensions %s",
+				strings.Join(pending, ", "))
+		}
+	}
+
+	return ans, nil
+}
+
 func (a *AutogenBot) ConsumeExtension(mkit *specs.MergeKit,
 	aspec *specs.AutogenSpec, atom, def *specs.AutogenAtom,
 	mapref *map[string]interface{},
@@ -77,6 +177,8 @@ func (a *AutogenBot) ConsumeExtension(mkit *specs.MergeKit,
 		return err
 	}
 
+	ext.SetBundleStore(a)
+
 	// Execute extension code
 	err = ext.Elaborate(a.RestGuard, atom, def, mapref)
 
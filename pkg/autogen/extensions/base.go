@@ -6,14 +6,20 @@ package extensions
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
+	nurl "net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
+	autogenart "github.com/macaroni-os/mark-devkit/pkg/autogen/artefacts"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
+	"github.com/geaaru/rest-guard/pkg/guard"
+	guard_specs "github.com/geaaru/rest-guard/pkg/specs"
 	executor "github.com/geaaru/tar-formers/pkg/executor"
 	tarf_specs "github.com/geaaru/tar-formers/pkg/specs"
 	"github.com/geaaru/tar-formers/pkg/tools"
@@ -21,7 +27,8 @@ import (
 )
 
 type ExtensionBase struct {
-	Opts map[string]string
+	Opts  map[string]string
+	Store BundleStore
 }
 
 func (e *ExtensionBase) GetOpts() map[string]string {
@@ -34,19 +41,70 @@ func (e *ExtensionBase) cleanup(mapref *map[string]interface{}) {
 	delete(values, "workdir")
 	delete(values, "download_dir")
 	delete(values, "mirror")
+	delete(values, "source_dir")
 }
 
-func (e *ExtensionBase) getTarformersConfig() *tarf_specs.Config {
-	// Check instance
-	config := tarf_specs.NewConfig(nil)
-	if logger.GetDefaultLogger().Config.GetGeneral().Debug {
-		config.GetLogging().Level = "info"
-	} else {
-		config.GetGeneral().Debug = false
-		config.GetLogging().Level = "info"
+// fetchUrl returns the content of the url in input.
+func fetchUrl(restGuard *guard.RestGuard, pageUrl string) ([]byte, error) {
+	uri, err := nurl.Parse(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	node := guard_specs.NewRestNode(uri.Host, uri.Host, uri.Scheme == "https")
+
+	service := guard_specs.NewRestService(uri.Host)
+	service.Retries = 3
+	service.AddNode(node)
+
+	t := service.GetTicket()
+	defer t.Rip()
+
+	_, err = restGuard.CreateRequest(t, "GET", uri.RequestURI())
+	if err != nil {
+		return nil, err
+	}
+
+	err = restGuard.Do(t)
+	if err != nil {
+		if t.Response != nil {
+			return nil, fmt.Errorf("%s - %s - %s", pageUrl, err.Error(), t.Response.Status)
+		} else {
+			return nil, fmt.Errorf("%s - %s", pageUrl, err.Error())
+		}
 	}
 
-	return config
+	if t.Response.Body == nil {
+		return nil, fmt.Errorf("%s - Received invalid response body", pageUrl)
+	}
+
+	if t.Response.StatusCode != 200 {
+		return nil, fmt.Errorf("%s - Received response %s", pageUrl, t.Response.Status)
+	}
+
+	return io.ReadAll(t.Response.Body)
+}
+
+// downloadMainArtefact downloads the artefact to the download dir.
+// The local artefacts, like the git snapshots, are already available
+// in the download dir.
+func (e *ExtensionBase) downloadMainArtefact(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, art *specs.AutogenArtefact,
+	downloadDir string) (*specs.RepoScanFile, error) {
+
+	if art.IsLocal() {
+		if !utils.Exists(filepath.Join(downloadDir, art.Name)) {
+			return nil, fmt.Errorf("local artefact %s not found", art.Name)
+		}
+		return &specs.RepoScanFile{
+			SrcUri: art.SrcUri,
+			Name:   art.Name,
+			Hashes: make(map[string]string, 0),
+		}, nil
+	}
+
+	return autogenart.DownloadArtefact(restGuard, atom,
+		art.SrcUri[0], art.Name, downloadDir)
 }
 
 func (e *ExtensionBase) unpackArtefact(downloadDir, targetDir string,
@@ -94,64 +152,182 @@ func (e *ExtensionBase) unpackArtefact(downloadDir, targetDir string,
 	patches, _ := values["patches"].([]interface{})
 
 	if len(patches) > 0 {
-		// Retrieve the path of the autogen specs
-		// in order to generate the patch path
-		filesDir, _ := e.Opts["files_dir"]
-
-		// Retrieve the patch of the sources
-		pkgSourceDir := ""
-		unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
-		entries, err := os.ReadDir(targetDir)
+		patchesList := []string{}
+		for idx := range patches {
+			patch, _ := patches[idx].(string)
+			patchesList = append(patchesList, patch)
+		}
+
+		pkgSourceDir, err := e.getPkgSourceDir(targetDir)
 		if err != nil {
 			return err
 		}
 
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
+		err = e.applyPatches(patchesList, pkgSourceDir)
+		if err != nil {
+			return err
+		}
+	}
 
-			if unpackDirPrefix != "" {
-				if strings.HasPrefix(entry.Name(), unpackDirPrefix) {
-					pkgSourceDir = filepath.Join(targetDir, entry.Name())
-					break
-				}
-			} else {
-				// POST: take the first directory
+	return nil
+}
+
+// getPkgSourceDir returns the directory of the sources of the package
+// inside the unpack directory. If the tarball contains different
+// directories the option unpack_srcdir_prefix selects the right one.
+func (e *ExtensionBase) getPkgSourceDir(targetDir string) (string, error) {
+	pkgSourceDir := ""
+	unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		if unpackDirPrefix != "" {
+			if strings.HasPrefix(entry.Name(), unpackDirPrefix) {
 				pkgSourceDir = filepath.Join(targetDir, entry.Name())
+				break
 			}
+		} else {
+			// POST: take the first directory
+			pkgSourceDir = filepath.Join(targetDir, entry.Name())
 		}
+	}
 
-		for idx := range patches {
+	return pkgSourceDir, nil
+}
 
-			patch, _ := patches[idx].(string)
-			patchPath, _ := filepath.Abs(filepath.Join(filesDir, patch))
-
-			patchDone := false
-			for _, pflag := range []string{"1", "0"} {
-				err := e.doPatch(patchPath, pkgSourceDir, pflag, true)
-				if err == nil {
-					err = e.doPatch(patchPath, pkgSourceDir, pflag, false)
-					if err != nil {
-						return fmt.Errorf(
-							"error on apply patch %s: %s",
-							patch, err.Error())
-					}
-					patchDone = true
-					break
+// applyPatches applies the patches to the sources. The paths of the
+// patches are relative to the files directory of the package.
+func (e *ExtensionBase) applyPatches(patches []string, pkgSourceDir string) error {
+	// Retrieve the path of the autogen specs
+	// in order to generate the patch path
+	filesDir, _ := e.Opts["files_dir"]
+
+	for _, patch := range patches {
+		patchPath, _ := filepath.Abs(filepath.Join(filesDir, patch))
+
+		patchDone := false
+		for _, pflag := range []string{"1", "0"} {
+			err := e.doPatch(patchPath, pkgSourceDir, pflag, true)
+			if err == nil {
+				err = e.doPatch(patchPath, pkgSourceDir, pflag, false)
+				if err != nil {
+					return fmt.Errorf(
+						"error on apply patch %s: %s",
+						patch, err.Error())
 				}
+				patchDone = true
+				break
 			}
+		}
 
-			if !patchDone {
-				return fmt.Errorf("patch %s is not usable.", patch)
-			}
-
+		if !patchDone {
+			return fmt.Errorf("patch %s is not usable.", patch)
 		}
 	}
 
 	return nil
 }
 
+// prepareSourceTree downloads and unpacks the main artefact and
+// returns the directory with the sources. When the extensions are
+// executed in a pipeline the sources are unpacked only one time in
+// the directory of the option source_dir and they are shared between
+// all the extensions of the package.
+func (e *ExtensionBase) prepareSourceTree(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom, art *specs.AutogenArtefact,
+	downloadDir, unpackDir string,
+	mapref *map[string]interface{}) (string, error) {
+
+	log := logger.GetDefaultLogger()
+
+	if sourceDir := e.Opts["source_dir"]; sourceDir != "" {
+		if entries, err := os.ReadDir(sourceDir); err == nil && len(entries) > 0 {
+			log.DebugC(fmt.Sprintf("[%s] Using the shared sources of %s",
+				atom.Name, art.Name))
+			return sourceDir, nil
+		}
+		unpackDir = sourceDir
+	}
+
+	// Download the main artefacts to the download dir.
+	log.DebugC(
+		fmt.Sprintf("[%s] Downloading %s from url %s",
+			atom.Name, art.Name, art.SrcUri[0],
+		))
+	repoFile, err := e.downloadMainArtefact(restGuard, atom,
+		art, downloadDir)
+	if err != nil {
+		return "", err
+	}
+
+	// Unpack the tarballs
+	log.Info(
+		fmt.Sprintf(":factory:[%s] Extracting file %s",
+			atom.Name, repoFile.Name,
+		))
+	err = e.unpackArtefact(downloadDir, unpackDir, repoFile,
+		atom, def, mapref)
+	if err != nil {
+		return "", err
+	}
+
+	return unpackDir, nil
+}
+
+// copySourceTree copies the files of the source directory to the
+// target directory without the git metadata.
+func copySourceTree(sourceDir, targetDir string) error {
+	return filepath.WalkDir(sourceDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(sourceDir, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(targetDir, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, content, info.Mode().Perm())
+		}
+
+		return nil
+	})
+}
+
 func (e *ExtensionBase) doPatch(patch, unpackDir, pflag string, dryRun bool) error {
 	log := logger.GetDefaultLogger()
 	patchBin := utils.TryResolveBinaryAbsPath("patch")
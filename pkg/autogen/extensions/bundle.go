@@ -0,0 +1,191 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+)
+
+// bundleWorkspace contains the directories and the options used by the
+// extensions that generate a bundle tarball from the lock file of the
+// package sources.
+type bundleWorkspace struct {
+	// The directory of the extension under the workdir. Ex: go-extension
+	ExtensionDir string
+	WorkDir      string
+	DownloadDir  string
+	PkgWorkDir   string
+	// The directory with the sources of the main artefact.
+	UnpackDir string
+
+	Mirror           string
+	BundleIdentifier string
+	BundleExtension  string
+	// The name of the directory inside the bundle tarball.
+	BundleDirName string
+	// The fingerprint of the lock inputs and of the options.
+	Fingerprint string
+
+	Artefacts []*specs.AutogenArtefact
+}
+
+// BundlesDir returns the directory where the bundle files are downloaded.
+func (w *bundleWorkspace) BundlesDir() string {
+	return filepath.Join(w.PkgWorkDir, w.BundleDirName)
+}
+
+// prepareBundleWorkspace creates the directories of the extension and
+// retrieves the sources of the main artefact, the first artefact of the
+// package. It returns nil when the package has no artefacts.
+func (e *ExtensionBase) prepareBundleWorkspace(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom, mapref *map[string]interface{},
+	name string) (*bundleWorkspace, error) {
+
+	values := *mapref
+
+	w := &bundleWorkspace{
+		ExtensionDir:     name + "-extension",
+		WorkDir:          e.Opts["workdir"],
+		DownloadDir:      e.Opts["download_dir"],
+		Mirror:           e.Opts["mirror"],
+		BundleIdentifier: e.Opts["bundle_identifier"],
+		// Permit both xz and .xz values.
+		BundleExtension: strings.ReplaceAll(e.Opts["bundle_extension"], ".", ""),
+	}
+	if !filepath.IsAbs(w.DownloadDir) {
+		w.DownloadDir, _ = filepath.Abs(w.DownloadDir)
+	}
+	if !filepath.IsAbs(w.WorkDir) {
+		w.WorkDir, _ = filepath.Abs(w.WorkDir)
+	}
+	if w.Mirror == "" {
+		w.Mirror = "mirror://macaroni"
+	}
+	if w.BundleIdentifier == "" {
+		w.BundleIdentifier = fmt.Sprintf("mark-%s-bundle", name)
+	}
+	if w.BundleExtension == "" {
+		w.BundleExtension = "xz"
+	}
+	w.BundleDirName = w.BundleIdentifier + "-" + atom.Name
+	w.PkgWorkDir = filepath.Join(w.WorkDir, w.ExtensionDir, atom.Name)
+	values["mirror"] = w.Mirror
+
+	err := os.MkdirAll(w.PkgWorkDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure download dir. Could be not present the first time.
+	err = os.MkdirAll(w.DownloadDir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Artefacts, _ = values["artefacts"].([]*specs.AutogenArtefact)
+	if len(w.Artefacts) == 0 {
+		logger.GetDefaultLogger().DebugC(
+			fmt.Sprintf(
+				"[%s] No artefacts found for %s bundle generation. Nothing to do.",
+				atom.Name, name,
+			))
+		return nil, nil
+	}
+
+	// Download and unpack the main artefact or use the sources
+	// shared by the previous extensions.
+	w.UnpackDir, err = e.prepareSourceTree(restGuard, atom, def, w.Artefacts[0],
+		w.DownloadDir, filepath.Join(w.PkgWorkDir, "unpack"), mapref)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// lookupWorkspaceBundle returns the name of the bundle tarball and the
+// artefact of the bundle if a bundle with the same fingerprint is already
+// available. The bundles are named with the version of the package and
+// with the option fingerprint_name with the fingerprint. The bundles are
+// always regenerated with the option fingerprint "false".
+func (e *ExtensionBase) lookupWorkspaceBundle(w *bundleWorkspace,
+	atom *specs.AutogenAtom, values map[string]interface{},
+	fingerprint string) (string, *specs.AutogenArtefact) {
+
+	w.Fingerprint = fingerprint
+
+	if e.Opts["fingerprint_name"] == "true" {
+		bundleTarball := e.getBundleTarballName(atom, values,
+			w.BundleIdentifier, w.BundleExtension, fingerprint)
+		return bundleTarball, e.lookupBundle(atom, w.DownloadDir, bundleTarball,
+			fingerprint, w.Mirror)
+	}
+
+	bundleTarball := e.getBundleTarballName(atom, values,
+		w.BundleIdentifier, w.BundleExtension, "")
+	return bundleTarball, e.lookupVersionedBundle(atom, w.DownloadDir,
+		bundleTarball, fingerprint, w.Mirror)
+}
+
+// createBundleTarball creates the bundle tarball with the
+// files downloaded in the bundles dir of the workspace.
+// The fingerprint is stored inside the bundle.
+func (e *ExtensionBase) createBundleTarball(w *bundleWorkspace,
+	atom *specs.AutogenAtom, bundleTarball string) (*specs.AutogenArtefact, error) {
+
+	logger.GetDefaultLogger().Debug(fmt.Sprintf("[%s] Creating tarball %s...",
+		atom.Name, bundleTarball))
+
+	if w.Fingerprint != "" {
+		err := os.MkdirAll(w.BundlesDir(), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+		err = os.WriteFile(filepath.Join(w.BundlesDir(), BundleFingerprintFile),
+			[]byte(w.Fingerprint+"\n"), 0644)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := e.createReproducibleTarball(
+		[]string{w.BundlesDir()},
+		filepath.Join(w.WorkDir, w.ExtensionDir, atom.Name),
+		filepath.Join(w.DownloadDir, bundleTarball))
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error on create tarball %s: %s",
+			bundleTarball, err.Error())
+	}
+
+	if w.Fingerprint != "" {
+		writeBundleIndex(w.DownloadDir, w.Fingerprint, bundleTarball)
+	}
+
+	return newBundleArtefact(bundleTarball, w.Mirror), nil
+}
+
+// completeBundle adds the bundle artefact to the artefacts of the
+// package and cleans the workspace.
+func (e *ExtensionBase) completeBundle(w *bundleWorkspace,
+	mapref *map[string]interface{}, bundleArt *specs.AutogenArtefact) {
+
+	values := *mapref
+	values["artefacts"] = append(w.Artefacts, bundleArt)
+
+	e.cleanup(mapref)
+
+	if !logger.GetDefaultLogger().Config.GetGeneral().Debug {
+		os.RemoveAll(filepath.Join(w.WorkDir, w.ExtensionDir))
+	}
+}
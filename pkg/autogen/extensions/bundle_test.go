@@ -0,0 +1,153 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	fingerprint1 = "1111111111111111aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	fingerprint2 = "2222222222222222bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+)
+
+// fakeBundleStore is a fetcher backend with the files of a directory.
+type fakeBundleStore struct {
+	Dir         string
+	DownloadDir string
+}
+
+func (s *fakeBundleStore) HasFile(name string) bool {
+	_, err := os.Stat(filepath.Join(s.Dir, name))
+	return err == nil
+}
+
+func (s *fakeBundleStore) GetFile(name string) error {
+	return helpers.CopyFile(filepath.Join(s.Dir, name),
+		filepath.Join(s.DownloadDir, name))
+}
+
+var _ = Describe("Bundles", func() {
+
+	var ext *ExtensionBase
+	var downloadDir string
+
+	atom := &specs.AutogenAtom{Name: "foo"}
+	files := map[string]string{
+		"cache/foo-1.0.zip": "foo",
+		"cache/bar-2.0.zip": "bar",
+	}
+	version := func(v string) map[string]interface{} {
+		return map[string]interface{}{"version": v}
+	}
+
+	BeforeEach(func() {
+		downloadDir = filepath.Join(GinkgoT().TempDir(), "downloads")
+		Expect(os.MkdirAll(downloadDir, 0755)).To(Succeed())
+		ext = &ExtensionBase{
+			Opts: map[string]string{
+				"workdir":      GinkgoT().TempDir(),
+				"download_dir": downloadDir,
+			},
+		}
+	})
+
+	It("stores the fingerprint inside the bundle", func() {
+		name, reused, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeFalse())
+		Expect(name).To(Equal("foo-1.0-mark-test-bundle.tar.xz"))
+
+		fingerprint, err := ReadBundleFingerprint(filepath.Join(downloadDir, name))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fingerprint).To(Equal(fingerprint1))
+	})
+
+	It("reuses the bundle of the same version with the same fingerprint", func() {
+		_, _, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+
+		name, reused, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeTrue())
+		Expect(name).To(Equal("foo-1.0-mark-test-bundle.tar.xz"))
+	})
+
+	It("regenerates the bundle with a different fingerprint", func() {
+		_, _, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+
+		name, reused, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint2, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeFalse())
+
+		fingerprint, err := ReadBundleFingerprint(filepath.Join(downloadDir, name))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fingerprint).To(Equal(fingerprint2))
+	})
+
+	It("reuses the bundle of another version with the same fingerprint", func() {
+		oldName, _, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+
+		name, reused, err := ext.BuildTestBundle(atom, version("1.1"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeTrue())
+		Expect(name).To(Equal("foo-1.1-mark-test-bundle.tar.xz"))
+
+		oldMd5, err := helpers.GetFileMd5(filepath.Join(downloadDir, oldName))
+		Expect(err).ToNot(HaveOccurred())
+		newMd5, err := helpers.GetFileMd5(filepath.Join(downloadDir, name))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(newMd5).To(Equal(oldMd5))
+	})
+
+	It("reuses the bundle available on the fetcher backend", func() {
+		storeDir := GinkgoT().TempDir()
+		_, _, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Move the bundle on the backend with an empty download dir.
+		Expect(os.Rename(filepath.Join(downloadDir, "foo-1.0-mark-test-bundle.tar.xz"),
+			filepath.Join(storeDir, "foo-1.0-mark-test-bundle.tar.xz"))).To(Succeed())
+		Expect(os.RemoveAll(filepath.Join(downloadDir, BundleIndexDir))).To(Succeed())
+		ext.SetBundleStore(&fakeBundleStore{Dir: storeDir, DownloadDir: downloadDir})
+
+		name, reused, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeTrue())
+		Expect(filepath.Join(downloadDir, name)).To(BeAnExistingFile())
+	})
+
+	It("never reuses the bundles without fingerprint", func() {
+		_, _, err := ext.BuildTestBundle(atom, version("1.0"), "", files)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, reused, err := ext.BuildTestBundle(atom, version("1.0"), "", files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeFalse())
+	})
+
+	It("names the bundle with the fingerprint with fingerprint_name", func() {
+		ext.Opts["fingerprint_name"] = "true"
+
+		name, _, err := ext.BuildTestBundle(atom, version("1.0"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(Equal("foo-mark-test-bundle-1111111111111111.tar.xz"))
+
+		name, reused, err := ext.BuildTestBundle(atom, version("1.1"), fingerprint1, files)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeTrue())
+		Expect(name).To(Equal("foo-mark-test-bundle-1111111111111111.tar.xz"))
+	})
+})
@@ -0,0 +1,346 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	autogenart "github.com/macaroni-os/mark-devkit/pkg/autogen/artefacts"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+type ExtensionComposer struct {
+	*ExtensionBase
+}
+
+type ComposerLock struct {
+	Packages    []*ComposerPackage `json:"packages"`
+	PackagesDev []*ComposerPackage `json:"packages-dev,omitempty"`
+}
+
+type ComposerPackage struct {
+	Name    string               `json:"name"`
+	Version string               `json:"version"`
+	Dist    *ComposerPackageDist `json:"dist,omitempty"`
+}
+
+type ComposerPackageDist struct {
+	Type      string `json:"type"`
+	Url       string `json:"url"`
+	Reference string `json:"reference,omitempty"`
+	Shasum    string `json:"shasum,omitempty"`
+}
+
+func NewComposerLock(data []byte) (*ComposerLock, error) {
+	ans := &ComposerLock{}
+	if err := json.Unmarshal(data, ans); err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
+
+// GetPackages returns the locked packages with the dev
+// packages if noDev is false.
+func (l *ComposerLock) GetPackages(noDev bool) []*ComposerPackage {
+	ans := append([]*ComposerPackage{}, l.Packages...)
+	if !noDev {
+		ans = append(ans, l.PackagesDev...)
+	}
+	return ans
+}
+
+// GetBundleFiles returns the names of the files of the bundle.
+func (l *ComposerLock) GetBundleFiles(noDev bool) []string {
+	ans := []string{}
+	for _, pkg := range l.GetPackages(noDev) {
+		if pkg.Dist == nil || pkg.Dist.Url == "" || pkg.Dist.Type == "path" {
+			continue
+		}
+		ans = append(ans, pkg.GetBundleName())
+	}
+	return ans
+}
+
+// Bytes returns the packages of the lock used to calculate
+// the fingerprint of the bundle.
+func (l *ComposerLock) Bytes() []byte {
+	rows := []string{}
+	for _, pkg := range l.GetPackages(false) {
+		row := pkg.Name + " " + pkg.Version
+		if pkg.Dist != nil {
+			row += fmt.Sprintf(" %s %s %s %s", pkg.Dist.Type, pkg.Dist.Url,
+				pkg.Dist.Reference, pkg.Dist.Shasum)
+		}
+		rows = append(rows, row)
+	}
+	sort.Strings(rows)
+	return []byte(strings.Join(rows, "\n"))
+}
+
+// GetBundleName returns the name of the file of the package
+// in the bundle. For example: symfony/console 6.4.1 => symfony-console-6.4.1.zip
+func (p *ComposerPackage) GetBundleName() string {
+	distType := "zip"
+	if p.Dist != nil && p.Dist.Type != "" {
+		distType = p.Dist.Type
+	}
+	return fmt.Sprintf("%s-%s.%s",
+		strings.ReplaceAll(p.Name, "/", "-"), p.Version, distType)
+}
+
+// GetUrl returns the url of the dist of the package. If the
+// repository is defined it's used as template with the
+// same placeholders of the composer mirrors:
+// %package%, %version%, %reference%, %type%.
+func (p *ComposerPackage) GetUrl(repository string) string {
+	if repository == "" {
+		return p.Dist.Url
+	}
+	r := strings.NewReplacer(
+		"%package%", p.Name,
+		"%version%", p.Version,
+		"%reference%", p.Dist.Reference,
+		"%type%", p.Dist.Type,
+	)
+	return r.Replace(repository)
+}
+
+func NewExtensionComposer(opts map[string]string) (*ExtensionComposer, error) {
+	return &ExtensionComposer{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		}}, nil
+}
+
+func (e *ExtensionComposer) GetName() string { return specs.ExtensionComposer }
+
+func (e *ExtensionComposer) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	values := *mapref
+
+	w, err := e.prepareBundleWorkspace(restGuard, atom, def, mapref, "composer")
+	if err != nil || w == nil {
+		return err
+	}
+
+	// Retrieve composer.lock
+	pkgUnpackDir, composerLock, err := e.retrieveComposerLock(atom, w.UnpackDir)
+	if err != nil {
+		return err
+	}
+	values["pkg_basedir"] = filepath.Base(pkgUnpackDir)
+
+	fingerprint, err := e.getFingerprint(specs.ExtensionComposer, composerLock.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var bundleFiles []string
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt != nil {
+		bundleFiles = composerLock.GetBundleFiles(e.Opts["no_dev"] == "true")
+	} else {
+		// Download bundle files
+		bundleFiles, err = e.downloadBundles(restGuard, atom, composerLock, w.BundlesDir())
+		if err != nil {
+			return err
+		}
+
+		// Create bundle tarball
+		bundleArt, err = e.createBundleTarball(w, atom, bundleTarball)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Values used by the templates to configure an
+	// artifact repository for the offline installation.
+	values["composer_bundle_dir"] = w.BundleDirName
+	values["composer_bundle_files"] = bundleFiles
+
+	e.completeBundle(w, mapref, bundleArt)
+
+	return nil
+}
+
+func (e *ExtensionComposer) downloadBundles(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, composerLock *ComposerLock,
+	bundlesDir string) ([]string, error) {
+	log := logger.GetDefaultLogger()
+	ans := []string{}
+
+	// Create bundle dir
+	err := os.MkdirAll(bundlesDir, os.ModePerm)
+	if err != nil {
+		return ans, err
+	}
+
+	repository := e.Opts["repository"]
+
+	for _, pkg := range composerLock.GetPackages(e.Opts["no_dev"] == "true") {
+		if pkg.Dist == nil || pkg.Dist.Url == "" {
+			log.Warning(fmt.Sprintf(
+				"[%s] Package %s %s without dist. Skipped.",
+				atom.Name, pkg.Name, pkg.Version))
+			continue
+		}
+		if pkg.Dist.Type == "path" {
+			continue
+		}
+
+		url := pkg.GetUrl(repository)
+		bundle := pkg.GetBundleName()
+
+		log.Debug(fmt.Sprintf("[%s] Downloading bundle %s %s at %s...",
+			atom.Name, pkg.Name, pkg.Version, url))
+
+		_, err = autogenart.DownloadArtefact(
+			restGuard, atom, url,
+			bundle, bundlesDir)
+		if err != nil {
+			return ans, err
+		}
+
+		// NOTE: The dists of github are generated in realtime
+		//       and packagist doesn't supply the shasum. In this case
+		//       the commit of the zip comment is compared with the
+		//       reference of the lock.
+		if pkg.Dist.Shasum != "" {
+			err = e.checkShasum(filepath.Join(bundlesDir, bundle), pkg.Dist.Shasum)
+		} else {
+			var verified bool
+			verified, err = e.checkReference(filepath.Join(bundlesDir, bundle), pkg)
+			if err == nil && !verified {
+				log.Warning(fmt.Sprintf(
+					"[%s] No shasum available for %s %s and reference not verifiable. Check skipped.",
+					atom.Name, pkg.Name, pkg.Version))
+			}
+		}
+		if err != nil {
+			return ans, err
+		}
+
+		ans = append(ans, bundle)
+	}
+
+	return ans, nil
+}
+
+// checkReference compares the reference of the package with the commit
+// stored by git archive in the comment of the zip dists.
+// It returns false if the dist doesn't contain the commit.
+func (e *ExtensionComposer) checkReference(file string, pkg *ComposerPackage) (bool, error) {
+	if pkg.Dist.Type != "zip" || pkg.Dist.Reference == "" {
+		return false, nil
+	}
+
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return false, fmt.Errorf("error on open dist %s: %s",
+			filepath.Base(file), err.Error())
+	}
+	defer r.Close()
+
+	commit := strings.TrimSpace(r.Comment)
+	if commit == "" {
+		return false, nil
+	}
+	if !strings.EqualFold(commit, pkg.Dist.Reference) {
+		return false, fmt.Errorf("reference mismatch for %s: %s != %s",
+			filepath.Base(file), commit, pkg.Dist.Reference)
+	}
+
+	return true, nil
+}
+
+func (e *ExtensionComposer) checkShasum(file, shasum string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != strings.ToLower(shasum) {
+		return fmt.Errorf("shasum mismatch for %s: %s != %s",
+			filepath.Base(file), sum, shasum)
+	}
+
+	return nil
+}
+
+func (e *ExtensionComposer) retrieveComposerLock(atom *specs.AutogenAtom,
+	targetDir string) (string, *ComposerLock, error) {
+
+	unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lockfile := e.Opts["lockfile"]
+	if lockfile == "" {
+		lockfile = "composer.lock"
+	}
+
+	lockPath := ""
+	pkgUnpackDir := ""
+
+	for _, entry := range entries {
+
+		if !entry.IsDir() {
+			continue
+		}
+
+		if unpackDirPrefix != "" && !strings.HasPrefix(entry.Name(), unpackDirPrefix) {
+			continue
+		}
+
+		lockPath = filepath.Join(targetDir, entry.Name(), lockfile)
+		if utils.Exists(lockPath) {
+			pkgUnpackDir = filepath.Join(targetDir, entry.Name())
+			break
+		}
+		lockPath = ""
+	}
+
+	if lockPath == "" {
+		return "", nil, fmt.Errorf("%s file not found", lockfile)
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error on read file %s: %s",
+			lockfile, err.Error())
+	}
+
+	composerLock, err := NewComposerLock(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("error on parse file %s: %s",
+			lockfile, err.Error())
+	}
+
+	return pkgUnpackDir, composerLock, nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const composerLock = `{
+  "packages": [
+    {"name": "symfony/console", "version": "v6.4.1",
+     "dist": {"type": "zip", "url": "https://api.github.com/repos/symfony/console/zipball/abc",
+              "reference": "abc", "shasum": ""}},
+    {"name": "acme/local", "version": "dev-main",
+     "dist": {"type": "path", "url": "../local"}},
+    {"name": "acme/meta", "version": "1.0.0"}
+  ],
+  "packages-dev": [
+    {"name": "phpunit/phpunit", "version": "10.5.0",
+     "dist": {"type": "tar", "url": "https://example.org/phpunit-10.5.0.tar",
+              "reference": "def"}}
+  ]
+}`
+
+var _ = Describe("Composer", func() {
+
+	lock, err := NewComposerLock([]byte(composerLock))
+
+	It("parses the lock file", func() {
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lock.Packages).To(HaveLen(3))
+		Expect(lock.PackagesDev).To(HaveLen(1))
+
+		_, err := NewComposerLock([]byte("{"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("GetBundleFiles",
+		func(noDev bool, expected []string) {
+			Expect(lock.GetBundleFiles(noDev)).To(Equal(expected))
+		},
+		Entry("with the dev packages", false, []string{
+			"symfony-console-v6.4.1.zip", "phpunit-phpunit-10.5.0.tar",
+		}),
+		Entry("without the dev packages", true, []string{
+			"symfony-console-v6.4.1.zip",
+		}),
+	)
+
+	DescribeTable("GetBundleName",
+		func(pkg *ComposerPackage, expected string) {
+			Expect(pkg.GetBundleName()).To(Equal(expected))
+		},
+		Entry("zip dist", &ComposerPackage{Name: "symfony/console", Version: "6.4.1",
+			Dist: &ComposerPackageDist{Type: "zip"}}, "symfony-console-6.4.1.zip"),
+		Entry("tar dist", &ComposerPackage{Name: "acme/foo", Version: "1.0.0",
+			Dist: &ComposerPackageDist{Type: "tar"}}, "acme-foo-1.0.0.tar"),
+		Entry("without dist type", &ComposerPackage{Name: "acme/foo", Version: "1.0.0",
+			Dist: &ComposerPackageDist{}}, "acme-foo-1.0.0.zip"),
+		Entry("without dist", &ComposerPackage{Name: "acme/foo", Version: "1.0.0"},
+			"acme-foo-1.0.0.zip"),
+	)
+
+	DescribeTable("GetUrl",
+		func(repository, expected string) {
+			pkg := &ComposerPackage{Name: "symfony/console", Version: "v6.4.1",
+				Dist: &ComposerPackageDist{Type: "zip", Reference: "abc",
+					Url: "https://api.github.com/repos/symfony/console/zipball/abc"}}
+			Expect(pkg.GetUrl(repository)).To(Equal(expected))
+		},
+		Entry("url of the lock", "",
+			"https://api.github.com/repos/symfony/console/zipball/abc"),
+		Entry("mirror", "https://mirror.example.org/dists/%package%/%version%/%reference%.%type%",
+			"https://mirror.example.org/dists/symfony/console/v6.4.1/abc.zip"),
+	)
+
+	Context("checkReference", func() {
+		ext := &ExtensionComposer{ExtensionBase: &ExtensionBase{}}
+
+		writeZip := func(comment string) string {
+			file := filepath.Join(GinkgoT().TempDir(), "dist.zip")
+			f, err := os.Create(file)
+			Expect(err).ToNot(HaveOccurred())
+			defer f.Close()
+			w := zip.NewWriter(f)
+			Expect(w.SetComment(comment)).To(Succeed())
+			Expect(w.Close()).To(Succeed())
+			return file
+		}
+
+		DescribeTable("compares the commit of the zip comment",
+			func(comment, distType, reference string, checked, valid bool) {
+				pkg := &ComposerPackage{Name: "acme/foo", Version: "1.0.0",
+					Dist: &ComposerPackageDist{Type: distType, Reference: reference}}
+				ok, err := ext.CheckReference(writeZip(comment), pkg)
+				if valid {
+					Expect(err).ToNot(HaveOccurred())
+				} else {
+					Expect(err).To(HaveOccurred())
+				}
+				Expect(ok).To(Equal(checked))
+			},
+			Entry("same commit", "ABC123\n", "zip", "abc123", true, true),
+			Entry("different commit", "def456", "zip", "abc123", false, false),
+			Entry("zip without comment", "", "zip", "abc123", false, true),
+			Entry("dist without reference", "abc123", "zip", "", false, true),
+			Entry("tar dist", "abc123", "tar", "abc123", false, true),
+		)
+	})
+})
+
+var _ = Describe("Nuget", func() {
+
+	DescribeTable("GetBundleName and GetUrl",
+		func(id, version, repository, bundle, url string) {
+			pkg := &NugetPackage{Id: id, Version: version}
+			Expect(pkg.GetBundleName()).To(Equal(bundle))
+			Expect(pkg.GetUrl(repository)).To(Equal(url))
+		},
+		Entry("lower case names", "Newtonsoft.Json", "13.0.3",
+			"https://api.nuget.org/v3-flatcontainer",
+			"newtonsoft.json.13.0.3.nupkg",
+			"https://api.nuget.org/v3-flatcontainer/newtonsoft.json/13.0.3/newtonsoft.json.13.0.3.nupkg"),
+		Entry("prerelease version and repository with slash", "Foo", "1.0.0-Beta1",
+			"https://nuget.example.org/flat/",
+			"foo.1.0.0-beta1.nupkg",
+			"https://nuget.example.org/flat/foo/1.0.0-beta1/foo.1.0.0-beta1.nupkg"),
+	)
+
+	It("fails with an invalid lock file", func() {
+		_, err := NewNugetLock([]byte("["))
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("retrieveNugetPackages", func() {
+		var targetDir string
+		ext := &ExtensionNuget{ExtensionBase: &ExtensionBase{Opts: map[string]string{}}}
+		atom := &specs.AutogenAtom{Name: "foo"}
+
+		writeFile := func(name, content string) {
+			f := filepath.Join(targetDir, name)
+			Expect(os.MkdirAll(filepath.Dir(f), 0755)).To(Succeed())
+			Expect(os.WriteFile(f, []byte(content), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			targetDir = GinkgoT().TempDir()
+		})
+
+		It("collects the packages of all the projects", func() {
+			writeFile("foo-1.0/src/App/packages.lock.json", `{
+  "version": 1,
+  "dependencies": {
+    "net8.0": {
+      "Newtonsoft.Json": {"type": "Direct", "resolved": "13.0.3", "contentHash": "aaa"},
+      "Lib": {"type": "Project"}
+    },
+    "net6.0": {
+      "Newtonsoft.Json": {"type": "Direct", "resolved": "13.0.3", "contentHash": "aaa"}
+    }
+  }
+}`)
+			writeFile("foo-1.0/src/Lib/packages.lock.json", `{
+  "version": 1,
+  "dependencies": {
+    "net8.0": {
+      "newtonsoft.json": {"type": "Transitive", "resolved": "13.0.3", "contentHash": "aaa"},
+      "Serilog": {"type": "Direct", "resolved": "3.1.1", "contentHash": "bbb"},
+      "Central": {"type": "CentralTransitive", "requested": "[1.0.0, )"}
+    }
+  }
+}`)
+
+			dir, pkgs, err := ext.RetrieveNugetPackages(atom, targetDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dir).To(Equal(filepath.Join(targetDir, "foo-1.0")))
+			Expect(pkgs).To(HaveLen(2))
+			Expect(pkgs[0]).To(Equal(&NugetPackage{Id: pkgs[0].Id, Version: "13.0.3", ContentHash: "aaa"}))
+			Expect(pkgs[0].Id).To(Or(Equal("Newtonsoft.Json"), Equal("newtonsoft.json")))
+			Expect(pkgs[1]).To(Equal(&NugetPackage{Id: "Serilog", Version: "3.1.1", ContentHash: "bbb"}))
+		})
+
+		It("fails without lock files", func() {
+			writeFile("foo-1.0/Foo.sln", "")
+			_, _, err := ext.RetrieveNugetPackages(atom, targetDir)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
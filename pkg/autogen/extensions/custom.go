@@ -88,6 +88,8 @@ func (e *ExtensionCustom) Elaborate(restGuard *guard.RestGuard,
 	values["download_dir"] = downloadDir
 	values["specfile"] = e.Opts["specfile"]
 	values["mirror"] = e.Opts["mirror"]
+	// The sources shared with the other extensions of the pipeline.
+	values["source_dir"] = e.Opts["source_dir"]
 
 	if values["mirror"] == "" {
 		values["mirror"] = "mirror://macaroni"
@@ -0,0 +1,93 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+)
+
+// BuildTestBundle creates the bundle of the atom with the files in input
+// as done by the lock extensions. It returns the name of the bundle and
+// true if an existing bundle with the same fingerprint is reused.
+func (e *ExtensionBase) BuildTestBundle(atom *specs.AutogenAtom,
+	values map[string]interface{}, fingerprint string,
+	files map[string]string) (string, bool, error) {
+
+	w := &bundleWorkspace{
+		ExtensionDir:     "test-extension",
+		WorkDir:          e.Opts["workdir"],
+		DownloadDir:      e.Opts["download_dir"],
+		Mirror:           "mirror://macaroni",
+		BundleIdentifier: "mark-test-bundle",
+		BundleExtension:  "xz",
+	}
+	w.BundleDirName = w.BundleIdentifier + "-" + atom.Name
+	w.PkgWorkDir = filepath.Join(w.WorkDir, w.ExtensionDir, atom.Name)
+
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt != nil {
+		return bundleTarball, true, nil
+	}
+
+	os.RemoveAll(w.BundlesDir())
+	for name, content := range files {
+		file := filepath.Join(w.BundlesDir(), name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return "", false, err
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return "", false, err
+		}
+	}
+
+	_, err := e.createBundleTarball(w, atom, bundleTarball)
+	return bundleTarball, false, err
+}
+
+// CreateGoModuleZip exposes the creation of the module zips to the tests.
+func CreateGoModuleZip(modDir, prefix, target string) error {
+	return createGoModuleZip(modDir, modDir, prefix, target)
+}
+
+// IsGoVendoredPackage exposes the rule of the vendored packages to the tests.
+func IsGoVendoredPackage(name, vers string) bool {
+	return isGoVendoredPackage(name, vers)
+}
+
+// DownloadBundles exposes the download of the go modules to the tests.
+func (e *ExtensionGolang) DownloadBundles(atom *specs.AutogenAtom,
+	goSum *GoSum, bundlesDir string) error {
+	restGuard, err := guard.NewRestGuard(specs.NewMarkDevkitConfig(nil).GetRest())
+	if err != nil {
+		return err
+	}
+	return e.downloadBundles(restGuard, atom, goSum, NewBundleLicenses(),
+		bundlesDir, filepath.Join(bundlesDir, "git"))
+}
+
+// CreateReproducibleTarball exposes the creation of the bundle
+// tarballs to the tests.
+func (e *ExtensionBase) CreateReproducibleTarball(archiveDirs []string,
+	baseDir, tarball string) error {
+	return e.createReproducibleTarball(archiveDirs, baseDir, tarball)
+}
+
+// CheckReference exposes the check of the commit of the composer
+// dists to the tests.
+func (e *ExtensionComposer) CheckReference(file string, pkg *ComposerPackage) (bool, error) {
+	return e.checkReference(file, pkg)
+}
+
+// RetrieveNugetPackages exposes the collection of the packages of
+// the lock files of the solution to the tests.
+func (e *ExtensionNuget) RetrieveNugetPackages(atom *specs.AutogenAtom,
+	targetDir string) (string, []*NugetPackage, error) {
+	return e.retrieveNugetPackages(atom, targetDir)
+}
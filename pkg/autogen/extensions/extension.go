@@ -7,6 +7,7 @@ package extensions
 import (
 	"fmt"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/plugins"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/geaaru/rest-guard/pkg/guard"
@@ -17,6 +18,7 @@ type Extension interface {
 		atom, def *specs.AutogenAtom,
 		mapref *map[string]interface{}) error
 	GetName() string
+	SetBundleStore(s BundleStore)
 }
 
 func NewExtension(t string, opts map[string]string) (Extension, error) {
@@ -29,7 +31,23 @@ func NewExtension(t string, opts map[string]string) (Extension, error) {
 		return NewExtensionRust(opts)
 	case specs.ExtensionGitSubmodules:
 		return NewExtensionGitSubmodules(opts)
+	case specs.ExtensionGitSnapshot:
+		return NewExtensionGitSnapshot(opts)
+	case specs.ExtensionPatch:
+		return NewExtensionPatch(opts)
+	case specs.ExtensionNode:
+		return NewExtensionNode(opts)
+	case specs.ExtensionPython:
+		return NewExtensionPython(opts)
+	case specs.ExtensionComposer:
+		return NewExtensionComposer(opts)
+	case specs.ExtensionNuget:
+		return NewExtensionNuget(opts)
 	default:
+		// Search an external plugin for the extension.
+		if _, err := plugins.FindPlugin(plugins.KindExtension, t); err == nil {
+			return NewExtensionPlugin(t, opts)
+		}
 		return nil, fmt.Errorf("Invalid extension %s", t)
 	}
 }
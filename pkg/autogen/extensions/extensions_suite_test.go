@@ -0,0 +1,21 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"testing"
+
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestExtensions(t *testing.T) {
+	RegisterFailHandler(Fail)
+	logger.NewMarkDevkitLogger(specs.NewMarkDevkitConfig(nil)).SetAsDefault()
+	RunSpecs(t, "Extensions Suite")
+}
@@ -0,0 +1,269 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	// The name of the file with the fingerprint stored inside the bundles.
+	BundleFingerprintFile = "mark-bundle-fingerprint"
+	// The directory of the download dir with the fingerprint index.
+	BundleIndexDir = ".mark-bundles-index"
+)
+
+// BundleStore permits to check if a bundle is already available
+// in the download directory or in the backend of the fetcher.
+type BundleStore interface {
+	HasFile(name string) bool
+	// GetFile downloads the file in the download directory.
+	GetFile(name string) error
+}
+
+// The options that don't change the content of the bundles.
+var fingerprintSkippedOpts = map[string]bool{
+	"workdir":      true,
+	"download_dir": true,
+	"specfile":     true,
+	"files_dir":    true,
+	"mirror":       true,
+	"source_dir":   true,
+}
+
+func (e *ExtensionBase) SetBundleStore(s BundleStore) { e.Store = s }
+
+// getFingerprint returns the sha256 of the lock inputs and of the
+// options of the extension. It returns an empty string if the
+// option fingerprint is false.
+func (e *ExtensionBase) getFingerprint(extension string, inputs ...[]byte) (string, error) {
+	if e.Opts["fingerprint"] == "false" {
+		return "", nil
+	}
+
+	mtime, err := e.getSourceDateEpoch()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "extension %s\n", extension)
+	fmt.Fprintf(h, "source_date_epoch %d\n", mtime.Unix())
+
+	keys := []string{}
+	for k := range e.Opts {
+		if _, skip := fingerprintSkippedOpts[k]; !skip {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "opt %s=%s\n", k, e.Opts[k])
+	}
+
+	for _, in := range inputs {
+		fmt.Fprintf(h, "input %d\n", len(in))
+		h.Write(in)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// getBundleTarballName returns the name of the bundle tarball. Without
+// fingerprint the name contains the version of the package. With the
+// fingerprint the name doesn't contain the version in order to reuse
+// the same bundle between versions with the same lock.
+func (e *ExtensionBase) getBundleTarballName(atom *specs.AutogenAtom,
+	values map[string]interface{},
+	bundleIdentifier, bundleExtension, fingerprint string) string {
+
+	if fingerprint != "" {
+		return fmt.Sprintf("%s-%s-%s.tar.%s", atom.Name, bundleIdentifier,
+			fingerprint[0:16], bundleExtension)
+	}
+
+	version, _ := values["version"].(string)
+	sha, _ := values["sha"].(string)
+	if sha == "" {
+		return fmt.Sprintf(
+			"%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier, bundleExtension,
+		)
+	}
+	return fmt.Sprintf(
+		"%s-%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier,
+		sha[0:7], bundleExtension,
+	)
+}
+
+// lookupBundle returns the artefact of the bundle if it's already
+// available in the download directory or in the fetcher backend.
+// The bundles of the backend are downloaded in the download directory.
+func (e *ExtensionBase) lookupBundle(atom *specs.AutogenAtom,
+	downloadDir, bundleTarball, fingerprint, mirror string) *specs.AutogenArtefact {
+
+	if fingerprint == "" || !e.fetchBundle(atom, downloadDir, bundleTarball) {
+		return nil
+	}
+
+	logger.GetDefaultLogger().Info(
+		fmt.Sprintf(":brain:[%s] Reusing bundle %s with fingerprint %s.",
+			atom.Name, bundleTarball, fingerprint[0:16]))
+
+	return newBundleArtefact(bundleTarball, mirror)
+}
+
+// lookupVersionedBundle returns the artefact of the bundle named with
+// the version if a bundle with the same fingerprint is available.
+// The fingerprint is stored inside the bundles. The bundles of the
+// other versions with the same fingerprint are retrieved through the
+// fingerprint index of the download directory and copied with the
+// name of the version.
+func (e *ExtensionBase) lookupVersionedBundle(atom *specs.AutogenAtom,
+	downloadDir, bundleTarball, fingerprint, mirror string) *specs.AutogenArtefact {
+	log := logger.GetDefaultLogger()
+
+	if fingerprint == "" {
+		return nil
+	}
+
+	candidates := []string{bundleTarball}
+	if indexed := readBundleIndex(downloadDir, fingerprint); indexed != "" &&
+		indexed != bundleTarball {
+		candidates = append(candidates, indexed)
+	}
+
+	for _, candidate := range candidates {
+		if !e.fetchBundle(atom, downloadDir, candidate) {
+			continue
+		}
+
+		bundleFingerprint, err := ReadBundleFingerprint(
+			filepath.Join(downloadDir, candidate))
+		if err != nil || bundleFingerprint != fingerprint {
+			log.Debug(fmt.Sprintf(
+				"[%s] Bundle %s with a different fingerprint.",
+				atom.Name, candidate))
+			continue
+		}
+
+		if candidate != bundleTarball {
+			err = helpers.CopyFile(filepath.Join(downloadDir, candidate),
+				filepath.Join(downloadDir, bundleTarball))
+			if err != nil {
+				log.Warning(fmt.Sprintf("[%s] Error on copy bundle %s: %s",
+					atom.Name, candidate, err.Error()))
+				continue
+			}
+		}
+
+		log.Info(
+			fmt.Sprintf(":brain:[%s] Reusing bundle %s with fingerprint %s.",
+				atom.Name, candidate, fingerprint[0:16]))
+
+		writeBundleIndex(downloadDir, fingerprint, bundleTarball)
+
+		return newBundleArtefact(bundleTarball, mirror)
+	}
+
+	return nil
+}
+
+// fetchBundle checks if the bundle is available in the download
+// directory and downloads it from the fetcher backend if needed.
+func (e *ExtensionBase) fetchBundle(atom *specs.AutogenAtom,
+	downloadDir, bundleTarball string) bool {
+
+	if utils.Exists(filepath.Join(downloadDir, bundleTarball)) {
+		return true
+	}
+
+	if e.Store == nil || !e.Store.HasFile(bundleTarball) {
+		return false
+	}
+
+	// The local artefacts must be available in the download dir.
+	if err := e.Store.GetFile(bundleTarball); err != nil {
+		logger.GetDefaultLogger().Warning(
+			fmt.Sprintf("[%s] Error on download bundle %s: %s",
+				atom.Name, bundleTarball, err.Error()))
+		return false
+	}
+
+	return true
+}
+
+func newBundleArtefact(bundleTarball, mirror string) *specs.AutogenArtefact {
+	local := true
+	return &specs.AutogenArtefact{
+		SrcUri: []string{fmt.Sprintf("%s/%s", mirror, bundleTarball)},
+		Name:   bundleTarball,
+		Local:  &local,
+	}
+}
+
+// ReadBundleFingerprint returns the fingerprint stored inside a bundle tarball.
+func ReadBundleFingerprint(tarball string) (string, error) {
+	data, err := readBundleFile(tarball, BundleFingerprintFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readBundleIndex returns the name of the last bundle created
+// with the fingerprint in input.
+func readBundleIndex(downloadDir, fingerprint string) string {
+	data, err := os.ReadFile(filepath.Join(downloadDir, BundleIndexDir, fingerprint))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// writeBundleIndex stores the name of the bundle with the fingerprint
+// in the fingerprint index of the download directory.
+func writeBundleIndex(downloadDir, fingerprint, bundleTarball string) {
+	indexDir := filepath.Join(downloadDir, BundleIndexDir)
+	err := os.MkdirAll(indexDir, os.ModePerm)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(indexDir, fingerprint),
+			[]byte(bundleTarball+"\n"), 0644)
+	}
+	if err != nil {
+		logger.GetDefaultLogger().Warning(
+			fmt.Sprintf("Error on update the bundles index for %s: %s",
+				bundleTarball, err.Error()))
+	}
+}
+
+// getBundleLicenses returns the licenses stored inside a bundle
+// reused. The bundle is downloaded from the fetcher backend
+// if it's not available in the download directory.
+func (e *ExtensionBase) getBundleLicenses(downloadDir, bundleTarball string) (string, error) {
+	tarball := filepath.Join(downloadDir, bundleTarball)
+	if !utils.Exists(tarball) {
+		if e.Store == nil {
+			return "", fmt.Errorf("bundle %s not available", bundleTarball)
+		}
+		if err := e.Store.GetFile(bundleTarball); err != nil {
+			return "", fmt.Errorf("error on download bundle %s: %s",
+				bundleTarball, err.Error())
+		}
+	}
+
+	return ReadBundleLicenses(tarball)
+}
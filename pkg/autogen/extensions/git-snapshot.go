@@ -0,0 +1,384 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+var gitShaRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ExtensionGitSnapshot creates the tarball of the sources from
+// a clone of the git repository in order to avoid the archives
+// of the forges that are not stable or not available.
+type ExtensionGitSnapshot struct {
+	*ExtensionBase
+}
+
+func NewExtensionGitSnapshot(opts map[string]string) (*ExtensionGitSnapshot, error) {
+	return &ExtensionGitSnapshot{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		}}, nil
+}
+
+func (e *ExtensionGitSnapshot) GetName() string { return specs.ExtensionGitSnapshot }
+
+func (e *ExtensionGitSnapshot) renderOpt(opt, defaultValue string,
+	values map[string]interface{}) (string, error) {
+	v := e.Opts[opt]
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	return helpers.RenderContentWithTemplates(
+		v, "", "", "git-snapshot."+opt, values, []string{},
+	)
+}
+
+func (e *ExtensionGitSnapshot) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	log := logger.GetDefaultLogger()
+	values := *mapref
+
+	downloadDir := e.Opts["download_dir"]
+	if !filepath.IsAbs(downloadDir) {
+		downloadDir, _ = filepath.Abs(downloadDir)
+	}
+	// Create the temporary directory of the package
+	workdir, _ := e.Opts["workdir"]
+	if !filepath.IsAbs(workdir) {
+		workdir, _ = filepath.Abs(workdir)
+	}
+	version, _ := values["version"].(string)
+	pn, _ := values["pn"].(string)
+	if pn == "" {
+		pn = atom.Name
+	}
+
+	cloneDir := filepath.Join(workdir, "gitsnapshot-extension", atom.Name)
+
+	mirror := e.Opts["mirror"]
+	if mirror == "" {
+		mirror = "mirror://macaroni"
+	}
+	bundleIdentifier := e.Opts["bundle_identifier"]
+	if bundleIdentifier == "" {
+		bundleIdentifier = "mark-git-snapshot"
+	}
+	bundleExtension := e.Opts["bundle_extension"]
+	if bundleExtension == "" {
+		bundleExtension = "xz"
+	}
+	// Permit both xz and .xz values.
+	bundleExtension = strings.ReplaceAll(bundleExtension, ".", "")
+	values["mirror"] = mirror
+
+	// The repository is defined by the generators (github, gitlab
+	// and forgejo) or by the option repo for the other generators.
+	gitRepo, _ := values["git_repo"].(string)
+	gitRepo, err := e.renderOpt("repo", gitRepo, values)
+	if err != nil {
+		return err
+	}
+	if gitRepo == "" {
+		return fmt.Errorf("No git repository defined. Set the option repo.")
+	}
+
+	// The revision to clone: the sha of the generator, the option
+	// ref or the original version as tag.
+	ref, _ := values["sha"].(string)
+	if ref == "" {
+		ref, _ = values["original_version"].(string)
+		if ref == "" {
+			ref = version
+		}
+	}
+	ref, err = e.renderOpt("ref", ref, values)
+	if err != nil {
+		return err
+	}
+
+	basedir := fmt.Sprintf("%s-%s", pn, version)
+	if _, present := values["pkg_basedir"].(string); present {
+		basedir, err = helpers.RenderContentWithTemplates(
+			values["pkg_basedir"].(string),
+			"", "", "pkg_basedir", values, []string{},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	basedir, err = e.renderOpt("prefix", basedir, values)
+	if err != nil {
+		return err
+	}
+
+	// With the sha already available the snapshot could be reused.
+	var snapshotArt *specs.AutogenArtefact
+	if gitShaRegex.MatchString(ref) {
+		values["sha"] = ref
+
+		snapshotTarball, err := e.getSnapshotTarballName(atom, values,
+			bundleIdentifier, bundleExtension, ref)
+		if err != nil {
+			return err
+		}
+		fingerprint, err := e.getFingerprint(specs.ExtensionGitSnapshot,
+			[]byte(gitRepo), []byte(ref), []byte(basedir))
+		if err != nil {
+			return err
+		}
+		snapshotArt = e.lookupBundle(atom, downloadDir, snapshotTarball,
+			fingerprint, mirror)
+	}
+
+	if snapshotArt == nil {
+		snapshotDir := filepath.Join(cloneDir, basedir)
+
+		// Always start from a clean directory.
+		err = os.RemoveAll(cloneDir)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(cloneDir, os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		sha, err := e.cloneSnapshot(atom, gitRepo, ref, snapshotDir)
+		if err != nil {
+			return err
+		}
+		values["sha"] = sha
+
+		snapshotTarball, err := e.getSnapshotTarballName(atom, values,
+			bundleIdentifier, bundleExtension, sha)
+		if err != nil {
+			return err
+		}
+
+		log.Info(fmt.Sprintf(":factory:[%s] Creating snapshot %s of %s@%s...",
+			atom.Name, snapshotTarball, gitRepo, sha[0:12]))
+
+		err = e.createReproducibleTarball(
+			[]string{snapshotDir},
+			cloneDir,
+			filepath.Join(downloadDir, snapshotTarball))
+		if err != nil {
+			return fmt.Errorf(
+				"error on create tarball %s: %s",
+				snapshotTarball, err.Error())
+		}
+
+		local := true
+		snapshotArt = &specs.AutogenArtefact{
+			SrcUri: []string{fmt.Sprintf("%s/%s", mirror, snapshotTarball)},
+			Name:   snapshotTarball,
+			Local:  &local,
+		}
+	}
+
+	// The snapshot replaces the upstream tarballs and it's always
+	// the first artefact in order to be used by the other extensions.
+	artefacts := []*specs.AutogenArtefact{snapshotArt}
+	if e.Opts["keep_artefacts"] == "true" {
+		upstreamArtefacts, _ := values["artefacts"].([]*specs.AutogenArtefact)
+		artefacts = append(artefacts, upstreamArtefacts...)
+	}
+	values["artefacts"] = artefacts
+	values["git_snapshot_dir"] = basedir
+
+	e.cleanup(mapref)
+
+	if !logger.GetDefaultLogger().Config.GetGeneral().Debug {
+		defer os.RemoveAll(filepath.Join(workdir, "gitsnapshot-extension"))
+	}
+
+	return nil
+}
+
+// fetchRevision fetches only the last commit of the revision in input:
+// the sha, the tag or the branch.
+func (e *ExtensionGitSnapshot) fetchRevision(gitRepo, ref, snapshotDir string,
+	auth transport.AuthMethod) (*git.Repository, error) {
+
+	refSpecs := []config.RefSpec{}
+	if gitShaRegex.MatchString(ref) {
+		refSpecs = append(refSpecs, config.RefSpec(ref+":refs/heads/snapshot"))
+	} else {
+		refSpecs = append(refSpecs,
+			config.RefSpec(fmt.Sprintf("+refs/tags/%s:refs/tags/%s", ref, ref)),
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", ref, ref)),
+		)
+	}
+
+	r, err := git.PlainInit(snapshotDir, false)
+	if err != nil {
+		return nil, err
+	}
+	_, err = r.CreateRemote(&config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{gitRepo},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, refSpec := range refSpecs {
+		err = r.Fetch(&git.FetchOptions{
+			RemoteName: "origin",
+			RefSpecs:   []config.RefSpec{refSpec},
+			Depth:      1,
+			Tags:       git.NoTags,
+			Auth:       auth,
+		})
+		if err == nil || err == git.NoErrAlreadyUpToDate {
+			return r, nil
+		}
+	}
+
+	return nil, err
+}
+
+// getSnapshotTarballName returns the name of the snapshot tarball
+// defined by the template of the option tarball_name or the default
+// name with the version and the sha.
+func (e *ExtensionGitSnapshot) getSnapshotTarballName(atom *specs.AutogenAtom,
+	values map[string]interface{},
+	bundleIdentifier, bundleExtension, sha string) (string, error) {
+
+	version, _ := values["version"].(string)
+
+	return e.renderOpt("tarball_name", fmt.Sprintf(
+		"%s-%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier,
+		sha[0:7], bundleExtension,
+	), values)
+}
+
+// cloneSnapshot clones the repository in the snapshot directory at
+// the revision in input, with the submodules if enabled, and removes
+// the git metadata. It returns the sha of the revision.
+func (e *ExtensionGitSnapshot) cloneSnapshot(atom *specs.AutogenAtom,
+	gitRepo, ref, snapshotDir string) (string, error) {
+
+	log := logger.GetDefaultLogger()
+
+	log.DebugC(fmt.Sprintf(":factory:[%s] Cloning repo %s at %s...",
+		atom.Name, gitRepo, ref))
+
+	auth, err := log.Config.GetAuthentication().GetGitAuth(gitRepo, "")
+	if err != nil {
+		return "", err
+	}
+
+	r, err := e.fetchRevision(gitRepo, ref, snapshotDir, auth)
+	if err != nil {
+		// The servers could not permit the fetch of a sha.
+		log.Debug(fmt.Sprintf("[%s] Shallow fetch of %s at %s failed: %s",
+			atom.Name, gitRepo, ref, err.Error()))
+
+		err = os.RemoveAll(snapshotDir)
+		if err != nil {
+			return "", err
+		}
+
+		r, err = git.PlainClone(snapshotDir, false, &git.CloneOptions{
+			URL:        gitRepo,
+			RemoteName: "origin",
+			Tags:       git.AllTags,
+			Auth:       auth,
+		})
+		if err != nil {
+			return "", fmt.Errorf("error on clone %s: %s", gitRepo, err.Error())
+		}
+	}
+
+	var hash *plumbing.Hash
+	for _, rev := range []string{
+		ref,
+		"refs/tags/" + ref,
+		"refs/remotes/origin/" + ref,
+	} {
+		hash, err = r.ResolveRevision(plumbing.Revision(rev))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("error on resolve revision %s of %s: %s",
+			ref, gitRepo, err.Error())
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return "", err
+	}
+	err = w.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true})
+	if err != nil {
+		return "", fmt.Errorf("error on checkout %s of %s: %s",
+			hash.String(), gitRepo, err.Error())
+	}
+
+	if e.Opts["submodules"] == "true" {
+		// The submodules are initialized only after the checkout
+		// of the revision.
+		subs, err := w.Submodules()
+		if err != nil {
+			return "", err
+		}
+		subOpts := &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		}
+		if auth != nil {
+			subOpts.Auth = auth
+		}
+		err = subs.Update(subOpts)
+		if err != nil {
+			return "", fmt.Errorf("error on update submodules of %s: %s",
+				gitRepo, err.Error())
+		}
+	}
+
+	// Drop the git metadata of the repository and of the submodules.
+	err = filepath.WalkDir(snapshotDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() != ".git" {
+			return nil
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
@@ -18,9 +18,6 @@ import (
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/geaaru/rest-guard/pkg/guard"
-	executor "github.com/geaaru/tar-formers/pkg/executor"
-	tarf_specs "github.com/geaaru/tar-formers/pkg/specs"
-	"github.com/geaaru/tar-formers/pkg/tools"
 )
 
 type ExtensionGitSubmodules struct {
@@ -110,6 +107,7 @@ func (e *ExtensionGitSubmodules) Elaborate(restGuard *guard.RestGuard,
 	gitRepo, present := values["git_repo"].(string)
 
 	cloneOpts := &kit.CloneOptions{
+		Config: log.Config,
 		GitCloneOptions: &git.CloneOptions{
 			RemoteName:        "origin",
 			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
@@ -171,6 +169,30 @@ func (e *ExtensionGitSubmodules) Elaborate(restGuard *guard.RestGuard,
 	// Retrieve artefacts.
 	artefacts, _ := values["artefacts"].([]*specs.AutogenArtefact)
 
+	// Add the submodules to the sources shared with the next
+	// extensions in order to elaborate the complete tree.
+	if e.Opts["source_dir"] != "" && len(artefacts) > 0 {
+		sourceDir, err := e.prepareSourceTree(restGuard, atom, def, artefacts[0],
+			downloadDir, filepath.Join(cloneDir, "unpack"), mapref)
+		if err != nil {
+			return err
+		}
+		pkgSourceDir, err := e.getPkgSourceDir(sourceDir)
+		if err != nil {
+			return err
+		}
+
+		for _, sub := range cloneOpts.Submodules {
+			subStatus, _ := sub.Status()
+			err = copySourceTree(filepath.Join(pkgWorkDir, subStatus.Path),
+				filepath.Join(pkgSourceDir, subStatus.Path))
+			if err != nil {
+				return fmt.Errorf("error on copy submodule %s: %s",
+					subStatus.Path, err.Error())
+			}
+		}
+	}
+
 	artefacts = append(artefacts, bundleArt)
 	values["artefacts"] = artefacts
 
@@ -190,42 +212,14 @@ func (e *ExtensionGitSubmodules) createBundleTarball(
 	var err error
 	log := logger.GetDefaultLogger()
 
-	tarformers := executor.NewTarFormers(e.getTarformersConfig())
-	s := tarf_specs.NewSpecFile()
-	// We don't need to keep the original permission of the files
-	// and owner.
-	s.SameOwner = false
-	s.SameChtimes = false
-	s.Writer = tarf_specs.NewWriter()
-	s.Writer.ArchiveDirs = archiveDirs
-
-	s.RenamePath = []tarf_specs.RenameRule{
-		{
-			Source: cloneDir,
-			Dest:   ".",
-		},
-	}
-	tarfOpts := tools.NewTarCompressionOpts(true)
-	defer tarfOpts.Close()
-
-	err = tools.PrepareTarWriter(bundleTarball, tarfOpts)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"error on prepare writer: %s",
-			err.Error())
-	}
-
-	if tarfOpts.CompressWriter != nil {
-		tarformers.SetWriter(tarfOpts.CompressWriter)
-	} else {
-		tarformers.SetWriter(tarfOpts.FileWriter)
-	}
-
 	fileName := filepath.Base(bundleTarball)
 	log.Debug(fmt.Sprintf("[%s] Creating tarball %s...",
 		atomName, fileName))
 
-	err = tarformers.RunTaskWriter(s)
+	err = e.createReproducibleTarball(
+		archiveDirs,
+		cloneDir,
+		bundleTarball)
 	if err != nil {
 		return nil, fmt.Errorf(
 			"error on create tarball %s: %s",
@@ -0,0 +1,403 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"archive/zip"
+	"fmt"
+	"go/version"
+	"io"
+	"io/fs"
+	nurl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+	guard_specs "github.com/geaaru/rest-guard/pkg/specs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+var (
+	goImportMetaRegex  = regexp.MustCompile(`(?is)<meta\s+name=["']go-import["']\s+content=["']([^"']+)["']`)
+	goPseudoVersionRev = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+-(?:.*[.-])?[0-9]{14}-([0-9a-f]{12})$`)
+	goModGoDirective   = regexp.MustCompile(`(?m)^\s*go\s+([0-9][^\s/]*)`)
+)
+
+// Hosts with repositories in the format host/owner/repo.
+var goKnownHosts = []string{
+	"github.com",
+	"bitbucket.org",
+	"codeberg.org",
+}
+
+type goRepoRoot struct {
+	Root string
+	Url  string
+}
+
+// goDirectFetcher creates the module files from the git repositories
+// like GOPROXY=direct.
+type goDirectFetcher struct {
+	restGuard *guard.RestGuard
+	atom      *specs.AutogenAtom
+	cloneDir  string
+	// The map of the repositories already cloned.
+	repos map[string]*git.Repository
+	roots map[string]*goRepoRoot
+}
+
+func newGoDirectFetcher(restGuard *guard.RestGuard, atom *specs.AutogenAtom,
+	cloneDir string) *goDirectFetcher {
+	return &goDirectFetcher{
+		restGuard: restGuard,
+		atom:      atom,
+		cloneDir:  cloneDir,
+		repos:     make(map[string]*git.Repository, 0),
+		roots:     make(map[string]*goRepoRoot, 0),
+	}
+}
+
+// Fetch creates the file of the module (zip or mod) in the target path.
+func (f *goDirectFetcher) Fetch(modPath, version, ext, target string) error {
+	log := logger.GetDefaultLogger()
+
+	root, err := f.getRepoRoot(modPath)
+	if err != nil {
+		return err
+	}
+
+	subdir := strings.TrimPrefix(strings.TrimPrefix(modPath, root.Root), "/")
+	// The tags of the modules in sub-directory have the path
+	// without the major version suffix (for example /v2) as prefix.
+	tagPrefix := subdir
+	if idx := strings.LastIndex(subdir, "/"); isGoMajorSuffix(subdir[idx+1:]) {
+		if idx < 0 {
+			tagPrefix = ""
+		} else {
+			tagPrefix = subdir[:idx]
+		}
+	}
+
+	r, err := f.cloneRepo(root)
+	if err != nil {
+		return err
+	}
+
+	rev := strings.TrimSuffix(version, "+incompatible")
+	if m := goPseudoVersionRev.FindStringSubmatch(rev); m != nil {
+		rev = m[1]
+	} else if tagPrefix != "" {
+		rev = tagPrefix + "/" + rev
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return fmt.Errorf("error on resolve revision %s of %s: %s",
+			rev, root.Url, err.Error())
+	}
+
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	err = w.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true})
+	if err != nil {
+		return fmt.Errorf("error on checkout %s of %s: %s",
+			hash.String(), root.Url, err.Error())
+	}
+
+	repoDir := filepath.Join(f.cloneDir, goRepoDirName(root.Url))
+	// The major version could be stored on a sub-directory or not.
+	modDir := filepath.Join(repoDir, subdir)
+	if !utils.Exists(filepath.Join(modDir, "go.mod")) {
+		modDir = filepath.Join(repoDir, tagPrefix)
+	}
+
+	log.Debug(fmt.Sprintf("[%s] Creating %s of %s %s from %s@%s...",
+		f.atom.Name, ext, modPath, version, root.Url, hash.String()[0:12]))
+
+	if ext == "mod" {
+		goModFile := filepath.Join(modDir, "go.mod")
+		if utils.Exists(goModFile) {
+			data, err := os.ReadFile(goModFile)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(target, data, 0644)
+		}
+		// The legacy modules without go.mod.
+		return os.WriteFile(target,
+			[]byte(fmt.Sprintf("module %s\n", modPath)), 0644)
+	}
+
+	return createGoModuleZip(modDir, repoDir, modPath+"@"+version, target)
+}
+
+func (f *goDirectFetcher) cloneRepo(root *goRepoRoot) (*git.Repository, error) {
+	if r, present := f.repos[root.Url]; present {
+		return r, nil
+	}
+
+	log := logger.GetDefaultLogger()
+	repoDir := filepath.Join(f.cloneDir, goRepoDirName(root.Url))
+
+	log.Info(fmt.Sprintf(":factory:[%s] Cloning %s...", f.atom.Name, root.Url))
+
+	// The revisions of the different versions of the modules
+	// are resolved on the same clone with all the tags.
+	opts := &git.CloneOptions{
+		URL:        root.Url,
+		RemoteName: "origin",
+		Tags:       git.AllTags,
+		NoCheckout: true,
+	}
+
+	// Use the credentials of the remote if available.
+	auth, err := log.Config.GetAuthentication().GetGitAuth(root.Url, "")
+	if err != nil {
+		return nil, err
+	}
+	opts.Auth = auth
+
+	r, err := git.PlainClone(repoDir, false, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error on clone %s: %s", root.Url, err.Error())
+	}
+
+	f.repos[root.Url] = r
+	return r, nil
+}
+
+// getRepoRoot returns the repository of the module. For the known
+// hosts the repository is the path with three elements else the
+// go-import meta tag is used.
+func (f *goDirectFetcher) getRepoRoot(modPath string) (*goRepoRoot, error) {
+	for prefix, root := range f.roots {
+		if modPath == prefix || strings.HasPrefix(modPath, prefix+"/") {
+			return root, nil
+		}
+	}
+
+	elems := strings.Split(modPath, "/")
+	for _, host := range goKnownHosts {
+		if elems[0] == host {
+			if len(elems) < 3 {
+				return nil, fmt.Errorf("invalid module path %s", modPath)
+			}
+			root := strings.Join(elems[0:3], "/")
+			f.roots[root] = &goRepoRoot{Root: root, Url: "https://" + root}
+			return f.roots[root], nil
+		}
+	}
+
+	root, err := f.discoverRepoRoot(modPath)
+	if err != nil {
+		return nil, err
+	}
+	f.roots[root.Root] = root
+
+	return root, nil
+}
+
+func (f *goDirectFetcher) discoverRepoRoot(modPath string) (*goRepoRoot, error) {
+	uri, err := nurl.Parse("https://" + modPath)
+	if err != nil {
+		return nil, err
+	}
+
+	node := guard_specs.NewRestNode(uri.Host, uri.Host, uri.Scheme == "https")
+
+	service := guard_specs.NewRestService(uri.Host)
+	service.Retries = 3
+	service.AddNode(node)
+
+	t := service.GetTicket()
+	defer t.Rip()
+
+	req, err := f.restGuard.CreateRequest(t, "GET", uri.Path)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = "go-get=1"
+
+	err = f.restGuard.Do(t)
+	if err != nil {
+		if t.Response != nil {
+			return nil, fmt.Errorf("%s - %s - %s", modPath, err.Error(), t.Response.Status)
+		} else {
+			return nil, fmt.Errorf("%s - %s", modPath, err.Error())
+		}
+	}
+
+	if t.Response.Body == nil {
+		return nil, fmt.Errorf("%s - Received invalid response body", modPath)
+	}
+
+	data, err := io.ReadAll(t.Response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range goImportMetaRegex.FindAllStringSubmatch(string(data), -1) {
+		fields := strings.Fields(m[1])
+		if len(fields) != 3 {
+			continue
+		}
+		if modPath != fields[0] && !strings.HasPrefix(modPath, fields[0]+"/") {
+			continue
+		}
+		if fields[1] != "git" {
+			return nil, fmt.Errorf("vcs %s of module %s not supported",
+				fields[1], modPath)
+		}
+		return &goRepoRoot{Root: fields[0], Url: fields[2]}, nil
+	}
+
+	return nil, fmt.Errorf("no go-import meta tag found for %s", modPath)
+}
+
+func isGoMajorSuffix(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func goRepoDirName(url string) string {
+	return strings.ReplaceAll(
+		strings.ReplaceAll(url, ":", "%3A"),
+		"/", "%2F")
+}
+
+// createGoModuleZip creates the module zip with the same rules
+// of golang.org/x/mod/zip: the VCS directories, the nested modules,
+// the vendored packages and the not regular files are excluded.
+func createGoModuleZip(modDir, repoDir, prefix, target string) error {
+	// The vendored packages depend on the go version of the module.
+	vers := ""
+	if data, err := os.ReadFile(filepath.Join(modDir, "go.mod")); err == nil {
+		vers = goModVersion(data)
+	}
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+
+	addFile := func(file, name string) error {
+		w, err := zw.Create(prefix + "/" + name)
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(w, in)
+		return err
+	}
+
+	hasLicense := false
+	err = filepath.WalkDir(modDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(modDir, p)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if p == modDir {
+				return nil
+			}
+			switch d.Name() {
+			case ".bzr", ".git", ".hg", ".svn":
+				return filepath.SkipDir
+			}
+			if utils.Exists(filepath.Join(p, "go.mod")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || isGoVendoredPackage(rel, vers) {
+			return nil
+		}
+		if rel == "LICENSE" {
+			hasLicense = true
+		}
+
+		return addFile(p, rel)
+	})
+	if err != nil {
+		zw.Close()
+		return err
+	}
+
+	// The modules in sub-directory inherit the LICENSE of the repository.
+	if !hasLicense && modDir != repoDir {
+		license := filepath.Join(repoDir, "LICENSE")
+		if utils.Exists(license) {
+			if err = addFile(license, "LICENSE"); err != nil {
+				zw.Close()
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+// goModVersion returns the go version of the go directive of
+// a go.mod file in the go/version format (ex. go1.24) or an empty
+// string if the directive is not present.
+func goModVersion(data []byte) string {
+	m := goModGoDirective.FindSubmatch(data)
+	if m == nil {
+		return ""
+	}
+	return version.Lang("go" + string(m[1]))
+}
+
+// isGoVendoredPackage returns true if the file is in a vendored package
+// with the rules of golang.org/x/mod/zip. The go version of the module
+// selects the rule: before go1.24 the "/vendor/" prefix of a nested
+// package is wrongly skipped (golang.org/issue/37397) and the
+// vendor/modules.txt file is included in the zip.
+func isGoVendoredPackage(name, vers string) bool {
+	newRule := version.Compare(vers, "go1.24") >= 0
+	if newRule && name == "vendor/modules.txt" {
+		return true
+	}
+
+	var i int
+	if strings.HasPrefix(name, "vendor/") {
+		i += len("vendor/")
+	} else if j := strings.Index(name, "/vendor/"); j >= 0 {
+		if newRule {
+			i = j + len("/vendor/")
+		} else {
+			i += len("/vendor/")
+		}
+	} else {
+		return false
+	}
+	return strings.Contains(name[i:], "/")
+}
@@ -0,0 +1,323 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoModFile contains the directives of a go.mod or go.work
+// file used by the golang extension.
+type GoModFile struct {
+	Module   string
+	Uses     []string
+	Replaces []*GoModReplace
+}
+
+type GoModReplace struct {
+	Old        string
+	OldVersion string
+	New        string
+	NewVersion string
+}
+
+// IsLocal returns true if the replacement is a directory.
+func (r *GoModReplace) IsLocal() bool {
+	return strings.HasPrefix(r.New, "./") || strings.HasPrefix(r.New, "../") ||
+		strings.HasPrefix(r.New, "/") || r.New == "." || r.New == ".."
+}
+
+// Matches returns true if the module and the version are
+// replaced by the directive.
+func (r *GoModReplace) Matches(module, version string) bool {
+	return r.Old == module && (r.OldVersion == "" || r.OldVersion == version)
+}
+
+// ParseGoModFile parses the content of a go.mod or a go.work file
+// and returns only the directives module, use and replace.
+func ParseGoModFile(content string) (*GoModFile, error) {
+	ans := &GoModFile{
+		Uses:     []string{},
+		Replaces: []*GoModReplace{},
+	}
+
+	block := ""
+	for idx, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields, err := goModFields(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", idx+1, err.Error())
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		var directive string
+		var args []string
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			directive, args = block, fields
+		} else {
+			if len(fields) == 2 && fields[1] == "(" {
+				block = fields[0]
+				continue
+			}
+			directive, args = fields[0], fields[1:]
+		}
+
+		switch directive {
+		case "module":
+			if len(args) > 0 {
+				ans.Module = args[0]
+			}
+		case "use":
+			ans.Uses = append(ans.Uses, args...)
+		case "replace":
+			r, err := newGoModReplace(args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", idx+1, err.Error())
+			}
+			ans.Replaces = append(ans.Replaces, r)
+		}
+	}
+
+	return ans, nil
+}
+
+func newGoModReplace(args []string) (*GoModReplace, error) {
+	arrow := -1
+	for i, a := range args {
+		if a == "=>" {
+			arrow = i
+			break
+		}
+	}
+	if arrow < 1 || arrow > 2 || len(args)-arrow-1 < 1 || len(args)-arrow-1 > 2 {
+		return nil, fmt.Errorf("invalid replace directive: %s", strings.Join(args, " "))
+	}
+
+	ans := &GoModReplace{
+		Old: args[0],
+		New: args[arrow+1],
+	}
+	if arrow == 2 {
+		ans.OldVersion = args[1]
+	}
+	if len(args) == arrow+3 {
+		ans.NewVersion = args[arrow+2]
+	}
+	return ans, nil
+}
+
+// goModFields splits the line in fields managing the quoted strings.
+func goModFields(line string) ([]string, error) {
+	ans := []string{}
+	line = strings.TrimSpace(line)
+	for line != "" {
+		switch line[0] {
+		case '"', '`':
+			end := strings.IndexByte(line[1:], line[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			s := line[:end+2]
+			if line[0] == '"' {
+				unquoted, err := strconv.Unquote(s)
+				if err != nil {
+					return nil, err
+				}
+				s = unquoted
+			} else {
+				s = s[1 : len(s)-1]
+			}
+			ans = append(ans, s)
+			line = strings.TrimSpace(line[end+2:])
+		default:
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			ans = append(ans, line[:end])
+			line = strings.TrimSpace(line[end:])
+		}
+	}
+	return ans, nil
+}
+
+// ParseGoProxy returns the list of the proxies of a GOPROXY string.
+// Both the separators "," and "|" are supported.
+func ParseGoProxy(goproxy string) []string {
+	ans := []string{}
+	for _, p := range strings.FieldsFunc(goproxy, func(r rune) bool {
+		return r == ',' || r == '|'
+	}) {
+		p = strings.TrimSuffix(strings.TrimSpace(p), "/")
+		if p != "" {
+			ans = append(ans, p)
+		}
+	}
+	return ans
+}
+
+// MatchGoPrivate returns true if the module path matches one of the
+// comma-separated glob patterns with the same rules of GOPRIVATE.
+func MatchGoPrivate(patterns, modPath string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+
+		// Compare the pattern with the prefix of the module
+		// path with the same number of elements.
+		n := strings.Count(pattern, "/")
+		prefix := modPath
+		for i := 0; i < len(modPath); i++ {
+			if modPath[i] == '/' {
+				if n == 0 {
+					prefix = modPath[:i]
+					break
+				}
+				n--
+			}
+		}
+		if n > 0 {
+			continue
+		}
+
+		if matched, _ := path.Match(pattern, prefix); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// EscapeGoModPath converts the upper case letters with
+// !lower case as described on https://golang.org/ref/mod#module-cache
+func EscapeGoModPath(str string) string {
+	var builder strings.Builder
+	for _, ch := range str {
+		if ch >= 'A' && ch <= 'Z' {
+			builder.WriteByte('!')
+			builder.WriteByte(byte(ch + 'a' - 'A'))
+		} else {
+			builder.WriteRune(ch)
+		}
+	}
+	return builder.String()
+}
+
+// goDirHash1 calculates the hash h1 used on go.sum files.
+func goDirHash1(files []string, open func(string) (io.ReadCloser, error)) (string, error) {
+	h := sha256.New()
+	files = append([]string{}, files...)
+	sort.Strings(files)
+	for _, file := range files {
+		if strings.Contains(file, "\n") {
+			return "", fmt.Errorf("filenames with newlines are not supported")
+		}
+		r, err := open(file)
+		if err != nil {
+			return "", err
+		}
+		hf := sha256.New()
+		_, err = io.Copy(hf, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%x  %s\n", hf.Sum(nil), file)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashGoZip returns the h1 hash of a module zip file.
+func HashGoZip(zipFile string) (string, error) {
+	z, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return "", err
+	}
+	defer z.Close()
+
+	files := []string{}
+	zfiles := make(map[string]*zip.File, 0)
+	for _, f := range z.File {
+		files = append(files, f.Name)
+		zfiles[f.Name] = f
+	}
+
+	return goDirHash1(files, func(name string) (io.ReadCloser, error) {
+		return zfiles[name].Open()
+	})
+}
+
+// HashGoMod returns the h1 hash of a go.mod file.
+func HashGoMod(goModFile string) (string, error) {
+	return goDirHash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return os.Open(goModFile)
+	})
+}
+
+// GoZipLicenses returns the SPDX identifiers of the license files
+// in the root directory of the module zip and the names of
+// the license files not recognized.
+func GoZipLicenses(zipFile string) ([]string, []string, error) {
+	ids := []string{}
+	unknown := []string{}
+
+	z, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return ids, unknown, err
+	}
+	defer z.Close()
+
+	for _, f := range z.File {
+		// The files are stored with the prefix <module>@<version>/
+		at := strings.Index(f.Name, "@")
+		if at < 0 {
+			continue
+		}
+		slash := strings.Index(f.Name[at:], "/")
+		if slash < 0 {
+			continue
+		}
+		name := f.Name[at+slash+1:]
+		if strings.Contains(name, "/") || !IsLicenseFile(name) {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return ids, unknown, err
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return ids, unknown, err
+		}
+
+		if id := ClassifyLicense(data); id != "" {
+			ids = append(ids, id)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+
+	return ids, unknown, nil
+}
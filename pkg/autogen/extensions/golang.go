@@ -6,8 +6,10 @@ package extensions
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	autogenart "github.com/macaroni-os/mark-devkit/pkg/autogen/artefacts"
@@ -15,9 +17,6 @@ import (
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/geaaru/rest-guard/pkg/guard"
-	executor "github.com/geaaru/tar-formers/pkg/executor"
-	tarf_specs "github.com/geaaru/tar-formers/pkg/specs"
-	"github.com/geaaru/tar-formers/pkg/tools"
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
@@ -30,9 +29,13 @@ type GoSum struct {
 }
 
 type GoSumRow struct {
+	// The module path escaped as described on
+	// https://golang.org/ref/mod#module-cache
 	Module   string
 	Version  string
 	Checksum string
+	// The module path not escaped.
+	Path string
 }
 
 func NewGoSum(content string) *GoSum {
@@ -42,37 +45,69 @@ func NewGoSum(content string) *GoSum {
 		Lines: []GoSumRow{},
 	}
 
-	escapeModStr := func(str string) string {
-		var builder strings.Builder
-		for _, ch := range str {
-			if ch >= 'A' && ch <= 'Z' {
-				builder.WriteByte('!')
-				builder.WriteByte(byte(ch + 'a' - 'A'))
-			} else {
-				builder.WriteRune(ch)
-			}
-		}
-		return builder.String()
-	}
-
 	for idx := range lines {
-		// As described on https://golang.org/ref/mod#module-cache
-		// we need to convert the upper case with !lower case
-		line := escapeModStr(lines[idx])
-		words := strings.Split(line, " ")
+		words := strings.Split(lines[idx], " ")
 		if len(words) < 3 {
 			continue
 		}
+		// As described on https://golang.org/ref/mod#module-cache
+		// we need to convert the upper case with !lower case
 		ans.Lines = append(ans.Lines, GoSumRow{
-			Module:   strings.TrimSpace(words[0]),
-			Version:  strings.TrimSpace(words[1]),
+			Module:   EscapeGoModPath(strings.TrimSpace(words[0])),
+			Version:  EscapeGoModPath(strings.TrimSpace(words[1])),
 			Checksum: strings.TrimSpace(words[2]),
+			Path:     strings.TrimSpace(words[0]),
 		})
 	}
 
 	return ans
 }
 
+// Merge adds the rows of the GoSum in input not already present.
+func (gs *GoSum) Merge(other *GoSum) {
+	rows := make(map[string]bool, 0)
+	for _, l := range gs.Lines {
+		rows[l.Module+" "+l.Version] = true
+	}
+	for _, l := range other.Lines {
+		if _, present := rows[l.Module+" "+l.Version]; !present {
+			gs.Lines = append(gs.Lines, l)
+			rows[l.Module+" "+l.Version] = true
+		}
+	}
+}
+
+// Bytes returns the sorted rows of the GoSum used
+// to calculate the fingerprint of the bundle.
+func (gs *GoSum) Bytes() []byte {
+	rows := []string{}
+	for _, l := range gs.Lines {
+		rows = append(rows, l.Path+" "+l.Version+" "+l.Checksum)
+	}
+	sort.Strings(rows)
+	return []byte(strings.Join(rows, "\n"))
+}
+
+// DropReplaced removes the rows of the modules replaced
+// with local directories.
+func (gs *GoSum) DropReplaced(replaces []*GoModReplace) {
+	lines := []GoSumRow{}
+	for _, l := range gs.Lines {
+		version := strings.TrimSuffix(l.Version, "/go.mod")
+		replaced := false
+		for _, r := range replaces {
+			if r.IsLocal() && r.Matches(l.Path, version) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			lines = append(lines, l)
+		}
+	}
+	gs.Lines = lines
+}
+
 func NewExtensionGolang(opts map[string]string) (*ExtensionGolang, error) {
 	return &ExtensionGolang{
 		ExtensionBase: &ExtensionBase{
@@ -89,236 +124,251 @@ func (e *ExtensionGolang) Elaborate(restGuard *guard.RestGuard,
 	log := logger.GetDefaultLogger()
 	values := *mapref
 
-	// Create the temporary directory of the package
-	workdir, _ := e.Opts["workdir"]
-	downloadDir := e.Opts["download_dir"]
-	if !filepath.IsAbs(downloadDir) {
-		downloadDir, _ = filepath.Abs(downloadDir)
-	}
-	if !filepath.IsAbs(workdir) {
-		workdir, _ = filepath.Abs(workdir)
+	w, err := e.prepareBundleWorkspace(restGuard, atom, def, mapref, "go")
+	if err != nil || w == nil {
+		return err
 	}
 
-	pkgWorkDir := filepath.Join(workdir, "go-extension", atom.Name)
-
-	err := os.MkdirAll(pkgWorkDir, os.ModePerm)
+	// Retrieve go.sum
+	goSum, err := e.retrieveGoSum(atom, w.UnpackDir)
 	if err != nil {
 		return err
 	}
 
-	mirror := e.Opts["mirror"]
-	if mirror == "" {
-		mirror = "mirror://macaroni"
-	}
-	bundleIdentifier := e.Opts["bundle_identifier"]
-	if bundleIdentifier == "" {
-		bundleIdentifier = "mark-go-bundle"
-	}
-	bundleExtension := e.Opts["bundle_extension"]
-	if bundleExtension == "" {
-		bundleExtension = "xz"
-	}
-	// Fix typo on bundle_extension value.
-	bundleExtension = strings.ReplaceAll(bundleExtension, ".", "")
-	values["mirror"] = mirror
-
-	// Ensure download dir. Could be not present the first time.
-	err = os.MkdirAll(downloadDir, os.ModePerm)
+	fingerprint, err := e.getFingerprint(specs.ExtensionGolang, goSum.Bytes())
 	if err != nil {
 		return err
 	}
 
-	// Retrieve artefacts.
-	artefacts, _ := values["artefacts"].([]*specs.AutogenArtefact)
-	// NOTE: I consider the first artefact the main for golang vendor generation.
-	if len(artefacts) == 0 {
-		log.DebugC(
-			fmt.Sprintf(
-				"[%s] No artefacts found for golang vendor generation. Nothing to do.",
-				atom.Name,
-			))
-		return nil
+	bundleLicenses := ""
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt != nil {
+		bundleLicenses, err = e.getBundleLicenses(w.DownloadDir, bundleTarball)
+		if err != nil {
+			log.Warning(fmt.Sprintf("[%s] Bundle %s regenerated: %s",
+				atom.Name, bundleTarball, err.Error()))
+			bundleArt = nil
+		}
 	}
+	if bundleArt == nil {
+		// Download bundle files
+		licenses := NewBundleLicenses()
+		err = e.downloadBundles(restGuard, atom, goSum, licenses, w.BundlesDir(),
+			filepath.Join(w.PkgWorkDir, "git"))
+		if err != nil {
+			return err
+		}
+		bundleLicenses = licenses.String()
 
-	art := artefacts[0]
-
-	// Download the main artefacts to the download dir.
-	log.DebugC(
-		fmt.Sprintf("[%s] Downloading %s from url %s",
-			atom.Name, art.Name, art.SrcUri[0],
-		))
-	repoFile, err := autogenart.DownloadArtefact(restGuard, atom,
-		art.SrcUri[0], art.Name, downloadDir)
-	if err != nil {
-		return err
+		// Create bundle tarball
+		bundleArt, err = e.createBundleTarball(w, atom, bundleTarball)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Unpack the tarballs
-	unpackDir := filepath.Join(pkgWorkDir, "unpack")
-	log.Info(
-		fmt.Sprintf(":factory:[%s] Extracting file %s",
-			atom.Name, repoFile.Name,
-		))
-	err = e.unpackArtefact(downloadDir, unpackDir, repoFile,
-		atom, def, mapref)
-	if err != nil {
-		return err
-	}
+	// The licenses of the modules with the Gentoo syntax.
+	values["bundle_licenses"] = bundleLicenses
 
-	// Retrieve go.sum
-	goSum, err := e.retrieveGoSum(atom, unpackDir)
-	if err != nil {
-		return err
-	}
+	e.completeBundle(w, mapref, bundleArt)
 
-	// Download bundle files
-	bundlesDir := filepath.Join(pkgWorkDir, bundleIdentifier+"-"+atom.Name)
-	err = e.downloadBundles(restGuard, atom, goSum, bundlesDir)
+	return nil
+}
+
+func (e *ExtensionGolang) downloadBundles(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, goSum *GoSum, licenses *BundleLicenses,
+	bundlesDir, cloneDir string) error {
+	log := logger.GetDefaultLogger()
+	var err error
+
+	// Create bundle dir
+	err = os.MkdirAll(bundlesDir, os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	// Create bundle tarball
-	version, _ := values["version"].(string)
-	sha, _ := values["sha"].(string)
-	bundleTarball := ""
-	if sha == "" {
-		bundleTarball = fmt.Sprintf(
-			"%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier, bundleExtension,
-		)
-	} else {
-		bundleTarball = fmt.Sprintf(
-			"%s-%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier,
-			sha[0:7], bundleExtension,
-		)
+	goproxy := e.Opts["goproxy"]
+	if goproxy == "" {
+		// The fallback to git with direct is enabled only
+		// if defined explicitly.
+		goproxy = "https://proxy.golang.org"
 	}
-	bundleArt, err := e.createBundleTarball(bundlesDir,
-		filepath.Join(downloadDir, bundleTarball), workdir, atom.Name, mirror)
-	if err != nil {
-		return err
+	proxies := ParseGoProxy(goproxy)
+	goprivate := e.Opts["goprivate"]
+	gonosumdb := e.Opts["gonosumdb"]
+	if gonosumdb == "" {
+		gonosumdb = goprivate
 	}
 
-	artefacts = append(artefacts, bundleArt)
-	values["artefacts"] = artefacts
+	directFetcher := newGoDirectFetcher(restGuard, atom, cloneDir)
 
-	e.cleanup(mapref)
+	for idx := range goSum.Lines {
+		row := goSum.Lines[idx]
+		moduleExt := "zip"
+		if strings.HasSuffix(row.Version, "go.mod") {
+			moduleExt = "mod"
+		}
+		version := strings.Split(row.Version, "/")[0]
+		moduleUri := fmt.Sprintf("%s/@v/%s.%s",
+			row.Module, version, moduleExt)
 
-	if !logger.GetDefaultLogger().Config.GetGeneral().Debug {
-		defer os.RemoveAll(filepath.Join(workdir, "go-extension"))
-	}
+		// Replace / with %2F
+		bundle := strings.ReplaceAll(moduleUri, "/", "%2F")
 
-	return nil
-}
+		// The private modules are always fetched from the git repositories.
+		moduleProxies := proxies
+		if goprivate != "" && MatchGoPrivate(goprivate, row.Path) {
+			moduleProxies = []string{"direct"}
+		}
 
-func (e *ExtensionGolang) createBundleTarball(
-	bundlesDir, bundleTarball, workDir, atomName, mirror string) (*specs.AutogenArtefact, error) {
-	var err error
-	log := logger.GetDefaultLogger()
+		err = fmt.Errorf("no proxies available")
+		for _, proxy := range moduleProxies {
+			if proxy == "off" {
+				err = fmt.Errorf("module lookup disabled by goproxy=off")
+				break
+			}
 
-	// Check instance
-	config := tarf_specs.NewConfig(nil)
-	if logger.GetDefaultLogger().Config.GetGeneral().Debug {
-		config.GetLogging().Level = "info"
-	} else {
-		config.GetGeneral().Debug = false
-		config.GetLogging().Level = "info"
-	}
+			if proxy == "direct" {
+				log.Debug(fmt.Sprintf("[%s] Fetching bundle %s %s directly...",
+					atom.Name, row.Path, row.Version))
+				err = directFetcher.Fetch(row.Path, version, moduleExt,
+					filepath.Join(bundlesDir, bundle))
+			} else {
+				url := fmt.Sprintf("%s/%s", proxy, moduleUri)
+				log.Debug(fmt.Sprintf("[%s] Downloading bundle %s %s at %s...",
+					atom.Name, row.Module, row.Version, url))
+				_, err = autogenart.DownloadArtefact(
+					restGuard, atom, url,
+					bundle, bundlesDir)
+			}
 
-	tarformers := executor.NewTarFormers(config)
-	s := tarf_specs.NewSpecFile()
-	// We don't need to keep the original permission of the files
-	// and owner.
-	s.SameOwner = false
-	s.SameChtimes = false
-	s.Writer = tarf_specs.NewWriter()
-	s.Writer.ArchiveDirs = []string{bundlesDir}
+			if err == nil {
+				break
+			}
+			log.Debug(fmt.Sprintf("[%s] Error on fetch %s %s from %s: %s",
+				atom.Name, row.Path, row.Version, proxy, err.Error()))
+		}
+		if err != nil {
+			return fmt.Errorf("error on fetch module %s %s: %s",
+				row.Path, row.Version, err.Error())
+		}
 
-	s.RenamePath = []tarf_specs.RenameRule{
-		{
-			Source: filepath.Join(workDir, "go-extension", atomName),
-			Dest:   ".",
-		},
-	}
-	tarfOpts := tools.NewTarCompressionOpts(true)
-	defer tarfOpts.Close()
+		if moduleExt == "zip" {
+			err = e.addModuleLicenses(atom, filepath.Join(bundlesDir, bundle),
+				&row, licenses)
+			if err != nil {
+				return err
+			}
+		}
 
-	err = tools.PrepareTarWriter(bundleTarball, tarfOpts)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"error on prepare writer: %s",
-			err.Error())
+		// Check the file with the hash of go.sum.
+		if gonosumdb != "" && MatchGoPrivate(gonosumdb, row.Path) {
+			continue
+		}
+		err = e.checkGoSum(filepath.Join(bundlesDir, bundle), moduleExt, &row)
+		if err != nil {
+			return err
+		}
 	}
 
-	if tarfOpts.CompressWriter != nil {
-		tarformers.SetWriter(tarfOpts.CompressWriter)
-	} else {
-		tarformers.SetWriter(tarfOpts.FileWriter)
-	}
+	return licenses.Write(filepath.Join(bundlesDir, BundleLicensesFile))
+}
 
-	fileName := filepath.Base(bundleTarball)
-	log.Debug(fmt.Sprintf("[%s] Creating tarball %s...",
-		atomName, fileName))
+func (e *ExtensionGolang) addModuleLicenses(atom *specs.AutogenAtom,
+	file string, row *GoSumRow, licenses *BundleLicenses) error {
+	log := logger.GetDefaultLogger()
 
-	err = tarformers.RunTaskWriter(s)
+	ids, unknown, err := GoZipLicenses(file)
 	if err != nil {
-		return nil, fmt.Errorf(
-			"srror on create tarball %s: %s",
-			fileName, err.Error())
+		return fmt.Errorf("error on read licenses of %s %s: %s",
+			row.Path, row.Version, err.Error())
+	}
+
+	for _, name := range unknown {
+		log.Warning(fmt.Sprintf("[%s] License file %s of %s %s not recognized.",
+			atom.Name, name, row.Path, row.Version))
+	}
+	if len(ids) == 0 && len(unknown) == 0 {
+		log.Warning(fmt.Sprintf("[%s] No license file found on %s %s.",
+			atom.Name, row.Path, row.Version))
 	}
 
-	artUri := fmt.Sprintf("%s/%s", mirror, fileName)
+	for _, id := range ids {
+		if err = licenses.AddSpdx(id); err != nil {
+			return err
+		}
+	}
 
-	local := true
-	return &specs.AutogenArtefact{
-		SrcUri: []string{artUri},
-		Name:   fileName,
-		Local:  &local,
-	}, nil
+	return nil
 }
 
-func (e *ExtensionGolang) downloadBundles(restGuard *guard.RestGuard,
-	atom *specs.AutogenAtom, goSum *GoSum,
-	bundlesDir string) error {
-	log := logger.GetDefaultLogger()
+func (e *ExtensionGolang) checkGoSum(file, moduleExt string, row *GoSumRow) error {
+	var hash string
 	var err error
 
-	// Create bundle dir
-	err = os.MkdirAll(bundlesDir, os.ModePerm)
+	if moduleExt == "mod" {
+		hash, err = HashGoMod(file)
+	} else {
+		hash, err = HashGoZip(file)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("error on calculate hash of %s: %s",
+			filepath.Base(file), err.Error())
 	}
 
-	for idx := range goSum.Lines {
-		moduleExt := "zip"
-		if strings.HasSuffix(goSum.Lines[idx].Version, "go.mod") {
-			moduleExt = "mod"
-		}
-		version := strings.Split(goSum.Lines[idx].Version, "/")[0]
-		moduleUri := fmt.Sprintf("%s/@v/%s.%s",
-			goSum.Lines[idx].Module, version, moduleExt)
-		url := fmt.Sprintf("https://proxy.golang.org/%s", moduleUri)
+	if hash != row.Checksum {
+		return fmt.Errorf("checksum mismatch for %s %s: %s != %s",
+			row.Path, row.Version, hash, row.Checksum)
+	}
 
-		// Replace / with %2F
-		bundle := strings.ReplaceAll(moduleUri, "/", "%2F")
+	return nil
+}
+
+func (e *ExtensionGolang) readGoModFile(file string) (*GoModFile, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error on read file %s: %s",
+			filepath.Base(file), err.Error())
+	}
+	ans, err := ParseGoModFile(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("error on parse file %s: %s",
+			filepath.Base(file), err.Error())
+	}
+	return ans, nil
+}
 
-		log.Debug(fmt.Sprintf("[%s] Downloading bundle %s %s at %s...",
-			atom.Name, goSum.Lines[idx].Module, goSum.Lines[idx].Version,
-			url))
+// readModuleDir reads the go.sum and the replace directives of the
+// go.mod of the module stored in the directory in input.
+func (e *ExtensionGolang) readModuleDir(dir string, goSum *GoSum,
+	replaces []*GoModReplace) ([]*GoModReplace, bool, error) {
 
-		_, err = autogenart.DownloadArtefact(
-			restGuard, atom, url,
-			bundle, bundlesDir)
+	goModPath := filepath.Join(dir, "go.mod")
+	if utils.Exists(goModPath) {
+		goMod, err := e.readGoModFile(goModPath)
 		if err != nil {
-			return err
+			return replaces, false, err
 		}
+		replaces = append(replaces, goMod.Replaces...)
+	}
 
+	goSumPath := filepath.Join(dir, "go.sum")
+	if !utils.Exists(goSumPath) {
+		return replaces, false, nil
 	}
 
-	return nil
+	data, err := os.ReadFile(goSumPath)
+	if err != nil {
+		return replaces, false, fmt.Errorf("error on read file go.sum: %s", err.Error())
+	}
+	goSum.Merge(NewGoSum(string(data)))
+
+	return replaces, true, nil
 }
 
 func (e *ExtensionGolang) retrieveGoSum(atom *specs.AutogenAtom,
 	targetDir string) (*GoSum, error) {
+	log := logger.GetDefaultLogger()
 
 	unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
 	entries, err := os.ReadDir(targetDir)
@@ -326,7 +376,7 @@ func (e *ExtensionGolang) retrieveGoSum(atom *specs.AutogenAtom,
 		return nil, err
 	}
 
-	goSumPath := ""
+	srcDir := ""
 
 	for _, entry := range entries {
 
@@ -334,28 +384,102 @@ func (e *ExtensionGolang) retrieveGoSum(atom *specs.AutogenAtom,
 			continue
 		}
 
-		goSumPath = filepath.Join(targetDir, entry.Name(), "go.sum")
-		if unpackDirPrefix != "" && strings.HasPrefix(entry.Name(), unpackDirPrefix) {
-			if !utils.Exists(goSumPath) {
-				goSumPath = ""
-				break
-			}
+		if unpackDirPrefix != "" && !strings.HasPrefix(entry.Name(), unpackDirPrefix) {
+			continue
 		}
 
-		if utils.Exists(goSumPath) {
+		dir := filepath.Join(targetDir, entry.Name())
+		if utils.Exists(filepath.Join(dir, "go.sum")) ||
+			utils.Exists(filepath.Join(dir, "go.work")) ||
+			utils.Exists(filepath.Join(dir, "go.mod")) {
+			srcDir = dir
 			break
 		}
-		goSumPath = ""
 	}
 
-	if goSumPath == "" {
+	if srcDir == "" {
 		return nil, fmt.Errorf("go.sum file not found")
 	}
 
-	data, err := os.ReadFile(goSumPath)
-	if err != nil {
-		return nil, fmt.Errorf("error on read file go.sum: %s", err.Error())
+	goSum := &GoSum{Lines: []GoSumRow{}}
+	replaces := []*GoModReplace{}
+	found := false
+
+	goWorkPath := filepath.Join(srcDir, "go.work")
+	if utils.Exists(goWorkPath) && e.Opts["gowork"] != "false" {
+		// Workspace with multiple modules
+		goWork, err := e.readGoModFile(goWorkPath)
+		if err != nil {
+			return nil, err
+		}
+		replaces = append(replaces, goWork.Replaces...)
+
+		for _, use := range goWork.Uses {
+			log.Debug(fmt.Sprintf("[%s] Reading workspace module %s...",
+				atom.Name, use))
+			var hasGoSum bool
+			replaces, hasGoSum, err = e.readModuleDir(
+				filepath.Join(srcDir, filepath.FromSlash(use)), goSum, replaces)
+			if err != nil {
+				return nil, err
+			}
+			found = found || hasGoSum
+		}
+
+		goWorkSumPath := filepath.Join(srcDir, "go.work.sum")
+		if utils.Exists(goWorkSumPath) {
+			data, err := os.ReadFile(goWorkSumPath)
+			if err != nil {
+				return nil, fmt.Errorf("error on read file go.work.sum: %s", err.Error())
+			}
+			goSum.Merge(NewGoSum(string(data)))
+			found = true
+		}
+
+	} else {
+		replaces, found, err = e.readModuleDir(srcDir, goSum, replaces)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return NewGoSum(string(data)), nil
+	// Read all the modules available in sub-directories.
+	if e.Opts["multi_module"] == "true" {
+		err = filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() || p == srcDir {
+				return nil
+			}
+			name := d.Name()
+			if name == "vendor" || name == "testdata" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if !utils.Exists(filepath.Join(p, "go.mod")) {
+				return nil
+			}
+
+			rel, _ := filepath.Rel(srcDir, p)
+			log.Debug(fmt.Sprintf("[%s] Reading module %s...", atom.Name, rel))
+			var hasGoSum bool
+			replaces, hasGoSum, err = e.readModuleDir(p, goSum, replaces)
+			found = found || hasGoSum
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("go.sum file not found")
+	}
+
+	// The modules replaced with local directories are
+	// available in the sources.
+	goSum.DropReplaced(replaces)
+
+	return goSum, nil
 }
@@ -0,0 +1,168 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The go.mod of github.com/pkg/errors v0.9.1 and its go.sum entry.
+const (
+	pkgErrorsGoMod = "module github.com/pkg/errors\n"
+	pkgErrorsSum   = "github.com/pkg/errors v0.9.1/go.mod h1:bwawxfHBFNV+L2hUp1rHADufV3IMtnDRdf1r5NINEl0="
+)
+
+// The hashes of the module created by writeGoModule calculated with
+// golang.org/x/mod/zip.CreateFromDir and golang.org/x/mod/sumdb/dirhash.
+var goModuleHashes = map[string]string{
+	"1.23": "h1:RlVP5dYo/a7+f+q8Ncvi54KZ2+PokdIcHJcvHa/njfI=",
+	"1.24": "h1:tWnN3iKFPlMfrvfodNIpgbXgMMtC8JKa/O80U3JYMUo=",
+}
+
+func writeGoModule(dir, goVersion string) {
+	files := map[string]string{
+		"go.mod":                        "module example.com/foo\n\ngo " + goVersion + "\n",
+		"LICENSE":                       "MIT\n",
+		"foo.go":                        "package foo\n",
+		"pkg/vendor/vendor.go":          "package vendor\n",
+		"vendor/modules.txt":            "# example.com/bar v1.0.0\n",
+		"vendor/example.com/bar/bar.go": "package bar\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(file), 0755)).To(Succeed())
+		Expect(os.WriteFile(file, []byte(content), 0644)).To(Succeed())
+	}
+}
+
+func createGoModuleZip(goVersion string) string {
+	dir := filepath.Join(GinkgoT().TempDir(), "foo")
+	writeGoModule(dir, goVersion)
+	target := filepath.Join(GinkgoT().TempDir(), "v1.0.0.zip")
+	Expect(CreateGoModuleZip(dir, "example.com/foo@v1.0.0", target)).To(Succeed())
+	return target
+}
+
+var _ = Describe("Golang", func() {
+
+	DescribeTable("vendored packages",
+		func(name, vers string, expected bool) {
+			Expect(IsGoVendoredPackage(name, vers)).To(Equal(expected))
+		},
+		Entry("vendored package", "vendor/example.com/bar/bar.go", "go1.23", true),
+		Entry("vendored package with go1.24", "vendor/example.com/bar/bar.go", "go1.24", true),
+		Entry("file of the vendor dir", "vendor/vendor.go", "go1.23", false),
+		Entry("modules.txt before go1.24", "vendor/modules.txt", "go1.23", false),
+		Entry("modules.txt with go1.24", "vendor/modules.txt", "go1.24", true),
+		Entry("nested vendor package before go1.24", "pkg/vendor/vendor.go", "go1.23", true),
+		Entry("nested vendor package with go1.24", "pkg/vendor/vendor.go", "go1.24", false),
+		Entry("nested vendored package with go1.24", "pkg/vendor/foo/foo.go", "go1.24", true),
+		Entry("without go version", "pkg/vendor/vendor.go", "", true),
+		Entry("not vendored", "pkg/foo/foo.go", "go1.24", false),
+	)
+
+	DescribeTable("module zip with the hash of go.sum",
+		func(goVersion string) {
+			hash, err := HashGoZip(createGoModuleZip(goVersion))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal(goModuleHashes[goVersion]))
+		},
+		Entry("go 1.23", "1.23"),
+		Entry("go 1.24", "1.24"),
+	)
+
+	It("calculates the hash of go.mod of go.sum", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "v0.9.1.mod")
+		Expect(os.WriteFile(file, []byte(pkgErrorsGoMod), 0644)).To(Succeed())
+
+		hash, err := HashGoMod(file)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).To(Equal(strings.Fields(pkgErrorsSum)[2]))
+	})
+
+	DescribeTable("MatchGoPrivate",
+		func(patterns, modPath string, expected bool) {
+			Expect(MatchGoPrivate(patterns, modPath)).To(Equal(expected))
+		},
+		Entry("same path", "example.com/foo", "example.com/foo", true),
+		Entry("prefix of the path", "example.com", "example.com/foo/bar", true),
+		Entry("glob", "*.corp.example.com", "git.corp.example.com/foo", true),
+		Entry("glob of an element", "example.com/*/private", "example.com/team/private/mod", true),
+		Entry("list of patterns", "other.org, example.com/", "example.com/foo", true),
+		Entry("partial element", "example.com/fo", "example.com/foo", false),
+		Entry("longer pattern", "example.com/foo/bar", "example.com/foo", false),
+		Entry("empty patterns", "", "example.com/foo", false),
+	)
+
+	Context("Download of the modules", func() {
+		var server *httptest.Server
+		var bundlesDir string
+		var zipFile string
+		atom := &specs.AutogenAtom{Name: "foo"}
+
+		BeforeEach(func() {
+			zipFile = createGoModuleZip("1.24")
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/example.com/foo/@v/v1.0.0.zip":
+					http.ServeFile(w, r, zipFile)
+				case "/github.com/pkg/errors/@v/v0.9.1.mod":
+					fmt.Fprint(w, pkgErrorsGoMod)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			DeferCleanup(server.Close)
+			bundlesDir = GinkgoT().TempDir()
+		})
+
+		goSum := func(zipHash string) *GoSum {
+			return NewGoSum(fmt.Sprintf("example.com/foo v1.0.0 %s\n%s\n",
+				zipHash, pkgErrorsSum))
+		}
+
+		It("downloads the modules from the goproxy", func() {
+			ext, _ := NewExtensionGolang(map[string]string{
+				"goproxy": "https://127.0.0.1:1," + server.URL,
+			})
+			Expect(ext.DownloadBundles(atom, goSum(goModuleHashes["1.24"]), bundlesDir)).To(Succeed())
+			Expect(filepath.Join(bundlesDir, "example.com%2Ffoo%2F@v%2Fv1.0.0.zip")).To(BeAnExistingFile())
+			Expect(filepath.Join(bundlesDir, "github.com%2Fpkg%2Ferrors%2F@v%2Fv0.9.1.mod")).To(BeAnExistingFile())
+		})
+
+		It("fails with the hash of go.sum not matching", func() {
+			ext, _ := NewExtensionGolang(map[string]string{"goproxy": server.URL})
+			err := ext.DownloadBundles(atom, goSum(goModuleHashes["1.23"]), bundlesDir)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("checksum mismatch for example.com/foo v1.0.0"))
+		})
+
+		It("skips the hash of the modules matching gonosumdb", func() {
+			ext, _ := NewExtensionGolang(map[string]string{
+				"goproxy":   server.URL,
+				"gonosumdb": "example.com",
+			})
+			Expect(ext.DownloadBundles(atom, goSum(goModuleHashes["1.23"]), bundlesDir)).To(Succeed())
+		})
+
+		It("doesn't download the modules with goproxy off", func() {
+			ext, _ := NewExtensionGolang(map[string]string{"goproxy": "off"})
+			err := ext.DownloadBundles(atom, goSum(goModuleHashes["1.24"]), bundlesDir)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("goproxy=off"))
+		})
+	})
+})
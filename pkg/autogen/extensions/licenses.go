@@ -0,0 +1,174 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+
+	"github.com/geaaru/tar-formers/pkg/tools"
+)
+
+// The name of the file with the licenses stored inside the bundles.
+const BundleLicensesFile = "mark-bundle-licenses"
+
+// The rules used to classify the license files. The order is
+// important: the first rule with all the texts matched is used.
+var licenseClassifierRules = []struct {
+	Spdx  string
+	Texts []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", []string{"eclipse public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"BSL-1.0", []string{"boost software license - version 1.0"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "names of its contributors may be used"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose", "copyright notice and this permission notice appear"}},
+	{"0BSD", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Zlib", []string{"altered source versions must be plainly marked as such"}},
+}
+
+var (
+	licenseFileRegex   = regexp.MustCompile(`(?i)^(licen[cs]e|copying)([-._].*)?$`)
+	licenseSpacesRegex = regexp.MustCompile(`\s+`)
+)
+
+// BundleLicenses contains the licenses of the dependencies
+// of a bundle in the Gentoo LICENSE syntax.
+type BundleLicenses struct {
+	Licenses map[string]bool
+}
+
+func NewBundleLicenses() *BundleLicenses {
+	return &BundleLicenses{
+		Licenses: make(map[string]bool, 0),
+	}
+}
+
+// AddSpdx adds the SPDX license expression in input.
+func (b *BundleLicenses) AddSpdx(expr string) error {
+	// The licenses in AND are stored as single items.
+	licenses, err := helpers.SpdxToGentooLicenses(expr)
+	if err != nil {
+		return err
+	}
+	for _, l := range licenses {
+		b.Licenses[l] = true
+	}
+	return nil
+}
+
+func (b *BundleLicenses) GetLicenses() []string {
+	ans := []string{}
+	for l := range b.Licenses {
+		ans = append(ans, l)
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// String returns the sorted licenses with the Gentoo LICENSE syntax.
+func (b *BundleLicenses) String() string {
+	return strings.Join(b.GetLicenses(), " ")
+}
+
+// Write stores the licenses in the file in input, one for line.
+func (b *BundleLicenses) Write(file string) error {
+	content := ""
+	for _, l := range b.GetLicenses() {
+		content += l + "\n"
+	}
+	return os.WriteFile(file, []byte(content), 0644)
+}
+
+// ReadBundleLicenses returns the licenses stored inside a bundle tarball.
+func ReadBundleLicenses(tarball string) (string, error) {
+	data, err := readBundleFile(tarball, BundleLicensesFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(data)), " "), nil
+}
+
+// readBundleFile returns the content of the file with the name in input
+// stored inside a bundle tarball.
+func readBundleFile(tarball, name string) ([]byte, error) {
+	opts := tools.NewTarReaderCompressionOpts(true)
+	defer opts.Close()
+
+	err := tools.PrepareTarReader(tarball, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = opts.FileReader
+	if opts.CompressReader != nil {
+		r = opts.CompressReader
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if path.Base(header.Name) != name {
+			continue
+		}
+
+		return io.ReadAll(tr)
+	}
+
+	return nil, fmt.Errorf("no %s file found on %s", name, path.Base(tarball))
+}
+
+// IsLicenseFile returns true if the file name is a license file
+// like LICENSE, LICENSE.md, LICENSE-MIT or COPYING.
+func IsLicenseFile(name string) bool {
+	return licenseFileRegex.MatchString(name) && !strings.HasSuffix(name, ".go")
+}
+
+// ClassifyLicense returns the SPDX identifier of the license text
+// in input or an empty string if the license is not recognized.
+func ClassifyLicense(content []byte) string {
+	text := strings.ToLower(licenseSpacesRegex.ReplaceAllString(string(content), " "))
+
+	for _, rule := range licenseClassifierRules {
+		matched := true
+		for _, t := range rule.Texts {
+			if !strings.Contains(text, t) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule.Spdx
+		}
+	}
+
+	return ""
+}
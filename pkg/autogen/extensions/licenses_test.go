@@ -0,0 +1,121 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Licenses", func() {
+
+	DescribeTable("IsLicenseFile",
+		func(name string, expected bool) {
+			Expect(IsLicenseFile(name)).To(Equal(expected))
+		},
+		Entry("LICENSE", "LICENSE", true),
+		Entry("LICENCE", "LICENCE", true),
+		Entry("lower case", "license", true),
+		Entry("with extension", "LICENSE.md", true),
+		Entry("with suffix", "LICENSE-MIT", true),
+		Entry("with underscore", "LICENSE_APACHE", true),
+		Entry("COPYING", "COPYING", true),
+		Entry("COPYING.LESSER", "COPYING.LESSER", true),
+		Entry("go source", "license.go", false),
+		Entry("prefix", "MY-LICENSE", false),
+		Entry("other words", "LICENSES", false),
+		Entry("readme", "README.md", false),
+	)
+
+	DescribeTable("ClassifyLicense",
+		func(text, expected string) {
+			Expect(ClassifyLicense([]byte(text))).To(Equal(expected))
+		},
+		Entry("MIT", `MIT License
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files`, "MIT"),
+		Entry("Apache-2.0", `
+                                 Apache License
+                           Version 2.0, January 2004`, "Apache-2.0"),
+		Entry("BSD-3-Clause", `Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+   * Neither the name of Google Inc. nor the names of its contributors`, "BSD-3-Clause"),
+		Entry("BSD-3-Clause without the name", `Redistribution and use in source and
+binary forms ... 3. The names of its contributors may be used to endorse`, "BSD-3-Clause"),
+		Entry("BSD-2-Clause", `Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:`, "BSD-2-Clause"),
+		Entry("ISC", `Permission to use, copy, modify, and/or distribute this software for any
+purpose with or without fee is hereby granted, provided that the above
+copyright notice and this permission notice appear in all copies.`, "ISC"),
+		Entry("0BSD", `Permission to use, copy, modify, and/or distribute this software for any
+purpose with or without fee is hereby granted.`, "0BSD"),
+		Entry("GPL-2.0", `GNU GENERAL PUBLIC LICENSE
+                       Version 2, June 1991`, "GPL-2.0"),
+		Entry("GPL-3.0", `GNU GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007`, "GPL-3.0"),
+		Entry("LGPL-2.1", `GNU LESSER GENERAL PUBLIC LICENSE
+                       Version 2.1, February 1999`, "LGPL-2.1"),
+		Entry("LGPL-3.0", `GNU LESSER GENERAL PUBLIC LICENSE
+                       Version 3, 29 June 2007`, "LGPL-3.0"),
+		Entry("AGPL-3.0", `GNU AFFERO GENERAL PUBLIC LICENSE
+                       Version 3, 19 November 2007`, "AGPL-3.0"),
+		Entry("MPL-2.0", `Mozilla Public License Version 2.0`, "MPL-2.0"),
+		Entry("Unlicense", `This is free and unencumbered software released into the public domain.`,
+			"Unlicense"),
+		Entry("Zlib", `2. Altered source versions must be plainly marked as such, and must not be
+   misrepresented as being the original software.`, "Zlib"),
+		Entry("unknown", `All rights reserved.`, ""),
+	)
+
+	Context("BundleLicenses", func() {
+
+		It("stores the licenses in AND as single items", func() {
+			b := NewBundleLicenses()
+			Expect(b.AddSpdx("MIT")).To(Succeed())
+			Expect(b.AddSpdx("Apache-2.0 AND BSD-3-Clause")).To(Succeed())
+			Expect(b.AddSpdx("MIT OR Apache-2.0")).To(Succeed())
+			Expect(b.AddSpdx("")).To(Succeed())
+			Expect(b.AddSpdx("MIT AND (")).ToNot(Succeed())
+
+			Expect(b.GetLicenses()).To(Equal([]string{
+				"Apache-2.0", "BSD", "MIT", "|| ( MIT Apache-2.0 )",
+			}))
+		})
+
+		It("reads the licenses stored inside the bundle", func() {
+			b := NewBundleLicenses()
+			Expect(b.AddSpdx("MIT AND Apache-2.0")).To(Succeed())
+
+			file := filepath.Join(GinkgoT().TempDir(), BundleLicensesFile)
+			Expect(b.Write(file)).To(Succeed())
+			data, err := os.ReadFile(file)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("Apache-2.0\nMIT\n"))
+
+			downloadDir := GinkgoT().TempDir()
+			ext := &ExtensionBase{
+				Opts: map[string]string{
+					"workdir":      GinkgoT().TempDir(),
+					"download_dir": downloadDir,
+				},
+			}
+			name, _, err := ext.BuildTestBundle(&specs.AutogenAtom{Name: "foo"},
+				map[string]interface{}{"version": "1.0"}, "",
+				map[string]string{BundleLicensesFile: string(data)})
+			Expect(err).ToNot(HaveOccurred())
+
+			licenses, err := ReadBundleLicenses(filepath.Join(downloadDir, name))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(licenses).To(Equal(b.String()))
+		})
+	})
+})
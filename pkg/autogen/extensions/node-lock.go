@@ -0,0 +1,541 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	nurl "net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	NodeManagerNpm  = "npm"
+	NodeManagerYarn = "yarn"
+	NodeManagerPnpm = "pnpm"
+)
+
+// The lock files in order of priority.
+var nodeLockFiles = []string{
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+}
+
+type NodeLock struct {
+	Manager  string
+	File     string
+	Packages []*NodePackage
+}
+
+// Bytes returns the packages of the lock used to calculate
+// the fingerprint of the bundle.
+func (l *NodeLock) Bytes() []byte {
+	rows := []string{}
+	for _, pkg := range l.Packages {
+		rows = append(rows, fmt.Sprintf("%s %s %s %s %s",
+			pkg.Name, pkg.Version, pkg.Resolved, pkg.Integrity, pkg.Checksum))
+	}
+	sort.Strings(rows)
+	return []byte(strings.Join(rows, "\n"))
+}
+
+// BundleFiles returns the names of the files of the bundle
+// in the same order used on download.
+func (l *NodeLock) BundleFiles() ([]string, error) {
+	ans := []string{}
+	bundles := make(map[string]bool, 0)
+	for _, pkg := range l.Packages {
+		bundle, err := NodeBundleFileName(pkg)
+		if err != nil {
+			return ans, fmt.Errorf("error on parse url %s: %s",
+				pkg.Resolved, err.Error())
+		}
+		if _, present := bundles[bundle]; present {
+			continue
+		}
+		bundles[bundle] = true
+		ans = append(ans, bundle)
+	}
+	return ans, nil
+}
+
+type NodePackage struct {
+	Name      string
+	Version   string
+	Resolved  string
+	Integrity string
+	// The checksum of the yarn berry cache archive. It's not the hash
+	// of the registry tarball and it's used only for the fingerprint.
+	Checksum string
+}
+
+type npmPackageLock struct {
+	LockfileVersion int                        `json:"lockfileVersion"`
+	Packages        map[string]*npmLockPackage `json:"packages,omitempty"`
+	Dependencies    map[string]*npmLockPackage `json:"dependencies,omitempty"`
+}
+
+type npmLockPackage struct {
+	Name         string                     `json:"name,omitempty"`
+	Version      string                     `json:"version,omitempty"`
+	Resolved     string                     `json:"resolved,omitempty"`
+	Integrity    string                     `json:"integrity,omitempty"`
+	Link         bool                       `json:"link,omitempty"`
+	Bundled      bool                       `json:"bundled,omitempty"`
+	InBundle     bool                       `json:"inBundle,omitempty"`
+	Dependencies map[string]*npmLockPackage `json:"dependencies,omitempty"`
+}
+
+type pnpmLock struct {
+	LockfileVersion interface{}                 `yaml:"lockfileVersion"`
+	Packages        map[string]*pnpmLockPackage `yaml:"packages,omitempty"`
+}
+
+type pnpmLockPackage struct {
+	Name       string              `yaml:"name,omitempty"`
+	Version    string              `yaml:"version,omitempty"`
+	Resolution *pnpmLockResolution `yaml:"resolution,omitempty"`
+}
+
+type pnpmLockResolution struct {
+	Integrity string `yaml:"integrity,omitempty"`
+	Tarball   string `yaml:"tarball,omitempty"`
+	Type      string `yaml:"type,omitempty"`
+}
+
+type yarnBerryPackage struct {
+	Resolution string `yaml:"resolution,omitempty"`
+	Checksum   string `yaml:"checksum,omitempty"`
+	LinkType   string `yaml:"linkType,omitempty"`
+}
+
+func NewNodeLock(manager, file string) *NodeLock {
+	return &NodeLock{
+		Manager:  manager,
+		File:     file,
+		Packages: []*NodePackage{},
+	}
+}
+
+// ParseNodeLock parses the content of a lock file of npm, yarn or pnpm.
+func ParseNodeLock(file string, data []byte) (*NodeLock, error) {
+	switch path.Base(file) {
+	case "package-lock.json", "npm-shrinkwrap.json":
+		return parseNpmLock(file, data)
+	case "yarn.lock":
+		return parseYarnLock(file, data)
+	case "pnpm-lock.yaml":
+		return parsePnpmLock(file, data)
+	default:
+		return nil, fmt.Errorf("unsupported lock file %s", file)
+	}
+}
+
+func (l *NodeLock) addPackage(name, version, resolved, integrity string) *NodePackage {
+	// Only the tarballs availables from the registries are
+	// bundled. Local, linked and git dependencies are ignored.
+	if !strings.HasPrefix(resolved, "https://") && !strings.HasPrefix(resolved, "http://") {
+		return nil
+	}
+	pkg := &NodePackage{
+		Name:      name,
+		Version:   version,
+		Resolved:  resolved,
+		Integrity: integrity,
+	}
+	l.Packages = append(l.Packages, pkg)
+	return pkg
+}
+
+// Sort the packages and drop the duplicated tarballs.
+func (l *NodeLock) normalize() {
+	sort.SliceStable(l.Packages, func(i, j int) bool {
+		return l.Packages[i].Resolved < l.Packages[j].Resolved
+	})
+
+	ans := []*NodePackage{}
+	for idx, p := range l.Packages {
+		if idx > 0 && p.Resolved == l.Packages[idx-1].Resolved {
+			continue
+		}
+		ans = append(ans, p)
+	}
+	l.Packages = ans
+}
+
+func parseNpmLock(file string, data []byte) (*NodeLock, error) {
+	lock := &npmPackageLock{}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("error on unmarshal %s: %s", file, err.Error())
+	}
+
+	ans := NewNodeLock(NodeManagerNpm, file)
+
+	if len(lock.Packages) > 0 {
+		// lockfileVersion 2 and 3
+		for key, pkg := range lock.Packages {
+			if key == "" || pkg.Link || pkg.InBundle {
+				continue
+			}
+			name := pkg.Name
+			if name == "" {
+				idx := strings.LastIndex(key, "node_modules/")
+				name = key[idx+len("node_modules/"):]
+			}
+			ans.addPackage(name, pkg.Version, pkg.Resolved, pkg.Integrity)
+		}
+	} else {
+		// lockfileVersion 1
+		var visit func(deps map[string]*npmLockPackage)
+		visit = func(deps map[string]*npmLockPackage) {
+			for name, pkg := range deps {
+				if pkg.Bundled {
+					continue
+				}
+				ans.addPackage(name, pkg.Version, pkg.Resolved, pkg.Integrity)
+				visit(pkg.Dependencies)
+			}
+		}
+		visit(lock.Dependencies)
+	}
+
+	ans.normalize()
+	return ans, nil
+}
+
+// parseYarnLock parses the lock file of yarn v1 (custom syntax) or
+// of yarn berry (YAML syntax).
+func parseYarnLock(file string, data []byte) (*NodeLock, error) {
+	content := string(data)
+	if strings.HasPrefix(content, "__metadata:") || strings.Contains(content, "\n__metadata:") {
+		return parseYarnBerryLock(file, data)
+	}
+
+	ans := NewNodeLock(NodeManagerYarn, file)
+
+	var name, version, resolved, integrity string
+	flush := func() {
+		if resolved != "" {
+			if integrity == "" {
+				integrity = yarnIntegrityFromUrl(resolved)
+			}
+			// Drop the hash fragment of the url
+			resolved = strings.Split(resolved, "#")[0]
+			ans.addPackage(name, version, resolved, integrity)
+		}
+		name, version, resolved, integrity = "", "", "", ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, " ") {
+			// New entry: "name@range", "name@range2":
+			flush()
+			descr := strings.Split(strings.TrimSuffix(line, ":"), ",")[0]
+			descr = strings.Trim(strings.TrimSpace(descr), "\"")
+			if idx := strings.LastIndex(descr, "@"); idx > 0 {
+				name = descr[:idx]
+			} else {
+				name = descr
+			}
+			continue
+		}
+
+		// Only the fields of the entry and not of the dependencies.
+		if strings.HasPrefix(line, "    ") {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		value := strings.Trim(fields[1], "\"")
+		switch fields[0] {
+		case "version":
+			version = value
+		case "resolved":
+			resolved = value
+		case "integrity":
+			integrity = value
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error on read %s: %s", file, err.Error())
+	}
+
+	ans.normalize()
+	return ans, nil
+}
+
+// yarnIntegrityFromUrl returns the integrity from the sha1
+// hash of the fragment of the resolved urls of yarn v1.
+func yarnIntegrityFromUrl(resolved string) string {
+	idx := strings.LastIndex(resolved, "#")
+	if idx < 0 {
+		return ""
+	}
+	data, err := hex.DecodeString(resolved[idx+1:])
+	if err != nil || len(data) != sha1.Size {
+		return ""
+	}
+	return "sha1-" + base64.StdEncoding.EncodeToString(data)
+}
+
+func parseYarnBerryLock(file string, data []byte) (*NodeLock, error) {
+	entries := make(map[string]*yarnBerryPackage, 0)
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error on unmarshal %s: %s", file, err.Error())
+	}
+
+	ans := NewNodeLock(NodeManagerYarn, file)
+	registry := "https://registry.yarnpkg.com"
+
+	for key, entry := range entries {
+		if key == "__metadata" || entry == nil || entry.Resolution == "" {
+			continue
+		}
+
+		// The resolution is in the format <name>@<protocol>:<reference>
+		idx := strings.Index(entry.Resolution[1:], "@")
+		if idx < 0 {
+			continue
+		}
+		name := entry.Resolution[:idx+1]
+		reference := entry.Resolution[idx+2:]
+
+		// The berry lock doesn't contain the integrity of the tarballs.
+		// The integrity is retrieved from the registry on download.
+		var pkg *NodePackage
+		switch {
+		case strings.HasPrefix(reference, "npm:"):
+			version := strings.TrimPrefix(reference, "npm:")
+			pkg = ans.addPackage(name, version, nodeRegistryTarballUrl(registry, name, version), "")
+		case strings.HasPrefix(reference, "https://") || strings.HasPrefix(reference, "http://"):
+			pkg = ans.addPackage(name, "", reference, "")
+		}
+		if pkg != nil {
+			pkg.Checksum = entry.Checksum
+		}
+	}
+
+	ans.normalize()
+	return ans, nil
+}
+
+func parsePnpmLock(file string, data []byte) (*NodeLock, error) {
+	lock := &pnpmLock{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("error on unmarshal %s: %s", file, err.Error())
+	}
+
+	ans := NewNodeLock(NodeManagerPnpm, file)
+	registry := "https://registry.npmjs.org"
+	lockVersion := fmt.Sprintf("%v", lock.LockfileVersion)
+
+	for key, pkg := range lock.Packages {
+		if pkg == nil || pkg.Resolution == nil ||
+			pkg.Resolution.Type == "git" || pkg.Resolution.Type == "directory" {
+			continue
+		}
+
+		name, version := parsePnpmPackageKey(key, lockVersion)
+		if pkg.Name != "" {
+			name = pkg.Name
+		}
+		if pkg.Version != "" {
+			version = pkg.Version
+		}
+
+		resolved := pkg.Resolution.Tarball
+		if resolved == "" {
+			if name == "" || version == "" || strings.Contains(version, ":") {
+				continue
+			}
+			resolved = nodeRegistryTarballUrl(registry, name, version)
+		}
+
+		ans.addPackage(name, version, resolved, pkg.Resolution.Integrity)
+	}
+
+	ans.normalize()
+	return ans, nil
+}
+
+// parsePnpmPackageKey returns the name and the version of the package
+// from the key of the packages section. For example:
+// v5: /@scope/name/1.0.0_peer@2.0.0
+// v6: /@scope/name@1.0.0(peer@2.0.0)
+// v9: @scope/name@1.0.0
+func parsePnpmPackageKey(key, lockVersion string) (string, string) {
+	key = strings.TrimPrefix(key, "/")
+	if idx := strings.Index(key, "("); idx > 0 {
+		key = key[:idx]
+	}
+
+	sep := "@"
+	if strings.HasPrefix(lockVersion, "5") {
+		sep = "/"
+	}
+
+	idx := strings.LastIndex(key, sep)
+	if idx <= 0 {
+		return "", ""
+	}
+	version := key[idx+1:]
+	if sep == "/" {
+		version = strings.Split(version, "_")[0]
+	}
+
+	return key[:idx], version
+}
+
+func nodeRegistryTarballUrl(registry, name, version string) string {
+	return fmt.Sprintf("%s/%s/-/%s-%s.tgz", registry, name, path.Base(name), version)
+}
+
+// NodeRegistryMetadataUrl returns the url of the registry metadata
+// of the package version from the url of the tarball. It returns an
+// empty string if the tarball is not available from a registry.
+func NodeRegistryMetadataUrl(pkg *NodePackage) string {
+	if pkg.Name == "" || pkg.Version == "" {
+		return ""
+	}
+	suffix := fmt.Sprintf("/%s/-/%s-%s.tgz", pkg.Name, path.Base(pkg.Name), pkg.Version)
+	if !strings.HasSuffix(pkg.Resolved, suffix) {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s",
+		strings.TrimSuffix(pkg.Resolved, suffix), pkg.Name, pkg.Version)
+}
+
+// ParseNodeRegistryIntegrity returns the integrity of the tarball
+// from the registry metadata of a package version.
+func ParseNodeRegistryIntegrity(data []byte) (string, error) {
+	meta := struct {
+		Dist struct {
+			Integrity string `json:"integrity,omitempty"`
+			Shasum    string `json:"shasum,omitempty"`
+		} `json:"dist"`
+	}{}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", err
+	}
+
+	if meta.Dist.Integrity != "" {
+		return meta.Dist.Integrity, nil
+	}
+	if meta.Dist.Shasum != "" {
+		return yarnIntegrityFromUrl("#" + meta.Dist.Shasum), nil
+	}
+	return "", nil
+}
+
+// NodeBundleFileName returns the name of the tarball in the bundle
+// with the same convention of the yarn offline mirror:
+// https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz => @babel-core-7.0.0.tgz
+// The tarballs without a .tgz name (for example from codeload) are named
+// with the package name and the version.
+func NodeBundleFileName(pkg *NodePackage) (string, error) {
+	u, err := nurl.Parse(pkg.Resolved)
+	if err != nil {
+		return "", err
+	}
+
+	ans := path.Base(u.Path)
+	if !strings.HasSuffix(ans, ".tgz") && pkg.Name != "" {
+		version := pkg.Version
+		if version == "" {
+			version = ans
+		}
+		return fmt.Sprintf("%s-%s.tgz",
+			strings.ReplaceAll(pkg.Name, "/", "-"), version), nil
+	}
+
+	if idx := strings.Index(u.Path, "/@"); idx >= 0 {
+		scope := strings.SplitN(u.Path[idx+1:], "/", 2)[0]
+		ans = scope + "-" + ans
+	}
+
+	return ans, nil
+}
+
+// CheckNodeIntegrity validates the file with the Subresource Integrity
+// string of the lock files. The strongest algorithm available is used.
+func CheckNodeIntegrity(file, integrity string) error {
+	algorithms := []string{"sha512", "sha384", "sha256", "sha1"}
+
+	expected := map[string][]string{}
+	for _, i := range strings.Fields(integrity) {
+		fields := strings.SplitN(i, "-", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		// Drop the options of the hash
+		expected[fields[0]] = append(expected[fields[0]], strings.Split(fields[1], "?")[0])
+	}
+
+	for _, alg := range algorithms {
+		hashes, ok := expected[alg]
+		if !ok {
+			continue
+		}
+
+		var h hash.Hash
+		switch alg {
+		case "sha512":
+			h = sha512.New()
+		case "sha384":
+			h = sha512.New384()
+		case "sha256":
+			h = sha256.New()
+		default:
+			h = sha1.New()
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+		for _, expectedDigest := range hashes {
+			if digest == expectedDigest {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("integrity mismatch for %s: %s-%s", path.Base(file), alg, digest)
+	}
+
+	return fmt.Errorf("no supported hash on integrity %s", integrity)
+}
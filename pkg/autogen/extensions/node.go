@@ -0,0 +1,260 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	autogenart "github.com/macaroni-os/mark-devkit/pkg/autogen/artefacts"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+type ExtensionNode struct {
+	*ExtensionBase
+}
+
+func NewExtensionNode(opts map[string]string) (*ExtensionNode, error) {
+	return &ExtensionNode{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		}}, nil
+}
+
+func (e *ExtensionNode) GetName() string { return specs.ExtensionNode }
+
+func (e *ExtensionNode) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	log := logger.GetDefaultLogger()
+	values := *mapref
+
+	w, err := e.prepareBundleWorkspace(restGuard, atom, def, mapref, "node")
+	if err != nil || w == nil {
+		return err
+	}
+
+	// Retrieve the lock file
+	pkgUnpackDir, nodeLock, err := e.retrieveNodeLock(atom, w.UnpackDir)
+	if err != nil {
+		return err
+	}
+	values["pkg_basedir"] = filepath.Base(pkgUnpackDir)
+
+	log.Info(
+		fmt.Sprintf(":factory:[%s] Found %d %s packages on %s.",
+			atom.Name, len(nodeLock.Packages), nodeLock.Manager,
+			nodeLock.File,
+		))
+
+	fingerprint, err := e.getFingerprint(specs.ExtensionNode, nodeLock.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var bundleFiles []string
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt != nil {
+		bundleFiles, err = nodeLock.BundleFiles()
+		if err != nil {
+			return err
+		}
+	} else {
+		// Download bundle files
+		bundleFiles, err = e.downloadBundles(restGuard, atom, nodeLock, w.BundlesDir())
+		if err != nil {
+			return err
+		}
+
+		// Create bundle tarball
+		bundleArt, err = e.createBundleTarball(w, atom, bundleTarball)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Values used by the templates to populate the offline
+	// cache of the package manager.
+	values["node_package_manager"] = nodeLock.Manager
+	values["node_lockfile"] = nodeLock.File
+	values["node_bundle_dir"] = w.BundleDirName
+	values["node_bundle_files"] = bundleFiles
+
+	e.completeBundle(w, mapref, bundleArt)
+
+	return nil
+}
+
+func (e *ExtensionNode) downloadBundles(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, nodeLock *NodeLock,
+	bundlesDir string) ([]string, error) {
+	log := logger.GetDefaultLogger()
+	ans := []string{}
+
+	// Create bundle dir
+	err := os.MkdirAll(bundlesDir, os.ModePerm)
+	if err != nil {
+		return ans, err
+	}
+
+	registry := strings.TrimSuffix(e.Opts["registry"], "/")
+	bundles := make(map[string]bool, 0)
+
+	for _, pkg := range nodeLock.Packages {
+		url := nodeMirrorUrl(pkg.Resolved, registry)
+
+		bundle, err := NodeBundleFileName(pkg)
+		if err != nil {
+			return ans, fmt.Errorf("error on parse url %s: %s",
+				pkg.Resolved, err.Error())
+		}
+		if _, present := bundles[bundle]; present {
+			continue
+		}
+		bundles[bundle] = true
+
+		log.Debug(fmt.Sprintf("[%s] Downloading bundle %s %s at %s...",
+			atom.Name, pkg.Name, pkg.Version, url))
+
+		_, err = autogenart.DownloadArtefact(
+			restGuard, atom, url,
+			bundle, bundlesDir)
+		if err != nil {
+			return ans, err
+		}
+
+		integrity := pkg.Integrity
+		if integrity == "" {
+			integrity, err = e.getRegistryIntegrity(restGuard, pkg, registry)
+			if err != nil {
+				return ans, err
+			}
+		}
+
+		err = CheckNodeIntegrity(filepath.Join(bundlesDir, bundle), integrity)
+		if err != nil {
+			return ans, err
+		}
+
+		ans = append(ans, bundle)
+	}
+
+	return ans, nil
+}
+
+// getRegistryIntegrity returns the integrity of the tarball of the
+// package from the registry metadata. It's used with the lock files
+// without integrity, like the yarn berry lock.
+func (e *ExtensionNode) getRegistryIntegrity(restGuard *guard.RestGuard,
+	pkg *NodePackage, registry string) (string, error) {
+
+	metaUrl := NodeRegistryMetadataUrl(pkg)
+	if metaUrl == "" {
+		return "", fmt.Errorf("no integrity available for %s %s (%s)",
+			pkg.Name, pkg.Version, pkg.Resolved)
+	}
+
+	data, err := fetchUrl(restGuard, nodeMirrorUrl(metaUrl, registry))
+	if err != nil {
+		return "", fmt.Errorf("error on retrieve integrity of %s %s: %s",
+			pkg.Name, pkg.Version, err.Error())
+	}
+
+	integrity, err := ParseNodeRegistryIntegrity(data)
+	if err != nil {
+		return "", fmt.Errorf("error on parse metadata of %s %s: %s",
+			pkg.Name, pkg.Version, err.Error())
+	}
+	if integrity == "" {
+		return "", fmt.Errorf("no integrity available for %s %s on registry",
+			pkg.Name, pkg.Version)
+	}
+
+	return integrity, nil
+}
+
+// nodeMirrorUrl permits to use a mirror of the default registries.
+func nodeMirrorUrl(url, registry string) string {
+	if registry == "" {
+		return url
+	}
+	for _, r := range []string{
+		"https://registry.npmjs.org",
+		"https://registry.yarnpkg.com",
+	} {
+		if strings.HasPrefix(url, r+"/") {
+			return registry + strings.TrimPrefix(url, r)
+		}
+	}
+	return url
+}
+
+func (e *ExtensionNode) retrieveNodeLock(atom *specs.AutogenAtom,
+	targetDir string) (string, *NodeLock, error) {
+
+	unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lockFiles := nodeLockFiles
+	if lockfile, ok := e.Opts["lockfile"]; ok && lockfile != "" {
+		lockFiles = []string{lockfile}
+	}
+
+	pkgUnpackDir := ""
+	lockPath := ""
+	lockFile := ""
+
+	for _, entry := range entries {
+
+		if !entry.IsDir() {
+			continue
+		}
+
+		if unpackDirPrefix != "" && !strings.HasPrefix(entry.Name(), unpackDirPrefix) {
+			continue
+		}
+
+		for _, f := range lockFiles {
+			p := filepath.Join(targetDir, entry.Name(), f)
+			if utils.Exists(p) {
+				pkgUnpackDir = filepath.Join(targetDir, entry.Name())
+				lockPath = p
+				lockFile = f
+				break
+			}
+		}
+
+		if lockPath != "" {
+			break
+		}
+	}
+
+	if lockPath == "" {
+		return "", nil, fmt.Errorf("node lock file not found")
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error on read file %s: %s",
+			lockFile, err.Error())
+	}
+
+	nodeLock, err := ParseNodeLock(lockFile, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return pkgUnpackDir, nodeLock, nil
+}
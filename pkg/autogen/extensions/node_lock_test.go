@@ -0,0 +1,254 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const npmLockV1 = `{
+  "lockfileVersion": 1,
+  "dependencies": {
+    "a": {
+      "version": "1.0.0",
+      "resolved": "https://registry.npmjs.org/a/-/a-1.0.0.tgz",
+      "integrity": "sha512-aaa",
+      "dependencies": {
+        "b": {
+          "version": "2.0.0",
+          "resolved": "https://registry.npmjs.org/b/-/b-2.0.0.tgz",
+          "integrity": "sha512-bbb"
+        }
+      }
+    },
+    "bundled": {"version": "1.0.0", "bundled": true,
+      "resolved": "https://registry.npmjs.org/bundled/-/bundled-1.0.0.tgz"}
+  }
+}`
+
+const npmLockV3 = `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app", "version": "1.0.0"},
+    "node_modules/a": {"version": "1.0.0",
+      "resolved": "https://registry.npmjs.org/a/-/a-1.0.0.tgz", "integrity": "sha512-aaa"},
+    "node_modules/x/node_modules/a": {"version": "1.0.0",
+      "resolved": "https://registry.npmjs.org/a/-/a-1.0.0.tgz", "integrity": "sha512-aaa"},
+    "node_modules/@scope/c": {"version": "3.0.0",
+      "resolved": "https://registry.npmjs.org/@scope/c/-/c-3.0.0.tgz", "integrity": "sha512-ccc"},
+    "node_modules/alias": {"name": "real", "version": "1.0.0",
+      "resolved": "https://registry.npmjs.org/real/-/real-1.0.0.tgz", "integrity": "sha512-rrr"},
+    "node_modules/local": {"resolved": "packages/local", "link": true},
+    "node_modules/git": {"version": "1.0.0", "resolved": "git+ssh://git@github.com/foo/git.git#abc"},
+    "node_modules/inbundle": {"version": "1.0.0", "inBundle": true,
+      "resolved": "https://registry.npmjs.org/inbundle/-/inbundle-1.0.0.tgz"}
+  }
+}`
+
+const yarnLockV1 = `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@scope/c@^3.0.0":
+  version "3.0.0"
+  resolved "https://registry.yarnpkg.com/@scope/c/-/c-3.0.0.tgz#0123456789abcdef0123456789abcdef01234567"
+  integrity sha512-ccc
+  dependencies:
+    a "^1.0.0"
+
+a@^1.0.0, a@^1.0.0-rc:
+  version "1.0.0"
+  resolved "https://registry.yarnpkg.com/a/-/a-1.0.0.tgz#0123456789abcdef0123456789abcdef01234567"
+
+local@file:./local:
+  version "1.0.0"
+`
+
+const yarnBerryLock = `__metadata:
+  version: 6
+  cacheKey: 8
+
+"@scope/c@npm:^3.0.0":
+  version: 3.0.0
+  resolution: "@scope/c@npm:3.0.0"
+  checksum: 1111
+  languageName: node
+  linkType: hard
+
+"a@npm:^1.0.0, a@npm:^1.0.1":
+  version: 1.0.1
+  resolution: "a@npm:1.0.1"
+  checksum: 2222
+  languageName: node
+  linkType: hard
+
+"app@workspace:.":
+  version: 0.0.0-use.local
+  resolution: "app@workspace:."
+  languageName: unknown
+  linkType: soft
+
+"t@https://example.org/t.tgz":
+  version: 1.0.0
+  resolution: "t@https://example.org/t.tgz"
+  checksum: 3333
+  languageName: node
+  linkType: hard
+`
+
+const pnpmLockV5 = `lockfileVersion: 5.4
+packages:
+  /a/1.0.0:
+    resolution: {integrity: sha512-aaa}
+  /@scope/c/3.0.0_a@1.0.0:
+    resolution: {integrity: sha512-ccc}
+  /g/1.0.0:
+    resolution: {type: git, repo: https://github.com/foo/g.git, commit: abc}
+`
+
+const pnpmLockV6 = `lockfileVersion: '6.0'
+packages:
+  /a@1.0.0:
+    resolution: {integrity: sha512-aaa}
+  /@scope/c@3.0.0(a@1.0.0):
+    resolution: {integrity: sha512-ccc}
+  /t@1.0.0:
+    resolution: {tarball: https://example.org/t-1.0.0.tgz}
+`
+
+const pnpmLockV9 = `lockfileVersion: '9.0'
+packages:
+  a@1.0.0:
+    resolution: {integrity: sha512-aaa}
+  '@scope/c@3.0.0':
+    resolution: {integrity: sha512-ccc}
+  d@file:local:
+    resolution: {directory: local, type: directory}
+`
+
+var _ = Describe("Node lock", func() {
+
+	DescribeTable("ParseNodeLock",
+		func(file, content, manager string, expected []string) {
+			lock, err := ParseNodeLock(file, []byte(content))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock.Manager).To(Equal(manager))
+
+			pkgs := []string{}
+			for _, p := range lock.Packages {
+				pkgs = append(pkgs, fmt.Sprintf("%s %s %s %s %s",
+					p.Name, p.Version, p.Resolved, p.Integrity, p.Checksum))
+			}
+			Expect(pkgs).To(Equal(expected))
+		},
+		Entry("npm v1", "package-lock.json", npmLockV1, NodeManagerNpm, []string{
+			"a 1.0.0 https://registry.npmjs.org/a/-/a-1.0.0.tgz sha512-aaa ",
+			"b 2.0.0 https://registry.npmjs.org/b/-/b-2.0.0.tgz sha512-bbb ",
+		}),
+		Entry("npm v3", "package-lock.json", npmLockV3, NodeManagerNpm, []string{
+			"@scope/c 3.0.0 https://registry.npmjs.org/@scope/c/-/c-3.0.0.tgz sha512-ccc ",
+			"a 1.0.0 https://registry.npmjs.org/a/-/a-1.0.0.tgz sha512-aaa ",
+			"real 1.0.0 https://registry.npmjs.org/real/-/real-1.0.0.tgz sha512-rrr ",
+		}),
+		Entry("npm shrinkwrap", "npm-shrinkwrap.json", npmLockV1, NodeManagerNpm, []string{
+			"a 1.0.0 https://registry.npmjs.org/a/-/a-1.0.0.tgz sha512-aaa ",
+			"b 2.0.0 https://registry.npmjs.org/b/-/b-2.0.0.tgz sha512-bbb ",
+		}),
+		Entry("yarn v1", "yarn.lock", yarnLockV1, NodeManagerYarn, []string{
+			"@scope/c 3.0.0 https://registry.yarnpkg.com/@scope/c/-/c-3.0.0.tgz sha512-ccc ",
+			"a 1.0.0 https://registry.yarnpkg.com/a/-/a-1.0.0.tgz sha1-ASNFZ4mrze8BI0VniavN7wEjRWc= ",
+		}),
+		Entry("yarn berry", "yarn.lock", yarnBerryLock, NodeManagerYarn, []string{
+			"t  https://example.org/t.tgz  3333",
+			"@scope/c 3.0.0 https://registry.yarnpkg.com/@scope/c/-/c-3.0.0.tgz  1111",
+			"a 1.0.1 https://registry.yarnpkg.com/a/-/a-1.0.1.tgz  2222",
+		}),
+		Entry("pnpm v5", "pnpm-lock.yaml", pnpmLockV5, NodeManagerPnpm, []string{
+			"@scope/c 3.0.0 https://registry.npmjs.org/@scope/c/-/c-3.0.0.tgz sha512-ccc ",
+			"a 1.0.0 https://registry.npmjs.org/a/-/a-1.0.0.tgz sha512-aaa ",
+		}),
+		Entry("pnpm v6", "pnpm-lock.yaml", pnpmLockV6, NodeManagerPnpm, []string{
+			"t 1.0.0 https://example.org/t-1.0.0.tgz  ",
+			"@scope/c 3.0.0 https://registry.npmjs.org/@scope/c/-/c-3.0.0.tgz sha512-ccc ",
+			"a 1.0.0 https://registry.npmjs.org/a/-/a-1.0.0.tgz sha512-aaa ",
+		}),
+		Entry("pnpm v9", "pnpm-lock.yaml", pnpmLockV9, NodeManagerPnpm, []string{
+			"@scope/c 3.0.0 https://registry.npmjs.org/@scope/c/-/c-3.0.0.tgz sha512-ccc ",
+			"a 1.0.0 https://registry.npmjs.org/a/-/a-1.0.0.tgz sha512-aaa ",
+		}),
+	)
+
+	It("fails with an unsupported lock file", func() {
+		_, err := ParseNodeLock("bun.lockb", []byte{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("NodeBundleFileName",
+		func(name, version, resolved, expected string) {
+			bundle, err := NodeBundleFileName(&NodePackage{
+				Name: name, Version: version, Resolved: resolved,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bundle).To(Equal(expected))
+		},
+		Entry("package", "a", "1.0.0", "https://registry.npmjs.org/a/-/a-1.0.0.tgz", "a-1.0.0.tgz"),
+		Entry("scoped package", "@babel/core", "7.0.0",
+			"https://registry.npmjs.org/@babel/core/-/core-7.0.0.tgz", "@babel-core-7.0.0.tgz"),
+		Entry("tarball without tgz name", "@scope/t", "1.0.0",
+			"https://codeload.github.com/foo/t/tar.gz/abc", "@scope-t-1.0.0.tgz"),
+	)
+
+	DescribeTable("NodeRegistryMetadataUrl",
+		func(pkg *NodePackage, expected string) {
+			Expect(NodeRegistryMetadataUrl(pkg)).To(Equal(expected))
+		},
+		Entry("registry tarball", &NodePackage{Name: "@scope/c", Version: "3.0.0",
+			Resolved: "https://registry.yarnpkg.com/@scope/c/-/c-3.0.0.tgz"},
+			"https://registry.yarnpkg.com/@scope/c/3.0.0"),
+		Entry("other tarball", &NodePackage{Name: "t", Version: "1.0.0",
+			Resolved: "https://example.org/t.tgz"}, ""),
+	)
+
+	Context("CheckNodeIntegrity", func() {
+		var file string
+		content := []byte("tarball")
+		sha512sum := sha512.Sum512(content)
+		sha1sum := sha1.Sum(content)
+		sri512 := "sha512-" + base64.StdEncoding.EncodeToString(sha512sum[:])
+		sri1 := "sha1-" + base64.StdEncoding.EncodeToString(sha1sum[:])
+
+		BeforeEach(func() {
+			file = filepath.Join(GinkgoT().TempDir(), "a-1.0.0.tgz")
+			Expect(os.WriteFile(file, content, 0644)).To(Succeed())
+		})
+
+		DescribeTable("validates the integrity",
+			func(integrity func() string, valid bool) {
+				err := CheckNodeIntegrity(file, integrity())
+				if valid {
+					Expect(err).ToNot(HaveOccurred())
+				} else {
+					Expect(err).To(HaveOccurred())
+				}
+			},
+			Entry("sha512", func() string { return sri512 }, true),
+			Entry("sha1", func() string { return sri1 }, true),
+			Entry("strongest algorithm is used", func() string { return "sha1-wrong " + sri512 }, true),
+			Entry("mismatch of the strongest algorithm", func() string { return sri1 + " sha512-wrong" }, false),
+			Entry("options of the hash", func() string { return sri512 + "?foo" }, true),
+			Entry("unsupported algorithm", func() string { return "md5-abc" }, false),
+		)
+	})
+})
@@ -0,0 +1,310 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	autogenart "github.com/macaroni-os/mark-devkit/pkg/autogen/artefacts"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+)
+
+type ExtensionNuget struct {
+	*ExtensionBase
+}
+
+type NugetLock struct {
+	Version      int                                     `json:"version"`
+	Dependencies map[string]map[string]*NugetLockPackage `json:"dependencies"`
+}
+
+type NugetLockPackage struct {
+	Type        string `json:"type"`
+	Resolved    string `json:"resolved,omitempty"`
+	ContentHash string `json:"contentHash,omitempty"`
+}
+
+type NugetPackage struct {
+	Id          string
+	Version     string
+	ContentHash string
+}
+
+func NewNugetLock(data []byte) (*NugetLock, error) {
+	ans := &NugetLock{}
+	if err := json.Unmarshal(data, ans); err != nil {
+		return nil, err
+	}
+	return ans, nil
+}
+
+// GetBundleName returns the name of the nupkg file as available on
+// the flat container of the NuGet feed.
+func (p *NugetPackage) GetBundleName() string {
+	return fmt.Sprintf("%s.%s.nupkg",
+		strings.ToLower(p.Id), strings.ToLower(p.Version))
+}
+
+func (p *NugetPackage) GetUrl(repository string) string {
+	id := strings.ToLower(p.Id)
+	version := strings.ToLower(p.Version)
+	return fmt.Sprintf("%s/%s/%s/%s",
+		strings.TrimSuffix(repository, "/"), id, version, p.GetBundleName())
+}
+
+// nugetPackagesBytes returns the packages used to calculate
+// the fingerprint of the bundle.
+func nugetPackagesBytes(packages []*NugetPackage) []byte {
+	rows := []string{}
+	for _, pkg := range packages {
+		rows = append(rows, pkg.Id+" "+pkg.Version+" "+pkg.ContentHash)
+	}
+	sort.Strings(rows)
+	return []byte(strings.Join(rows, "\n"))
+}
+
+func NewExtensionNuget(opts map[string]string) (*ExtensionNuget, error) {
+	return &ExtensionNuget{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		}}, nil
+}
+
+func (e *ExtensionNuget) GetName() string { return specs.ExtensionNuget }
+
+func (e *ExtensionNuget) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	values := *mapref
+
+	w, err := e.prepareBundleWorkspace(restGuard, atom, def, mapref, "nuget")
+	if err != nil || w == nil {
+		return err
+	}
+
+	// Retrieve the packages of all packages.lock.json files
+	pkgUnpackDir, packages, err := e.retrieveNugetPackages(atom, w.UnpackDir)
+	if err != nil {
+		return err
+	}
+	values["pkg_basedir"] = filepath.Base(pkgUnpackDir)
+
+	fingerprint, err := e.getFingerprint(specs.ExtensionNuget,
+		nugetPackagesBytes(packages))
+	if err != nil {
+		return err
+	}
+
+	bundleFiles := []string{}
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt != nil {
+		for _, pkg := range packages {
+			bundleFiles = append(bundleFiles, pkg.GetBundleName())
+		}
+	} else {
+		// Download bundle files
+		bundleFiles, err = e.downloadBundles(restGuard, atom, packages, w.BundlesDir())
+		if err != nil {
+			return err
+		}
+
+		// Create bundle tarball
+		bundleArt, err = e.createBundleTarball(w, atom, bundleTarball)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Values used by the templates to configure a
+	// local NuGet feed for the offline restore.
+	values["nuget_bundle_dir"] = w.BundleDirName
+	values["nuget_bundle_files"] = bundleFiles
+
+	e.completeBundle(w, mapref, bundleArt)
+
+	return nil
+}
+
+func (e *ExtensionNuget) downloadBundles(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, packages []*NugetPackage,
+	bundlesDir string) ([]string, error) {
+	log := logger.GetDefaultLogger()
+	ans := []string{}
+
+	// Create bundle dir
+	err := os.MkdirAll(bundlesDir, os.ModePerm)
+	if err != nil {
+		return ans, err
+	}
+
+	repository := e.Opts["repository"]
+	if repository == "" {
+		repository = "https://api.nuget.org/v3-flatcontainer"
+	}
+
+	for _, pkg := range packages {
+		url := pkg.GetUrl(repository)
+		bundle := pkg.GetBundleName()
+
+		log.Debug(fmt.Sprintf("[%s] Downloading bundle %s %s at %s...",
+			atom.Name, pkg.Id, pkg.Version, url))
+
+		_, err = autogenart.DownloadArtefact(
+			restGuard, atom, url,
+			bundle, bundlesDir)
+		if err != nil {
+			return ans, err
+		}
+
+		if pkg.ContentHash != "" {
+			err = e.checkContentHash(filepath.Join(bundlesDir, bundle), pkg.ContentHash)
+			if err != nil {
+				return ans, err
+			}
+		} else {
+			log.Warning(fmt.Sprintf(
+				"[%s] No contentHash available for %s. Check skipped.",
+				atom.Name, bundle))
+		}
+
+		ans = append(ans, bundle)
+	}
+
+	return ans, nil
+}
+
+// The contentHash of the lock file is the base64 of
+// the sha512 of the nupkg file.
+func (e *ExtensionNuget) checkContentHash(file, contentHash string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha512.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+
+	sum := base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if sum != contentHash {
+		return fmt.Errorf("contentHash mismatch for %s: %s != %s",
+			filepath.Base(file), sum, contentHash)
+	}
+
+	return nil
+}
+
+// retrieveNugetPackages reads all the packages.lock.json files of the
+// projects of the solution and returns the list of the packages
+// without duplicates.
+func (e *ExtensionNuget) retrieveNugetPackages(atom *specs.AutogenAtom,
+	targetDir string) (string, []*NugetPackage, error) {
+	log := logger.GetDefaultLogger()
+
+	unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lockfile := e.Opts["lockfile"]
+	if lockfile == "" {
+		lockfile = "packages.lock.json"
+	}
+
+	pkgUnpackDir := ""
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if unpackDirPrefix != "" && !strings.HasPrefix(entry.Name(), unpackDirPrefix) {
+			continue
+		}
+		pkgUnpackDir = filepath.Join(targetDir, entry.Name())
+		break
+	}
+
+	if pkgUnpackDir == "" {
+		return "", nil, fmt.Errorf("no source directory found")
+	}
+
+	pkgsMap := make(map[string]*NugetPackage, 0)
+	nLocks := 0
+	err = filepath.WalkDir(pkgUnpackDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != filepath.Base(lockfile) {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("error on read file %s: %s", p, err.Error())
+		}
+		lock, err := NewNugetLock(data)
+		if err != nil {
+			return fmt.Errorf("error on parse file %s: %s", p, err.Error())
+		}
+
+		rel, _ := filepath.Rel(pkgUnpackDir, p)
+		log.Debug(fmt.Sprintf("[%s] Parsing %s...", atom.Name, rel))
+		nLocks++
+
+		for _, deps := range lock.Dependencies {
+			for id, dep := range deps {
+				// The projects of the solution are not available on the feed.
+				if dep.Type == "Project" || dep.Resolved == "" {
+					continue
+				}
+				key := strings.ToLower(id + "@" + dep.Resolved)
+				if _, present := pkgsMap[key]; !present {
+					pkgsMap[key] = &NugetPackage{
+						Id:          id,
+						Version:     dep.Resolved,
+						ContentHash: dep.ContentHash,
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if nLocks == 0 {
+		return "", nil, fmt.Errorf("%s file not found", lockfile)
+	}
+
+	keys := []string{}
+	for k := range pkgsMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ans := []*NugetPackage{}
+	for _, k := range keys {
+		ans = append(ans, pkgsMap[k])
+	}
+
+	return pkgUnpackDir, ans, nil
+}
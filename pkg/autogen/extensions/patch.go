@@ -0,0 +1,92 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+)
+
+// ExtensionPatch applies the patches to the sources shared between
+// the extensions in order to modify the sources before the next
+// extensions (for example to update go.mod before vendoring).
+type ExtensionPatch struct {
+	*ExtensionBase
+}
+
+func NewExtensionPatch(opts map[string]string) (*ExtensionPatch, error) {
+	if len(strings.Fields(opts["patches"])) == 0 {
+		return nil, fmt.Errorf("patches option not defined")
+	}
+
+	return &ExtensionPatch{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		}}, nil
+}
+
+func (e *ExtensionPatch) GetName() string { return specs.ExtensionPatch }
+
+func (e *ExtensionPatch) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	log := logger.GetDefaultLogger()
+	values := *mapref
+
+	if e.Opts["source_dir"] == "" {
+		return fmt.Errorf("patch extension without shared sources")
+	}
+
+	downloadDir := e.Opts["download_dir"]
+	if !filepath.IsAbs(downloadDir) {
+		downloadDir, _ = filepath.Abs(downloadDir)
+	}
+	workdir, _ := e.Opts["workdir"]
+	if !filepath.IsAbs(workdir) {
+		workdir, _ = filepath.Abs(workdir)
+	}
+	pkgWorkDir := filepath.Join(workdir, "patch-extension", atom.Name)
+
+	// Retrieve artefacts.
+	artefacts, _ := values["artefacts"].([]*specs.AutogenArtefact)
+	if len(artefacts) == 0 {
+		return fmt.Errorf("No artefacts found to patch")
+	}
+
+	unpackDir, err := e.prepareSourceTree(restGuard, atom, def, artefacts[0],
+		downloadDir, filepath.Join(pkgWorkDir, "unpack"), mapref)
+	if err != nil {
+		return err
+	}
+
+	pkgSourceDir, err := e.getPkgSourceDir(unpackDir)
+	if err != nil {
+		return err
+	}
+
+	patches := strings.Fields(e.Opts["patches"])
+
+	log.Info(fmt.Sprintf(":factory:[%s] Applying %d patches to the sources...",
+		atom.Name, len(patches)))
+
+	err = e.applyPatches(patches, pkgSourceDir)
+	if err != nil {
+		return err
+	}
+
+	if !logger.GetDefaultLogger().Config.GetGeneral().Debug {
+		defer os.RemoveAll(filepath.Join(workdir, "patch-extension"))
+	}
+
+	return nil
+}
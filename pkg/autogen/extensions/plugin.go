@@ -0,0 +1,88 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/plugins"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+)
+
+// ExtensionPlugin is an extension implemented by the external
+// executable mark-devkit-extension-<name>.
+type ExtensionPlugin struct {
+	*ExtensionBase
+	Type   string
+	Plugin *plugins.Plugin
+}
+
+func NewExtensionPlugin(t string, opts map[string]string) (*ExtensionPlugin, error) {
+	p, err := plugins.NewPlugin(plugins.KindExtension, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.HasCapability(plugins.CapabilityElaborate) {
+		return nil, fmt.Errorf("extension plugin %s without capability %s",
+			t, plugins.CapabilityElaborate)
+	}
+
+	return &ExtensionPlugin{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		},
+		Type:   t,
+		Plugin: p,
+	}, nil
+}
+
+func (e *ExtensionPlugin) GetName() string { return e.Type }
+
+func (e *ExtensionPlugin) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	values := *mapref
+
+	opts := make(map[string]string, 0)
+	for k, v := range e.Opts {
+		opts[k] = v
+	}
+	// The plugins receive the absolute paths.
+	for _, k := range []string{"download_dir", "workdir", "files_dir", "specfile"} {
+		if opts[k] != "" && !filepath.IsAbs(opts[k]) {
+			opts[k], _ = filepath.Abs(opts[k])
+		}
+	}
+	if opts["mirror"] == "" {
+		opts["mirror"] = "mirror://macaroni"
+	}
+
+	// Ensure download dir. Could be not present the first time.
+	err := os.MkdirAll(opts["download_dir"], os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	params := plugins.NewCallParams(atom, opts, values)
+	params.Def = def
+
+	result := &plugins.CallResult{}
+	err = e.Plugin.Call(plugins.MethodExtensionElaborate, params, result)
+	if err != nil {
+		return err
+	}
+
+	result.Apply(values)
+
+	e.cleanup(mapref)
+
+	return nil
+}
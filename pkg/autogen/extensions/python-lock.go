@@ -0,0 +1,586 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	nurl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	PythonLockPoetry       = "poetry"
+	PythonLockUv           = "uv"
+	PythonLockRequirements = "requirements"
+
+	PythonDistSdist = "sdist"
+	PythonDistWheel = "wheel"
+	PythonDistAll   = "all"
+
+	PythonDefaultIndex = "https://pypi.org/simple"
+)
+
+// The lock files in order of priority.
+var pythonLockFiles = []string{
+	"poetry.lock",
+	"uv.lock",
+	"requirements.txt",
+}
+
+var pythonNameNormalizeRegex = regexp.MustCompile(`[-_.]+`)
+
+type PythonLock struct {
+	Format   string
+	File     string
+	Packages []*PythonPackage
+}
+
+type PythonPackage struct {
+	Name    string
+	Version string
+	// The PEP 503 index where resolve the files without url.
+	Index string
+	Files []*PythonDistFile
+}
+
+type PythonDistFile struct {
+	Name string
+	Url  string
+	// The hash in the format <algorithm>:<hex digest>
+	Hash string
+}
+
+type poetryLock struct {
+	Packages []*poetryLockPackage `toml:"package"`
+	Metadata *poetryLockMetadata  `toml:"metadata,omitempty"`
+}
+
+type poetryLockMetadata struct {
+	// Used by the lock files generated before poetry 1.2
+	Files map[string][]*poetryLockFile `toml:"files,omitempty"`
+}
+
+type poetryLockPackage struct {
+	Name    string            `toml:"name"`
+	Version string            `toml:"version"`
+	Files   []*poetryLockFile `toml:"files,omitempty"`
+	Source  *poetryLockSource `toml:"source,omitempty"`
+}
+
+type poetryLockFile struct {
+	File string `toml:"file"`
+	Hash string `toml:"hash"`
+}
+
+type poetryLockSource struct {
+	Type      string `toml:"type,omitempty"`
+	Url       string `toml:"url,omitempty"`
+	Reference string `toml:"reference,omitempty"`
+}
+
+type uvLock struct {
+	Packages []*uvLockPackage `toml:"package"`
+}
+
+type uvLockPackage struct {
+	Name    string         `toml:"name"`
+	Version string         `toml:"version"`
+	Source  map[string]any `toml:"source,omitempty"`
+	Sdist   *uvLockFile    `toml:"sdist,omitempty"`
+	Wheels  []*uvLockFile  `toml:"wheels,omitempty"`
+}
+
+type uvLockFile struct {
+	Url  string `toml:"url,omitempty"`
+	Hash string `toml:"hash,omitempty"`
+}
+
+func NewPythonLock(format, file string) *PythonLock {
+	return &PythonLock{
+		Format:   format,
+		File:     file,
+		Packages: []*PythonPackage{},
+	}
+}
+
+// NormalizePythonName returns the name of the package
+// normalized as described by PEP 503.
+func NormalizePythonName(name string) string {
+	return strings.ToLower(pythonNameNormalizeRegex.ReplaceAllString(name, "-"))
+}
+
+// ParsePythonLock parses the content of a poetry.lock, uv.lock or
+// of a requirements.txt with hashes. If the index is empty the
+// index defined in the lock file or PyPI is used. The dir is the
+// directory of the lock file used to read the files included by
+// the requirements.
+func ParsePythonLock(dir, file string, data []byte, index string) (*PythonLock, error) {
+	var ans *PythonLock
+	var err error
+
+	switch base := path.Base(file); {
+	case base == "poetry.lock":
+		ans, err = parsePoetryLock(file, data, index)
+	case base == "uv.lock":
+		ans, err = parseUvLock(file, data, index)
+	case strings.HasSuffix(base, ".txt"):
+		ans, err = parseRequirements(dir, file, data, index)
+	default:
+		return nil, fmt.Errorf("unsupported lock file %s", file)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range ans.Packages {
+		if pkg.Index == "" {
+			pkg.Index = PythonDefaultIndex
+		}
+	}
+
+	sort.SliceStable(ans.Packages, func(i, j int) bool {
+		return ans.Packages[i].Name < ans.Packages[j].Name
+	})
+
+	return ans, nil
+}
+
+func parsePoetryLock(file string, data []byte, index string) (*PythonLock, error) {
+	lock := &poetryLock{}
+	if err := toml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("error on unmarshal %s: %s", file, err.Error())
+	}
+
+	ans := NewPythonLock(PythonLockPoetry, file)
+
+	for _, p := range lock.Packages {
+		pkg := &PythonPackage{
+			Name:    NormalizePythonName(p.Name),
+			Version: p.Version,
+			Index:   index,
+			Files:   []*PythonDistFile{},
+		}
+
+		files := p.Files
+		if len(files) == 0 && lock.Metadata != nil {
+			files = lock.Metadata.Files[p.Name]
+		}
+
+		directUrl := ""
+		if p.Source != nil {
+			switch p.Source.Type {
+			case "legacy":
+				pkg.Index = p.Source.Url
+			case "url":
+				directUrl = p.Source.Url
+			default:
+				// git, directory and file sources are not
+				// available from an index.
+				continue
+			}
+		}
+
+		for _, f := range files {
+			df := &PythonDistFile{
+				Name: f.File,
+				Hash: f.Hash,
+			}
+			if directUrl != "" {
+				df.Url = directUrl
+			}
+			pkg.Files = append(pkg.Files, df)
+		}
+
+		ans.Packages = append(ans.Packages, pkg)
+	}
+
+	return ans, nil
+}
+
+func parseUvLock(file string, data []byte, index string) (*PythonLock, error) {
+	lock := &uvLock{}
+	if err := toml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("error on unmarshal %s: %s", file, err.Error())
+	}
+
+	ans := NewPythonLock(PythonLockUv, file)
+
+	for _, p := range lock.Packages {
+		_, isRegistry := p.Source["registry"]
+		_, isUrl := p.Source["url"]
+		if !isRegistry && !isUrl {
+			// The git, editable, virtual, directory and path
+			// sources are not downloadable.
+			continue
+		}
+
+		pkg := &PythonPackage{
+			Name:    NormalizePythonName(p.Name),
+			Version: p.Version,
+			Index:   index,
+			Files:   []*PythonDistFile{},
+		}
+
+		lockFiles := p.Wheels
+		if p.Sdist != nil {
+			lockFiles = append([]*uvLockFile{p.Sdist}, lockFiles...)
+		}
+		for _, f := range lockFiles {
+			if f.Url == "" {
+				continue
+			}
+			name := f.Url
+			if u, err := nurl.Parse(f.Url); err == nil {
+				name, _ = nurl.PathUnescape(path.Base(u.Path))
+			}
+			pkg.Files = append(pkg.Files, &PythonDistFile{
+				Name: name,
+				Url:  f.Url,
+				Hash: f.Hash,
+			})
+		}
+
+		ans.Packages = append(ans.Packages, pkg)
+	}
+
+	return ans, nil
+}
+
+// requirementsParser parses a requirements file and the requirements
+// and constraints files included with the -r and -c options.
+type requirementsParser struct {
+	// The directory of the main requirements file.
+	Dir   string
+	Index string
+
+	Packages    []*PythonPackage
+	Constraints map[string]*PythonPackage
+	// The requirements without version or hashes that
+	// must be completed by the constraints.
+	Unpinned map[string]string
+
+	visited map[string]bool
+}
+
+func parseRequirements(dir, file string, data []byte, index string) (*PythonLock, error) {
+	ans := NewPythonLock(PythonLockRequirements, file)
+	r := &requirementsParser{
+		Dir:         dir,
+		Index:       index,
+		Packages:    []*PythonPackage{},
+		Constraints: make(map[string]*PythonPackage, 0),
+		Unpinned:    make(map[string]string, 0),
+		visited:     make(map[string]bool, 0),
+	}
+
+	err := r.parse(file, data, false)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range r.Packages {
+		requirement, unpinned := r.Unpinned[pkg.Name]
+		if !unpinned {
+			ans.Packages = append(ans.Packages, pkg)
+			continue
+		}
+
+		c, present := r.Constraints[pkg.Name]
+		if pkg.Version == "" {
+			if !present {
+				return nil, fmt.Errorf("requirement %s in %s is not pinned",
+					requirement, file)
+			}
+			pkg.Version = c.Version
+		}
+		if len(pkg.Files) == 0 {
+			if !present || c.Version != pkg.Version || len(c.Files) == 0 {
+				return nil, fmt.Errorf("requirement %s in %s is without hashes",
+					requirement, file)
+			}
+			pkg.Files = c.Files
+		}
+		ans.Packages = append(ans.Packages, pkg)
+	}
+
+	return ans, nil
+}
+
+// parse parses the content of a requirements file. The packages of the
+// constraints files are used only to complete the requirements.
+func (r *requirementsParser) parse(file string, data []byte, constraint bool) error {
+	r.visited[file] = true
+
+	// Join the continuation lines
+	content := strings.ReplaceAll(string(data), "\\\r\n", " ")
+	content = strings.ReplaceAll(content, "\\\n", " ")
+
+	for _, line := range strings.Split(content, "\n") {
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if strings.HasPrefix(fields[0], "-") {
+			// Options of pip
+			opt, value := parseRequirementsOption(fields)
+			switch opt {
+			case "-i", "--index-url":
+				if r.Index == "" {
+					r.Index = value
+				}
+			case "-r", "--requirement", "-c", "--constraint":
+				err := r.include(file, value, constraint || opt == "-c" || opt == "--constraint")
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		requirement := strings.Split(line, "--hash")[0]
+		requirement = strings.TrimSpace(strings.Split(requirement, ";")[0])
+		reqFields := strings.SplitN(requirement, "==", 2)
+
+		name := strings.TrimSpace(strings.Split(reqFields[0], "[")[0])
+		pkg := &PythonPackage{
+			Name:  NormalizePythonName(name),
+			Index: r.Index,
+			Files: []*PythonDistFile{},
+		}
+		if len(reqFields) == 2 {
+			pkg.Version = strings.TrimSpace(reqFields[1])
+		}
+
+		for idx, f := range fields {
+			if !strings.HasPrefix(f, "--hash") {
+				continue
+			}
+			h := strings.TrimLeft(strings.TrimPrefix(f, "--hash"), "=")
+			if h == "" && idx+1 < len(fields) {
+				// The hash in the format --hash <algorithm>:<digest>
+				h = fields[idx+1]
+			}
+			pkg.Files = append(pkg.Files, &PythonDistFile{Hash: h})
+		}
+
+		if constraint {
+			if pkg.Version == "" {
+				return fmt.Errorf("constraint %s in %s is not pinned",
+					requirement, file)
+			}
+			r.Constraints[pkg.Name] = pkg
+			continue
+		}
+
+		if pkg.Version == "" || len(pkg.Files) == 0 {
+			r.Unpinned[pkg.Name] = requirement
+		}
+		r.Packages = append(r.Packages, pkg)
+	}
+
+	return nil
+}
+
+// include parses a requirements or constraints file defined
+// with a path relative to the file that includes it.
+func (r *requirementsParser) include(file, include string, constraint bool) error {
+	if include == "" {
+		return fmt.Errorf("invalid include in %s", file)
+	}
+	if path.IsAbs(include) || strings.Contains(include, "://") {
+		return fmt.Errorf("include %s in %s is not relative to the sources",
+			include, file)
+	}
+
+	included := path.Join(path.Dir(file), include)
+	if included == ".." || strings.HasPrefix(included, "../") {
+		return fmt.Errorf("include %s in %s is outside of the sources",
+			include, file)
+	}
+	if _, present := r.visited[included]; present {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(r.Dir, filepath.FromSlash(included)))
+	if err != nil {
+		return fmt.Errorf("error on read file %s included by %s: %s",
+			included, file, err.Error())
+	}
+
+	return r.parse(included, data, constraint)
+}
+
+// parseRequirementsOption returns the option and the value of the
+// option in the formats: -r file, -rfile, --requirement file and
+// --requirement=file.
+func parseRequirementsOption(fields []string) (string, string) {
+	opt := fields[0]
+	value := ""
+
+	if strings.HasPrefix(opt, "--") {
+		if idx := strings.Index(opt, "="); idx > 0 {
+			return opt[:idx], opt[idx+1:]
+		}
+	} else if len(opt) > 2 {
+		return opt[:2], opt[2:]
+	}
+
+	if len(fields) > 1 {
+		value = fields[1]
+	}
+	return opt, value
+}
+
+// Bytes returns the packages of the lock used to calculate
+// the fingerprint of the bundle.
+func (l *PythonLock) Bytes() []byte {
+	rows := []string{}
+	for _, pkg := range l.Packages {
+		row := pkg.Name + " " + pkg.Version + " " + pkg.Index
+		for _, f := range pkg.Files {
+			row += fmt.Sprintf(" %s %s %s", f.Name, f.Url, f.Hash)
+		}
+		rows = append(rows, row)
+	}
+	sort.Strings(rows)
+	return []byte(strings.Join(rows, "\n"))
+}
+
+// GetBundleFiles returns the names of the files of the bundle for
+// the dist type in input. It returns false if the names are available
+// only from the index.
+func (l *PythonLock) GetBundleFiles(distType string) ([]string, bool) {
+	ans := []string{}
+	bundles := make(map[string]bool, 0)
+	for _, pkg := range l.Packages {
+		if len(pkg.Files) == 0 {
+			return nil, false
+		}
+		for _, f := range pkg.Files {
+			if f.Name == "" {
+				return nil, false
+			}
+		}
+
+		files, err := pkg.SelectFiles(distType)
+		if err != nil {
+			return nil, false
+		}
+		for _, f := range files {
+			if _, present := bundles[f.Name]; !present {
+				bundles[f.Name] = true
+				ans = append(ans, f.Name)
+			}
+		}
+	}
+	return ans, true
+}
+
+func (f *PythonDistFile) IsSdist() bool {
+	for _, ext := range []string{".tar.gz", ".zip", ".tar.bz2", ".tgz"} {
+		if strings.HasSuffix(f.Name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUniversalWheel returns true for the pure python wheels.
+func (f *PythonDistFile) IsUniversalWheel() bool {
+	return strings.HasSuffix(f.Name, "-none-any.whl")
+}
+
+// SelectFiles returns the files to download in the bundle
+// for the dist type in input.
+func (p *PythonPackage) SelectFiles(distType string) ([]*PythonDistFile, error) {
+	if distType == PythonDistAll {
+		return p.Files, nil
+	}
+
+	sdists := []*PythonDistFile{}
+	wheels := []*PythonDistFile{}
+	for _, f := range p.Files {
+		if f.IsSdist() {
+			sdists = append(sdists, f)
+		} else if f.IsUniversalWheel() {
+			wheels = append(wheels, f)
+		}
+	}
+
+	first, second := sdists, wheels
+	if distType == PythonDistWheel {
+		first, second = wheels, sdists
+	}
+	if len(first) > 0 {
+		return first, nil
+	}
+	if len(second) > 0 {
+		return second, nil
+	}
+
+	return nil, fmt.Errorf("no sdist or pure wheel available for %s %s",
+		p.Name, p.Version)
+}
+
+// CheckPythonHash validates the file with the hash in the
+// format <algorithm>:<hex digest> or <algorithm>=<hex digest>.
+func CheckPythonHash(file, expected string) error {
+	sep := strings.IndexAny(expected, ":=")
+	if sep < 0 {
+		return fmt.Errorf("invalid hash %s", expected)
+	}
+	alg := expected[:sep]
+	digest := strings.ToLower(expected[sep+1:])
+
+	var h hash.Hash
+	switch alg {
+	case "sha256":
+		h = sha256.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return fmt.Errorf("unsupported hash algorithm %s", alg)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(h, f); err != nil {
+		return err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != digest {
+		return fmt.Errorf("hash mismatch for %s: %s:%s", path.Base(file), alg, sum)
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"fmt"
+	"html"
+	nurl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	autogenart "github.com/macaroni-os/mark-devkit/pkg/autogen/artefacts"
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/geaaru/rest-guard/pkg/guard"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+var pythonIndexLinkRegex = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']+)["'][^>]*>([^<]*)</a>`)
+
+type ExtensionPython struct {
+	*ExtensionBase
+}
+
+func NewExtensionPython(opts map[string]string) (*ExtensionPython, error) {
+	return &ExtensionPython{
+		ExtensionBase: &ExtensionBase{
+			Opts: opts,
+		}}, nil
+}
+
+func (e *ExtensionPython) GetName() string { return specs.ExtensionPython }
+
+func (e *ExtensionPython) Elaborate(restGuard *guard.RestGuard,
+	atom, def *specs.AutogenAtom,
+	mapref *map[string]interface{}) error {
+
+	log := logger.GetDefaultLogger()
+	values := *mapref
+
+	distType := e.Opts["dist_type"]
+	switch distType {
+	case "":
+		distType = PythonDistSdist
+	case PythonDistSdist, PythonDistWheel, PythonDistAll:
+	default:
+		return fmt.Errorf("invalid dist_type %s", distType)
+	}
+
+	w, err := e.prepareBundleWorkspace(restGuard, atom, def, mapref, "python")
+	if err != nil || w == nil {
+		return err
+	}
+
+	// Retrieve the lock file
+	pkgUnpackDir, pyLock, err := e.retrievePythonLock(atom, w.UnpackDir)
+	if err != nil {
+		return err
+	}
+	values["pkg_basedir"] = filepath.Base(pkgUnpackDir)
+
+	log.Info(
+		fmt.Sprintf(":factory:[%s] Found %d python packages on %s.",
+			atom.Name, len(pyLock.Packages), pyLock.File,
+		))
+
+	fingerprint, err := e.getFingerprint(specs.ExtensionPython, pyLock.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// Without the names of the files in the lock the bundle
+	// content depends on the index and it's always regenerated.
+	bundleFiles, named := pyLock.GetBundleFiles(distType)
+	if !named {
+		fingerprint = ""
+	}
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt == nil {
+		// Download bundle files
+		bundleFiles, err = e.downloadBundles(restGuard, atom, pyLock,
+			w.BundlesDir(), distType)
+		if err != nil {
+			return err
+		}
+
+		// Create bundle tarball
+		bundleArt, err = e.createBundleTarball(w, atom, bundleTarball)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Values used by the templates to install the
+	// pinned dependencies with pip --no-index --find-links.
+	values["python_lock_format"] = pyLock.Format
+	values["python_lockfile"] = pyLock.File
+	values["python_bundle_dir"] = w.BundleDirName
+	values["python_bundle_files"] = bundleFiles
+
+	e.completeBundle(w, mapref, bundleArt)
+
+	return nil
+}
+
+func (e *ExtensionPython) downloadBundles(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, pyLock *PythonLock,
+	bundlesDir, distType string) ([]string, error) {
+	log := logger.GetDefaultLogger()
+	ans := []string{}
+
+	// Create bundle dir
+	err := os.MkdirAll(bundlesDir, os.ModePerm)
+	if err != nil {
+		return ans, err
+	}
+
+	bundles := make(map[string]bool, 0)
+
+	for _, pkg := range pyLock.Packages {
+		err = e.resolveFiles(restGuard, atom, pkg)
+		if err != nil {
+			return ans, err
+		}
+
+		files, err := pkg.SelectFiles(distType)
+		if err != nil {
+			return ans, err
+		}
+
+		for _, f := range files {
+			if _, present := bundles[f.Name]; present {
+				continue
+			}
+			bundles[f.Name] = true
+
+			log.Debug(fmt.Sprintf("[%s] Downloading bundle %s %s at %s...",
+				atom.Name, pkg.Name, pkg.Version, f.Url))
+
+			_, err = autogenart.DownloadArtefact(
+				restGuard, atom, f.Url,
+				f.Name, bundlesDir)
+			if err != nil {
+				return ans, err
+			}
+
+			if f.Hash != "" {
+				err = CheckPythonHash(filepath.Join(bundlesDir, f.Name), f.Hash)
+				if err != nil {
+					return ans, err
+				}
+			} else {
+				log.Warning(fmt.Sprintf(
+					"[%s] No hash available for %s. Check skipped.",
+					atom.Name, f.Name))
+			}
+
+			ans = append(ans, f.Name)
+		}
+	}
+
+	return ans, nil
+}
+
+// resolveFiles retrieves the urls of the files without url from the
+// PEP 503 simple index. The files are matched by name or by hash.
+// The files of the lock not available on the index are reported and
+// ignored, the package must have at least one file.
+func (e *ExtensionPython) resolveFiles(restGuard *guard.RestGuard,
+	atom *specs.AutogenAtom, pkg *PythonPackage) error {
+
+	// Without files in the lock all the files of
+	// the version available on the index are used.
+	toResolve := len(pkg.Files) == 0
+	for _, f := range pkg.Files {
+		if f.Url == "" {
+			toResolve = true
+			break
+		}
+	}
+	if !toResolve {
+		return nil
+	}
+
+	indexFiles, err := e.fetchIndexFiles(restGuard, pkg.Index, pkg.Name)
+	if err != nil {
+		return err
+	}
+
+	files := []*PythonDistFile{}
+	if len(pkg.Files) == 0 {
+		prefix := pkg.Name + "-" + pkg.Version
+		for _, idxFile := range indexFiles {
+			name := strings.ToLower(strings.ReplaceAll(idxFile.Name, "_", "-"))
+			if strings.HasPrefix(name, prefix+"-") ||
+				strings.HasPrefix(name, prefix+".tar") || name == prefix+".zip" {
+				files = append(files, idxFile)
+			}
+		}
+	}
+
+	unmatched := []string{}
+	for _, f := range pkg.Files {
+		if f.Url != "" {
+			files = append(files, f)
+			continue
+		}
+
+		matched := false
+		for _, idxFile := range indexFiles {
+			if (f.Name != "" && f.Name == idxFile.Name) ||
+				(f.Name == "" && f.Hash != "" && pythonHashEqual(f.Hash, idxFile.Hash)) {
+				f.Name = idxFile.Name
+				f.Url = idxFile.Url
+				if f.Hash == "" {
+					f.Hash = idxFile.Hash
+				}
+				files = append(files, f)
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			if f.Name != "" {
+				unmatched = append(unmatched, f.Name)
+			} else {
+				unmatched = append(unmatched, f.Hash)
+			}
+		}
+	}
+
+	if len(unmatched) > 0 {
+		logger.GetDefaultLogger().Warning(fmt.Sprintf(
+			"[%s] Files of %s %s not available on index %s: %s",
+			atom.Name, pkg.Name, pkg.Version, pkg.Index,
+			strings.Join(unmatched, ", ")))
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no files found on index %s for %s %s",
+			pkg.Index, pkg.Name, pkg.Version)
+	}
+	pkg.Files = files
+
+	return nil
+}
+
+func (e *ExtensionPython) fetchIndexFiles(restGuard *guard.RestGuard,
+	index, name string) ([]*PythonDistFile, error) {
+
+	index = strings.TrimSuffix(index, "/")
+	pageUrl := fmt.Sprintf("%s/%s/", index, name)
+
+	data, err := fetchUrl(restGuard, pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePythonSimpleIndex(pageUrl, string(data))
+}
+
+// ParsePythonSimpleIndex returns the files available
+// on the HTML page of a PEP 503 simple index.
+func ParsePythonSimpleIndex(pageUrl, content string) ([]*PythonDistFile, error) {
+	base, err := nurl.Parse(pageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := []*PythonDistFile{}
+	for _, m := range pythonIndexLinkRegex.FindAllStringSubmatch(content, -1) {
+		href, err := nurl.Parse(html.UnescapeString(m[1]))
+		if err != nil {
+			continue
+		}
+		u := base.ResolveReference(href)
+
+		f := &PythonDistFile{
+			Name: strings.TrimSpace(html.UnescapeString(m[2])),
+		}
+		if u.Fragment != "" {
+			f.Hash = u.Fragment
+		}
+		u.Fragment = ""
+		f.Url = u.String()
+		if f.Name == "" {
+			f.Name, _ = nurl.PathUnescape(path.Base(u.Path))
+		}
+
+		ans = append(ans, f)
+	}
+
+	return ans, nil
+}
+
+func pythonHashEqual(h1, h2 string) bool {
+	return strings.Replace(h1, "=", ":", 1) == strings.Replace(h2, "=", ":", 1)
+}
+
+func (e *ExtensionPython) retrievePythonLock(atom *specs.AutogenAtom,
+	targetDir string) (string, *PythonLock, error) {
+
+	unpackDirPrefix, _ := e.Opts["unpack_srcdir_prefix"]
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	lockFiles := pythonLockFiles
+	if lockfile, ok := e.Opts["lockfile"]; ok && lockfile != "" {
+		lockFiles = []string{lockfile}
+	}
+
+	pkgUnpackDir := ""
+	lockPath := ""
+	lockFile := ""
+
+	for _, entry := range entries {
+
+		if !entry.IsDir() {
+			continue
+		}
+
+		if unpackDirPrefix != "" && !strings.HasPrefix(entry.Name(), unpackDirPrefix) {
+			continue
+		}
+
+		for _, f := range lockFiles {
+			p := filepath.Join(targetDir, entry.Name(), f)
+			if utils.Exists(p) {
+				pkgUnpackDir = filepath.Join(targetDir, entry.Name())
+				lockPath = p
+				lockFile = f
+				break
+			}
+		}
+
+		if lockPath != "" {
+			break
+		}
+	}
+
+	if lockPath == "" {
+		return "", nil, fmt.Errorf("python lock file not found")
+	}
+
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error on read file %s: %s",
+			lockFile, err.Error())
+	}
+
+	pyLock, err := ParsePythonLock(pkgUnpackDir, lockFile, data, e.Opts["index"])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return pkgUnpackDir, pyLock, nil
+}
@@ -0,0 +1,279 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const poetryLock = `[[package]]
+name = "Foo_Bar"
+version = "1.0.0"
+files = [
+    {file = "Foo_Bar-1.0.0-py3-none-any.whl", hash = "sha256:aaa"},
+    {file = "Foo_Bar-1.0.0.tar.gz", hash = "sha256:bbb"},
+]
+
+[[package]]
+name = "private"
+version = "2.0.0"
+files = [
+    {file = "private-2.0.0.tar.gz", hash = "sha256:ccc"},
+]
+
+[package.source]
+type = "legacy"
+url = "https://pypi.example.org/simple"
+reference = "private"
+
+[[package]]
+name = "direct"
+version = "3.0.0"
+files = [
+    {file = "direct-3.0.0.tar.gz", hash = "sha256:ddd"},
+]
+
+[package.source]
+type = "url"
+url = "https://example.org/direct-3.0.0.tar.gz"
+
+[[package]]
+name = "local"
+version = "0.1.0"
+files = []
+
+[package.source]
+type = "directory"
+url = "../local"
+`
+
+const poetryLockLegacy = `[[package]]
+name = "foo"
+version = "1.0.0"
+
+[metadata.files]
+foo = [
+    {file = "foo-1.0.0.tar.gz", hash = "sha256:aaa"},
+]
+`
+
+const uvLock = `version = 1
+
+[[package]]
+name = "app"
+version = "0.1.0"
+source = { editable = "." }
+
+[[package]]
+name = "foo"
+version = "1.0.0"
+source = { registry = "https://pypi.org/simple" }
+sdist = { url = "https://files.example.org/foo-1.0.0.tar.gz", hash = "sha256:aaa" }
+wheels = [
+    { url = "https://files.example.org/foo-1.0.0-py3-none-any.whl", hash = "sha256:bbb" },
+]
+
+[[package]]
+name = "bar"
+version = "2.0.0"
+source = { url = "https://example.org/bar%2B1-2.0.0.tar.gz" }
+sdist = { url = "https://example.org/bar%2B1-2.0.0.tar.gz", hash = "sha256:ccc" }
+
+[[package]]
+name = "g"
+version = "1.0.0"
+source = { git = "https://github.com/foo/g?rev=abc" }
+`
+
+var _ = Describe("Python lock", func() {
+
+	// lockRows returns the packages of the lock in the format:
+	// name version index [file url hash]...
+	lockRows := func(lock *PythonLock) []string {
+		rows := []string{}
+		for _, p := range lock.Packages {
+			row := fmt.Sprintf("%s %s %s", p.Name, p.Version, p.Index)
+			for _, f := range p.Files {
+				row += fmt.Sprintf(" [%s %s %s]", f.Name, f.Url, f.Hash)
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	}
+
+	DescribeTable("ParsePythonLock",
+		func(file, content, index, format string, expected []string) {
+			lock, err := ParsePythonLock("", file, []byte(content), index)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lock.Format).To(Equal(format))
+			Expect(lockRows(lock)).To(Equal(expected))
+		},
+		Entry("poetry", "poetry.lock", poetryLock, "", PythonLockPoetry, []string{
+			"direct 3.0.0 https://pypi.org/simple" +
+				" [direct-3.0.0.tar.gz https://example.org/direct-3.0.0.tar.gz sha256:ddd]",
+			"foo-bar 1.0.0 https://pypi.org/simple" +
+				" [Foo_Bar-1.0.0-py3-none-any.whl  sha256:aaa]" +
+				" [Foo_Bar-1.0.0.tar.gz  sha256:bbb]",
+			"private 2.0.0 https://pypi.example.org/simple [private-2.0.0.tar.gz  sha256:ccc]",
+		}),
+		Entry("poetry with the index in input", "poetry.lock", poetryLockLegacy,
+			"https://mirror.example.org/simple", PythonLockPoetry, []string{
+				"foo 1.0.0 https://mirror.example.org/simple [foo-1.0.0.tar.gz  sha256:aaa]",
+			}),
+		Entry("uv", "uv.lock", uvLock, "", PythonLockUv, []string{
+			"bar 2.0.0 https://pypi.org/simple" +
+				" [bar+1-2.0.0.tar.gz https://example.org/bar%2B1-2.0.0.tar.gz sha256:ccc]",
+			"foo 1.0.0 https://pypi.org/simple" +
+				" [foo-1.0.0.tar.gz https://files.example.org/foo-1.0.0.tar.gz sha256:aaa]" +
+				" [foo-1.0.0-py3-none-any.whl https://files.example.org/foo-1.0.0-py3-none-any.whl sha256:bbb]",
+		}),
+		Entry("requirements", "requirements.txt", `# comment
+--index-url https://pypi.example.org/simple
+Foo.Bar[extra]==1.0.0 ; python_version >= "3.8" \
+    --hash=sha256:aaa \
+    --hash=sha256:bbb
+baz==2.0.0 --hash sha256:ccc  # inline comment
+`, "", PythonLockRequirements, []string{
+			"baz 2.0.0 https://pypi.example.org/simple [  sha256:ccc]",
+			"foo-bar 1.0.0 https://pypi.example.org/simple [  sha256:aaa] [  sha256:bbb]",
+		}),
+	)
+
+	It("fails with an unsupported lock file", func() {
+		_, err := ParsePythonLock("", "Pipfile.lock", []byte("{}"), "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("requirements with includes", func() {
+		var dir string
+
+		writeFile := func(name, content string) {
+			f := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(f), 0755)).To(Succeed())
+			Expect(os.WriteFile(f, []byte(content), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+		})
+
+		It("completes the requirements with the constraints", func() {
+			writeFile("reqs/base.txt", "foo\nbar==2.0.0\n-c ../constraints.txt\n")
+			writeFile("constraints.txt",
+				"foo==1.0.0 --hash=sha256:aaa\nbar==2.0.0 --hash=sha256:bbb\nunused==1.0 --hash=sha256:ccc\n")
+
+			lock, err := ParsePythonLock(dir, "requirements.txt",
+				[]byte("-r reqs/base.txt\nbaz==3.0.0 --hash=sha256:ddd\n"), "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lockRows(lock)).To(Equal([]string{
+				"bar 2.0.0 https://pypi.org/simple [  sha256:bbb]",
+				"baz 3.0.0 https://pypi.org/simple [  sha256:ddd]",
+				"foo 1.0.0 https://pypi.org/simple [  sha256:aaa]",
+			}))
+		})
+
+		It("parses only once the files included more times", func() {
+			writeFile("a.txt", "-r b.txt\nfoo==1.0.0 --hash=sha256:aaa\n")
+			writeFile("b.txt", "-r a.txt\n")
+
+			lock, err := ParsePythonLock(dir, "requirements.txt",
+				[]byte("-ra.txt\n--requirement=b.txt\n"), "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lockRows(lock)).To(Equal([]string{
+				"foo 1.0.0 https://pypi.org/simple [  sha256:aaa]",
+			}))
+		})
+
+		DescribeTable("fails with invalid requirements",
+			func(content string) {
+				writeFile("constraints.txt", "foo==2.0.0 --hash=sha256:aaa\n")
+				_, err := ParsePythonLock(dir, "requirements.txt", []byte(content), "")
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("not pinned", "foo\nbar\n-c constraints.txt\n"),
+			Entry("without hashes", "bar==1.0.0\n"),
+			Entry("constraint of another version", "foo==1.0.0\n-c constraints.txt\n"),
+			Entry("include outside of the sources", "-r ../other.txt\n"),
+			Entry("absolute include", "-r /etc/requirements.txt\n"),
+			Entry("remote include", "-r https://example.org/requirements.txt\n"),
+			Entry("missing include", "-r missing.txt\n"),
+		)
+	})
+
+	DescribeTable("SelectFiles",
+		func(distType string, files []string, expected []string) {
+			pkg := &PythonPackage{Name: "foo", Version: "1.0.0"}
+			for _, f := range files {
+				pkg.Files = append(pkg.Files, &PythonDistFile{Name: f})
+			}
+
+			selected, err := pkg.SelectFiles(distType)
+			if expected == nil {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).ToNot(HaveOccurred())
+			names := []string{}
+			for _, f := range selected {
+				names = append(names, f.Name)
+			}
+			Expect(names).To(Equal(expected))
+		},
+		Entry("sdist", PythonDistSdist,
+			[]string{"foo-1.0.0-py3-none-any.whl", "foo-1.0.0.tar.gz"},
+			[]string{"foo-1.0.0.tar.gz"}),
+		Entry("wheel", PythonDistWheel,
+			[]string{"foo-1.0.0-py3-none-any.whl", "foo-1.0.0.tar.gz"},
+			[]string{"foo-1.0.0-py3-none-any.whl"}),
+		Entry("sdist fallback to the pure wheel", PythonDistSdist,
+			[]string{"foo-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl", "foo-1.0.0-py3-none-any.whl"},
+			[]string{"foo-1.0.0-py3-none-any.whl"}),
+		Entry("wheel fallback to the sdist", PythonDistWheel,
+			[]string{"foo-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl", "foo-1.0.0.zip"},
+			[]string{"foo-1.0.0.zip"}),
+		Entry("all", PythonDistAll,
+			[]string{"foo-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl", "foo-1.0.0.tar.gz"},
+			[]string{"foo-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl", "foo-1.0.0.tar.gz"}),
+		Entry("only binary wheels", PythonDistSdist,
+			[]string{"foo-1.0.0-cp312-cp312-manylinux_2_17_x86_64.whl"}, nil),
+	)
+
+	Context("CheckPythonHash", func() {
+		var file string
+		content := []byte("sdist")
+		sum := sha256.Sum256(content)
+		digest := hex.EncodeToString(sum[:])
+
+		BeforeEach(func() {
+			file = filepath.Join(GinkgoT().TempDir(), "foo-1.0.0.tar.gz")
+			Expect(os.WriteFile(file, content, 0644)).To(Succeed())
+		})
+
+		DescribeTable("validates the hash",
+			func(hash func() string, valid bool) {
+				err := CheckPythonHash(file, hash())
+				if valid {
+					Expect(err).ToNot(HaveOccurred())
+				} else {
+					Expect(err).To(HaveOccurred())
+				}
+			},
+			Entry("lock format", func() string { return "sha256:" + digest }, true),
+			Entry("index format", func() string { return "sha256=" + digest }, true),
+			Entry("mismatch", func() string { return "sha256:" + digest[1:] + "0" }, false),
+			Entry("unsupported algorithm", func() string { return "crc32:" + digest }, false),
+			Entry("invalid hash", func() string { return digest }, false),
+		)
+	})
+})
@@ -5,10 +5,14 @@ See AUTHORS and LICENSE for the license details and contributors.
 package extensions
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
+	"io"
 	nurl "net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -19,9 +23,6 @@ import (
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/geaaru/rest-guard/pkg/guard"
-	executor "github.com/geaaru/tar-formers/pkg/executor"
-	tarf_specs "github.com/geaaru/tar-formers/pkg/specs"
-	"github.com/geaaru/tar-formers/pkg/tools"
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 	"github.com/pelletier/go-toml/v2"
 )
@@ -54,6 +55,7 @@ type CargoPackage struct {
 	Source       any      `toml:"source,omitempty"`
 	Checksum     string   `toml:"checksum,omitempty"`
 	Dependencies []string `toml:"dependencies,omitempty"`
+	License      any      `toml:"license,omitempty"`
 }
 
 type CargoLocalDeps struct {
@@ -72,6 +74,15 @@ func (cp *CargoPackage) GetVersion() string {
 	return ""
 }
 
+// GetLicense returns the SPDX expression of the license. The
+// license inherited from the workspace is not resolved.
+func (cp *CargoPackage) GetLicense() string {
+	if license, ok := cp.License.(string); ok {
+		return license
+	}
+	return ""
+}
+
 func (cp *CargoPackage) GetSource() string {
 	if source, ok := cp.Source.(string); ok {
 		return source
@@ -101,6 +112,18 @@ func (e *ExtensionRust) GetCratesSkipped() map[string]bool {
 	return ans
 }
 
+// Bytes returns the sorted packages of the Cargo.lock used
+// to calculate the fingerprint of the bundle.
+func (c *CargoLock) Bytes() []byte {
+	rows := []string{}
+	for _, pkg := range c.Packages {
+		rows = append(rows, fmt.Sprintf("%s %v %v %s",
+			pkg.Name, pkg.Version, pkg.Source, pkg.Checksum))
+	}
+	sort.Strings(rows)
+	return []byte(strings.Join(rows, "\n"))
+}
+
 func (e *ExtensionRust) Elaborate(restGuard *guard.RestGuard,
 	atom, def *specs.AutogenAtom,
 	mapref *map[string]interface{}) error {
@@ -108,133 +131,64 @@ func (e *ExtensionRust) Elaborate(restGuard *guard.RestGuard,
 	log := logger.GetDefaultLogger()
 	values := *mapref
 
-	// Create the temporary directory of the package
-	workdir, _ := e.Opts["workdir"]
-	downloadDir := e.Opts["download_dir"]
-	if !filepath.IsAbs(downloadDir) {
-		downloadDir, _ = filepath.Abs(downloadDir)
-	}
-	if !filepath.IsAbs(workdir) {
-		workdir, _ = filepath.Abs(workdir)
-	}
-
-	pkgWorkDir := filepath.Join(workdir, "rust-extension", atom.Name)
-
-	err := os.MkdirAll(pkgWorkDir, os.ModePerm)
-	if err != nil {
-		return err
-	}
-
-	mirror := e.Opts["mirror"]
-	if mirror == "" {
-		mirror = "mirror://macaroni"
-	}
-	bundleIdentifier := e.Opts["bundle_identifier"]
-	if bundleIdentifier == "" {
-		bundleIdentifier = "mark-rust-bundle"
-	}
-	bundleExtension := e.Opts["bundle_extension"]
-	if bundleExtension == "" {
-		bundleExtension = "xz"
-	}
-	// Fix typo on bundle_extension value.
-	bundleExtension = strings.ReplaceAll(bundleExtension, ".", "")
-	values["mirror"] = mirror
-
-	// Ensure download dir. Could be not present the first time.
-	err = os.MkdirAll(downloadDir, os.ModePerm)
-	if err != nil {
-		return err
-	}
-
-	// Retrieve artefacts.
-	artefacts, _ := values["artefacts"].([]*specs.AutogenArtefact)
-	// NOTE: I consider the first artefact the main for golang vendor generation.
-	if len(artefacts) == 0 {
-		log.DebugC(
-			fmt.Sprintf(
-				"[%s] No artefacts found for rust vendor generation. Nothing to do.",
-				atom.Name,
-			))
-		return nil
-	}
-
-	art := artefacts[0]
-
-	// Download the main artefacts to the download dir.
-	log.DebugC(
-		fmt.Sprintf("[%s] Downloading %s from url %s",
-			atom.Name, art.Name, art.SrcUri[0],
-		))
-	repoFile, err := autogenart.DownloadArtefact(restGuard, atom,
-		art.SrcUri[0], art.Name, downloadDir)
-	if err != nil {
-		return err
-	}
-
-	// Unpack the tarballs
-	unpackDir := filepath.Join(pkgWorkDir, "unpack")
-	log.Info(
-		fmt.Sprintf(":factory:[%s] Extracting file %s",
-			atom.Name, repoFile.Name,
-		))
-	err = e.unpackArtefact(downloadDir, unpackDir, repoFile,
-		atom, def, mapref)
-	if err != nil {
+	w, err := e.prepareBundleWorkspace(restGuard, atom, def, mapref, "rust")
+	if err != nil || w == nil {
 		return err
 	}
 
 	// Retrieve Cargo.lock
-	pkgUnpackDir, cargoLock, err := e.retrieveCargoLock(atom, unpackDir)
+	pkgUnpackDir, cargoLock, err := e.retrieveCargoLock(atom, w.UnpackDir)
 	if err != nil {
 		return err
 	}
 	values["pkg_basedir"] = filepath.Base(pkgUnpackDir)
 
-	// Read all Cargo.toml in order to identify all local crates
-	localCrates, err := e.parseCargoToml(atom, pkgUnpackDir, true)
-	if err != nil {
-		return fmt.Errorf(
-			"Error on parse cargo toml: %s", err.Error())
-	}
-
-	// Download cargo bundles files
-	bundlesDir := filepath.Join(pkgWorkDir, bundleIdentifier+"-"+atom.Name)
-	err = e.downloadBundles(restGuard, atom, cargoLock, localCrates,
-		pkgUnpackDir, bundlesDir)
+	fingerprint, err := e.getFingerprint(specs.ExtensionRust, cargoLock.Bytes())
 	if err != nil {
 		return err
 	}
 
-	// Create bundle tarball
-	version, _ := values["version"].(string)
-	sha, _ := values["sha"].(string)
-	bundleTarball := ""
-	if sha == "" {
-		bundleTarball = fmt.Sprintf(
-			"%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier, bundleExtension,
-		)
-	} else {
-		bundleTarball = fmt.Sprintf(
-			"%s-%s-%s-%s.tar.%s", atom.Name, version, bundleIdentifier,
-			sha[0:7], bundleExtension,
-		)
-	}
-	bundleArt, err := e.createBundleTarball(bundlesDir,
-		filepath.Join(downloadDir, bundleTarball), workdir, atom.Name, mirror)
-	if err != nil {
-		return err
+	bundleLicenses := ""
+	bundleTarball, bundleArt := e.lookupWorkspaceBundle(w, atom, values, fingerprint)
+	if bundleArt != nil {
+		bundleLicenses, err = e.getBundleLicenses(w.DownloadDir, bundleTarball)
+		if err != nil {
+			log.Warning(fmt.Sprintf("[%s] Bundle %s regenerated: %s",
+				atom.Name, bundleTarball, err.Error()))
+			bundleArt = nil
+		}
 	}
+	if bundleArt == nil {
+		// Read all Cargo.toml in order to identify all local crates
+		localCrates, err := e.parseCargoToml(atom, pkgUnpackDir, true)
+		if err != nil {
+			return fmt.Errorf(
+				"Error on parse cargo toml: %s", err.Error())
+		}
 
-	artefacts = append(artefacts, bundleArt)
-	values["artefacts"] = artefacts
-
-	e.cleanup(mapref)
+		// Download cargo bundles files
+		licenses := NewBundleLicenses()
+		// The git crates are cloned in the work dir of the package
+		// in order to keep clean the sources shared between extensions.
+		err = e.downloadBundles(restGuard, atom, cargoLock, localCrates,
+			licenses, w.PkgWorkDir, w.BundlesDir())
+		if err != nil {
+			return err
+		}
+		bundleLicenses = licenses.String()
 
-	if !logger.GetDefaultLogger().Config.GetGeneral().Debug {
-		defer os.RemoveAll(filepath.Join(workdir, "rust-extension"))
+		// Create bundle tarball
+		bundleArt, err = e.createBundleTarball(w, atom, bundleTarball)
+		if err != nil {
+			return err
+		}
 	}
 
+	// The licenses of the crates with the Gentoo syntax.
+	values["bundle_licenses"] = bundleLicenses
+
+	e.completeBundle(w, mapref, bundleArt)
+
 	return nil
 }
 
@@ -257,66 +211,9 @@ func (e *ExtensionRust) parseCargoToml(atom *specs.AutogenAtom,
 	return ans, err
 }
 
-func (e *ExtensionRust) createBundleTarball(
-	bundlesDir, bundleTarball, workDir, atomName, mirror string) (*specs.AutogenArtefact, error) {
-	var err error
-	log := logger.GetDefaultLogger()
-
-	tarformers := executor.NewTarFormers(e.getTarformersConfig())
-	s := tarf_specs.NewSpecFile()
-	// We don't need to keep the original permission of the files
-	// and owner.
-	s.SameOwner = false
-	s.SameChtimes = false
-	s.Writer = tarf_specs.NewWriter()
-	s.Writer.ArchiveDirs = []string{bundlesDir}
-
-	s.RenamePath = []tarf_specs.RenameRule{
-		{
-			Source: filepath.Join(workDir, "rust-extension", atomName),
-			Dest:   ".",
-		},
-	}
-	tarfOpts := tools.NewTarCompressionOpts(true)
-	defer tarfOpts.Close()
-
-	err = tools.PrepareTarWriter(bundleTarball, tarfOpts)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"error on prepare writer: %s",
-			err.Error())
-	}
-
-	if tarfOpts.CompressWriter != nil {
-		tarformers.SetWriter(tarfOpts.CompressWriter)
-	} else {
-		tarformers.SetWriter(tarfOpts.FileWriter)
-	}
-
-	fileName := filepath.Base(bundleTarball)
-	log.Debug(fmt.Sprintf("[%s] Creating tarball %s...",
-		atomName, fileName))
-
-	err = tarformers.RunTaskWriter(s)
-	if err != nil {
-		return nil, fmt.Errorf(
-			"error on create tarball %s: %s",
-			fileName, err.Error())
-	}
-
-	artUri := fmt.Sprintf("%s/%s", mirror, fileName)
-
-	local := true
-	return &specs.AutogenArtefact{
-		SrcUri: []string{artUri},
-		Name:   fileName,
-		Local:  &local,
-	}, nil
-}
-
 func (e *ExtensionRust) downloadBundles(restGuard *guard.RestGuard,
 	atom *specs.AutogenAtom, cargoLock *CargoLock, localCrates *CargoLocalDeps,
-	unpackDir, bundlesDir string) error {
+	licenses *BundleLicenses, workDir, bundlesDir string) error {
 	log := logger.GetDefaultLogger()
 	var err error
 	var sourceOrigin string
@@ -382,6 +279,16 @@ func (e *ExtensionRust) downloadBundles(restGuard *guard.RestGuard,
 				return err
 			}
 
+			license, err := readCrateLicense(filepath.Join(bundlesDir, bundle),
+				pkg.Name, pkg.GetVersion())
+			if err != nil {
+				return err
+			}
+			err = e.addCrateLicense(atom, &pkg, license, licenses)
+			if err != nil {
+				return err
+			}
+
 		} else {
 			// Keep old logic for now. Maybe we can avoid this.
 			// Drop initial git+ string
@@ -402,18 +309,87 @@ func (e *ExtensionRust) downloadBundles(restGuard *guard.RestGuard,
 	if len(gitDeps) > 0 {
 		for url, gitPkgs := range gitDeps {
 			// POST: git bundle
-			err = e.processGitCrate(atom, gitPkgs, url, bundlesDir, unpackDir)
+			err = e.processGitCrate(atom, gitPkgs, url, bundlesDir, workDir,
+				licenses)
 			if err != nil {
 				return err
 			}
 		}
 	}
 
+	return licenses.Write(filepath.Join(bundlesDir, BundleLicensesFile))
+}
+
+func (e *ExtensionRust) addCrateLicense(atom *specs.AutogenAtom,
+	pkg *CargoPackage, license string, licenses *BundleLicenses) error {
+	if license == "" {
+		logger.GetDefaultLogger().Warning(fmt.Sprintf(
+			"[%s] No license available for crate %s %s.",
+			atom.Name, pkg.Name, pkg.GetVersion()))
+		return nil
+	}
+
+	err := licenses.AddSpdx(license)
+	if err != nil {
+		return fmt.Errorf("error on license of crate %s %s: %s",
+			pkg.Name, pkg.GetVersion(), err.Error())
+	}
 	return nil
 }
 
+// readCrateLicense returns the license of the Cargo.toml
+// stored inside the crate file.
+func readCrateLicense(crateFile, name, version string) (string, error) {
+	f, err := os.Open(crateFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("error on read crate %s: %s",
+			filepath.Base(crateFile), err.Error())
+	}
+	defer gz.Close()
+
+	cargoToml := fmt.Sprintf("%s-%s/Cargo.toml", name, version)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("error on read crate %s: %s",
+				filepath.Base(crateFile), err.Error())
+		}
+		if strings.TrimPrefix(header.Name, "./") != cargoToml {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return "", err
+		}
+		crate := &CargoToml{}
+		if err = toml.Unmarshal(data, crate); err != nil {
+			return "", fmt.Errorf("error on parse %s: %s",
+				cargoToml, err.Error())
+		}
+		if crate.Package == nil {
+			return "", nil
+		}
+		return crate.Package.GetLicense(), nil
+	}
+
+	return "", fmt.Errorf("no Cargo.toml found on crate %s",
+		filepath.Base(crateFile))
+}
+
 func (e *ExtensionRust) processGitCrate(atom *specs.AutogenAtom,
-	pkgs []*CargoPackage, url, bundlesDir, unpackDir string) error {
+	pkgs []*CargoPackage, url, bundlesDir, workDir string,
+	licenses *BundleLicenses) error {
 	log := logger.GetDefaultLogger()
 
 	var ref, branch string
@@ -451,7 +427,7 @@ func (e *ExtensionRust) processGitCrate(atom *specs.AutogenAtom,
 	cloneBasenameDir := strings.ReplaceAll(
 		strings.ReplaceAll(gitRepo, ":", "%3A"),
 		"/", "%2F") + "-" + ref
-	cloneDir := filepath.Join(unpackDir, "staging", cloneBasenameDir)
+	cloneDir := filepath.Join(workDir, "staging", cloneBasenameDir)
 
 	singleBranch := true
 	if e.Opts["crates_git_single_branch"] == "false" {
@@ -459,6 +435,7 @@ func (e *ExtensionRust) processGitCrate(atom *specs.AutogenAtom,
 	}
 
 	cloneOpts := &kit.CloneOptions{
+		Config: log.Config,
 		GitCloneOptions: &git.CloneOptions{
 			SingleBranch: singleBranch,
 			RemoteName:   "origin",
@@ -509,6 +486,14 @@ func (e *ExtensionRust) processGitCrate(atom *specs.AutogenAtom,
 				pkg.Name, gitRepo)
 		}
 
+		if localCrate.Package != nil {
+			err = e.addCrateLicense(atom, pkg, localCrate.Package.GetLicense(),
+				licenses)
+			if err != nil {
+				return err
+			}
+		}
+
 		if cloneDir == localCrate.Path {
 			markConfigContent += pkg.Name +
 				" = { path = \"%CRATES_DIR%/" + cloneBasenameDir + "\" }\n"
@@ -528,42 +513,13 @@ func (e *ExtensionRust) processGitCrate(atom *specs.AutogenAtom,
 
 	// Create bundle of the git crate
 	archiveName := fmt.Sprintf("%s.tar.xz", cloneBasenameDir)
-	tarformers := executor.NewTarFormers(e.getTarformersConfig())
-	s := tarf_specs.NewSpecFile()
-	// We don't need to keep the original permission of the files
-	// and owner.
-	s.SameOwner = false
-	s.SameChtimes = false
-	s.Writer = tarf_specs.NewWriter()
-	s.Writer.ArchiveDirs = []string{cloneDir}
-
-	s.RenamePath = []tarf_specs.RenameRule{
-		{
-			Source: filepath.Join(unpackDir, "staging"),
-			Dest:   ".",
-		},
-	}
-	tarfOpts := tools.NewTarCompressionOpts(true)
-	defer tarfOpts.Close()
-
-	err = tools.PrepareTarWriter(
-		filepath.Join(bundlesDir, archiveName), tarfOpts)
-	if err != nil {
-		return fmt.Errorf(
-			"error on prepare writer: %s",
-			err.Error())
-	}
-
-	if tarfOpts.CompressWriter != nil {
-		tarformers.SetWriter(tarfOpts.CompressWriter)
-	} else {
-		tarformers.SetWriter(tarfOpts.FileWriter)
-	}
-
 	log.Debug(fmt.Sprintf("[%s] Creating tarball %s...",
 		atom.Name, archiveName))
 
-	err = tarformers.RunTaskWriter(s)
+	err = e.createReproducibleTarball(
+		[]string{cloneDir},
+		filepath.Join(workDir, "staging"),
+		filepath.Join(bundlesDir, archiveName))
 	if err != nil {
 		return fmt.Errorf(
 			"error on create tarball %s: %s",
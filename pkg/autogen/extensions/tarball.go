@@ -0,0 +1,202 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsnet/compress/bzip2"
+	"github.com/geaaru/tar-formers/pkg/tools"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// getSourceDateEpoch returns the modification time used for all the
+// entries of the bundles. The option source_date_epoch has priority
+// over the environment variable SOURCE_DATE_EPOCH. Without both
+// the Unix epoch is used.
+func (e *ExtensionBase) getSourceDateEpoch() (time.Time, error) {
+	epoch, _ := e.Opts["source_date_epoch"]
+	if epoch == "" {
+		epoch = os.Getenv("SOURCE_DATE_EPOCH")
+	}
+	if epoch == "" {
+		return time.Unix(0, 0).UTC(), nil
+	}
+
+	secs, err := strconv.ParseInt(strings.TrimSpace(epoch), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid source date epoch %s: %s",
+			epoch, err.Error())
+	}
+
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// createReproducibleTarball creates the tarball with the content of
+// the archiveDirs. The names of the entries are relative to baseDir with
+// the prefix "./". The entries are sorted and the timestamps, the owner
+// and the permissions are normalized in order to produce byte-identical
+// tarballs from the same files on different machines.
+func (e *ExtensionBase) createReproducibleTarball(archiveDirs []string,
+	baseDir, tarball string) error {
+	mtime, err := e.getSourceDateEpoch()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(tarball)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	bw := bufio.NewWriter(out)
+
+	cw, err := newReproducibleCompressWriter(tarball, bw)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(cw)
+
+	dirs := append([]string{}, archiveDirs...)
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		// WalkDir visits the entries of every directory in lexical order.
+		err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			return writeReproducibleEntry(tw, p, d, err, baseDir, mtime)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	if err = cw.Close(); err != nil {
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+
+	return out.Close()
+}
+
+func writeReproducibleEntry(tw *tar.Writer, p string, d fs.DirEntry, err error,
+	baseDir string, mtime time.Time) error {
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(baseDir, p)
+	if err != nil {
+		return err
+	}
+	name := "./" + filepath.ToSlash(rel)
+	if rel == "." {
+		name = "./"
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    name,
+		ModTime: mtime,
+		Format:  tar.FormatPAX,
+	}
+
+	switch {
+	case d.IsDir():
+		header.Typeflag = tar.TypeDir
+		header.Mode = 0755
+		if !strings.HasSuffix(header.Name, "/") {
+			header.Name += "/"
+		}
+	case d.Type()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+		header.Typeflag = tar.TypeSymlink
+		header.Linkname = link
+		header.Mode = 0777
+	case d.Type().IsRegular():
+		header.Typeflag = tar.TypeReg
+		header.Size = info.Size()
+		header.Mode = 0644
+		if info.Mode().Perm()&0111 != 0 {
+			header.Mode = 0755
+		}
+	default:
+		// Devices, sockets and pipes are not used on bundles.
+		return nil
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newReproducibleCompressWriter returns the compressor selected by
+// the extension of the tarball with fixed settings. The parallel
+// compressors are avoided because the output could change with the
+// number of the CPUs.
+func newReproducibleCompressWriter(tarball string, w io.Writer) (io.WriteCloser, error) {
+	switch tools.GetCompressionMode(tarball) {
+	case tools.Gzip:
+		// The header is without name and modification time.
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	case tools.Zstd:
+		return zstd.NewWriter(w,
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithEncoderLevel(zstd.SpeedDefault),
+		)
+	case tools.Xz:
+		return xz.NewWriter(w)
+	case tools.Bzip2:
+		return bzip2.NewWriter(w, &bzip2.WriterConfig{
+			Level: bzip2.BestCompression,
+		})
+	default:
+		return nopWriteCloser{w}, nil
+	}
+}
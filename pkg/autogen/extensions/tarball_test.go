@@ -0,0 +1,148 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package extensions_test
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/extensions"
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type tarballFile struct {
+	Name    string
+	Content string
+	Exec    bool
+}
+
+var tarballFiles = []tarballFile{
+	{Name: "bundle/a/b/c.txt", Content: "c"},
+	{Name: "bundle/a/z.txt", Content: "z"},
+	{Name: "bundle/bin/run.sh", Content: "#!/bin/sh\n", Exec: true},
+	{Name: "bundle/m.txt", Content: "m"},
+	{Name: "other/x.txt", Content: "x"},
+}
+
+// writeTarballTree creates the files in the order in input with the
+// permissions and the modification time defined by the variant.
+func writeTarballTree(files []tarballFile, variant int) string {
+	baseDir := GinkgoT().TempDir()
+	mtime := time.Date(2020+variant, time.March, variant+1, 10, 0, 0, 0, time.UTC)
+	fileMode := []os.FileMode{0644, 0600}[variant]
+	execMode := []os.FileMode{0755, 0700}[variant]
+	dirMode := []os.FileMode{0755, 0700}[variant]
+
+	for _, f := range files {
+		file := filepath.Join(baseDir, f.Name)
+		Expect(os.MkdirAll(filepath.Dir(file), dirMode)).To(Succeed())
+		mode := fileMode
+		if f.Exec {
+			mode = execMode
+		}
+		Expect(os.WriteFile(file, []byte(f.Content), mode)).To(Succeed())
+		Expect(os.Chmod(file, mode)).To(Succeed())
+	}
+	Expect(os.Symlink("run.sh", filepath.Join(baseDir, "bundle/bin/link"))).To(Succeed())
+
+	err := filepath.Walk(baseDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			return err
+		}
+		return os.Chtimes(p, mtime, mtime)
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	return baseDir
+}
+
+func createTarball(ext *ExtensionBase, baseDir, name string) string {
+	tarball := filepath.Join(GinkgoT().TempDir(), name)
+	Expect(ext.CreateReproducibleTarball([]string{
+		filepath.Join(baseDir, "other"),
+		filepath.Join(baseDir, "bundle"),
+	}, baseDir, tarball)).To(Succeed())
+
+	md5, err := helpers.GetFileMd5(tarball)
+	Expect(err).ToNot(HaveOccurred())
+	return md5
+}
+
+var _ = Describe("Reproducible tarball", func() {
+
+	reversed := make([]tarballFile, len(tarballFiles))
+	for idx, f := range tarballFiles {
+		reversed[len(tarballFiles)-1-idx] = f
+	}
+
+	DescribeTable("creates the same tarball from different trees",
+		func(name string) {
+			ext := &ExtensionBase{Opts: map[string]string{}}
+			first := createTarball(ext, writeTarballTree(tarballFiles, 0), name)
+			second := createTarball(ext, writeTarballTree(reversed, 1), name)
+			Expect(second).To(Equal(first))
+		},
+		Entry("tar", "bundle.tar"),
+		Entry("gzip", "bundle.tar.gz"),
+		Entry("zstd", "bundle.tar.zst"),
+		Entry("xz", "bundle.tar.xz"),
+		Entry("bzip2", "bundle.tar.bz2"),
+	)
+
+	It("normalizes the entries", func() {
+		ext := &ExtensionBase{Opts: map[string]string{"source_date_epoch": "1700000000"}}
+		baseDir := writeTarballTree(reversed, 1)
+		tarball := filepath.Join(GinkgoT().TempDir(), "bundle.tar")
+		Expect(ext.CreateReproducibleTarball([]string{
+			filepath.Join(baseDir, "bundle"),
+			filepath.Join(baseDir, "other"),
+		}, baseDir, tarball)).To(Succeed())
+
+		f, err := os.Open(tarball)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+
+		names := []string{}
+		modes := map[string]int64{}
+		tr := tar.NewReader(f)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+			names = append(names, h.Name)
+			modes[h.Name] = h.Mode
+			Expect(h.ModTime.Unix()).To(Equal(int64(1700000000)))
+			Expect(h.Uid).To(Equal(0))
+			Expect(h.Gid).To(Equal(0))
+		}
+
+		Expect(names).To(Equal([]string{
+			"./bundle/", "./bundle/a/", "./bundle/a/b/", "./bundle/a/b/c.txt",
+			"./bundle/a/z.txt", "./bundle/bin/", "./bundle/bin/link",
+			"./bundle/bin/run.sh", "./bundle/m.txt",
+			"./other/", "./other/x.txt",
+		}))
+		Expect(modes["./bundle/a/"]).To(Equal(int64(0755)))
+		Expect(modes["./bundle/m.txt"]).To(Equal(int64(0644)))
+		Expect(modes["./bundle/bin/run.sh"]).To(Equal(int64(0755)))
+	})
+
+	It("changes the tarball with the source date epoch", func() {
+		baseDir := writeTarballTree(tarballFiles, 0)
+		first := createTarball(&ExtensionBase{Opts: map[string]string{"source_date_epoch": "0"}},
+			baseDir, "bundle.tar.gz")
+		second := createTarball(&ExtensionBase{Opts: map[string]string{"source_date_epoch": "1"}},
+			baseDir, "bundle.tar.gz")
+		Expect(second).ToNot(Equal(first))
+	})
+})
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/kit"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
@@ -37,6 +38,7 @@ func NewDirlistingGenerator(opts map[string]string) *DirlistingGenerator {
 
 	// Overide the default check redirect
 	rg.Client.CheckRedirect = kit.CheckRedirect
+	cassette.WrapClient(rg.Client)
 	ans := &DirlistingGenerator{
 		BaseGenerator: NewBaseGenerator(opts),
 		RestGuard:     rg,
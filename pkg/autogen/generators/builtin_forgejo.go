@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
@@ -42,6 +43,9 @@ func NewForgejoGenerator(opts map[string]string) *ForgejoGenerator {
 		if remotePresent && remote.Token != "" {
 			opts = append(opts, client.SetToken(remote.Token))
 		}
+		if c := cassette.NewClient(); c != nil {
+			opts = append(opts, client.SetHTTPClient(c))
+		}
 
 		ans.Client, err = client.NewClient(host, opts...)
 		if err != nil {
@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
@@ -51,7 +52,12 @@ func NewGitlabGenerator(opts map[string]string) *GitlabGenerator {
 		// We need to pass the api path
 		host := fmt.Sprintf("%s/api/v%s", host, version)
 
-		ans.Client, err = client.NewClient(token, client.WithBaseURL(host))
+		clientOpts := []client.ClientOptionFunc{client.WithBaseURL(host)}
+		if c := cassette.NewClient(); c != nil {
+			clientOpts = append(clientOpts, client.WithHTTPClient(c))
+		}
+
+		ans.Client, err = client.NewClient(token, clientOpts...)
 		if err != nil {
 			log.Error(fmt.Sprintf("error on setup gitlab client for host %s: %s",
 				host, err.Error()))
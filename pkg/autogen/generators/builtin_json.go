@@ -12,6 +12,7 @@ import (
 	"path"
 	"regexp"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/kit"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
@@ -36,6 +37,7 @@ func NewJsonGenerator(opts map[string]string) *JsonGenerator {
 
 	// Overide the default check redirect
 	rg.Client.CheckRedirect = kit.CheckRedirect
+	cassette.WrapClient(rg.Client)
 	ans := &JsonGenerator{
 		BaseGenerator: NewBaseGenerator(opts),
 		RestGuard:     rg,
@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/cassette"
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/kit"
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
@@ -33,6 +34,7 @@ func NewPypiGenerator() *PypiGenerator {
 	rg, _ := guard.NewRestGuard(log.Config.GetRest())
 	// Overide the default check redirect
 	rg.Client.CheckRedirect = kit.CheckRedirect
+	cassette.WrapClient(rg.Client)
 	return &PypiGenerator{
 		RestGuard: rg,
 	}
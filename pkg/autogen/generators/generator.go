@@ -7,6 +7,7 @@ package generators
 import (
 	"fmt"
 
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/plugins"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 )
 
@@ -35,6 +36,10 @@ func NewGenerator(t string, opts map[string]string) (Generator, error) {
 	case specs.GeneratorBuiltinJson:
 		return NewJsonGenerator(opts), nil
 	default:
+		// Search an external plugin for the generator.
+		if _, err := plugins.FindPlugin(plugins.KindGenerator, t); err == nil {
+			return NewPluginGenerator(t, opts)
+		}
 		return nil, fmt.Errorf("Invalid generator type %s", t)
 	}
 }
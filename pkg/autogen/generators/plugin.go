@@ -0,0 +1,85 @@
+/*
+	Copyright © 2024-2026 Macaroni OS Linux
+	See AUTHORS and LICENSE for the license details and contributors.
+*/
+
+package generators
+
+import (
+	"fmt"
+
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/plugins"
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+)
+
+// PluginGenerator is a generator implemented by the external
+// executable mark-devkit-generator-<name>.
+type PluginGenerator struct {
+	*BaseGenerator
+	Type   string
+	Plugin *plugins.Plugin
+}
+
+func NewPluginGenerator(t string, opts map[string]string) (*PluginGenerator, error) {
+	p, err := plugins.NewPlugin(plugins.KindGenerator, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.HasCapability(plugins.CapabilityProcess) {
+		return nil, fmt.Errorf("generator plugin %s without capability %s",
+			t, plugins.CapabilityProcess)
+	}
+
+	return &PluginGenerator{
+		BaseGenerator: NewBaseGenerator(opts),
+		Type:          t,
+		Plugin:        p,
+	}, nil
+}
+
+func (g *PluginGenerator) GetType() string {
+	return g.Type
+}
+
+func (g *PluginGenerator) SetVersion(atom *specs.AutogenAtom, version string,
+	mapref *map[string]interface{}) error {
+	values := *mapref
+
+	if g.Plugin.HasCapability(plugins.CapabilitySetVersion) {
+		params := plugins.NewCallParams(atom, g.Opts, values)
+		params.Version = version
+
+		result := &plugins.CallResult{}
+		err := g.Plugin.Call(plugins.MethodGeneratorSetVersion, params, result)
+		if err != nil {
+			return err
+		}
+
+		result.Apply(values)
+	}
+
+	return g.BaseGenerator.setVersion(atom, version, mapref)
+}
+
+// Retrieve metadata and all availables versions
+func (g *PluginGenerator) Process(atom *specs.AutogenAtom) (*map[string]interface{}, error) {
+	values := make(map[string]interface{}, 0)
+
+	result := &plugins.CallResult{}
+	err := g.Plugin.Call(plugins.MethodGeneratorProcess,
+		plugins.NewCallParams(atom, g.Opts, values), result)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Apply(values)
+
+	err = helpers.SanitizeMapVersionsField(atom.Name, &values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &values, nil
+}
@@ -0,0 +1,309 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package autogen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	GoldenStatusOk      = "ok"
+	GoldenStatusFailed  = "failed"
+	GoldenStatusUpdated = "updated"
+
+	// The placeholder used in the golden files in place
+	// of the work directory.
+	GoldenWorkDirPlaceholder = "${WORKDIR}"
+)
+
+// GoldenResult contains the result of the comparison of the files
+// generated for a package with the golden files.
+type GoldenResult struct {
+	CatPkg   string
+	Status   string
+	Messages []string
+}
+
+// GetGoldenDir returns the directory of the golden files of the
+// specfile. For the specfile foo.yml the directory is foo.golden
+// in the same directory.
+func GetGoldenDir(specfile string) string {
+	base := filepath.Base(specfile)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return filepath.Join(filepath.Dir(specfile), base+".golden")
+}
+
+// GetGoldenCassette returns the default cassette file of the specfile
+// used to replay the HTTP requests.
+func GetGoldenCassette(specfile string) string {
+	base := filepath.Base(specfile)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return filepath.Join(filepath.Dir(specfile), base+".cassette.yml")
+}
+
+// Test executes the autogen of the specfile without merge and compares
+// the ebuilds, the values and the Manifest of every package with the
+// golden files. With update the golden files are replaced.
+func (a *AutogenBot) Test(specfile, kitFile, goldenDir string,
+	update bool, opts *AutogenBotOpts) ([]*GoldenResult, error) {
+
+	aspec := specs.NewAutogenSpec()
+	mkit := specs.NewMergeKit()
+
+	err := aspec.LoadFile(specfile)
+	if err != nil {
+		return nil, err
+	}
+	aspec.Prepare()
+
+	err = mkit.LoadFile(kitFile)
+	if err != nil {
+		return nil, err
+	}
+	targetKit, err := mkit.GetTargetKit()
+	if err != nil {
+		return nil, err
+	}
+
+	// The files are compared after the elaboration.
+	if opts.CleanWorkingDir {
+		defer os.RemoveAll(a.WorkDir)
+	}
+	opts.CleanWorkingDir = false
+	opts.MergeAutogen = false
+	opts.Push = false
+	opts.PullRequest = false
+	opts.SyncFiles = false
+	opts.SaveGeneratedValues = true
+	// The golden files are never updated with a broken elaboration.
+	opts.StopOnError = true
+
+	err = a.Run(specfile, kitFile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workDir, err := filepath.Abs(a.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := []*GoldenResult{}
+	for _, catpkg := range a.getSpecPackages(aspec, opts) {
+		stagingDir := filepath.Join(a.GetSourcesDir(), targetKit.Name, catpkg)
+		valuesDir := filepath.Join(a.GetValuesDir(), catpkg)
+		pkgGoldenDir := filepath.Join(goldenDir, catpkg)
+
+		generated, err := readGoldenFiles(stagingDir, valuesDir)
+		if err != nil {
+			return nil, err
+		}
+		// The values could contain the paths of the work directory.
+		for name, content := range generated {
+			generated[name] = strings.ReplaceAll(content, workDir,
+				GoldenWorkDirPlaceholder)
+		}
+
+		golden, err := readGoldenFiles(pkgGoldenDir)
+		if err != nil {
+			return nil, err
+		}
+
+		res := compareGoldenFiles(catpkg, golden, generated)
+
+		if update && res.Status == GoldenStatusFailed {
+			if len(generated) == 0 {
+				// Nothing to update. The elaboration is failed.
+				ans = append(ans, res)
+				continue
+			}
+
+			err = writeGoldenFiles(pkgGoldenDir, generated)
+			if err != nil {
+				return nil, err
+			}
+			res.Status = GoldenStatusUpdated
+		}
+
+		ans = append(ans, res)
+	}
+
+	return ans, nil
+}
+
+// getSpecPackages returns the list of the category/package of the
+// specfile sorted by name.
+func (a *AutogenBot) getSpecPackages(aspec *specs.AutogenSpec,
+	opts *AutogenBotOpts) []string {
+
+	pkgs := make(map[string]bool, 0)
+	for _, def := range aspec.Definitions {
+		if def.Generator == "" {
+			continue
+		}
+		for _, pkg := range def.Packages {
+			for _, atom := range pkg {
+				if opts.HasAtoms() && !opts.AtomInFilter(atom.Name) {
+					continue
+				}
+				pkgs[fmt.Sprintf("%s/%s", atom.GetCategory(def.Defaults), atom.Name)] = true
+			}
+		}
+	}
+
+	ans := []string{}
+	for catpkg := range pkgs {
+		ans = append(ans, catpkg)
+	}
+	sort.Strings(ans)
+
+	return ans
+}
+
+// readGoldenFiles returns the content of the Manifest, the ebuilds and
+// the values files available in the directories in input.
+func readGoldenFiles(dirs ...string) (map[string]string, error) {
+	ans := make(map[string]string, 0)
+
+	for _, dir := range dirs {
+		if !utils.Exists(dir) {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("error on reading dir %s: %s",
+				dir, err.Error())
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() {
+				continue
+			}
+			if name != "Manifest" && !strings.HasSuffix(name, ".ebuild") &&
+				!strings.HasSuffix(name, ".values.yml") {
+				continue
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				return nil, fmt.Errorf("error on read file %s: %s",
+					name, err.Error())
+			}
+			ans[name] = string(data)
+		}
+	}
+
+	return ans, nil
+}
+
+func writeGoldenFiles(dir string, files map[string]string) error {
+	// Remove the golden files of the versions not more generated.
+	if utils.Exists(dir) {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := helpers.EnsureDirWithoutIds(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		err = os.WriteFile(file, []byte(content), 0644)
+		if err != nil {
+			return fmt.Errorf("error on write file %s: %s", file, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func compareGoldenFiles(catpkg string, golden, generated map[string]string) *GoldenResult {
+	ans := &GoldenResult{
+		CatPkg:   catpkg,
+		Status:   GoldenStatusOk,
+		Messages: []string{},
+	}
+
+	if len(generated) == 0 {
+		ans.Status = GoldenStatusFailed
+		ans.Messages = append(ans.Messages, "no files generated")
+		return ans
+	}
+
+	names := []string{}
+	for name := range golden {
+		names = append(names, name)
+	}
+	for name := range generated {
+		if _, present := golden[name]; !present {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		expected, inGolden := golden[name]
+		content, isGenerated := generated[name]
+
+		switch {
+		case !inGolden:
+			ans.Messages = append(ans.Messages,
+				fmt.Sprintf("%s: not present in the golden files", name))
+		case !isGenerated:
+			ans.Messages = append(ans.Messages,
+				fmt.Sprintf("%s: not generated", name))
+		case expected != content:
+			ans.Messages = append(ans.Messages,
+				fmt.Sprintf("%s: differs from the golden file\n%s",
+					name, getGoldenDiff(expected, content)))
+		}
+	}
+
+	if len(ans.Messages) > 0 {
+		ans.Status = GoldenStatusFailed
+	}
+
+	return ans
+}
+
+// getGoldenDiff returns the lines changed between the golden
+// file and the generated file.
+func getGoldenDiff(expected, content string) string {
+	var sb strings.Builder
+
+	for _, d := range diff.Do(expected, content) {
+		prefix := ""
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			prefix = "- "
+		case diffmatchpatch.DiffInsert:
+			prefix = "+ "
+		default:
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(d.Text, "\n"), "\n") {
+			sb.WriteString(prefix + line + "\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -71,9 +71,10 @@ func (a *AutogenBot) GeneratePackageOnStaging(mkit *specs.MergeKit,
 				continue
 			}
 
-			renderedValue, err := helpers.RenderContentWithTemplates(
+			renderedValue, err := helpers.RenderContentWithLibrary(
 				fieldValue,
 				"", "", "ebuild."+field, values, []string{},
+				tmplEngine.GetLibraryDirs(),
 			)
 			if err != nil {
 				a.Logger.Warning(fmt.Sprintf("[%s] Error on render variable %s: %s",
@@ -211,6 +212,19 @@ func (a *AutogenBot) processLocalArtefact(atom *specs.AutogenAtom,
 
 	artefactPath := filepath.Join(a.GetDownloadDir(), tarballName)
 
+	if !utils.Exists(artefactPath) && a.Fetcher != nil {
+		// The bundles reused by the extensions could be
+		// available only in the fetcher backend.
+		backendFile, err := a.Fetcher.GetFileMetadata(tarballName)
+		if err != nil {
+			return nil, err
+		}
+		ans.Hashes = backendFile.Hashes
+		ans.Size = backendFile.Size
+
+		return ans, nil
+	}
+
 	hashes, err := helpers.GetFileHashes(artefactPath)
 	if err != nil {
 		return nil, err
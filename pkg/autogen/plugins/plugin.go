@@ -0,0 +1,243 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+)
+
+// Plugin is an external executable that implements a generator or
+// an extension. Every call starts a new process that receives on
+// stdin the handshake and the request and replies on stdout with
+// a JSON message for every request. The stderr is used for the logs.
+type Plugin struct {
+	Kind            string
+	Name            string
+	Path            string
+	ProtocolVersion int
+	Version         string
+	Capabilities    map[string]bool
+}
+
+// GetExecutableName returns the name of the executable of the plugin,
+// for example mark-devkit-generator-foo.
+func GetExecutableName(kind, name string) string {
+	return fmt.Sprintf("mark-devkit-%s-%s", kind, name)
+}
+
+// FindPlugin returns the path of the plugin executable searched
+// in the directories of the configuration and in the PATH.
+func FindPlugin(kind, name string) (string, error) {
+	execName := GetExecutableName(kind, name)
+
+	if log := logger.GetDefaultLogger(); log != nil {
+		for _, dir := range log.Config.GetGeneral().PluginsDirs {
+			p := filepath.Join(dir, execName)
+			if info, err := os.Stat(p); err == nil && !info.IsDir() &&
+				info.Mode().Perm()&0111 != 0 {
+				return filepath.Abs(p)
+			}
+		}
+	}
+
+	return exec.LookPath(execName)
+}
+
+// NewPlugin searches the plugin and negotiates the protocol version
+// and the capabilities.
+func NewPlugin(kind, name string) (*Plugin, error) {
+	p, err := FindPlugin(kind, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &Plugin{
+		Kind:         kind,
+		Name:         name,
+		Path:         p,
+		Capabilities: make(map[string]bool, 0),
+	}
+
+	hs, err := ans.handshake()
+	if err != nil {
+		return nil, err
+	}
+
+	return ans, ans.setHandshake(hs)
+}
+
+func (p *Plugin) HasCapability(c string) bool {
+	_, present := p.Capabilities[c]
+	return present
+}
+
+func (p *Plugin) handshakeRequest() *Request {
+	return &Request{
+		ProtocolVersion: ProtocolVersion,
+		Id:              0,
+		Method:          MethodHandshake,
+		Params: &HandshakeParams{
+			Kind:              p.Kind,
+			Name:              p.Name,
+			ProtocolVersions:  []int{ProtocolVersion1},
+			MarkDevkitVersion: specs.MARKDEVKIT_VERSION,
+		},
+	}
+}
+
+func (p *Plugin) handshake() (*HandshakeResult, error) {
+	responses, err := p.run([]*Request{p.handshakeRequest()})
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &HandshakeResult{}
+	if err := p.decodeResponse(responses[0], ans); err != nil {
+		return nil, err
+	}
+
+	return ans, nil
+}
+
+func (p *Plugin) setHandshake(hs *HandshakeResult) error {
+	if hs.ProtocolVersion != ProtocolVersion1 {
+		return fmt.Errorf("plugin %s: protocol version %d not supported",
+			p.Path, hs.ProtocolVersion)
+	}
+
+	p.ProtocolVersion = hs.ProtocolVersion
+	p.Version = hs.Version
+	p.Capabilities = make(map[string]bool, 0)
+	for _, c := range hs.Capabilities {
+		p.Capabilities[c] = true
+	}
+
+	return nil
+}
+
+// Call executes the method of the plugin and decodes the result.
+func (p *Plugin) Call(method string, params, result interface{}) error {
+	responses, err := p.run([]*Request{
+		p.handshakeRequest(),
+		{
+			ProtocolVersion: p.ProtocolVersion,
+			Id:              1,
+			Method:          method,
+			Params:          params,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// The handshake is repeated on every process.
+	hs := &HandshakeResult{}
+	if err := p.decodeResponse(responses[0], hs); err != nil {
+		return err
+	}
+	if err := p.setHandshake(hs); err != nil {
+		return err
+	}
+
+	return p.decodeResponse(responses[1], result)
+}
+
+func (p *Plugin) decodeResponse(r *Response, result interface{}) error {
+	if r.Error != nil {
+		r.Error.Plugin = p.Name
+		return r.Error
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(r.Result, result)
+	if err != nil {
+		return fmt.Errorf("plugin %s: invalid result: %s", p.Name, err.Error())
+	}
+	return nil
+}
+
+func (p *Plugin) run(requests []*Request) ([]*Response, error) {
+	log := logger.GetDefaultLogger()
+
+	var stdin bytes.Buffer
+	enc := json.NewEncoder(&stdin)
+	for _, r := range requests {
+		if err := enc.Encode(r); err != nil {
+			return nil, fmt.Errorf("error on encode request %s: %s",
+				r.Method, err.Error())
+		}
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.Path)
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("MARKDEVKIT_PLUGIN_PROTOCOL=%d", ProtocolVersion))
+
+	if log != nil {
+		log.Debug(fmt.Sprintf("Running plugin %s (%s)...", p.Path,
+			requests[len(requests)-1].Method))
+	}
+
+	// The structured errors are returned also with exit code not zero.
+	runErr := cmd.Run()
+
+	ans := []*Response{}
+	scanner := bufio.NewScanner(&stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		r := &Response{}
+		if err := json.Unmarshal(line, r); err != nil {
+			return nil, fmt.Errorf("plugin %s: invalid message: %s",
+				p.Name, err.Error())
+		}
+		ans = append(ans, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// The plugin could stop on the first error.
+	if len(ans) > 0 && len(ans) < len(requests) && ans[len(ans)-1].Error != nil {
+		return ans, nil
+	}
+
+	if len(ans) != len(requests) {
+		if runErr != nil {
+			return nil, fmt.Errorf("error on run plugin %s: %s", p.Path, runErr.Error())
+		}
+		return nil, fmt.Errorf("plugin %s: received %d responses for %d requests",
+			p.Name, len(ans), len(requests))
+	}
+	for idx, r := range ans {
+		if r.Id != requests[idx].Id {
+			return nil, fmt.Errorf("plugin %s: unexpected response id %d",
+				p.Name, r.Id)
+		}
+	}
+
+	if runErr != nil && ans[len(ans)-1].Error == nil {
+		return nil, fmt.Errorf("error on run plugin %s: %s", p.Path, runErr.Error())
+	}
+
+	return ans, nil
+}
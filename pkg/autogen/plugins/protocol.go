@@ -0,0 +1,140 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+)
+
+// The versions of the protocol supported. The plugin replies to
+// the handshake with the version selected.
+const (
+	ProtocolVersion1 = 1
+
+	ProtocolVersion = ProtocolVersion1
+)
+
+const (
+	KindGenerator = "generator"
+	KindExtension = "extension"
+
+	MethodHandshake           = "handshake"
+	MethodGeneratorProcess    = "generator.process"
+	MethodGeneratorSetVersion = "generator.set_version"
+	MethodExtensionElaborate  = "extension.elaborate"
+
+	// The capabilities of the plugins.
+	CapabilityProcess    = "process"
+	CapabilitySetVersion = "set_version"
+	CapabilityElaborate  = "elaborate"
+)
+
+// Request is the message sent to the plugin. Every message is
+// a JSON object on a single line.
+type Request struct {
+	ProtocolVersion int         `json:"protocol_version"`
+	Id              int         `json:"id"`
+	Method          string      `json:"method"`
+	Params          interface{} `json:"params,omitempty"`
+}
+
+// Response is the message received from the plugin for every request.
+type Response struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Id              int             `json:"id"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Error           *PluginError    `json:"error,omitempty"`
+}
+
+// PluginError is the structured error returned by the plugin.
+type PluginError struct {
+	Plugin  string                 `json:"-"`
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *PluginError) Error() string {
+	ans := fmt.Sprintf("plugin %s: %s", e.Plugin, e.Message)
+	if e.Code != "" {
+		ans += fmt.Sprintf(" (%s)", e.Code)
+	}
+	return ans
+}
+
+type HandshakeParams struct {
+	Kind              string `json:"kind"`
+	Name              string `json:"name"`
+	ProtocolVersions  []int  `json:"protocol_versions"`
+	MarkDevkitVersion string `json:"mark_devkit_version"`
+}
+
+type HandshakeResult struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Name            string   `json:"name,omitempty"`
+	Version         string   `json:"version,omitempty"`
+	Capabilities    []string `json:"capabilities"`
+}
+
+// CallParams contains the input of the generator and extension
+// methods. The artefacts are sent separately from the vars.
+type CallParams struct {
+	Name      string                   `json:"name"`
+	Atom      *specs.AutogenAtom       `json:"atom"`
+	Def       *specs.AutogenAtom       `json:"def,omitempty"`
+	Opts      map[string]string        `json:"opts,omitempty"`
+	Version   string                   `json:"version,omitempty"`
+	Vars      map[string]interface{}   `json:"vars,omitempty"`
+	Artefacts []*specs.AutogenArtefact `json:"artefacts,omitempty"`
+}
+
+// CallResult contains the output of the generator and extension
+// methods. The vars are merged with the existing values and the
+// artefacts, if present, replace the existing artefacts.
+type CallResult struct {
+	Vars       map[string]interface{}   `json:"vars,omitempty"`
+	DeleteVars []string                 `json:"delete_vars,omitempty"`
+	Artefacts  []*specs.AutogenArtefact `json:"artefacts,omitempty"`
+}
+
+// NewCallParams returns the params of a call with the values in input.
+func NewCallParams(atom *specs.AutogenAtom, opts map[string]string,
+	values map[string]interface{}) *CallParams {
+	ans := &CallParams{
+		Name: atom.Name,
+		Atom: atom,
+		Opts: opts,
+		Vars: make(map[string]interface{}, 0),
+	}
+
+	for k, v := range values {
+		if k == "artefacts" {
+			ans.Artefacts, _ = v.([]*specs.AutogenArtefact)
+			continue
+		}
+		ans.Vars[k] = v
+	}
+
+	return ans
+}
+
+// Apply merges the result in the values.
+func (r *CallResult) Apply(values map[string]interface{}) {
+	for _, k := range r.DeleteVars {
+		delete(values, k)
+	}
+	for k, v := range r.Vars {
+		if k == "artefacts" {
+			continue
+		}
+		values[k] = v
+	}
+	if r.Artefacts != nil {
+		values["artefacts"] = r.Artefacts
+	}
+}
@@ -7,8 +7,52 @@ package autogen
 import (
 	"fmt"
 	"path/filepath"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
+// HasFile returns true if the file is available in the download
+// directory or in the fetcher backend. It's used by the extensions
+// to reuse the bundles already generated.
+func (a *AutogenBot) HasFile(name string) bool {
+	if utils.Exists(filepath.Join(a.GetDownloadDir(), name)) {
+		return true
+	}
+
+	if a.Fetcher == nil || a.Fetcher.GetType() == "dir" {
+		return false
+	}
+
+	a.backendMutex.Lock()
+	defer a.backendMutex.Unlock()
+
+	if a.backendFiles == nil {
+		files, err := a.Fetcher.GetFilesList()
+		if err != nil {
+			a.Logger.Warning(fmt.Sprintf(
+				"Error on retrieve the files of the backend: %s", err.Error()))
+			return false
+		}
+
+		a.backendFiles = make(map[string]bool, len(files))
+		for idx := range files {
+			a.backendFiles[files[idx]] = true
+		}
+	}
+
+	_, present := a.backendFiles[a.Fetcher.GetFilePath(name)]
+	return present
+}
+
+// GetFile downloads the file from the fetcher backend
+// to the download directory.
+func (a *AutogenBot) GetFile(name string) error {
+	if a.Fetcher == nil {
+		return fmt.Errorf("no fetcher available")
+	}
+	return a.Fetcher.GetFile(name, filepath.Join(a.GetDownloadDir(), name))
+}
+
 func (a *AutogenBot) syncTarballs(opts *AutogenBotOpts) error {
 
 	if a.Fetcher.GetType() == "dir" {
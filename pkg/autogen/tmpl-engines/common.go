@@ -12,10 +12,13 @@ import (
 )
 
 type CoreTemplateEngine struct {
-	Logger *log.MarkDevkitLogger
+	Logger      *log.MarkDevkitLogger
+	LibraryDirs []string
 }
 
 func (c *CoreTemplateEngine) SetLogger(l *log.MarkDevkitLogger) { c.Logger = l }
+func (c *CoreTemplateEngine) SetLibraryDirs(dirs []string)      { c.LibraryDirs = dirs }
+func (c *CoreTemplateEngine) GetLibraryDirs() []string          { return c.LibraryDirs }
 
 func (c *CoreTemplateEngine) GetTemplateFile(aspec *specs.AutogenSpec,
 	atom, def *specs.AutogenAtom) string {
@@ -25,3 +28,16 @@ func (c *CoreTemplateEngine) GetTemplateFile(aspec *specs.AutogenSpec,
 
 	return templateFilePath
 }
+
+// GetSearchPaths returns the directories used to resolve the templates
+// included by the template of the atom: the directory of the template,
+// the library directories and the directory of the specfile.
+func (c *CoreTemplateEngine) GetSearchPaths(aspec *specs.AutogenSpec,
+	atom, def *specs.AutogenAtom) []string {
+
+	ans := []string{filepath.Dir(c.GetTemplateFile(aspec, atom, def))}
+	ans = append(ans, c.LibraryDirs...)
+	ans = append(ans, filepath.Dir(aspec.File))
+
+	return ans
+}
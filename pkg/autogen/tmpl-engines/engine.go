@@ -14,6 +14,8 @@ import (
 type TemplateEngine interface {
 	Render(aspec *specs.AutogenSpec, atom, def *specs.AutogenAtom, values *map[string]interface{}, targetFile string) error
 	SetLogger(l *log.MarkDevkitLogger)
+	SetLibraryDirs(dirs []string)
+	GetLibraryDirs() []string
 }
 
 func NewTemplateEngine(t string, opts []string) (TemplateEngine, error) {
@@ -24,6 +26,8 @@ func NewTemplateEngine(t string, opts []string) (TemplateEngine, error) {
 		return NewPongo2TemplateEngine(), nil
 	case specs.TmplEngineJ2cli:
 		return NewJ2CliTemplateEngine(opts), nil
+	case specs.TmplEngineJinja2:
+		return NewJinja2TemplateEngine(opts), nil
 	default:
 		return nil, fmt.Errorf("Invalid template engine %s", t)
 	}
@@ -35,9 +35,9 @@ func (t *HelmTemplateEngine) Render(aspec *specs.AutogenSpec,
 	}
 
 	// Render the content
-	content, err := helpers.RenderContentWithTemplates(
+	content, err := helpers.RenderContentWithLibrary(
 		string(data),
-		"", "", targetFile, values, []string{},
+		"", "", targetFile, values, []string{}, t.LibraryDirs,
 	)
 	if err != nil {
 		return err
@@ -0,0 +1,57 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package tmplengine
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/macaroni-os/mark-devkit/pkg/autogen/tmpl-engines/jinja2"
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+)
+
+type Jinja2TemplateEngine struct {
+	*CoreTemplateEngine
+	Opts []string
+}
+
+func NewJinja2TemplateEngine(opts []string) *Jinja2TemplateEngine {
+	return &Jinja2TemplateEngine{
+		CoreTemplateEngine: &CoreTemplateEngine{},
+		Opts:               opts,
+	}
+}
+
+func (t *Jinja2TemplateEngine) Render(aspec *specs.AutogenSpec,
+	atom, def *specs.AutogenAtom, valref *map[string]interface{},
+	targetFile string) error {
+
+	templateFilePath := t.GetTemplateFile(aspec, atom, def)
+
+	env := jinja2.NewEnvironment(t.GetSearchPaths(aspec, atom, def))
+	env.AddGlobals(helpers.EbuildFuncMap())
+
+	// Same options of the j2cli tool for an easy migration.
+	for _, opt := range t.Opts {
+		switch opt {
+		case "--undefined":
+			env.StrictUndefined = false
+		default:
+			if t.Logger != nil {
+				t.Logger.Warning(fmt.Sprintf(
+					"[%s] Ignoring unsupported jinja2 option %s.", atom.Name, opt))
+			}
+		}
+	}
+
+	content, err := env.RenderFile(templateFilePath, *valref)
+	if err != nil {
+		return err
+	}
+
+	// Write the file
+	return os.WriteFile(targetFile, []byte(content), 0644)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Error is the error of the parsing or the rendering of a template
+// with the location of the statement.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// Environment contains the configuration shared by the templates
+// rendered. It's equivalent to the environment of jinja2 with
+// the options used by j2cli: the extensions do and loopcontrols,
+// keep_trailing_newline and the strict undefined.
+type Environment struct {
+	// Directories used to resolve the templates of the include,
+	// import and extends tags.
+	SearchPaths []string
+	// Raise an error on the usage of the undefined variables.
+	StrictUndefined bool
+	Globals         map[string]interface{}
+
+	templates map[string][]node
+}
+
+func NewEnvironment(searchPaths []string) *Environment {
+	return &Environment{
+		SearchPaths:     searchPaths,
+		StrictUndefined: true,
+		Globals:         defaultGlobals(),
+		templates:       make(map[string][]node, 0),
+	}
+}
+
+// AddGlobals adds the values or the go functions available
+// to all templates.
+func (e *Environment) AddGlobals(globals map[string]interface{}) {
+	for k, v := range globals {
+		e.Globals[k] = toValue(v)
+	}
+}
+
+// RenderFile renders the template file with the values.
+func (e *Environment) RenderFile(file string, values map[string]interface{}) (string, error) {
+	root, err := e.rootScope(values)
+	if err != nil {
+		return "", err
+	}
+
+	return e.render(file, root, 0)
+}
+
+// RenderString renders the template source with the values.
+// The name is used on the error messages.
+func (e *Environment) RenderString(name, src string, values map[string]interface{}) (string, error) {
+	root, err := e.rootScope(values)
+	if err != nil {
+		return "", err
+	}
+
+	nodes, err := parse(name, src)
+	if err != nil {
+		return "", err
+	}
+
+	return e.renderNodes(name, nodes, root, 0)
+}
+
+func (e *Environment) rootScope(values map[string]interface{}) (*scope, error) {
+	ctx, err := fromYaml(values)
+	if err != nil {
+		return nil, fmt.Errorf("Error on convert values: %s", err.Error())
+	}
+
+	root := newScope(nil)
+	for _, k := range ctx.Keys {
+		root.set(k, ctx.Values[k])
+	}
+
+	return root, nil
+}
+
+// resolve returns the path of the template from the search paths.
+func (e *Environment) resolve(name, from string) (string, error) {
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err == nil {
+			return name, nil
+		}
+	} else {
+		for _, dir := range e.SearchPaths {
+			p := filepath.Join(dir, name)
+			if _, err := os.Stat(p); err == nil {
+				return p, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("template '%s' not found in %s", name,
+		strings.Join(e.SearchPaths, ", "))
+}
+
+func (e *Environment) load(file string) ([]node, error) {
+	if nodes, ok := e.templates[file]; ok {
+		return nodes, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := parse(file, string(data))
+	if err != nil {
+		return nil, err
+	}
+	e.templates[file] = nodes
+
+	return nodes, nil
+}
+
+func (e *Environment) render(file string, sc *scope, depth int) (string, error) {
+	if depth > maxRenderDepth {
+		return "", fmt.Errorf("maximum recursion depth exceeded on template %s", file)
+	}
+
+	nodes, err := e.load(file)
+	if err != nil {
+		return "", err
+	}
+
+	return e.renderNodes(file, nodes, sc, depth)
+}
+
+func (e *Environment) renderNodes(file string, nodes []node, sc *scope, depth int) (string, error) {
+	r := &renderer{
+		env:    e,
+		file:   file,
+		blocks: make(map[string][]*blockNode, 0),
+		depth:  depth,
+	}
+	r.collectBlocks(nodes)
+
+	for {
+		var sb strings.Builder
+		if err := r.exec(nodes, sc, &sb); err != nil {
+			if err == errBreak || err == errContinue {
+				return "", &Error{File: r.file, Line: 0, Msg: err.Error()}
+			}
+			return "", err
+		}
+
+		if r.parent == "" {
+			return sb.String(), nil
+		}
+
+		// The output of the child template is replaced by the
+		// output of the parent template with the blocks overridden.
+		parent, err := e.resolve(r.parent, r.file)
+		if err != nil {
+			return "", &Error{File: r.file, Line: 1, Msg: err.Error()}
+		}
+		nodes, err = e.load(parent)
+		if err != nil {
+			return "", err
+		}
+		r.file = parent
+		r.parent = ""
+		r.collectBlocks(nodes)
+	}
+}
@@ -0,0 +1,812 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type scope struct {
+	vars   map[string]interface{}
+	parent *scope
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		vars:   make(map[string]interface{}, 0),
+		parent: parent,
+	}
+}
+
+func (s *scope) lookup(name string) (interface{}, bool) {
+	for c := s; c != nil; c = c.parent {
+		if v, ok := c.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (s *scope) set(name string, v interface{}) {
+	s.vars[name] = v
+}
+
+// renderer executes the nodes of a template.
+type renderer struct {
+	env  *Environment
+	file string
+	// Blocks of the templates chain of extends. The first
+	// element is the block of the most derived template.
+	blocks map[string][]*blockNode
+	// Template to extend after the rendering of the current template.
+	parent string
+	depth  int
+}
+
+func (r *renderer) errorf(n node, format string, args ...interface{}) error {
+	return &Error{File: r.file, Line: n.line(), Msg: fmt.Sprintf(format, args...)}
+}
+
+// wrap adds the location to the errors without location.
+func (r *renderer) wrap(n node, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{File: r.file, Line: n.line(), Msg: err.Error()}
+}
+
+// checkDefined returns an error for the undefined values when the
+// strict mode is enabled.
+func (r *renderer) checkDefined(n node, v interface{}) error {
+	if u, ok := v.(*Undefined); ok && r.env.StrictUndefined {
+		return r.errorf(n, "%s", u.Hint)
+	}
+	return nil
+}
+
+func (r *renderer) evalDefined(e expr, sc *scope) (interface{}, error) {
+	v, err := r.eval(e, sc)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkDefined(e, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *renderer) eval(e expr, sc *scope) (interface{}, error) {
+	switch n := e.(type) {
+	case *literalExpr:
+		return n.Value, nil
+
+	case *nameExpr:
+		if v, ok := sc.lookup(n.Name); ok {
+			return v, nil
+		}
+		if v, ok := r.env.Globals[n.Name]; ok {
+			return v, nil
+		}
+		return newUndefinedName(n.Name), nil
+
+	case *getattrExpr:
+		obj, err := r.evalDefined(n.Obj, sc)
+		if err != nil {
+			return nil, err
+		}
+		if u, ok := obj.(*Undefined); ok {
+			return u, nil
+		}
+		return getAttr(obj, n.Attr), nil
+
+	case *getitemExpr:
+		obj, err := r.evalDefined(n.Obj, sc)
+		if err != nil {
+			return nil, err
+		}
+		key, err := r.evalDefined(n.Key, sc)
+		if err != nil {
+			return nil, err
+		}
+		if u, ok := obj.(*Undefined); ok {
+			return u, nil
+		}
+		v, err := getItem(obj, key)
+		return v, r.wrap(n, err)
+
+	case *sliceExpr:
+		return r.evalSlice(n, sc)
+
+	case *callExpr:
+		return r.evalCall(n, sc)
+
+	case *filterExpr:
+		return r.evalFilter(n, sc)
+
+	case *testExpr:
+		return r.evalTest(n, sc)
+
+	case *binaryExpr:
+		return r.evalBinary(n, sc)
+
+	case *unaryExpr:
+		v, err := r.evalDefined(n.Expr, sc)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "not":
+			return !truthy(v), nil
+		case "-":
+			switch val := v.(type) {
+			case int:
+				return -val, nil
+			case float64:
+				return -val, nil
+			}
+			return nil, r.errorf(n, "bad operand type for unary -: '%s'", typeName(v))
+		default:
+			if !isNumber(v) {
+				return nil, r.errorf(n, "bad operand type for unary +: '%s'", typeName(v))
+			}
+			return v, nil
+		}
+
+	case *compareExpr:
+		left, err := r.evalDefined(n.Left, sc)
+		if err != nil {
+			return nil, err
+		}
+		for idx, op := range n.Ops {
+			right, err := r.evalDefined(n.Exprs[idx], sc)
+			if err != nil {
+				return nil, err
+			}
+			ok, err := compareOp(op, left, right)
+			if err != nil {
+				return nil, r.wrap(n, err)
+			}
+			if !ok {
+				return false, nil
+			}
+			left = right
+		}
+		return true, nil
+
+	case *condExpr:
+		cond, err := r.evalDefined(n.Cond, sc)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return r.eval(n.Then, sc)
+		}
+		if n.Else == nil {
+			return &Undefined{Hint: "the inline if-expression evaluated to false and no else section was defined"}, nil
+		}
+		return r.eval(n.Else, sc)
+
+	case *listExpr:
+		ans := NewList()
+		ans.Tuple = n.Tuple
+		for _, item := range n.Items {
+			v, err := r.evalDefined(item, sc)
+			if err != nil {
+				return nil, err
+			}
+			ans.Items = append(ans.Items, v)
+		}
+		return ans, nil
+
+	case *dictExpr:
+		ans := NewDict()
+		for idx := range n.Keys {
+			k, err := r.evalDefined(n.Keys[idx], sc)
+			if err != nil {
+				return nil, err
+			}
+			v, err := r.evalDefined(n.Values[idx], sc)
+			if err != nil {
+				return nil, err
+			}
+			ans.Set(toString(k), v)
+		}
+		return ans, nil
+	}
+
+	return nil, r.errorf(e, "unsupported expression")
+}
+
+func compareOp(op string, left, right interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return equals(left, right), nil
+	case "!=":
+		return !equals(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "notin":
+		ok, err := contains(right, left)
+		return !ok, err
+	}
+
+	c, err := compare(left, right)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "<":
+		return c < 0, nil
+	case ">":
+		return c > 0, nil
+	case "<=":
+		return c <= 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func (r *renderer) evalBinary(n *binaryExpr, sc *scope) (interface{}, error) {
+	left, err := r.evalDefined(n.Left, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit operators return the operand like python.
+	switch n.Op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return r.evalDefined(n.Right, sc)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return r.evalDefined(n.Right, sc)
+	}
+
+	right, err := r.evalDefined(n.Right, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := binaryOp(n.Op, left, right)
+	return v, r.wrap(n, err)
+}
+
+func binaryOp(op string, left, right interface{}) (interface{}, error) {
+	if op == "~" {
+		return toString(left) + toString(right), nil
+	}
+
+	unsupported := fmt.Errorf("unsupported operand type(s) for %s: '%s' and '%s'",
+		op, typeName(left), typeName(right))
+
+	if isNumber(left) && isNumber(right) {
+		li, lIsInt := left.(int)
+		ri, rIsInt := right.(int)
+		lb, lIsBool := left.(bool)
+		rb, rIsBool := right.(bool)
+		if lIsBool {
+			li, lIsInt = boolToInt(lb), true
+		}
+		if rIsBool {
+			ri, rIsInt = boolToInt(rb), true
+		}
+		lf, _ := toFloat(left)
+		rf, _ := toFloat(right)
+		bothInt := lIsInt && rIsInt
+
+		switch op {
+		case "+":
+			if bothInt {
+				return li + ri, nil
+			}
+			return lf + rf, nil
+		case "-":
+			if bothInt {
+				return li - ri, nil
+			}
+			return lf - rf, nil
+		case "*":
+			if bothInt {
+				return li * ri, nil
+			}
+			return lf * rf, nil
+		case "/":
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return lf / rf, nil
+		case "//":
+			if rf == 0 {
+				return nil, fmt.Errorf("integer division or modulo by zero")
+			}
+			if bothInt {
+				q := li / ri
+				if (li%ri != 0) && ((li < 0) != (ri < 0)) {
+					q--
+				}
+				return q, nil
+			}
+			return math.Floor(lf / rf), nil
+		case "%":
+			if rf == 0 {
+				return nil, fmt.Errorf("integer division or modulo by zero")
+			}
+			if bothInt {
+				m := li % ri
+				if m != 0 && ((m < 0) != (ri < 0)) {
+					m += ri
+				}
+				return m, nil
+			}
+			m := math.Mod(lf, rf)
+			if m != 0 && ((m < 0) != (rf < 0)) {
+				m += rf
+			}
+			return m, nil
+		case "**":
+			if bothInt && ri >= 0 {
+				ans := 1
+				for i := 0; i < ri; i++ {
+					ans *= li
+				}
+				return ans, nil
+			}
+			return math.Pow(lf, rf), nil
+		}
+		return nil, unsupported
+	}
+
+	switch op {
+	case "+":
+		switch l := left.(type) {
+		case string:
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		case *List:
+			if r, ok := right.(*List); ok {
+				items := append([]interface{}{}, l.Items...)
+				return NewList(append(items, r.Items...)...), nil
+			}
+		}
+	case "*":
+		if s, ok := left.(string); ok {
+			if n, ok := right.(int); ok {
+				if n < 0 {
+					n = 0
+				}
+				return strings.Repeat(s, n), nil
+			}
+		}
+		if s, ok := right.(string); ok {
+			if n, ok := left.(int); ok {
+				if n < 0 {
+					n = 0
+				}
+				return strings.Repeat(s, n), nil
+			}
+		}
+		if l, ok := left.(*List); ok {
+			if n, ok := right.(int); ok {
+				ans := NewList()
+				for i := 0; i < n; i++ {
+					ans.Items = append(ans.Items, l.Items...)
+				}
+				return ans, nil
+			}
+		}
+	case "%":
+		if s, ok := left.(string); ok {
+			return printfFormat(s, right)
+		}
+	}
+
+	return nil, unsupported
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// printfFormat implements the python printf-style formatting
+// of the strings for the conversions s, r, d, i and f.
+func printfFormat(format string, arg interface{}) (string, error) {
+	args := []interface{}{arg}
+	if l, ok := arg.(*List); ok {
+		args = l.Items
+	}
+
+	var sb strings.Builder
+	idx := 0
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			return "", fmt.Errorf("incomplete format")
+		}
+		flags := format[i+1 : j]
+		verb := format[j]
+		i = j
+
+		if verb == '%' {
+			sb.WriteByte('%')
+			continue
+		}
+		if idx >= len(args) {
+			return "", fmt.Errorf("not enough arguments for format string")
+		}
+		a := args[idx]
+		idx++
+
+		switch verb {
+		case 's':
+			sb.WriteString(fmt.Sprintf("%"+flags+"s", toString(a)))
+		case 'r':
+			sb.WriteString(fmt.Sprintf("%"+flags+"s", repr(a)))
+		case 'd', 'i':
+			n, err := toInt(a)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(fmt.Sprintf("%"+flags+"d", n))
+		case 'f', 'e', 'g', 'x', 'X', 'o':
+			if verb == 'x' || verb == 'X' || verb == 'o' {
+				n, err := toInt(a)
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(fmt.Sprintf("%"+flags+string(verb), n))
+			} else {
+				f, err := toFloat(a)
+				if err != nil {
+					return "", err
+				}
+				sb.WriteString(fmt.Sprintf("%"+flags+string(verb), f))
+			}
+		default:
+			return "", fmt.Errorf("unsupported format character '%c'", verb)
+		}
+	}
+
+	if idx < len(args) {
+		return "", fmt.Errorf("not all arguments converted during string formatting")
+	}
+
+	return sb.String(), nil
+}
+
+func (r *renderer) evalSlice(n *sliceExpr, sc *scope) (interface{}, error) {
+	obj, err := r.evalDefined(n.Obj, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := []*int{nil, nil, nil}
+	for idx, e := range []expr{n.Start, n.Stop, n.Step} {
+		if e == nil {
+			continue
+		}
+		v, err := r.evalDefined(e, sc)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			continue
+		}
+		i, err := toInt(v)
+		if err != nil {
+			return nil, r.wrap(n, err)
+		}
+		bounds[idx] = &i
+	}
+
+	v, err := slice(obj, bounds[0], bounds[1], bounds[2])
+	return v, r.wrap(n, err)
+}
+
+// slice implements the python slicing of strings and lists.
+func slice(obj interface{}, start, stop, step *int) (interface{}, error) {
+	var items []interface{}
+	var runes []rune
+	isString := false
+	size := 0
+
+	switch val := obj.(type) {
+	case string:
+		isString = true
+		runes = []rune(val)
+		size = len(runes)
+	case *List:
+		items = val.Items
+		size = len(items)
+	default:
+		return nil, fmt.Errorf("'%s' is not subscriptable", typeName(obj))
+	}
+
+	st := 1
+	if step != nil {
+		st = *step
+	}
+	if st == 0 {
+		return nil, fmt.Errorf("slice step cannot be zero")
+	}
+
+	norm := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += size
+		}
+		if st > 0 {
+			if i < 0 {
+				i = 0
+			}
+			if i > size {
+				i = size
+			}
+		} else {
+			if i < -1 {
+				i = -1
+			}
+			if i > size-1 {
+				i = size - 1
+			}
+		}
+		return i
+	}
+
+	var b, e int
+	if st > 0 {
+		b, e = norm(start, 0), norm(stop, size)
+	} else {
+		b, e = norm(start, size-1), norm(stop, -1)
+	}
+
+	indexes := []int{}
+	for i := b; (st > 0 && i < e) || (st < 0 && i > e); i += st {
+		indexes = append(indexes, i)
+	}
+
+	if isString {
+		var sb strings.Builder
+		for _, i := range indexes {
+			sb.WriteRune(runes[i])
+		}
+		return sb.String(), nil
+	}
+
+	ans := NewList()
+	for _, i := range indexes {
+		ans.Items = append(ans.Items, items[i])
+	}
+	return ans, nil
+}
+
+// getAttr returns the attribute of the object or the item with
+// the same name like the getattr of jinja2.
+func getAttr(obj interface{}, attr string) interface{} {
+	if m := getMethod(obj, attr); m != nil {
+		return m
+	}
+
+	switch val := obj.(type) {
+	case *Dict:
+		if v, ok := val.Get(attr); ok {
+			return v
+		}
+	case *Namespace:
+		if v, ok := val.Attrs.Get(attr); ok {
+			return v
+		}
+	case *List:
+		if v, err := getItem(obj, attr); err == nil {
+			return v
+		}
+	case *module:
+		if v, ok := val.exports.Get(attr); ok {
+			return v
+		}
+	}
+
+	return newUndefinedAttr(obj, attr)
+}
+
+// getItem returns the item of the object with the attribute
+// as fallback like the subscript of jinja2.
+func getItem(obj, key interface{}) (interface{}, error) {
+	switch val := obj.(type) {
+	case *Dict:
+		if v, ok := val.Get(toString(key)); ok {
+			return v, nil
+		}
+		return newUndefinedAttr(obj, toString(key)), nil
+
+	case *List, string:
+		idx, err := toInt(key)
+		if err != nil {
+			if s, ok := key.(string); ok {
+				return getAttrOnly(obj, s), nil
+			}
+			return nil, fmt.Errorf("indices must be integers, not %s", typeName(key))
+		}
+		size, _ := length(obj)
+		if idx < 0 {
+			idx += size
+		}
+		if idx < 0 || idx >= size {
+			return &Undefined{
+				Hint: fmt.Sprintf("%s has no element %d", typeName(obj), idx),
+			}, nil
+		}
+		if l, ok := val.(*List); ok {
+			return l.Items[idx], nil
+		}
+		return string([]rune(val.(string))[idx]), nil
+	}
+
+	if s, ok := key.(string); ok {
+		return getAttr(obj, s), nil
+	}
+
+	return newUndefinedAttr(obj, toString(key)), nil
+}
+
+func getAttrOnly(obj interface{}, attr string) interface{} {
+	if m := getMethod(obj, attr); m != nil {
+		return m
+	}
+	return newUndefinedAttr(obj, attr)
+}
+
+func (r *renderer) evalArgs(args []expr, kwargs []*kwarg, sc *scope) ([]interface{}, map[string]interface{}, error) {
+	vargs := make([]interface{}, 0, len(args))
+	for _, a := range args {
+		v, err := r.eval(a, sc)
+		if err != nil {
+			return nil, nil, err
+		}
+		vargs = append(vargs, v)
+	}
+
+	vkwargs := make(map[string]interface{}, len(kwargs))
+	for _, k := range kwargs {
+		v, err := r.eval(k.Value, sc)
+		if err != nil {
+			return nil, nil, err
+		}
+		vkwargs[k.Name] = v
+	}
+
+	return vargs, vkwargs, nil
+}
+
+func (r *renderer) evalCall(n *callExpr, sc *scope) (interface{}, error) {
+	fn, err := r.eval(n.Fn, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	args, kwargs, err := r.evalArgs(n.Args, n.Kwargs, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f := fn.(type) {
+	case *Macro:
+		v, err := f.call(args, kwargs)
+		return v, r.wrap(n, err)
+
+	case builtinFunc:
+		// The undefined values are accepted only by the macros.
+		for _, a := range args {
+			if err := r.checkDefined(n, a); err != nil {
+				return nil, err
+			}
+		}
+		for _, a := range kwargs {
+			if err := r.checkDefined(n, a); err != nil {
+				return nil, err
+			}
+		}
+		v, err := f(args, kwargs)
+		return v, r.wrap(n, err)
+
+	case *Undefined:
+		if r.env.StrictUndefined {
+			return nil, r.errorf(n, "%s", f.Hint)
+		}
+		return nil, r.errorf(n, "'%s' is not callable", repr(f))
+	}
+
+	return nil, r.errorf(n, "'%s' is not callable", typeName(fn))
+}
+
+func (r *renderer) evalFilter(n *filterExpr, sc *scope) (interface{}, error) {
+	f, ok := filters[n.Name]
+	if !ok {
+		return nil, r.errorf(n, "no filter named '%s'", n.Name)
+	}
+
+	v, err := r.eval(n.Expr, sc)
+	if err != nil {
+		return nil, err
+	}
+	// Only the default filter manages the undefined values.
+	if n.Name != "default" && n.Name != "d" {
+		if err := r.checkDefined(n, v); err != nil {
+			return nil, err
+		}
+	}
+
+	args, kwargs, err := r.evalArgs(n.Args, n.Kwargs, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range args {
+		if err := r.checkDefined(n, a); err != nil {
+			return nil, err
+		}
+	}
+
+	ans, err := f(r, v, args, kwargs)
+	return ans, r.wrap(n, err)
+}
+
+func (r *renderer) evalTest(n *testExpr, sc *scope) (interface{}, error) {
+	t, ok := tests[n.Name]
+	if !ok {
+		return nil, r.errorf(n, "no test named '%s'", n.Name)
+	}
+
+	v, err := r.eval(n.Expr, sc)
+	if err != nil {
+		return nil, err
+	}
+	if n.Name != "defined" && n.Name != "undefined" {
+		if err := r.checkDefined(n, v); err != nil {
+			return nil, err
+		}
+	}
+
+	args, _, err := r.evalArgs(n.Args, nil, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range args {
+		if err := r.checkDefined(n, a); err != nil {
+			return nil, err
+		}
+	}
+
+	ans, err := t(v, args)
+	if err != nil {
+		return nil, r.wrap(n, err)
+	}
+	if n.Negate {
+		return !ans, nil
+	}
+	return ans, nil
+}
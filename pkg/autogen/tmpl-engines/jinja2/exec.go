@@ -0,0 +1,503 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	errBreak    = errors.New("break outside of a loop")
+	errContinue = errors.New("continue outside of a loop")
+)
+
+// Maximum depth of the nested include, import and macro calls.
+const maxRenderDepth = 500
+
+// The name of the rendered body of the filter blocks. It isn't
+// a valid identifier so it can't be used by the templates.
+const filterBlockVar = "filter-block"
+
+// Macro is a macro defined in a template.
+type Macro struct {
+	Name string
+	node *macroNode
+	r    *renderer
+	// Scope where the macro is been defined.
+	closure *scope
+}
+
+// module contains the exported names of an imported template.
+type module struct {
+	exports *Dict
+}
+
+func (m *Macro) call(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if m.r.depth > maxRenderDepth {
+		return nil, fmt.Errorf("maximum recursion depth exceeded on macro '%s'", m.Name)
+	}
+
+	sc := newScope(m.closure)
+	varargs := NewList()
+	extraKwargs := NewDict()
+
+	for idx, arg := range args {
+		if idx < len(m.node.Params) {
+			sc.set(m.node.Params[idx], arg)
+		} else {
+			varargs.Items = append(varargs.Items, arg)
+		}
+	}
+
+	for _, param := range m.node.Params[min(len(args), len(m.node.Params)):] {
+		if v, ok := kwargs[param]; ok {
+			sc.set(param, v)
+			delete(kwargs, param)
+			continue
+		}
+		if def, ok := m.node.Defaults[param]; ok {
+			v, err := m.r.eval(def, sc)
+			if err != nil {
+				return nil, err
+			}
+			sc.set(param, v)
+			continue
+		}
+		sc.set(param, &Undefined{
+			Hint: fmt.Sprintf("parameter '%s' was not provided", param),
+		})
+	}
+
+	for k, v := range kwargs {
+		found := false
+		for _, param := range m.node.Params {
+			if param == k {
+				found = true
+				break
+			}
+		}
+		if found {
+			return nil, fmt.Errorf("macro '%s' got multiple values for argument '%s'",
+				m.Name, k)
+		}
+		extraKwargs.Set(k, v)
+	}
+	sc.set("varargs", varargs)
+	sc.set("kwargs", extraKwargs)
+
+	var sb strings.Builder
+	m.r.depth++
+	err := m.r.exec(m.node.Body, sc, &sb)
+	m.r.depth--
+	if err != nil {
+		return nil, err
+	}
+
+	return sb.String(), nil
+}
+
+func (r *renderer) exec(nodes []node, sc *scope, w *strings.Builder) error {
+	for _, n := range nodes {
+		if err := r.execNode(n, sc, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *renderer) output(n node, v interface{}, w *strings.Builder) error {
+	if err := r.checkDefined(n, v); err != nil {
+		return err
+	}
+	w.WriteString(toString(v))
+	return nil
+}
+
+func (r *renderer) execNode(n node, sc *scope, w *strings.Builder) error {
+	switch s := n.(type) {
+	case *textNode:
+		w.WriteString(s.Text)
+
+	case *outputNode:
+		v, err := r.eval(s.Expr, sc)
+		if err != nil {
+			return err
+		}
+		return r.output(s, v, w)
+
+	case *ifNode:
+		for idx, cond := range s.Conds {
+			v, err := r.evalDefined(cond, sc)
+			if err != nil {
+				return err
+			}
+			if truthy(v) {
+				return r.exec(s.Bodies[idx], sc, w)
+			}
+		}
+		return r.exec(s.Else, sc, w)
+
+	case *forNode:
+		return r.execFor(s, sc, w)
+
+	case *setNode:
+		v, err := r.evalDefined(s.Value, sc)
+		if err != nil {
+			return err
+		}
+		return r.assign(s, s.Targets, v, sc)
+
+	case *setBlockNode:
+		var sb strings.Builder
+		if err := r.exec(s.Body, newScope(sc), &sb); err != nil {
+			return err
+		}
+		sc.set(s.Name, sb.String())
+
+	case *macroNode:
+		sc.set(s.Name, &Macro{Name: s.Name, node: s, r: r, closure: sc})
+
+	case *includeNode:
+		return r.execInclude(s, sc, w)
+
+	case *importNode:
+		m, err := r.importTemplate(s, s.Template, sc)
+		if err != nil {
+			return err
+		}
+		sc.set(s.Alias, m)
+
+	case *fromImportNode:
+		m, err := r.importTemplate(s, s.Template, sc)
+		if err != nil {
+			return err
+		}
+		for _, name := range s.Names {
+			v, ok := m.exports.Get(name[0])
+			if !ok {
+				return r.errorf(s, "the template does not export the requested name '%s'",
+					name[0])
+			}
+			sc.set(name[1], v)
+		}
+
+	case *extendsNode:
+		v, err := r.evalDefined(s.Template, sc)
+		if err != nil {
+			return err
+		}
+		if r.parent != "" {
+			return r.errorf(s, "extended multiple times")
+		}
+		r.parent = toString(v)
+
+	case *blockNode:
+		// The blocks of the child templates are rendered by the parent.
+		if r.parent != "" {
+			return nil
+		}
+		return r.execBlock(s.Name, 0, sc, w)
+
+	case *withNode:
+		inner := newScope(sc)
+		for idx, name := range s.Names {
+			v, err := r.evalDefined(s.Values[idx], sc)
+			if err != nil {
+				return err
+			}
+			inner.set(name, v)
+		}
+		return r.exec(s.Body, inner, w)
+
+	case *filterBlockNode:
+		var sb strings.Builder
+		inner := newScope(sc)
+		if err := r.exec(s.Body, inner, &sb); err != nil {
+			return err
+		}
+		inner.set(filterBlockVar, sb.String())
+		v, err := r.eval(s.Filter, inner)
+		if err != nil {
+			return err
+		}
+		return r.output(s, v, w)
+
+	case *doNode:
+		_, err := r.evalDefined(s.Expr, sc)
+		return err
+
+	case *breakNode:
+		return errBreak
+
+	case *continueNode:
+		return errContinue
+
+	default:
+		return r.errorf(n, "unsupported statement")
+	}
+
+	return nil
+}
+
+func (r *renderer) assign(n node, targets []expr, v interface{}, sc *scope) error {
+	values := []interface{}{v}
+	if len(targets) > 1 {
+		items, err := iterate(v)
+		if err != nil {
+			return r.wrap(n, err)
+		}
+		if len(items) != len(targets) {
+			return r.errorf(n, "expected %d values to unpack but got %d",
+				len(targets), len(items))
+		}
+		values = items
+	}
+
+	for idx, t := range targets {
+		switch target := t.(type) {
+		case *nameExpr:
+			sc.set(target.Name, values[idx])
+		case *getattrExpr:
+			obj, err := r.evalDefined(target.Obj, sc)
+			if err != nil {
+				return err
+			}
+			ns, ok := obj.(*Namespace)
+			if !ok {
+				return r.errorf(n, "cannot assign attribute on non-namespace object")
+			}
+			ns.Attrs.Set(target.Attr, values[idx])
+		}
+	}
+
+	return nil
+}
+
+func (r *renderer) execFor(s *forNode, sc *scope, w *strings.Builder) error {
+	iterValue, err := r.evalDefined(s.Iter, sc)
+	if err != nil {
+		return err
+	}
+	if _, ok := iterValue.(*Undefined); ok {
+		// Undefined values are empty on lenient mode.
+		iterValue = NewList()
+	}
+
+	items, err := iterate(iterValue)
+	if err != nil {
+		return r.wrap(s, err)
+	}
+	// Copy the items to permit the changes of the list in the loop.
+	items = append([]interface{}{}, items...)
+
+	bind := func(target *scope, item interface{}) error {
+		if len(s.Targets) == 1 {
+			target.set(s.Targets[0], item)
+			return nil
+		}
+		values, err := iterate(item)
+		if err != nil {
+			return r.wrap(s, err)
+		}
+		if len(values) != len(s.Targets) {
+			return r.errorf(s, "expected %d values to unpack but got %d",
+				len(s.Targets), len(values))
+		}
+		for idx, name := range s.Targets {
+			target.set(name, values[idx])
+		}
+		return nil
+	}
+
+	if s.Filter != nil {
+		filtered := []interface{}{}
+		for _, item := range items {
+			inner := newScope(sc)
+			if err := bind(inner, item); err != nil {
+				return err
+			}
+			v, err := r.evalDefined(s.Filter, inner)
+			if err != nil {
+				return err
+			}
+			if truthy(v) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	if len(items) == 0 {
+		return r.exec(s.Else, sc, w)
+	}
+
+	for idx, item := range items {
+		inner := newScope(sc)
+		if err := bind(inner, item); err != nil {
+			return err
+		}
+		inner.set("loop", newLoop(items, idx))
+
+		err := r.exec(s.Body, inner, w)
+		if err == errBreak {
+			break
+		}
+		if err != nil && err != errContinue {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newLoop(items []interface{}, idx int) *Dict {
+	size := len(items)
+	loop := NewDict()
+	loop.Set("index", idx+1)
+	loop.Set("index0", idx)
+	loop.Set("revindex", size-idx)
+	loop.Set("revindex0", size-idx-1)
+	loop.Set("first", idx == 0)
+	loop.Set("last", idx == size-1)
+	loop.Set("length", size)
+	loop.Set("depth", 1)
+	loop.Set("depth0", 0)
+	if idx > 0 {
+		loop.Set("previtem", items[idx-1])
+	} else {
+		loop.Set("previtem", &Undefined{Hint: "there is no previous item"})
+	}
+	if idx < size-1 {
+		loop.Set("nextitem", items[idx+1])
+	} else {
+		loop.Set("nextitem", &Undefined{Hint: "there is no next item"})
+	}
+	loop.Set("cycle", builtinFunc(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("no items for cycling given")
+		}
+		return args[idx%len(args)], nil
+	}))
+
+	return loop
+}
+
+func (r *renderer) execBlock(name string, level int, sc *scope, w *strings.Builder) error {
+	chain := r.blocks[name]
+	if level >= len(chain) {
+		return fmt.Errorf("there is no parent block called '%s'", name)
+	}
+
+	inner := newScope(sc)
+	inner.set("super", builtinFunc(func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		var sb strings.Builder
+		if err := r.execBlock(name, level+1, sc, &sb); err != nil {
+			return nil, err
+		}
+		return sb.String(), nil
+	}))
+
+	return r.exec(chain[level].Body, inner, w)
+}
+
+func (r *renderer) execInclude(s *includeNode, sc *scope, w *strings.Builder) error {
+	v, err := r.evalDefined(s.Template, sc)
+	if err != nil {
+		return err
+	}
+
+	// A list of templates selects the first template available.
+	names := []interface{}{v}
+	if l, ok := v.(*List); ok {
+		names = l.Items
+	}
+
+	var path string
+	for _, name := range names {
+		path, err = r.env.resolve(toString(name), r.file)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if s.IgnoreMissing {
+			return nil
+		}
+		return r.wrap(s, err)
+	}
+
+	// Without context the template receives only the globals.
+	parentScope := newScope(nil)
+	if s.WithContext {
+		parentScope = sc
+	}
+
+	out, err := r.env.render(path, newScope(parentScope), r.depth+1)
+	if err != nil {
+		return r.wrap(s, err)
+	}
+	w.WriteString(out)
+
+	return nil
+}
+
+func (r *renderer) importTemplate(n node, tpl expr, sc *scope) (*module, error) {
+	v, err := r.evalDefined(tpl, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := r.env.resolve(toString(v), r.file)
+	if err != nil {
+		return nil, r.wrap(n, err)
+	}
+
+	// The imported templates don't receive the current context.
+	moduleScope := newScope(nil)
+	if _, err := r.env.render(path, moduleScope, r.depth+1); err != nil {
+		return nil, r.wrap(n, err)
+	}
+
+	names := []string{}
+	for k := range moduleScope.vars {
+		// The names starting with underscore are private.
+		if !strings.HasPrefix(k, "_") {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	m := &module{exports: NewDict()}
+	for _, k := range names {
+		m.exports.Set(k, moduleScope.vars[k])
+	}
+
+	return m, nil
+}
+
+// collectBlocks registers the blocks of the template. The blocks
+// already registered by a child template have the priority.
+func (r *renderer) collectBlocks(nodes []node) {
+	for _, n := range nodes {
+		switch s := n.(type) {
+		case *blockNode:
+			r.blocks[s.Name] = append(r.blocks[s.Name], s)
+			r.collectBlocks(s.Body)
+		case *ifNode:
+			for _, b := range s.Bodies {
+				r.collectBlocks(b)
+			}
+			r.collectBlocks(s.Else)
+		case *forNode:
+			r.collectBlocks(s.Body)
+			r.collectBlocks(s.Else)
+		case *withNode:
+			r.collectBlocks(s.Body)
+		}
+	}
+}
@@ -0,0 +1,738 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type filterFunc func(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+// The filters are initialized on init because some of
+// them call the others filters.
+var filters map[string]filterFunc
+
+func init() {
+	filters = map[string]filterFunc{
+		"abs":        filterAbs,
+		"attr":       filterAttr,
+		"batch":      filterBatch,
+		"capitalize": filterString(capitalizeString),
+		"count":      filterLength,
+		"d":          filterDefault,
+		"default":    filterDefault,
+		"dictsort":   filterDictsort,
+		"e":          filterEscape,
+		"escape":     filterEscape,
+		"first":      filterFirst,
+		"float":      filterFloat,
+		"format":     filterFormat,
+		"indent":     filterIndent,
+		"int":        filterInt,
+		"items":      filterItems,
+		"join":       filterJoin,
+		"last":       filterLast,
+		"length":     filterLength,
+		"list":       filterList,
+		"lower":      filterString(strings.ToLower),
+		"map":        filterMap,
+		"max":        filterMinMax(false),
+		"min":        filterMinMax(true),
+		"reject":     filterSelect(false),
+		"rejectattr": filterSelectAttr(false),
+		"replace":    filterReplace,
+		"reverse":    filterReverse,
+		"round":      filterRound,
+		"safe":       filterSafe,
+		"select":     filterSelect(true),
+		"selectattr": filterSelectAttr(true),
+		"sort":       filterSort,
+		"string":     filterSafe,
+		"sum":        filterSum,
+		"title":      filterString(titleString),
+		"tojson":     filterTojson,
+		"trim":       filterTrim,
+		"truncate":   filterTruncate,
+		"unique":     filterUnique,
+		"upper":      filterString(strings.ToUpper),
+		"wordcount":  filterWordcount,
+	}
+}
+
+// kwargOrArg returns the argument from the position or the name.
+func kwargOrArg(args []interface{}, kwargs map[string]interface{}, idx int, name string, def interface{}) interface{} {
+	if v, ok := kwargs[name]; ok {
+		return v
+	}
+	if idx < len(args) {
+		return args[idx]
+	}
+	return def
+}
+
+// attrGetter returns the function that reads the attribute of the items
+// with the support of the dotted notation.
+func attrGetter(attribute string) func(interface{}) interface{} {
+	parts := strings.Split(attribute, ".")
+	return func(item interface{}) interface{} {
+		for _, p := range parts {
+			if _, ok := item.(*Undefined); ok {
+				return item
+			}
+			if v, err := getItem(item, p); err == nil {
+				item = v
+			} else {
+				item = getAttr(item, p)
+			}
+		}
+		return item
+	}
+}
+
+func filterString(fn func(string) string) filterFunc {
+	return func(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return fn(toString(v)), nil
+	}
+}
+
+func filterAbs(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case int:
+		if val < 0 {
+			return -val, nil
+		}
+		return val, nil
+	case float64:
+		return math.Abs(val), nil
+	}
+	return nil, fmt.Errorf("bad operand type for abs(): '%s'", typeName(v))
+}
+
+func filterAttr(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("attr filter requires the name of the attribute")
+	}
+	return getAttr(v, toString(args[0])), nil
+}
+
+func filterBatch(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	count, err := toInt(kwargOrArg(args, kwargs, 0, "linecount", nil))
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("batch filter requires a positive number of items")
+	}
+	fill, hasFill := kwargs["fill_with"]
+	if len(args) > 1 {
+		fill, hasFill = args[1], true
+	}
+
+	ans := NewList()
+	for i := 0; i < len(items); i += count {
+		end := i + count
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := NewList(append([]interface{}{}, items[i:end]...)...)
+		for hasFill && len(batch.Items) < count {
+			batch.Items = append(batch.Items, fill)
+		}
+		ans.Items = append(ans.Items, batch)
+	}
+	return ans, nil
+}
+
+func filterDefault(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	def := kwargOrArg(args, kwargs, 0, "default_value", "")
+	boolean := truthy(kwargOrArg(args, kwargs, 1, "boolean", false))
+
+	if _, ok := v.(*Undefined); ok {
+		return def, nil
+	}
+	if boolean && !truthy(v) {
+		return def, nil
+	}
+	return v, nil
+}
+
+func filterDictsort(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	d, ok := v.(*Dict)
+	if !ok {
+		return nil, fmt.Errorf("dictsort filter requires a dict, not %s", typeName(v))
+	}
+	caseSensitive := truthy(kwargOrArg(args, kwargs, 0, "case_sensitive", false))
+	by := toString(kwargOrArg(args, kwargs, 1, "by", "key"))
+	reverse := truthy(kwargOrArg(args, kwargs, 2, "reverse", false))
+
+	items := []interface{}{}
+	for _, k := range d.Keys {
+		items = append(items, NewTuple(k, d.Values[k]))
+	}
+
+	pos := 0
+	if by == "value" {
+		pos = 1
+	}
+	err := sortItems(items, func(item interface{}) interface{} {
+		return item.(*List).Items[pos]
+	}, caseSensitive, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewList(items...), nil
+}
+
+func filterEscape(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	replacer := strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&#34;", "'", "&#39;",
+	)
+	return replacer.Replace(toString(v)), nil
+}
+
+func filterFirst(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return &Undefined{Hint: "No first item, sequence was empty."}, nil
+	}
+	return items[0], nil
+}
+
+func filterLast(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return &Undefined{Hint: "No last item, sequence was empty."}, nil
+	}
+	return items[len(items)-1], nil
+}
+
+func filterFloat(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		def := kwargOrArg(args, kwargs, 0, "default", 0.0)
+		return toFloat(def)
+	}
+	return f, nil
+}
+
+func filterInt(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	i, err := toInt(v)
+	if err == nil {
+		return i, nil
+	}
+	// Permit the conversion of the strings of floats.
+	if s, ok := v.(string); ok {
+		if f, err := toFloat(s); err == nil {
+			return int(f), nil
+		}
+	}
+	def := kwargOrArg(args, kwargs, 0, "default", 0)
+	return toInt(def)
+}
+
+func filterFormat(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if len(kwargs) > 0 {
+		return pyFormat(toString(v), nil, kwargs)
+	}
+	if len(args) == 1 {
+		return printfFormat(toString(v), args[0])
+	}
+	return printfFormat(toString(v), NewList(args...))
+}
+
+func filterIndent(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	width := kwargOrArg(args, kwargs, 0, "width", 4)
+	first := truthy(kwargOrArg(args, kwargs, 1, "first", false))
+	blank := truthy(kwargOrArg(args, kwargs, 2, "blank", false))
+
+	indent := ""
+	if s, ok := width.(string); ok {
+		indent = s
+	} else {
+		n, err := toInt(width)
+		if err != nil {
+			return nil, err
+		}
+		indent = strings.Repeat(" ", n)
+	}
+
+	s := toString(v)
+	lines := strings.Split(s, "\n")
+	for idx, line := range lines {
+		if idx == 0 && !first {
+			continue
+		}
+		if line == "" && !blank {
+			continue
+		}
+		lines[idx] = indent + line
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func filterItems(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	d, ok := v.(*Dict)
+	if !ok {
+		return nil, fmt.Errorf("items filter requires a dict, not %s", typeName(v))
+	}
+	ans := NewList()
+	for _, k := range d.Keys {
+		ans.Items = append(ans.Items, NewTuple(k, d.Values[k]))
+	}
+	return ans, nil
+}
+
+func filterJoin(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	sep := toString(kwargOrArg(args, kwargs, 0, "d", ""))
+	attribute := kwargOrArg(args, kwargs, 1, "attribute", nil)
+
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []string{}
+	for _, item := range items {
+		if attribute != nil {
+			item = attrGetter(toString(attribute))(item)
+		}
+		parts = append(parts, toString(item))
+	}
+
+	return strings.Join(parts, sep), nil
+}
+
+func filterLength(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return length(v)
+}
+
+func filterList(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	return NewList(append([]interface{}{}, items...)...), nil
+}
+
+func filterMap(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := NewList()
+	if attribute, ok := kwargs["attribute"]; ok {
+		getter := attrGetter(toString(attribute))
+		def, hasDefault := kwargs["default"]
+		for _, item := range items {
+			val := getter(item)
+			if _, ok := val.(*Undefined); ok && hasDefault {
+				val = def
+			}
+			ans.Items = append(ans.Items, val)
+		}
+		return ans, nil
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("map requires a filter argument")
+	}
+	name := toString(args[0])
+	f, ok := filters[name]
+	if !ok {
+		return nil, fmt.Errorf("no filter named '%s'", name)
+	}
+	for _, item := range items {
+		val, err := f(r, item, args[1:], kwargs)
+		if err != nil {
+			return nil, err
+		}
+		ans.Items = append(ans.Items, val)
+	}
+
+	return ans, nil
+}
+
+func filterMinMax(isMin bool) filterFunc {
+	return func(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		items, err := iterate(v)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return &Undefined{Hint: "No aggregated item, sequence was empty."}, nil
+		}
+
+		caseSensitive := truthy(kwargOrArg(args, kwargs, 0, "case_sensitive", false))
+		key := sortKey(caseSensitive, kwargOrArg(args, kwargs, 1, "attribute", nil))
+
+		ans := items[0]
+		for _, item := range items[1:] {
+			c, err := compare(key(item), key(ans))
+			if err != nil {
+				return nil, err
+			}
+			if (isMin && c < 0) || (!isMin && c > 0) {
+				ans = item
+			}
+		}
+		return ans, nil
+	}
+}
+
+func filterSelect(keep bool) filterFunc {
+	return func(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		items, err := iterate(v)
+		if err != nil {
+			return nil, err
+		}
+
+		ans := NewList()
+		for _, item := range items {
+			ok := truthy(item)
+			if len(args) > 0 {
+				name := toString(args[0])
+				t, exists := tests[name]
+				if !exists {
+					return nil, fmt.Errorf("no test named '%s'", name)
+				}
+				ok, err = t(item, args[1:])
+				if err != nil {
+					return nil, err
+				}
+			}
+			if ok == keep {
+				ans.Items = append(ans.Items, item)
+			}
+		}
+		return ans, nil
+	}
+}
+
+func filterSelectAttr(keep bool) filterFunc {
+	return func(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("missing parameter for attribute name")
+		}
+		items, err := iterate(v)
+		if err != nil {
+			return nil, err
+		}
+		getter := attrGetter(toString(args[0]))
+
+		ans := NewList()
+		for _, item := range items {
+			val := getter(item)
+			var ok bool
+			if len(args) > 1 {
+				name := toString(args[1])
+				t, exists := tests[name]
+				if !exists {
+					return nil, fmt.Errorf("no test named '%s'", name)
+				}
+				ok, err = t(val, args[2:])
+				if err != nil {
+					return nil, err
+				}
+			} else {
+				ok = truthy(val)
+			}
+			if ok == keep {
+				ans.Items = append(ans.Items, item)
+			}
+		}
+		return ans, nil
+	}
+}
+
+func filterReplace(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("replace filter requires the old and the new strings")
+	}
+	count := -1
+	if c := kwargOrArg(args, kwargs, 2, "count", nil); c != nil {
+		n, err := toInt(c)
+		if err != nil {
+			return nil, err
+		}
+		count = n
+	}
+	return strings.Replace(toString(v), toString(args[0]), toString(args[1]), count), nil
+}
+
+func filterReverse(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		runes := []rune(s)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}
+
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	ans := NewList()
+	for i := len(items) - 1; i >= 0; i-- {
+		ans.Items = append(ans.Items, items[i])
+	}
+	return ans, nil
+}
+
+func filterRound(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return nil, err
+	}
+	precision, err := toInt(kwargOrArg(args, kwargs, 0, "precision", 0))
+	if err != nil {
+		return nil, err
+	}
+	method := toString(kwargOrArg(args, kwargs, 1, "method", "common"))
+
+	p := math.Pow(10, float64(precision))
+	switch method {
+	case "common":
+		f = math.Round(f*p) / p
+	case "ceil":
+		f = math.Ceil(f*p) / p
+	case "floor":
+		f = math.Floor(f*p) / p
+	default:
+		return nil, fmt.Errorf("method must be common, ceil or floor")
+	}
+	return f, nil
+}
+
+func filterSafe(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return toString(v), nil
+}
+
+// sortKey returns the function that returns the value used to
+// sort the items.
+func sortKey(caseSensitive bool, attribute interface{}) func(interface{}) interface{} {
+	var getter func(interface{}) interface{}
+	if attribute != nil {
+		getter = attrGetter(toString(attribute))
+	}
+	return func(item interface{}) interface{} {
+		if getter != nil {
+			item = getter(item)
+		}
+		if s, ok := item.(string); ok && !caseSensitive {
+			return strings.ToLower(s)
+		}
+		return item
+	}
+}
+
+func sortItems(items []interface{}, key func(interface{}) interface{}, caseSensitive, reverse bool) error {
+	var sortErr error
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := key(items[i]), key(items[j])
+		if s, ok := a.(string); ok && !caseSensitive {
+			a = strings.ToLower(s)
+		}
+		if s, ok := b.(string); ok && !caseSensitive {
+			b = strings.ToLower(s)
+		}
+		c, err := compare(a, b)
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	return sortErr
+}
+
+func filterSort(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	items = append([]interface{}{}, items...)
+
+	reverse := truthy(kwargOrArg(args, kwargs, 0, "reverse", false))
+	caseSensitive := truthy(kwargOrArg(args, kwargs, 1, "case_sensitive", false))
+	attribute := kwargOrArg(args, kwargs, 2, "attribute", nil)
+
+	if err := sortItems(items, sortKey(true, attribute), caseSensitive, reverse); err != nil {
+		return nil, err
+	}
+
+	return NewList(items...), nil
+}
+
+func filterSum(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	attribute := kwargOrArg(args, kwargs, 0, "attribute", nil)
+	var ans interface{} = kwargOrArg(args, kwargs, 1, "start", 0)
+
+	for _, item := range items {
+		if attribute != nil {
+			item = attrGetter(toString(attribute))(item)
+		}
+		ans, err = binaryOp("+", ans, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ans, nil
+}
+
+func filterTojson(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	data, err := jsonDumps(v)
+	if err != nil {
+		return nil, err
+	}
+	// Same escaping of jinja2 for the usage in HTML.
+	replacer := strings.NewReplacer(
+		"<", "\\u003c", ">", "\\u003e", "&", "\\u0026", "'", "\\u0027",
+	)
+	return replacer.Replace(data), nil
+}
+
+// jsonDumps returns the JSON of the value with the same format
+// of the json.dumps of python with the keys sorted.
+func jsonDumps(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil, *Undefined:
+		return "null", nil
+	case bool:
+		if val {
+			return "true", nil
+		}
+		return "false", nil
+	case int, float64:
+		return repr(val), nil
+	case *List:
+		parts := make([]string, 0, len(val.Items))
+		for _, item := range val.Items {
+			s, err := jsonDumps(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case *Dict:
+		keys := append([]string{}, val.Keys...)
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			key, _ := json.Marshal(k)
+			s, err := jsonDumps(val.Values[k])
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, string(key)+": "+s)
+		}
+		return "{" + strings.Join(parts, ", ") + "}", nil
+	case *Namespace:
+		return jsonDumps(val.Attrs)
+	case string:
+		data, err := json.Marshal(val)
+		return string(data), err
+	}
+	return "", fmt.Errorf("object of type %s is not JSON serializable", typeName(v))
+}
+
+func filterTrim(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return stripChars(toString(v), args, true, true)
+}
+
+func filterTruncate(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	s := []rune(toString(v))
+	size, err := toInt(kwargOrArg(args, kwargs, 0, "length", 255))
+	if err != nil {
+		return nil, err
+	}
+	killwords := truthy(kwargOrArg(args, kwargs, 1, "killwords", false))
+	end := toString(kwargOrArg(args, kwargs, 2, "end", "..."))
+	// Default leeway of the jinja2 environment.
+	leeway := 5
+	if l := kwargOrArg(args, kwargs, 3, "leeway", nil); l != nil {
+		leeway, err = toInt(l)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if size < len([]rune(end)) {
+		return nil, fmt.Errorf("expected length >= %d, got %d", len([]rune(end)), size)
+	}
+	if leeway < 0 {
+		return nil, fmt.Errorf("expected leeway >= 0, got %d", leeway)
+	}
+	if len(s) <= size+leeway {
+		return string(s), nil
+	}
+
+	ans := string(s[:size-len([]rune(end))])
+	if !killwords {
+		if idx := strings.LastIndex(ans, " "); idx >= 0 {
+			ans = ans[:idx]
+		}
+	}
+	return ans + end, nil
+}
+
+func filterUnique(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := iterate(v)
+	if err != nil {
+		return nil, err
+	}
+	caseSensitive := truthy(kwargOrArg(args, kwargs, 0, "case_sensitive", false))
+	key := sortKey(caseSensitive, kwargOrArg(args, kwargs, 1, "attribute", nil))
+
+	ans := NewList()
+	seen := []interface{}{}
+	for _, item := range items {
+		k := key(item)
+		found := false
+		for _, s := range seen {
+			if equals(s, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			seen = append(seen, k)
+			ans.Items = append(ans.Items, item)
+		}
+	}
+	return ans, nil
+}
+
+func filterWordcount(r *renderer, v interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return len(strings.FieldsFunc(toString(v), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_'
+	})), nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/tmpl-engines/jinja2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The values available on the filters and tests tables.
+func tableValues() map[string]interface{} {
+	return map[string]interface{}{
+		"n":     -3,
+		"f":     2.567,
+		"s":     "hello WORLD",
+		"empty": "",
+		"nums":  []interface{}{3, 1, 2},
+		"dict":  map[string]interface{}{"b": 1, "a": 2},
+		"users": []interface{}{
+			map[string]interface{}{"name": "zed", "age": 30, "admin": true},
+			map[string]interface{}{"name": "amy", "age": 20, "admin": false},
+		},
+	}
+}
+
+func render(src string, values map[string]interface{}) (string, error) {
+	env := NewEnvironment([]string{})
+	return env.RenderString("test", src, values)
+}
+
+var _ = Describe("Jinja2 filters", func() {
+
+	DescribeTable("Rendering",
+		func(src, expected string) {
+			out, err := render(src, tableValues())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(expected))
+		},
+		Entry("abs", `{{ n|abs }}`, "3"),
+		Entry("attr", `{% set ns = namespace(a=1) %}{{ ns|attr("a") }}`, "1"),
+		Entry("batch", `{% for b in [1, 2, 3, 4, 5]|batch(2) %}{{ b }};{% endfor %}`, "[1, 2];[3, 4];[5];"),
+		Entry("batch fill_with", `{{ [1, 2, 3]|batch(2, "x")|list }}`, "[[1, 2], [3, 'x']]"),
+		Entry("capitalize", `{{ s|capitalize }}`, "Hello world"),
+		Entry("count", `{{ nums|count }}`, "3"),
+		Entry("d", `{{ missing|d("x") }}`, "x"),
+		Entry("default undefined", `{{ missing|default("x") }}`, "x"),
+		Entry("default empty", `{{ empty|default("x") }}`, ""),
+		Entry("default boolean", `{{ empty|default("x", true) }}`, "x"),
+		Entry("dictsort", `{% for k, v in dict|dictsort %}{{ k }}={{ v }};{% endfor %}`, "a=2;b=1;"),
+		Entry("dictsort by value", `{% for k, v in dict|dictsort(by="value") %}{{ k }}={{ v }};{% endfor %}`, "b=1;a=2;"),
+		Entry("dictsort tuples", `{{ dict|dictsort }}`, "[('a', 2), ('b', 1)]"),
+		Entry("dictsort tuple item", `{{ (dict|dictsort)[0] }}`, "('a', 2)"),
+		Entry("e", `{{ "<a&>"|e }}`, "&lt;a&amp;&gt;"),
+		Entry("escape", `{{ "'\""|escape }}`, "&#39;&#34;"),
+		Entry("first", `{{ nums|first }}`, "3"),
+		Entry("float", `{{ "1.5"|float }}`, "1.5"),
+		Entry("float invalid", `{{ "x"|float }}`, "0.0"),
+		Entry("format", `{{ "%s-%d"|format("a", 3) }}`, "a-3"),
+		Entry("indent", `{{ "a\nb"|indent(2) }}`, "a\n  b"),
+		Entry("indent first", `{{ "a\nb"|indent(2, true) }}`, "  a\n  b"),
+		Entry("int", `{{ "42"|int }}`, "42"),
+		Entry("int from float", `{{ "4.7"|int }}`, "4"),
+		Entry("int invalid", `{{ "x"|int }}`, "0"),
+		Entry("items", `{% for k, v in {"b": 1, "a": 2}|items %}{{ k }}={{ v }};{% endfor %}`, "b=1;a=2;"),
+		Entry("items tuples", `{{ {"b": 1}|items|list }}`, "[('b', 1)]"),
+		Entry("join", `{{ nums|join(",") }}`, "3,1,2"),
+		Entry("join attribute", `{{ users|join(", ", attribute="name") }}`, "zed, amy"),
+		Entry("last", `{{ nums|last }}`, "2"),
+		Entry("length", `{{ "abc"|length }}`, "3"),
+		Entry("list", `{{ "ab"|list }}`, "['a', 'b']"),
+		Entry("lower", `{{ s|lower }}`, "hello world"),
+		Entry("map attribute", `{{ users|map(attribute="name")|join(",") }}`, "zed,amy"),
+		Entry("map filter", `{{ ["1", "2"]|map("int")|sum }}`, "3"),
+		Entry("max", `{{ nums|max }}`, "3"),
+		Entry("min", `{{ nums|min }}`, "1"),
+		Entry("reject", `{{ [1, 2, 3, 4]|reject("odd")|join(",") }}`, "2,4"),
+		Entry("rejectattr", `{{ users|rejectattr("admin")|map(attribute="name")|join }}`, "amy"),
+		Entry("replace", `{{ "aaa"|replace("a", "b") }}`, "bbb"),
+		Entry("replace count", `{{ "aaa"|replace("a", "b", 2) }}`, "bba"),
+		Entry("reverse list", `{{ nums|reverse|join }}`, "213"),
+		Entry("reverse string", `{{ "abc"|reverse }}`, "cba"),
+		Entry("round", `{{ f|round(2) }}`, "2.57"),
+		Entry("round floor", `{{ f|round(1, "floor") }}`, "2.5"),
+		Entry("round ceil", `{{ f|round(0, "ceil") }}`, "3.0"),
+		Entry("safe", `{{ "<b>"|safe }}`, "<b>"),
+		Entry("select", `{{ [1, 2, 3, 4]|select("odd")|join(",") }}`, "1,3"),
+		Entry("select without test", `{{ [0, 1, "", "a"]|select|join(",") }}`, "1,a"),
+		Entry("selectattr", `{{ users|selectattr("admin")|map(attribute="name")|join }}`, "zed"),
+		Entry("selectattr with test", `{{ users|selectattr("age", "even")|list|length }}`, "2"),
+		Entry("sort", `{{ nums|sort|join }}`, "123"),
+		Entry("sort reverse", `{{ nums|sort(reverse=true)|join }}`, "321"),
+		Entry("sort attribute", `{{ users|sort(attribute="name")|map(attribute="name")|join(",") }}`, "amy,zed"),
+		Entry("string", `{{ (1|string) ~ "a" }}`, "1a"),
+		Entry("sum", `{{ nums|sum }}`, "6"),
+		Entry("sum attribute", `{{ users|sum(attribute="age") }}`, "50"),
+		Entry("title", `{{ s|title }}`, "Hello World"),
+		Entry("tojson", `{{ {"b": [1, "x"], "a": none}|tojson }}`, `{"a": null, "b": [1, "x"]}`),
+		Entry("trim", `{{ "  a  "|trim }}`, "a"),
+		Entry("truncate", `{{ "foo bar baz qux"|truncate(9) }}`, "foo..."),
+		Entry("truncate leeway", `{{ "foo bar baz qux"|truncate(11) }}`, "foo bar baz qux"),
+		Entry("truncate killwords", `{{ "foo bar baz qux"|truncate(9, true, leeway=0) }}`, "foo ba..."),
+		Entry("truncate end", `{{ "foo bar baz qux"|truncate(9, end="!", leeway=0) }}`, "foo bar!"),
+		Entry("truncate short", `{{ "foo"|truncate(9) }}`, "foo"),
+		Entry("unique", `{{ [1, 2, 1, 3]|unique|join }}`, "123"),
+		Entry("upper", `{{ s|upper }}`, "HELLO WORLD"),
+		Entry("wordcount", `{{ "a b  c"|wordcount }}`, "3"),
+	)
+
+	DescribeTable("Filter block",
+		func(src, expected string) {
+			out, err := render(src, tableValues())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(expected))
+		},
+		Entry("single filter", `{% filter upper %}hello {{ s }}{% endfilter %}`, "HELLO HELLO WORLD"),
+		Entry("chained filters", `{% filter lower|replace("o", "0") %}FOO{% endfilter %}`, "f00"),
+		Entry("filter with arguments", "{% filter indent(2, true) %}a\nb{% endfilter %}", "  a\n  b"),
+		Entry("nested blocks", `{% filter upper %}a{% filter trim %} b {% endfilter %}c{% endfilter %}`, "ABC"),
+	)
+
+	DescribeTable("str.format",
+		func(src, expected string) {
+			out, err := render(src, tableValues())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(expected))
+		},
+		Entry("automatic fields", `{{ "{}-{}".format("a", 1) }}`, "a-1"),
+		Entry("positional fields", `{{ "{1}{0}".format("a", "b") }}`, "ba"),
+		Entry("keyword fields", `{{ "{name}!".format(name="x") }}`, "x!"),
+		Entry("escaped braces", `{{ "{{{}}}".format(1) }}`, "{1}"),
+		Entry("right align", `{{ "[{:>5}]".format("ab") }}`, "[   ab]"),
+		Entry("left align", `{{ "[{:<5}]".format("ab") }}`, "[ab   ]"),
+		Entry("center with fill", `{{ "[{:*^6}]".format("ab") }}`, "[**ab**]"),
+		Entry("number right aligned", `{{ "[{:5}]".format(42) }}`, "[   42]"),
+		Entry("string left aligned", `{{ "[{:5}]".format("ab") }}`, "[ab   ]"),
+		Entry("zero padding", `{{ "{:03d}".format(7) }}`, "007"),
+		Entry("zero padding negative", `{{ "{:05d}".format(-7) }}`, "-0007"),
+		Entry("sign", `{{ "{:+d}".format(7) }}`, "+7"),
+		Entry("precision", `{{ "{:.2f}".format(f) }}`, "2.57"),
+		Entry("width and precision", `{{ "{:>8.3f}".format(f) }}`, "   2.567"),
+		Entry("thousands", `{{ "{:,}".format(1234567) }}`, "1,234,567"),
+		Entry("hex", `{{ "{:#x}".format(255) }}`, "0xff"),
+		Entry("percentage", `{{ "{:.1%}".format(0.25) }}`, "25.0%"),
+		Entry("string truncated", `{{ "{:.3}".format("abcdef") }}`, "abc"),
+		Entry("keyword with spec", `{{ "{v:>4}".format(v=1) }}`, "   1"),
+	)
+
+	It("str.format with invalid spec", func() {
+		_, err := render(`{{ "{:d}".format("a") }}`, tableValues())
+		Expect(err).ShouldNot(BeNil())
+	})
+
+	It("Unknown filter", func() {
+		_, err := render("a\n{{ s|nofilter }}", tableValues())
+		Expect(err).ShouldNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("test:2:"))
+	})
+
+})
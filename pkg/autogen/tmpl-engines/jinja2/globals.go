@@ -0,0 +1,90 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+)
+
+func defaultGlobals() map[string]interface{} {
+	return map[string]interface{}{
+		"range":     builtinFunc(globalRange),
+		"dict":      builtinFunc(globalDict),
+		"namespace": builtinFunc(globalNamespace),
+		"joiner":    builtinFunc(globalJoiner),
+	}
+}
+
+func globalRange(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("range expected from 1 to 3 arguments, got %d", len(args))
+	}
+
+	bounds := []int{}
+	for _, a := range args {
+		i, err := toInt(a)
+		if err != nil {
+			return nil, err
+		}
+		bounds = append(bounds, i)
+	}
+
+	start, stop, step := 0, bounds[0], 1
+	if len(bounds) > 1 {
+		start, stop = bounds[0], bounds[1]
+	}
+	if len(bounds) > 2 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("range() arg 3 must not be zero")
+	}
+
+	ans := NewList()
+	for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+		ans.Items = append(ans.Items, i)
+	}
+	return ans, nil
+}
+
+func globalDict(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	ans := NewDict()
+	for _, a := range args {
+		d, ok := a.(*Dict)
+		if !ok {
+			return nil, fmt.Errorf("dict() argument must be a dict, not %s", typeName(a))
+		}
+		for _, k := range d.Keys {
+			ans.Set(k, d.Values[k])
+		}
+	}
+	for _, k := range sortedKeys(kwargs) {
+		ans.Set(k, kwargs[k])
+	}
+	return ans, nil
+}
+
+func globalNamespace(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	d, err := globalDict(args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return &Namespace{Attrs: d.(*Dict)}, nil
+}
+
+func globalJoiner(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	sep := ", "
+	if len(args) > 0 {
+		sep = toString(args[0])
+	}
+	used := false
+	return builtinFunc(func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		if !used {
+			used = true
+			return "", nil
+		}
+		return sep, nil
+	}), nil
+}
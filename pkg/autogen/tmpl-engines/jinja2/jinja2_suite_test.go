@@ -0,0 +1,17 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestJinja2(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Jinja2 engine Suite")
+}
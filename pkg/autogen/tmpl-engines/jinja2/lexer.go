@@ -0,0 +1,392 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokData tokenKind = iota
+	tokVarBegin
+	tokVarEnd
+	tokBlockBegin
+	tokBlockEnd
+	tokName
+	tokString
+	tokInt
+	tokFloat
+	tokOp
+	tokEOF
+)
+
+type token struct {
+	Kind  tokenKind
+	Value string
+	Line  int
+}
+
+func (t *token) String() string {
+	switch t.Kind {
+	case tokData:
+		return "template data"
+	case tokVarBegin:
+		return "'{{'"
+	case tokVarEnd:
+		return "'}}'"
+	case tokBlockBegin:
+		return "'{%'"
+	case tokBlockEnd:
+		return "'%}'"
+	case tokEOF:
+		return "end of template"
+	case tokString:
+		return fmt.Sprintf("string '%s'", t.Value)
+	default:
+		return fmt.Sprintf("'%s'", t.Value)
+	}
+}
+
+// Operators sorted by length to match the longest first.
+var lexerOperators = []string{
+	"**", "//", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "~", "<", ">", "=",
+	"(", ")", "[", "]", "{", "}", ",", ".", ":", "|",
+}
+
+type lexer struct {
+	file   string
+	src    string
+	pos    int
+	line   int
+	tokens []*token
+	// Strip the whitespaces of the next data token.
+	lstripNext bool
+}
+
+func tokenize(file, src string) ([]*token, error) {
+	l := &lexer{
+		file:   file,
+		src:    src,
+		line:   1,
+		tokens: []*token{},
+	}
+
+	if err := l.run(); err != nil {
+		return nil, err
+	}
+
+	return l.tokens, nil
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return &Error{File: l.file, Line: l.line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) emit(kind tokenKind, value string, line int) {
+	l.tokens = append(l.tokens, &token{Kind: kind, Value: value, Line: line})
+}
+
+func (l *lexer) emitData(data string, line int) {
+	if l.lstripNext {
+		data = strings.TrimLeftFunc(data, unicode.IsSpace)
+		l.lstripNext = false
+	}
+	if data != "" {
+		l.emit(tokData, data, line)
+	}
+}
+
+// rstripLastData removes the trailing whitespaces of the
+// last data token for the tags starting with "-".
+func (l *lexer) rstripLastData() {
+	if len(l.tokens) == 0 {
+		return
+	}
+	last := l.tokens[len(l.tokens)-1]
+	if last.Kind != tokData {
+		return
+	}
+	last.Value = strings.TrimRightFunc(last.Value, unicode.IsSpace)
+	if last.Value == "" {
+		l.tokens = l.tokens[:len(l.tokens)-1]
+	}
+}
+
+func (l *lexer) advance(n int) {
+	l.line += strings.Count(l.src[l.pos:l.pos+n], "\n")
+	l.pos += n
+}
+
+func (l *lexer) run() error {
+	for l.pos < len(l.src) {
+		idx := l.nextTagIndex()
+		if idx < 0 {
+			l.emitData(l.src[l.pos:], l.line)
+			l.advance(len(l.src) - l.pos)
+			break
+		}
+
+		if idx > 0 {
+			line := l.line
+			l.emitData(l.src[l.pos:l.pos+idx], line)
+			l.advance(idx)
+		} else {
+			// Consume the lstrip for empty data.
+			l.lstripNext = false
+		}
+
+		tag := l.src[l.pos : l.pos+2]
+		l.advance(2)
+
+		if l.pos < len(l.src) && l.src[l.pos] == '-' {
+			l.rstripLastData()
+			l.advance(1)
+		} else if l.pos < len(l.src) && l.src[l.pos] == '+' {
+			l.advance(1)
+		}
+
+		var err error
+		switch tag {
+		case "{#":
+			err = l.lexComment()
+		case "{{":
+			l.emit(tokVarBegin, tag, l.line)
+			err = l.lexExpression("}}", tokVarEnd)
+		case "{%":
+			if l.isRawBlock() {
+				err = l.lexRaw()
+			} else {
+				l.emit(tokBlockBegin, tag, l.line)
+				err = l.lexExpression("%}", tokBlockEnd)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	l.emit(tokEOF, "", l.line)
+	return nil
+}
+
+func (l *lexer) nextTagIndex() int {
+	ans := -1
+	for _, tag := range []string{"{{", "{%", "{#"} {
+		idx := strings.Index(l.src[l.pos:], tag)
+		if idx >= 0 && (ans < 0 || idx < ans) {
+			ans = idx
+		}
+	}
+	return ans
+}
+
+func (l *lexer) lexComment() error {
+	idx := strings.Index(l.src[l.pos:], "#}")
+	if idx < 0 {
+		return l.errorf("missing end of comment tag")
+	}
+	if idx > 0 && l.src[l.pos+idx-1] == '-' {
+		l.lstripNext = true
+	}
+	l.advance(idx + 2)
+	return nil
+}
+
+func (l *lexer) isRawBlock() bool {
+	rest := strings.TrimLeftFunc(l.src[l.pos:], unicode.IsSpace)
+	if !strings.HasPrefix(rest, "raw") {
+		return false
+	}
+	rest = strings.TrimLeftFunc(rest[3:], unicode.IsSpace)
+	return strings.HasPrefix(rest, "%}") || strings.HasPrefix(rest, "-%}")
+}
+
+func (l *lexer) lexRaw() error {
+	end := strings.Index(l.src[l.pos:], "%}")
+	if l.src[l.pos+end-1] == '-' {
+		l.lstripNext = true
+	}
+	l.advance(end + 2)
+
+	// Search the endraw tag
+	offset := l.pos
+	for {
+		idx := strings.Index(l.src[offset:], "{%")
+		if idx < 0 {
+			return l.errorf("missing endraw tag")
+		}
+		start := offset + idx
+		p := start + 2
+		stripBefore := false
+		if p < len(l.src) && l.src[p] == '-' {
+			stripBefore = true
+			p++
+		}
+		rest := strings.TrimLeftFunc(l.src[p:], unicode.IsSpace)
+		if strings.HasPrefix(rest, "endraw") {
+			after := strings.TrimLeftFunc(rest[6:], unicode.IsSpace)
+			stripAfter := strings.HasPrefix(after, "-%}")
+			if stripAfter || strings.HasPrefix(after, "%}") {
+				data := l.src[l.pos:start]
+				if stripBefore {
+					data = strings.TrimRightFunc(data, unicode.IsSpace)
+				}
+				line := l.line
+				l.emitData(data, line)
+				tagEnd := len(l.src) - len(after) + 2
+				if stripAfter {
+					tagEnd++
+				}
+				l.advance(tagEnd - l.pos)
+				l.lstripNext = stripAfter
+				return nil
+			}
+		}
+		offset = start + 2
+	}
+}
+
+func (l *lexer) lexExpression(end string, endKind tokenKind) error {
+	for {
+		// Skip whitespaces
+		for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+			l.advance(1)
+		}
+
+		if l.pos >= len(l.src) {
+			return l.errorf("unexpected end of template, missing '%s'", end)
+		}
+
+		rest := l.src[l.pos:]
+		if strings.HasPrefix(rest, "-"+end) {
+			l.emit(endKind, end, l.line)
+			l.advance(len(end) + 1)
+			l.lstripNext = true
+			return nil
+		}
+		if strings.HasPrefix(rest, "+"+end) {
+			l.emit(endKind, end, l.line)
+			l.advance(len(end) + 1)
+			return nil
+		}
+		if strings.HasPrefix(rest, end) {
+			l.emit(endKind, end, l.line)
+			l.advance(len(end))
+			return nil
+		}
+
+		c := rest[0]
+		switch {
+		case c == '"' || c == '\'':
+			if err := l.lexString(c); err != nil {
+				return err
+			}
+		case c >= '0' && c <= '9':
+			l.lexNumber()
+		case c == '_' || unicode.IsLetter(rune(c)):
+			n := 0
+			for n < len(rest) && (rest[n] == '_' || unicode.IsLetter(rune(rest[n])) ||
+				unicode.IsDigit(rune(rest[n]))) {
+				n++
+			}
+			l.emit(tokName, rest[:n], l.line)
+			l.advance(n)
+		default:
+			found := false
+			for _, op := range lexerOperators {
+				if strings.HasPrefix(rest, op) {
+					l.emit(tokOp, op, l.line)
+					l.advance(len(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return l.errorf("unexpected char '%c'", c)
+			}
+		}
+	}
+}
+
+func (l *lexer) lexString(quote byte) error {
+	line := l.line
+	var sb strings.Builder
+	i := l.pos + 1
+	for {
+		if i >= len(l.src) {
+			return l.errorf("unterminated string")
+		}
+		c := l.src[i]
+		if c == quote {
+			break
+		}
+		if c == '\\' && i+1 < len(l.src) {
+			i++
+			switch l.src[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '\\', '\'', '"':
+				sb.WriteByte(l.src[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(l.src[i])
+			}
+			i++
+			continue
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	l.emit(tokString, sb.String(), line)
+	l.advance(i + 1 - l.pos)
+	return nil
+}
+
+func (l *lexer) lexNumber() {
+	rest := l.src[l.pos:]
+	n := 0
+	isFloat := false
+	for n < len(rest) {
+		c := rest[n]
+		if c >= '0' && c <= '9' || c == '_' {
+			n++
+		} else if c == '.' && !isFloat && n+1 < len(rest) && rest[n+1] >= '0' && rest[n+1] <= '9' {
+			isFloat = true
+			n++
+		} else {
+			break
+		}
+	}
+	// Exponent of the floats
+	if n < len(rest) && (rest[n] == 'e' || rest[n] == 'E') {
+		m := n + 1
+		if m < len(rest) && (rest[m] == '+' || rest[m] == '-') {
+			m++
+		}
+		if m < len(rest) && rest[m] >= '0' && rest[m] <= '9' {
+			for m < len(rest) && rest[m] >= '0' && rest[m] <= '9' {
+				m++
+			}
+			n = m
+			isFloat = true
+		}
+	}
+	value := strings.ReplaceAll(rest[:n], "_", "")
+	if isFloat {
+		l.emit(tokFloat, value, l.line)
+	} else {
+		l.emit(tokInt, value, l.line)
+	}
+	l.advance(n)
+}
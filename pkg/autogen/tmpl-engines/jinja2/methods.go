@@ -0,0 +1,825 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// getMethod returns the python method of the strings, lists and
+// dicts with the name or nil.
+func getMethod(obj interface{}, name string) interface{} {
+	switch val := obj.(type) {
+	case string:
+		return stringMethod(val, name)
+	case *List:
+		return listMethod(val, name)
+	case *Dict:
+		return dictMethod(val, name)
+	}
+	return nil
+}
+
+func argString(args []interface{}, idx int, def string) (string, error) {
+	if idx >= len(args) || args[idx] == nil {
+		return def, nil
+	}
+	s, ok := args[idx].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d must be str, not %s", idx+1, typeName(args[idx]))
+	}
+	return s, nil
+}
+
+func argInt(args []interface{}, idx int, def int) (int, error) {
+	if idx >= len(args) || args[idx] == nil {
+		return def, nil
+	}
+	return toInt(args[idx])
+}
+
+func stripChars(s string, args []interface{}, left, right bool) (string, error) {
+	if len(args) == 0 || args[0] == nil {
+		if left {
+			s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		}
+		if right {
+			s = strings.TrimRightFunc(s, unicode.IsSpace)
+		}
+		return s, nil
+	}
+
+	chars, err := argString(args, 0, "")
+	if err != nil {
+		return "", err
+	}
+	if left {
+		s = strings.TrimLeft(s, chars)
+	}
+	if right {
+		s = strings.TrimRight(s, chars)
+	}
+	return s, nil
+}
+
+// pySplit implements the split of python with the max number of splits.
+func pySplit(s string, args []interface{}, fromRight bool) (interface{}, error) {
+	sep, err := argString(args, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	maxsplit, err := argInt(args, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	if sep == "" {
+		if len(args) > 0 && args[0] != nil {
+			return nil, fmt.Errorf("empty separator")
+		}
+		parts = splitWhitespaces(s, maxsplit, fromRight)
+	} else if fromRight && maxsplit >= 0 {
+		parts = []string{}
+		rest := s
+		for i := 0; i < maxsplit; i++ {
+			idx := strings.LastIndex(rest, sep)
+			if idx < 0 {
+				break
+			}
+			parts = append([]string{rest[idx+len(sep):]}, parts...)
+			rest = rest[:idx]
+		}
+		parts = append([]string{rest}, parts...)
+	} else if maxsplit >= 0 {
+		parts = strings.SplitN(s, sep, maxsplit+1)
+	} else {
+		parts = strings.Split(s, sep)
+	}
+
+	ans := NewList()
+	for _, p := range parts {
+		ans.Items = append(ans.Items, p)
+	}
+	return ans, nil
+}
+
+// splitWhitespaces splits the string on runs of whitespaces
+// like the split of python without separator.
+func splitWhitespaces(s string, maxsplit int, fromRight bool) []string {
+	if maxsplit < 0 {
+		return strings.Fields(s)
+	}
+
+	parts := []string{}
+	if fromRight {
+		rest := strings.TrimRightFunc(s, unicode.IsSpace)
+		for i := 0; i < maxsplit && rest != ""; i++ {
+			idx := strings.LastIndexFunc(rest, unicode.IsSpace)
+			if idx < 0 {
+				break
+			}
+			parts = append([]string{rest[idx+1:]}, parts...)
+			rest = strings.TrimRightFunc(rest[:idx], unicode.IsSpace)
+		}
+		if rest != "" {
+			parts = append([]string{rest}, parts...)
+		}
+		return parts
+	}
+
+	rest := strings.TrimLeftFunc(s, unicode.IsSpace)
+	for i := 0; i < maxsplit && rest != ""; i++ {
+		idx := strings.IndexFunc(rest, unicode.IsSpace)
+		if idx < 0 {
+			break
+		}
+		parts = append(parts, rest[:idx])
+		rest = strings.TrimLeftFunc(rest[idx:], unicode.IsSpace)
+	}
+	if rest != "" {
+		parts = append(parts, rest)
+	}
+	return parts
+}
+
+func matchAffix(s string, arg interface{}, fn func(string, string) bool) (bool, error) {
+	if l, ok := arg.(*List); ok {
+		for _, item := range l.Items {
+			if fn(s, toString(item)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	a, ok := arg.(string)
+	if !ok {
+		return false, fmt.Errorf("expected a str or a tuple of str, not %s", typeName(arg))
+	}
+	return fn(s, a), nil
+}
+
+// pyFormat implements the str.format of python for the automatic
+// ({}), positional ({0}) and keyword ({name}) fields.
+func pyFormat(s string, args []interface{}, kwargs map[string]interface{}) (string, error) {
+	var sb strings.Builder
+	auto := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '{' && i+1 < len(s) && s[i+1] == '{' {
+			sb.WriteByte('{')
+			i++
+			continue
+		}
+		if c == '}' && i+1 < len(s) && s[i+1] == '}' {
+			sb.WriteByte('}')
+			i++
+			continue
+		}
+		if c != '{' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("single '{' encountered in format string")
+		}
+		field := s[i+1 : i+end]
+		i += end
+
+		spec := ""
+		if idx := strings.IndexByte(field, ':'); idx >= 0 {
+			field, spec = field[:idx], field[idx+1:]
+		}
+
+		var v interface{}
+		if field == "" {
+			if auto >= len(args) {
+				return "", fmt.Errorf("replacement index %d out of range", auto)
+			}
+			v = args[auto]
+			auto++
+		} else if n, err := toInt(field); err == nil {
+			if n >= len(args) {
+				return "", fmt.Errorf("replacement index %d out of range", n)
+			}
+			v = args[n]
+		} else {
+			var ok bool
+			v, ok = kwargs[field]
+			if !ok {
+				return "", fmt.Errorf("missing key '%s' in format string", field)
+			}
+		}
+
+		formatted, err := formatSpec(v, spec)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(formatted)
+	}
+
+	return sb.String(), nil
+}
+
+// The format specification after the fill and the align characters:
+// [sign][#][0][width][,][.precision][type]
+var formatSpecRegexp = regexp.MustCompile(`^([-+ ]?)(#?)(0?)(\d*)(,?)(?:\.(\d+))?([bcdeEfFgGosxX%]?)$`)
+
+// formatSpec formats the value with the format specification of the
+// str.format of python: [[fill]align][sign][#][0][width][,][.precision][type].
+func formatSpec(v interface{}, spec string) (string, error) {
+	if spec == "" {
+		return toString(v), nil
+	}
+
+	fill, align := " ", ""
+	runes := []rune(spec)
+	isAlign := func(c rune) bool { return strings.ContainsRune("<>^=", c) }
+	if len(runes) >= 2 && isAlign(runes[1]) {
+		fill, align = string(runes[0]), string(runes[1])
+		runes = runes[2:]
+	} else if len(runes) >= 1 && isAlign(runes[0]) {
+		align = string(runes[0])
+		runes = runes[1:]
+	}
+
+	m := formatSpecRegexp.FindStringSubmatch(string(runes))
+	if m == nil {
+		return "", fmt.Errorf("invalid format specifier '%s'", spec)
+	}
+	sign, alt, zero, verb := m[1], m[2] == "#", m[3] == "0", m[7]
+	width, _ := strconv.Atoi(m[4])
+	precision := -1
+	if m[6] != "" {
+		precision, _ = strconv.Atoi(m[6])
+	}
+
+	_, isString := v.(string)
+	if verb == "" {
+		switch v.(type) {
+		case int, bool:
+			verb = "d"
+		case float64:
+			if precision >= 0 {
+				verb = "g"
+			}
+		default:
+			verb = "s"
+		}
+	}
+
+	if zero && align == "" {
+		fill, align = "0", "="
+	}
+
+	body, prefix := "", ""
+	switch verb {
+	case "s":
+		if !isString && sign != "" {
+			return "", fmt.Errorf("sign not allowed in string format specifier")
+		}
+		body = toString(v)
+		if precision >= 0 && len([]rune(body)) > precision {
+			body = string([]rune(body)[:precision])
+		}
+		if align == "" {
+			align = "<"
+		}
+
+	case "":
+		// Float without presentation type.
+		f, _ := toFloat(v)
+		body = formatFloat(f)
+
+	case "d", "b", "o", "x", "X", "c":
+		if isString {
+			return "", fmt.Errorf("unknown format code '%s' for object of type 'str'", verb)
+		}
+		n, err := toInt(v)
+		if err != nil {
+			return "", err
+		}
+		if _, isFloat := v.(float64); isFloat {
+			return "", fmt.Errorf("unknown format code '%s' for object of type 'float'", verb)
+		}
+		if n < 0 {
+			prefix = "-"
+			n = -n
+		}
+		switch verb {
+		case "d":
+			body = strconv.Itoa(n)
+			if m[5] == "," {
+				body = groupThousands(body)
+			}
+		case "b":
+			body = strconv.FormatInt(int64(n), 2)
+		case "o":
+			body = strconv.FormatInt(int64(n), 8)
+		case "x":
+			body = strconv.FormatInt(int64(n), 16)
+		case "X":
+			body = strings.ToUpper(strconv.FormatInt(int64(n), 16))
+		case "c":
+			body = string(rune(n))
+		}
+		if alt && verb != "d" && verb != "c" {
+			prefix += "0" + verb
+		}
+
+	default:
+		if isString {
+			return "", fmt.Errorf("unknown format code '%s' for object of type 'str'", verb)
+		}
+		f, err := toFloat(v)
+		if err != nil {
+			return "", err
+		}
+		if precision < 0 {
+			precision = 6
+		}
+		if f < 0 {
+			prefix = "-"
+			f = -f
+		}
+		switch verb {
+		case "%":
+			body = strconv.FormatFloat(f*100, 'f', precision, 64) + "%"
+		case "F":
+			body = strings.ToUpper(strconv.FormatFloat(f, 'f', precision, 64))
+		case "g", "G":
+			if precision == 0 {
+				precision = 1
+			}
+			body = strconv.FormatFloat(f, verb[0], precision, 64)
+		default:
+			body = strconv.FormatFloat(f, verb[0], precision, 64)
+		}
+		if m[5] == "," {
+			intPart, decimals, _ := strings.Cut(body, ".")
+			body = groupThousands(intPart)
+			if decimals != "" {
+				body += "." + decimals
+			}
+		}
+	}
+
+	if prefix == "" && verb != "s" {
+		switch sign {
+		case "+":
+			prefix = "+"
+		case " ":
+			prefix = " "
+		}
+	}
+	if align == "" {
+		align = ">"
+	}
+
+	pad := width - len([]rune(prefix+body))
+	if pad <= 0 {
+		return prefix + body, nil
+	}
+	switch align {
+	case "<":
+		return prefix + body + strings.Repeat(fill, pad), nil
+	case "^":
+		return strings.Repeat(fill, pad/2) + prefix + body +
+			strings.Repeat(fill, pad-pad/2), nil
+	case "=":
+		return prefix + strings.Repeat(fill, pad) + body, nil
+	default:
+		return strings.Repeat(fill, pad) + prefix + body, nil
+	}
+}
+
+// groupThousands adds the comma separator to the digits.
+func groupThousands(digits string) string {
+	var sb strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func stringMethod(s, name string) interface{} {
+	var fn func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+	switch name {
+	case "split":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return pySplit(s, args, false)
+		}
+	case "rsplit":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return pySplit(s, args, true)
+		}
+	case "splitlines":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			ans := NewList()
+			if s == "" {
+				return ans, nil
+			}
+			lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+			if lines[len(lines)-1] == "" {
+				lines = lines[:len(lines)-1]
+			}
+			for _, l := range lines {
+				ans.Items = append(ans.Items, l)
+			}
+			return ans, nil
+		}
+	case "strip":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return stripChars(s, args, true, true)
+		}
+	case "lstrip":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return stripChars(s, args, true, false)
+		}
+	case "rstrip":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return stripChars(s, args, false, true)
+		}
+	case "startswith":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("startswith() takes exactly one argument")
+			}
+			return matchAffix(s, args[0], strings.HasPrefix)
+		}
+	case "endswith":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("endswith() takes exactly one argument")
+			}
+			return matchAffix(s, args[0], strings.HasSuffix)
+		}
+	case "replace":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			old, err := argString(args, 0, "")
+			if err != nil {
+				return nil, err
+			}
+			new, err := argString(args, 1, "")
+			if err != nil {
+				return nil, err
+			}
+			count, err := argInt(args, 2, -1)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Replace(s, old, new, count), nil
+		}
+	case "upper":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return strings.ToUpper(s), nil
+		}
+	case "lower":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return strings.ToLower(s), nil
+		}
+	case "title":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return titleString(s), nil
+		}
+	case "capitalize":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return capitalizeString(s), nil
+		}
+	case "join":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("join() takes exactly one argument")
+			}
+			items, err := iterate(args[0])
+			if err != nil {
+				return nil, err
+			}
+			parts := []string{}
+			for _, item := range items {
+				parts = append(parts, toString(item))
+			}
+			return strings.Join(parts, s), nil
+		}
+	case "find", "index":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			sub, err := argString(args, 0, "")
+			if err != nil {
+				return nil, err
+			}
+			idx := strings.Index(s, sub)
+			if idx >= 0 {
+				idx = len([]rune(s[:idx]))
+			} else if name == "index" {
+				return nil, fmt.Errorf("substring not found")
+			}
+			return idx, nil
+		}
+	case "count":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			sub, err := argString(args, 0, "")
+			if err != nil {
+				return nil, err
+			}
+			return strings.Count(s, sub), nil
+		}
+	case "format":
+		fn = func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			return pyFormat(s, args, kwargs)
+		}
+	case "zfill":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			width, err := argInt(args, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			for len(s) < width {
+				s = "0" + s
+			}
+			return s, nil
+		}
+	case "isdigit", "isalpha", "isalnum", "isspace", "islower", "isupper":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return stringIs(s, name), nil
+		}
+	default:
+		return nil
+	}
+
+	return builtinFunc(fn)
+}
+
+func stringIs(s, name string) bool {
+	if s == "" {
+		return false
+	}
+	switch name {
+	case "islower":
+		return strings.ToLower(s) == s && strings.ToUpper(s) != s
+	case "isupper":
+		return strings.ToUpper(s) == s && strings.ToLower(s) != s
+	}
+	for _, c := range s {
+		var ok bool
+		switch name {
+		case "isdigit":
+			ok = unicode.IsDigit(c)
+		case "isalpha":
+			ok = unicode.IsLetter(c)
+		case "isalnum":
+			ok = unicode.IsLetter(c) || unicode.IsDigit(c)
+		case "isspace":
+			ok = unicode.IsSpace(c)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func titleString(s string) string {
+	var sb strings.Builder
+	prevLetter := false
+	for _, c := range s {
+		if unicode.IsLetter(c) {
+			if prevLetter {
+				sb.WriteRune(unicode.ToLower(c))
+			} else {
+				sb.WriteRune(unicode.ToUpper(c))
+			}
+			prevLetter = true
+		} else {
+			sb.WriteRune(c)
+			prevLetter = false
+		}
+	}
+	return sb.String()
+}
+
+func capitalizeString(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(strings.ToLower(s))
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func listMethod(l *List, name string) interface{} {
+	var fn func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+	switch name {
+	case "append":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("append() takes exactly one argument")
+			}
+			l.Items = append(l.Items, args[0])
+			return nil, nil
+		}
+	case "extend":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("extend() takes exactly one argument")
+			}
+			items, err := iterate(args[0])
+			if err != nil {
+				return nil, err
+			}
+			l.Items = append(l.Items, items...)
+			return nil, nil
+		}
+	case "insert":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("insert() takes exactly two arguments")
+			}
+			idx, err := toInt(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if idx < 0 {
+				idx += len(l.Items)
+			}
+			idx = max(0, min(idx, len(l.Items)))
+			l.Items = append(l.Items[:idx], append([]interface{}{args[1]}, l.Items[idx:]...)...)
+			return nil, nil
+		}
+	case "pop":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(l.Items) == 0 {
+				return nil, fmt.Errorf("pop from empty list")
+			}
+			idx, err := argInt(args, 0, -1)
+			if err != nil {
+				return nil, err
+			}
+			if idx < 0 {
+				idx += len(l.Items)
+			}
+			if idx < 0 || idx >= len(l.Items) {
+				return nil, fmt.Errorf("pop index out of range")
+			}
+			v := l.Items[idx]
+			l.Items = append(l.Items[:idx], l.Items[idx+1:]...)
+			return v, nil
+		}
+	case "remove":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("remove() takes exactly one argument")
+			}
+			for idx, item := range l.Items {
+				if equals(item, args[0]) {
+					l.Items = append(l.Items[:idx], l.Items[idx+1:]...)
+					return nil, nil
+				}
+			}
+			return nil, fmt.Errorf("list.remove(x): x not in list")
+		}
+	case "index":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("index() takes exactly one argument")
+			}
+			for idx, item := range l.Items {
+				if equals(item, args[0]) {
+					return idx, nil
+				}
+			}
+			return nil, fmt.Errorf("%s is not in list", repr(args[0]))
+		}
+	case "count":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("count() takes exactly one argument")
+			}
+			n := 0
+			for _, item := range l.Items {
+				if equals(item, args[0]) {
+					n++
+				}
+			}
+			return n, nil
+		}
+	case "reverse":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			for i, j := 0, len(l.Items)-1; i < j; i, j = i+1, j-1 {
+				l.Items[i], l.Items[j] = l.Items[j], l.Items[i]
+			}
+			return nil, nil
+		}
+	default:
+		return nil
+	}
+
+	return builtinFunc(fn)
+}
+
+func dictMethod(d *Dict, name string) interface{} {
+	var fn func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+	switch name {
+	case "items":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			ans := NewList()
+			for _, k := range d.Keys {
+				ans.Items = append(ans.Items, NewTuple(k, d.Values[k]))
+			}
+			return ans, nil
+		}
+	case "keys":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			ans := NewList()
+			for _, k := range d.Keys {
+				ans.Items = append(ans.Items, k)
+			}
+			return ans, nil
+		}
+	case "values":
+		fn = func(_ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			ans := NewList()
+			for _, k := range d.Keys {
+				ans.Items = append(ans.Items, d.Values[k])
+			}
+			return ans, nil
+		}
+	case "get":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) < 1 || len(args) > 2 {
+				return nil, fmt.Errorf("get() takes one or two arguments")
+			}
+			if v, ok := d.Get(toString(args[0])); ok {
+				return v, nil
+			}
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return nil, nil
+		}
+	case "update":
+		fn = func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+			for _, a := range args {
+				other, ok := a.(*Dict)
+				if !ok {
+					return nil, fmt.Errorf("update() argument must be a dict")
+				}
+				for _, k := range other.Keys {
+					d.Set(k, other.Values[k])
+				}
+			}
+			for k, v := range kwargs {
+				d.Set(k, v)
+			}
+			return nil, nil
+		}
+	case "pop":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) < 1 || len(args) > 2 {
+				return nil, fmt.Errorf("pop() takes one or two arguments")
+			}
+			k := toString(args[0])
+			if v, ok := d.Get(k); ok {
+				d.Delete(k)
+				return v, nil
+			}
+			if len(args) == 2 {
+				return args[1], nil
+			}
+			return nil, fmt.Errorf("key '%s' not found", k)
+		}
+	case "setdefault":
+		fn = func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) < 1 || len(args) > 2 {
+				return nil, fmt.Errorf("setdefault() takes one or two arguments")
+			}
+			k := toString(args[0])
+			if v, ok := d.Get(k); ok {
+				return v, nil
+			}
+			var v interface{}
+			if len(args) == 2 {
+				v = args[1]
+			}
+			d.Set(k, v)
+			return v, nil
+		}
+	default:
+		return nil
+	}
+
+	return builtinFunc(fn)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+// Statements
+
+type node interface {
+	line() int
+}
+
+type pos struct {
+	Line int
+}
+
+func (p pos) line() int { return p.Line }
+
+type textNode struct {
+	pos
+	Text string
+}
+
+type outputNode struct {
+	pos
+	Expr expr
+}
+
+type ifNode struct {
+	pos
+	Conds  []expr
+	Bodies [][]node
+	Else   []node
+}
+
+type forNode struct {
+	pos
+	Targets []string
+	Iter    expr
+	Filter  expr
+	Body    []node
+	Else    []node
+}
+
+type setNode struct {
+	pos
+	Targets []expr
+	Value   expr
+}
+
+type setBlockNode struct {
+	pos
+	Name string
+	Body []node
+}
+
+type macroNode struct {
+	pos
+	Name     string
+	Params   []string
+	Defaults map[string]expr
+	Body     []node
+}
+
+type includeNode struct {
+	pos
+	Template      expr
+	IgnoreMissing bool
+	WithContext   bool
+}
+
+type importNode struct {
+	pos
+	Template expr
+	Alias    string
+}
+
+type fromImportNode struct {
+	pos
+	Template expr
+	// Pairs of name and alias
+	Names [][2]string
+}
+
+type extendsNode struct {
+	pos
+	Template expr
+}
+
+type blockNode struct {
+	pos
+	Name string
+	Body []node
+}
+
+type withNode struct {
+	pos
+	Names  []string
+	Values []expr
+	Body   []node
+}
+
+type filterBlockNode struct {
+	pos
+	// The filters applied to the body bound to filterBlockVar.
+	Filter expr
+	Body   []node
+}
+
+type doNode struct {
+	pos
+	Expr expr
+}
+
+type breakNode struct {
+	pos
+}
+
+type continueNode struct {
+	pos
+}
+
+// Expressions
+
+type expr interface {
+	line() int
+}
+
+type literalExpr struct {
+	pos
+	Value interface{}
+}
+
+type nameExpr struct {
+	pos
+	Name string
+}
+
+type getattrExpr struct {
+	pos
+	Obj  expr
+	Attr string
+}
+
+type getitemExpr struct {
+	pos
+	Obj expr
+	Key expr
+}
+
+type sliceExpr struct {
+	pos
+	Obj   expr
+	Start expr
+	Stop  expr
+	Step  expr
+}
+
+type callExpr struct {
+	pos
+	Fn     expr
+	Args   []expr
+	Kwargs []*kwarg
+}
+
+type kwarg struct {
+	Name  string
+	Value expr
+}
+
+type filterExpr struct {
+	pos
+	Expr   expr
+	Name   string
+	Args   []expr
+	Kwargs []*kwarg
+}
+
+type testExpr struct {
+	pos
+	Expr   expr
+	Name   string
+	Args   []expr
+	Negate bool
+}
+
+type binaryExpr struct {
+	pos
+	Op    string
+	Left  expr
+	Right expr
+}
+
+type unaryExpr struct {
+	pos
+	Op   string
+	Expr expr
+}
+
+type compareExpr struct {
+	pos
+	Left  expr
+	Ops   []string
+	Exprs []expr
+}
+
+type condExpr struct {
+	pos
+	Cond expr
+	Then expr
+	Else expr
+}
+
+type listExpr struct {
+	pos
+	Items []expr
+	Tuple bool
+}
+
+type dictExpr struct {
+	pos
+	Keys   []expr
+	Values []expr
+}
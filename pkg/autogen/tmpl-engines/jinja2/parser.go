@@ -0,0 +1,1112 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type parser struct {
+	file   string
+	tokens []*token
+	pos    int
+}
+
+func parse(file, src string) ([]node, error) {
+	tokens, err := tokenize(file, src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{file: file, tokens: tokens}
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, p.errorf(p.current(), "unexpected tag '%s'", end)
+	}
+
+	return body, nil
+}
+
+func (p *parser) errorf(t *token, format string, args ...interface{}) error {
+	return &Error{File: p.file, Line: t.Line, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) current() *token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peek(n int) *token {
+	if p.pos+n < len(p.tokens) {
+		return p.tokens[p.pos+n]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() *token {
+	t := p.tokens[p.pos]
+	if p.pos < len(p.tokens)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(op string) bool {
+	t := p.current()
+	return t.Kind == tokOp && t.Value == op
+}
+
+func (p *parser) isName(name string) bool {
+	t := p.current()
+	return t.Kind == tokName && t.Value == name
+}
+
+func (p *parser) expectOp(op string) (*token, error) {
+	if !p.isOp(op) {
+		return nil, p.errorf(p.current(), "expected '%s' but found %s", op, p.current())
+	}
+	return p.next(), nil
+}
+
+func (p *parser) expectName() (*token, error) {
+	if p.current().Kind != tokName {
+		return nil, p.errorf(p.current(), "expected a name but found %s", p.current())
+	}
+	return p.next(), nil
+}
+
+func (p *parser) expectKind(kind tokenKind) (*token, error) {
+	if p.current().Kind != kind {
+		return nil, p.errorf(p.current(), "unexpected %s", p.current())
+	}
+	return p.next(), nil
+}
+
+func (p *parser) expectBlockEnd() error {
+	_, err := p.expectKind(tokBlockEnd)
+	return err
+}
+
+// parseBody parses the nodes until a block tag not managed
+// (for example endif, else) is found. The name of the tag is returned
+// and the parser is positioned after the name of the tag.
+func (p *parser) parseBody() ([]node, string, error) {
+	ans := []node{}
+
+	for {
+		t := p.current()
+		switch t.Kind {
+		case tokEOF:
+			return ans, "", nil
+
+		case tokData:
+			p.next()
+			ans = append(ans, &textNode{pos: pos{t.Line}, Text: t.Value})
+
+		case tokVarBegin:
+			p.next()
+			e, err := p.parseExpression()
+			if err != nil {
+				return nil, "", err
+			}
+			if _, err := p.expectKind(tokVarEnd); err != nil {
+				return nil, "", err
+			}
+			ans = append(ans, &outputNode{pos: pos{t.Line}, Expr: e})
+
+		case tokBlockBegin:
+			p.next()
+			nameTok, err := p.expectName()
+			if err != nil {
+				return nil, "", err
+			}
+
+			n, err := p.parseStatement(nameTok)
+			if err != nil {
+				return nil, "", err
+			}
+			if n == nil {
+				// End tag of the parent statement
+				return ans, nameTok.Value, nil
+			}
+			ans = append(ans, n)
+
+		default:
+			return nil, "", p.errorf(t, "unexpected %s", t)
+		}
+	}
+}
+
+func (p *parser) parseStatement(t *token) (node, error) {
+	switch t.Value {
+	case "if":
+		return p.parseIf(t)
+	case "for":
+		return p.parseFor(t)
+	case "set":
+		return p.parseSet(t)
+	case "macro":
+		return p.parseMacro(t)
+	case "include":
+		return p.parseInclude(t)
+	case "import":
+		return p.parseImport(t)
+	case "from":
+		return p.parseFromImport(t)
+	case "extends":
+		tpl, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &extendsNode{pos: pos{t.Line}, Template: tpl}, p.expectBlockEnd()
+	case "block":
+		return p.parseBlock(t)
+	case "with":
+		return p.parseWith(t)
+	case "filter":
+		return p.parseFilterBlock(t)
+	case "do":
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &doNode{pos: pos{t.Line}, Expr: e}, p.expectBlockEnd()
+	case "break":
+		return &breakNode{pos: pos{t.Line}}, p.expectBlockEnd()
+	case "continue":
+		return &continueNode{pos: pos{t.Line}}, p.expectBlockEnd()
+	case "elif", "else", "endif", "endfor", "endset", "endmacro",
+		"endblock", "endwith", "endfilter":
+		// Managed by the parent statement
+		return nil, nil
+	default:
+		return nil, p.errorf(t, "unknown tag '%s'", t.Value)
+	}
+}
+
+// parseEndBody parses the body of a statement that must be
+// closed by one of the end tags.
+func (p *parser) parseEndBody(start *token, ends ...string) ([]node, string, error) {
+	body, end, err := p.parseBody()
+	if err != nil {
+		return nil, "", err
+	}
+	for _, e := range ends {
+		if e == end {
+			return body, end, nil
+		}
+	}
+	if end == "" {
+		return nil, "", p.errorf(start, "missing end of '%s' tag, expected %s",
+			start.Value, ends[len(ends)-1])
+	}
+	return nil, "", p.errorf(p.peek(-1), "unexpected tag '%s', expected %s",
+		end, ends[len(ends)-1])
+}
+
+func (p *parser) parseIf(t *token) (node, error) {
+	ans := &ifNode{pos: pos{t.Line}}
+
+	for {
+		cond, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectBlockEnd(); err != nil {
+			return nil, err
+		}
+
+		body, end, err := p.parseEndBody(t, "elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		ans.Conds = append(ans.Conds, cond)
+		ans.Bodies = append(ans.Bodies, body)
+
+		switch end {
+		case "elif":
+			continue
+		case "else":
+			if err := p.expectBlockEnd(); err != nil {
+				return nil, err
+			}
+			ans.Else, _, err = p.parseEndBody(t, "endif")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return ans, p.expectBlockEnd()
+	}
+}
+
+func (p *parser) parseFor(t *token) (node, error) {
+	ans := &forNode{pos: pos{t.Line}}
+
+	for {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		ans.Targets = append(ans.Targets, name.Value)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+
+	if !p.isName("in") {
+		return nil, p.errorf(p.current(), "expected 'in' but found %s", p.current())
+	}
+	p.next()
+
+	iter, err := p.parseCondExpr(false)
+	if err != nil {
+		return nil, err
+	}
+	ans.Iter = iter
+
+	if p.isName("if") {
+		p.next()
+		ans.Filter, err = p.parseCondExpr(true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expectBlockEnd(); err != nil {
+		return nil, err
+	}
+
+	body, end, err := p.parseEndBody(t, "else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	ans.Body = body
+
+	if end == "else" {
+		if err := p.expectBlockEnd(); err != nil {
+			return nil, err
+		}
+		ans.Else, _, err = p.parseEndBody(t, "endfor")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ans, p.expectBlockEnd()
+}
+
+func (p *parser) parseSet(t *token) (node, error) {
+	targets := []expr{}
+	for {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		var target expr = &nameExpr{pos: pos{name.Line}, Name: name.Value}
+		if p.isOp(".") {
+			// Namespace attribute
+			p.next()
+			attr, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			target = &getattrExpr{pos: pos{name.Line}, Obj: target, Attr: attr.Value}
+		}
+		targets = append(targets, target)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+
+	if p.current().Kind == tokBlockEnd {
+		// Block set
+		name, ok := targets[0].(*nameExpr)
+		if len(targets) != 1 || !ok {
+			return nil, p.errorf(t, "invalid target of block set")
+		}
+		p.next()
+		body, _, err := p.parseEndBody(t, "endset")
+		if err != nil {
+			return nil, err
+		}
+		return &setBlockNode{pos: pos{t.Line}, Name: name.Name, Body: body}, p.expectBlockEnd()
+	}
+
+	if _, err := p.expectOp("="); err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseTuple()
+	if err != nil {
+		return nil, err
+	}
+
+	return &setNode{pos: pos{t.Line}, Targets: targets, Value: value}, p.expectBlockEnd()
+}
+
+func (p *parser) parseMacro(t *token) (node, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	ans := &macroNode{
+		pos:      pos{t.Line},
+		Name:     name.Value,
+		Params:   []string{},
+		Defaults: make(map[string]expr, 0),
+	}
+
+	if _, err := p.expectOp("("); err != nil {
+		return nil, err
+	}
+	for !p.isOp(")") {
+		param, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		ans.Params = append(ans.Params, param.Value)
+		if p.isOp("=") {
+			p.next()
+			ans.Defaults[param.Value], err = p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expectOp(")"); err != nil {
+		return nil, err
+	}
+	if err := p.expectBlockEnd(); err != nil {
+		return nil, err
+	}
+
+	ans.Body, _, err = p.parseEndBody(t, "endmacro")
+	if err != nil {
+		return nil, err
+	}
+
+	// Optional name of the macro on the end tag
+	if p.current().Kind == tokName {
+		p.next()
+	}
+
+	return ans, p.expectBlockEnd()
+}
+
+func (p *parser) parseContextModifier() (bool, error) {
+	if (p.isName("with") || p.isName("without")) && p.peek(1).Kind == tokName &&
+		p.peek(1).Value == "context" {
+		with := p.next().Value == "with"
+		p.next()
+		return with, nil
+	}
+	return true, nil
+}
+
+func (p *parser) parseInclude(t *token) (node, error) {
+	tpl, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	ans := &includeNode{pos: pos{t.Line}, Template: tpl, WithContext: true}
+
+	if p.isName("ignore") && p.peek(1).Kind == tokName && p.peek(1).Value == "missing" {
+		p.next()
+		p.next()
+		ans.IgnoreMissing = true
+	}
+
+	ans.WithContext, err = p.parseContextModifier()
+	if err != nil {
+		return nil, err
+	}
+
+	return ans, p.expectBlockEnd()
+}
+
+func (p *parser) parseImport(t *token) (node, error) {
+	tpl, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isName("as") {
+		return nil, p.errorf(p.current(), "expected 'as' but found %s", p.current())
+	}
+	p.next()
+	alias, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.parseContextModifier(); err != nil {
+		return nil, err
+	}
+
+	return &importNode{pos: pos{t.Line}, Template: tpl, Alias: alias.Value},
+		p.expectBlockEnd()
+}
+
+func (p *parser) parseFromImport(t *token) (node, error) {
+	tpl, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isName("import") {
+		return nil, p.errorf(p.current(), "expected 'import' but found %s", p.current())
+	}
+	p.next()
+
+	ans := &fromImportNode{pos: pos{t.Line}, Template: tpl}
+	for {
+		if p.isName("with") || p.isName("without") {
+			break
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		alias := name.Value
+		if p.isName("as") {
+			p.next()
+			a, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			alias = a.Value
+		}
+		ans.Names = append(ans.Names, [2]string{name.Value, alias})
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.parseContextModifier(); err != nil {
+		return nil, err
+	}
+
+	return ans, p.expectBlockEnd()
+}
+
+func (p *parser) parseBlock(t *token) (node, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectBlockEnd(); err != nil {
+		return nil, err
+	}
+
+	body, _, err := p.parseEndBody(t, "endblock")
+	if err != nil {
+		return nil, err
+	}
+	if p.current().Kind == tokName {
+		p.next()
+	}
+
+	return &blockNode{pos: pos{t.Line}, Name: name.Value, Body: body}, p.expectBlockEnd()
+}
+
+func (p *parser) parseWith(t *token) (node, error) {
+	ans := &withNode{pos: pos{t.Line}}
+	for p.current().Kind != tokBlockEnd {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expectOp("="); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		ans.Names = append(ans.Names, name.Value)
+		ans.Values = append(ans.Values, value)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectBlockEnd(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	ans.Body, _, err = p.parseEndBody(t, "endwith")
+	if err != nil {
+		return nil, err
+	}
+
+	return ans, p.expectBlockEnd()
+}
+
+// parseFilterBlock parses the filter block: the filters are applied
+// to the name filterBlockVar that contains the rendered body.
+func (p *parser) parseFilterBlock(t *token) (node, error) {
+	var e expr = &nameExpr{pos: pos{t.Line}, Name: filterBlockVar}
+	for {
+		ft := p.current()
+		name, err := p.parseDottedName()
+		if err != nil {
+			return nil, err
+		}
+		f := &filterExpr{pos: pos{ft.Line}, Expr: e, Name: name}
+		if p.isOp("(") {
+			f.Args, f.Kwargs, err = p.parseCallArgs()
+			if err != nil {
+				return nil, err
+			}
+		}
+		e = f
+		if !p.isOp("|") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expectBlockEnd(); err != nil {
+		return nil, err
+	}
+
+	body, _, err := p.parseEndBody(t, "endfilter")
+	if err != nil {
+		return nil, err
+	}
+
+	return &filterBlockNode{pos: pos{t.Line}, Filter: e, Body: body}, p.expectBlockEnd()
+}
+
+// Expressions
+
+// parseTuple parses an expression or a tuple without parenthesis.
+func (p *parser) parseTuple() (expr, error) {
+	t := p.current()
+	first, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp(",") {
+		return first, nil
+	}
+
+	items := []expr{first}
+	for p.isOp(",") {
+		p.next()
+		if p.current().Kind == tokBlockEnd || p.current().Kind == tokVarEnd {
+			break
+		}
+		item, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return &listExpr{pos: pos{t.Line}, Items: items, Tuple: true}, nil
+}
+
+func (p *parser) parseExpression() (expr, error) {
+	return p.parseCondExpr(true)
+}
+
+func (p *parser) parseCondExpr(withCondExpr bool) (expr, error) {
+	t := p.current()
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for withCondExpr && p.isName("if") {
+		p.next()
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		var elseExpr expr
+		if p.isName("else") {
+			p.next()
+			elseExpr, err = p.parseCondExpr(true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		e = &condExpr{pos: pos{t.Line}, Cond: cond, Then: e, Else: elseExpr}
+	}
+
+	return e, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isName("or") {
+		t := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: pos{t.Line}, Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isName("and") {
+		t := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: pos{t.Line}, Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.isName("not") {
+		t := p.next()
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{pos: pos{t.Line}, Op: "not", Expr: e}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (expr, error) {
+	t := p.current()
+	left, err := p.parseMath1()
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &compareExpr{pos: pos{t.Line}, Left: left}
+	for {
+		op := ""
+		cur := p.current()
+		if cur.Kind == tokOp {
+			switch cur.Value {
+			case "==", "!=", "<", ">", "<=", ">=":
+				op = cur.Value
+				p.next()
+			}
+		} else if cur.Kind == tokName {
+			if cur.Value == "in" {
+				op = "in"
+				p.next()
+			} else if cur.Value == "not" && p.peek(1).Kind == tokName && p.peek(1).Value == "in" {
+				op = "notin"
+				p.next()
+				p.next()
+			}
+		}
+		if op == "" {
+			break
+		}
+
+		right, err := p.parseMath1()
+		if err != nil {
+			return nil, err
+		}
+		ans.Ops = append(ans.Ops, op)
+		ans.Exprs = append(ans.Exprs, right)
+	}
+
+	if len(ans.Ops) == 0 {
+		return left, nil
+	}
+	return ans, nil
+}
+
+func (p *parser) parseMath1() (expr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		t := p.next()
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: pos{t.Line}, Op: t.Value, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseConcat() (expr, error) {
+	left, err := p.parseMath2()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("~") {
+		t := p.next()
+		right, err := p.parseMath2()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: pos{t.Line}, Op: "~", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMath2() (expr, error) {
+	left, err := p.parsePow()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("//") || p.isOp("%") {
+		t := p.next()
+		right, err := p.parsePow()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: pos{t.Line}, Op: t.Value, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parsePow() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("**") {
+		t := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{pos: pos{t.Line}, Op: "**", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isOp("-") || p.isOp("+") {
+		t := p.next()
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{pos: pos{t.Line}, Op: t.Value, Expr: e}, nil
+	}
+
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	e, err = p.parsePostfix(e)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseFilterExpr(e)
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.current()
+	switch t.Kind {
+	case tokName:
+		p.next()
+		switch t.Value {
+		case "true", "True":
+			return &literalExpr{pos: pos{t.Line}, Value: true}, nil
+		case "false", "False":
+			return &literalExpr{pos: pos{t.Line}, Value: false}, nil
+		case "none", "None":
+			return &literalExpr{pos: pos{t.Line}, Value: nil}, nil
+		}
+		return &nameExpr{pos: pos{t.Line}, Name: t.Value}, nil
+
+	case tokString:
+		p.next()
+		value := t.Value
+		// Adjacent strings are concatenated
+		for p.current().Kind == tokString {
+			value += p.next().Value
+		}
+		return &literalExpr{pos: pos{t.Line}, Value: value}, nil
+
+	case tokInt:
+		p.next()
+		v, err := strconv.Atoi(t.Value)
+		if err != nil {
+			return nil, p.errorf(t, "invalid integer %s", t.Value)
+		}
+		return &literalExpr{pos: pos{t.Line}, Value: v}, nil
+
+	case tokFloat:
+		p.next()
+		v, err := strconv.ParseFloat(t.Value, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid float %s", t.Value)
+		}
+		return &literalExpr{pos: pos{t.Line}, Value: v}, nil
+
+	case tokOp:
+		switch t.Value {
+		case "(":
+			p.next()
+			if p.isOp(")") {
+				p.next()
+				return &listExpr{pos: pos{t.Line}, Items: []expr{}, Tuple: true}, nil
+			}
+			e, err := p.parseTuple()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+
+		case "[":
+			p.next()
+			ans := &listExpr{pos: pos{t.Line}, Items: []expr{}}
+			for !p.isOp("]") {
+				item, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				ans.Items = append(ans.Items, item)
+				if !p.isOp(",") {
+					break
+				}
+				p.next()
+			}
+			if _, err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			return ans, nil
+
+		case "{":
+			p.next()
+			ans := &dictExpr{pos: pos{t.Line}}
+			for !p.isOp("}") {
+				k, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				if _, err := p.expectOp(":"); err != nil {
+					return nil, err
+				}
+				v, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				ans.Keys = append(ans.Keys, k)
+				ans.Values = append(ans.Values, v)
+				if !p.isOp(",") {
+					break
+				}
+				p.next()
+			}
+			if _, err := p.expectOp("}"); err != nil {
+				return nil, err
+			}
+			return ans, nil
+		}
+	}
+
+	return nil, p.errorf(t, "unexpected %s", t)
+}
+
+func (p *parser) parsePostfix(e expr) (expr, error) {
+	for {
+		t := p.current()
+		switch {
+		case p.isOp("."):
+			p.next()
+			attr := p.current()
+			if attr.Kind != tokName && attr.Kind != tokInt {
+				return nil, p.errorf(attr, "expected an attribute name but found %s", attr)
+			}
+			p.next()
+			e = &getattrExpr{pos: pos{t.Line}, Obj: e, Attr: attr.Value}
+
+		case p.isOp("["):
+			p.next()
+			var err error
+			e, err = p.parseSubscript(e, t)
+			if err != nil {
+				return nil, err
+			}
+
+		case p.isOp("("):
+			args, kwargs, err := p.parseCallArgs()
+			if err != nil {
+				return nil, err
+			}
+			e = &callExpr{pos: pos{t.Line}, Fn: e, Args: args, Kwargs: kwargs}
+
+		default:
+			return e, nil
+		}
+	}
+}
+
+func (p *parser) parseSubscript(obj expr, t *token) (expr, error) {
+	var parts [3]expr
+	idx := 0
+	isSlice := false
+
+	for !p.isOp("]") {
+		if p.isOp(":") {
+			p.next()
+			isSlice = true
+			idx++
+			if idx > 2 {
+				return nil, p.errorf(t, "invalid slice")
+			}
+			continue
+		}
+		e, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		parts[idx] = e
+	}
+	p.next()
+
+	if !isSlice {
+		if parts[0] == nil {
+			return nil, p.errorf(t, "missing subscript")
+		}
+		return &getitemExpr{pos: pos{t.Line}, Obj: obj, Key: parts[0]}, nil
+	}
+
+	return &sliceExpr{pos: pos{t.Line}, Obj: obj, Start: parts[0], Stop: parts[1],
+		Step: parts[2]}, nil
+}
+
+func (p *parser) parseCallArgs() ([]expr, []*kwarg, error) {
+	args := []expr{}
+	kwargs := []*kwarg{}
+
+	if _, err := p.expectOp("("); err != nil {
+		return nil, nil, err
+	}
+	for !p.isOp(")") {
+		if p.current().Kind == tokName && p.peek(1).Kind == tokOp && p.peek(1).Value == "=" {
+			name := p.next().Value
+			p.next()
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs = append(kwargs, &kwarg{Name: name, Value: value})
+		} else {
+			if len(kwargs) > 0 {
+				return nil, nil, p.errorf(p.current(),
+					"positional argument after keyword argument")
+			}
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, value)
+		}
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expectOp(")"); err != nil {
+		return nil, nil, err
+	}
+
+	return args, kwargs, nil
+}
+
+func (p *parser) parseFilterExpr(e expr) (expr, error) {
+	for {
+		t := p.current()
+		if p.isOp("|") {
+			p.next()
+			name, err := p.parseDottedName()
+			if err != nil {
+				return nil, err
+			}
+			f := &filterExpr{pos: pos{t.Line}, Expr: e, Name: name}
+			if p.isOp("(") {
+				f.Args, f.Kwargs, err = p.parseCallArgs()
+				if err != nil {
+					return nil, err
+				}
+			}
+			e = f
+		} else if p.isName("is") {
+			p.next()
+			negate := false
+			if p.isName("not") {
+				p.next()
+				negate = true
+			}
+			name, err := p.parseDottedName()
+			if err != nil {
+				return nil, err
+			}
+			te := &testExpr{pos: pos{t.Line}, Expr: e, Name: name, Negate: negate}
+
+			cur := p.current()
+			if p.isOp("(") {
+				te.Args, _, err = p.parseCallArgs()
+				if err != nil {
+					return nil, err
+				}
+			} else if cur.Kind == tokString || cur.Kind == tokInt || cur.Kind == tokFloat ||
+				p.isOp("[") || p.isOp("{") ||
+				(cur.Kind == tokName && cur.Value != "else" && cur.Value != "or" &&
+					cur.Value != "and" && cur.Value != "if") {
+				// Test with a single argument without parenthesis.
+				arg, err := p.parsePrimary()
+				if err != nil {
+					return nil, err
+				}
+				arg, err = p.parsePostfix(arg)
+				if err != nil {
+					return nil, err
+				}
+				te.Args = []expr{arg}
+			}
+			e = te
+		} else {
+			return e, nil
+		}
+	}
+}
+
+func (p *parser) parseDottedName() (string, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return "", err
+	}
+	ans := name.Value
+	for p.isOp(".") {
+		p.next()
+		n, err := p.expectName()
+		if err != nil {
+			return "", err
+		}
+		ans += "." + n.Value
+	}
+	return ans, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/autogen/tmpl-engines/jinja2"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Jinja2 rendering", func() {
+
+	// Every directory of testdata/golden contains the main.j2 template,
+	// the templates used by the main template and the expected output.
+	Context("Golden files", func() {
+		dirs, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+		if err != nil {
+			panic(err)
+		}
+
+		for _, dir := range dirs {
+			dir := dir
+			It(filepath.Base(dir), func() {
+				env := NewEnvironment([]string{dir})
+				out, err := env.RenderFile(filepath.Join(dir, "main.j2"),
+					map[string]interface{}{
+						"name":    "foo",
+						"version": "1.2.3",
+						"items":   []interface{}{"a", "b", "c"},
+					})
+				Expect(err).Should(BeNil())
+
+				expected, err := os.ReadFile(filepath.Join(dir, "expected.txt"))
+				Expect(err).Should(BeNil())
+				Expect(out).To(Equal(string(expected)))
+			})
+		}
+	})
+
+	DescribeTable("Errors with position",
+		func(src, expected string) {
+			_, err := render(src, map[string]interface{}{"s": "a"})
+			Expect(err).ShouldNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring(expected))
+		},
+		Entry("undefined variable", "a\nb\n{{ missing }}", "test:3:"),
+		Entry("undefined attribute", "a\n{{ s.missing.x }}", "test:2:"),
+		Entry("unknown tag", "{% if true %}\n{% foo %}{% endif %}", "test:2: unknown tag 'foo'"),
+		Entry("unclosed block", "a\n{% if true %}\nb", "test:2:"),
+		Entry("unclosed variable", "a\n\n{{ s", "test:3:"),
+		Entry("missing include", "\n{% include \"missing.j2\" %}", "test:2:"),
+		Entry("break outside loop", "{% break %}", "break"),
+	)
+
+	It("Undefined variables without strict mode", func() {
+		env := NewEnvironment([]string{})
+		env.StrictUndefined = false
+		out, err := env.RenderString("test", "[{{ missing }}]", map[string]interface{}{})
+		Expect(err).Should(BeNil())
+		Expect(out).To(Equal("[]"))
+	})
+
+	It("Go functions as globals", func() {
+		env := NewEnvironment([]string{})
+		env.AddGlobals(map[string]interface{}{
+			"concat": func(a, b string) string { return a + b },
+		})
+		out, err := env.RenderString("test", `{{ concat("a", "b") }}`, map[string]interface{}{})
+		Expect(err).Should(BeNil())
+		Expect(out).To(Equal("ab"))
+	})
+
+})
@@ -0,0 +1,158 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"strings"
+)
+
+type testFunc func(v interface{}, args []interface{}) (bool, error)
+
+var tests map[string]testFunc
+
+func init() {
+	tests = map[string]testFunc{
+		"defined": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(*Undefined)
+			return !ok, nil
+		},
+		"undefined": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(*Undefined)
+			return ok, nil
+		},
+		"none": func(v interface{}, _ []interface{}) (bool, error) {
+			return v == nil, nil
+		},
+		"boolean": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(bool)
+			return ok, nil
+		},
+		"true": func(v interface{}, _ []interface{}) (bool, error) {
+			b, ok := v.(bool)
+			return ok && b, nil
+		},
+		"false": func(v interface{}, _ []interface{}) (bool, error) {
+			b, ok := v.(bool)
+			return ok && !b, nil
+		},
+		"string": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(string)
+			return ok, nil
+		},
+		"number": func(v interface{}, _ []interface{}) (bool, error) {
+			switch v.(type) {
+			case int, float64:
+				return true, nil
+			}
+			return false, nil
+		},
+		"integer": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(int)
+			return ok, nil
+		},
+		"float": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(float64)
+			return ok, nil
+		},
+		"sequence": func(v interface{}, _ []interface{}) (bool, error) {
+			switch v.(type) {
+			case string, *List, *Dict:
+				return true, nil
+			}
+			return false, nil
+		},
+		"iterable": func(v interface{}, _ []interface{}) (bool, error) {
+			switch v.(type) {
+			case string, *List, *Dict:
+				return true, nil
+			}
+			return false, nil
+		},
+		"mapping": func(v interface{}, _ []interface{}) (bool, error) {
+			_, ok := v.(*Dict)
+			return ok, nil
+		},
+		"callable": func(v interface{}, _ []interface{}) (bool, error) {
+			switch v.(type) {
+			case *Macro, builtinFunc:
+				return true, nil
+			}
+			return false, nil
+		},
+		"lower": func(v interface{}, _ []interface{}) (bool, error) {
+			s := toString(v)
+			return strings.ToLower(s) == s, nil
+		},
+		"upper": func(v interface{}, _ []interface{}) (bool, error) {
+			s := toString(v)
+			return strings.ToUpper(s) == s, nil
+		},
+		"odd": func(v interface{}, _ []interface{}) (bool, error) {
+			i, err := toInt(v)
+			return i%2 != 0, err
+		},
+		"even": func(v interface{}, _ []interface{}) (bool, error) {
+			i, err := toInt(v)
+			return i%2 == 0, err
+		},
+		"divisibleby": func(v interface{}, args []interface{}) (bool, error) {
+			if len(args) != 1 {
+				return false, fmt.Errorf("divisibleby test requires one argument")
+			}
+			i, err := toInt(v)
+			if err != nil {
+				return false, err
+			}
+			n, err := toInt(args[0])
+			if err != nil {
+				return false, err
+			}
+			if n == 0 {
+				return false, fmt.Errorf("integer division or modulo by zero")
+			}
+			return i%n == 0, nil
+		},
+		"sameas": func(v interface{}, args []interface{}) (bool, error) {
+			if len(args) != 1 {
+				return false, fmt.Errorf("sameas test requires one argument")
+			}
+			switch a := v.(type) {
+			case *List, *Dict, *Namespace, *Macro:
+				return a == args[0], nil
+			}
+			return equals(v, args[0]), nil
+		},
+		"in": func(v interface{}, args []interface{}) (bool, error) {
+			if len(args) != 1 {
+				return false, fmt.Errorf("in test requires one argument")
+			}
+			return contains(args[0], v)
+		},
+	}
+
+	comparisons := map[string][]string{
+		"==": {"eq", "equalto", "=="},
+		"!=": {"ne", "!="},
+		"<":  {"lt", "lessthan", "<"},
+		"<=": {"le", "<="},
+		">":  {"gt", "greaterthan", ">"},
+		">=": {"ge", ">="},
+	}
+	for op, names := range comparisons {
+		for _, name := range names {
+			tests[name] = compareTest(op)
+		}
+	}
+}
+
+func compareTest(op string) testFunc {
+	return func(v interface{}, args []interface{}) (bool, error) {
+		if len(args) != 1 {
+			return false, fmt.Errorf("comparison test requires one argument")
+		}
+		return compareOp(op, v, args[0])
+	}
+}
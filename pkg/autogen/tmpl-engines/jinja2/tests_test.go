@@ -0,0 +1,49 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Jinja2 tests", func() {
+
+	DescribeTable("Rendering",
+		func(src, expected string) {
+			out, err := render(src, tableValues())
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(expected))
+		},
+		Entry("defined", `{{ s is defined }}/{{ missing is defined }}`, "True/False"),
+		Entry("undefined", `{{ missing is undefined }}/{{ s is undefined }}`, "True/False"),
+		Entry("none", `{{ none is none }}/{{ s is none }}`, "True/False"),
+		Entry("boolean", `{{ true is boolean }}/{{ 1 is boolean }}`, "True/False"),
+		Entry("true", `{{ true is true }}/{{ 1 is true }}`, "True/False"),
+		Entry("false", `{{ false is false }}/{{ 0 is false }}`, "True/False"),
+		Entry("string", `{{ s is string }}/{{ n is string }}`, "True/False"),
+		Entry("number", `{{ f is number }}/{{ s is number }}`, "True/False"),
+		Entry("integer", `{{ n is integer }}/{{ f is integer }}`, "True/False"),
+		Entry("float", `{{ f is float }}/{{ n is float }}`, "True/False"),
+		Entry("sequence", `{{ nums is sequence }}/{{ n is sequence }}`, "True/False"),
+		Entry("iterable", `{{ dict is iterable }}/{{ n is iterable }}`, "True/False"),
+		Entry("mapping", `{{ dict is mapping }}/{{ nums is mapping }}`, "True/False"),
+		Entry("callable", `{{ range is callable }}/{{ s is callable }}`, "True/False"),
+		Entry("lower", `{{ "abc" is lower }}/{{ s is lower }}`, "True/False"),
+		Entry("upper", `{{ "ABC" is upper }}/{{ s is upper }}`, "True/False"),
+		Entry("odd", `{{ 3 is odd }}/{{ 2 is odd }}`, "True/False"),
+		Entry("even", `{{ 2 is even }}/{{ 3 is even }}`, "True/False"),
+		Entry("divisibleby", `{{ 9 is divisibleby(3) }}/{{ 9 is divisibleby 2 }}`, "True/False"),
+		Entry("sameas", `{{ none is sameas none }}/{{ 1 is sameas 2 }}`, "True/False"),
+		Entry("in", `{{ 1 is in nums }}/{{ 5 is in nums }}`, "True/False"),
+		Entry("not", `{{ 2 is not odd }}`, "True"),
+	)
+
+	It("Unknown test", func() {
+		_, err := render("{{ s is notatest }}", tableValues())
+		Expect(err).ShouldNot(BeNil())
+	})
+
+})
@@ -0,0 +1,735 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package jinja2
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The runtime values are: nil (None), bool, int, float64, string,
+// *List, *Dict, *Undefined, *Namespace, *Macro and builtinFunc.
+
+// List is a mutable sequence like the python lists. The tuples are
+// lists printed with the parenthesis.
+type List struct {
+	Items []interface{}
+	Tuple bool
+}
+
+// Dict is a mapping that preserves the insertion order of the keys
+// like the python dicts.
+type Dict struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+// Undefined is the value of the variables and the attributes
+// not available.
+type Undefined struct {
+	Hint string
+}
+
+// Namespace is the object returned by the namespace() global
+// and used to change values from the inner scopes.
+type Namespace struct {
+	Attrs *Dict
+}
+
+type builtinFunc func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+func NewList(items ...interface{}) *List {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return &List{Items: items}
+}
+
+func NewTuple(items ...interface{}) *List {
+	ans := NewList(items...)
+	ans.Tuple = true
+	return ans
+}
+
+func NewDict() *Dict {
+	return &Dict{
+		Keys:   []string{},
+		Values: make(map[string]interface{}, 0),
+	}
+}
+
+func (d *Dict) Get(k string) (interface{}, bool) {
+	v, ok := d.Values[k]
+	return v, ok
+}
+
+func (d *Dict) Set(k string, v interface{}) {
+	if _, ok := d.Values[k]; !ok {
+		d.Keys = append(d.Keys, k)
+	}
+	d.Values[k] = v
+}
+
+func (d *Dict) Delete(k string) {
+	if _, ok := d.Values[k]; !ok {
+		return
+	}
+	delete(d.Values, k)
+	for idx, key := range d.Keys {
+		if key == k {
+			d.Keys = append(d.Keys[:idx], d.Keys[idx+1:]...)
+			break
+		}
+	}
+}
+
+func (d *Dict) Len() int { return len(d.Keys) }
+
+func (u *Undefined) Error() string {
+	return u.Hint
+}
+
+// newUndefinedName returns the undefined value of a missing variable.
+func newUndefinedName(name string) *Undefined {
+	return &Undefined{Hint: fmt.Sprintf("'%s' is undefined", name)}
+}
+
+// newUndefinedAttr returns the undefined value of a missing attribute.
+func newUndefinedAttr(obj interface{}, attr string) *Undefined {
+	return &Undefined{
+		Hint: fmt.Sprintf("'%s' has no attribute '%s'", typeName(obj), attr),
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "None"
+	case bool:
+		return "bool object"
+	case int:
+		return "int object"
+	case float64:
+		return "float object"
+	case string:
+		return "str object"
+	case *List:
+		if v.(*List).Tuple {
+			return "tuple object"
+		}
+		return "list object"
+	case *Dict:
+		return "dict object"
+	case *Namespace:
+		return "namespace object"
+	case *Undefined:
+		return "jinja2.runtime.Undefined"
+	default:
+		return "object"
+	}
+}
+
+// fromYaml converts the values in the runtime values through the
+// yaml serialization like the j2cli tool that loads the data file.
+// The order of the maps keys is preserved.
+func fromYaml(values interface{}) (*Dict, error) {
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		return NewDict(), nil
+	}
+
+	v, err := fromYamlNode(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	d, ok := v.(*Dict)
+	if !ok {
+		return NewDict(), nil
+	}
+
+	return d, nil
+}
+
+func fromYamlNode(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return fromYamlNode(n.Content[0])
+
+	case yaml.AliasNode:
+		return fromYamlNode(n.Alias)
+
+	case yaml.SequenceNode:
+		ans := NewList()
+		for _, c := range n.Content {
+			v, err := fromYamlNode(c)
+			if err != nil {
+				return nil, err
+			}
+			ans.Items = append(ans.Items, v)
+		}
+		return ans, nil
+
+	case yaml.MappingNode:
+		ans := NewDict()
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v, err := fromYamlNode(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			ans.Set(n.Content[i].Value, v)
+		}
+		return ans, nil
+
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return toValue(v), nil
+	}
+}
+
+// toValue converts a go value in a runtime value.
+func toValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, bool, int, float64, string, *List, *Dict, *Undefined,
+		*Namespace, *Macro, builtinFunc:
+		return v
+	case []interface{}:
+		ans := NewList()
+		for _, item := range val {
+			ans.Items = append(ans.Items, toValue(item))
+		}
+		return ans
+	case []string:
+		ans := NewList()
+		for _, item := range val {
+			ans.Items = append(ans.Items, item)
+		}
+		return ans
+	case map[string]interface{}:
+		ans := NewDict()
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ans.Set(k, toValue(val[k]))
+		}
+		return ans
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		ans := NewList()
+		for i := 0; i < rv.Len(); i++ {
+			ans.Items = append(ans.Items, toValue(rv.Index(i).Interface()))
+		}
+		return ans
+	case reflect.Map:
+		ans := NewDict()
+		keys := []string{}
+		values := make(map[string]interface{}, 0)
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := fmt.Sprintf("%v", iter.Key().Interface())
+			keys = append(keys, k)
+			values[k] = toValue(iter.Value().Interface())
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ans.Set(k, values[k])
+		}
+		return ans
+	case reflect.Func:
+		return wrapGoFunc(v)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+// toGo converts a runtime value in a go value.
+func toGo(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *List:
+		ans := make([]interface{}, 0, len(val.Items))
+		for _, item := range val.Items {
+			ans = append(ans, toGo(item))
+		}
+		return ans
+	case *Dict:
+		ans := make(map[string]interface{}, len(val.Keys))
+		for _, k := range val.Keys {
+			ans[k] = toGo(val.Values[k])
+		}
+		return ans
+	case *Namespace:
+		return toGo(val.Attrs)
+	case *Undefined:
+		return nil
+	default:
+		return v
+	}
+}
+
+// wrapGoFunc permits to call a go function from the templates
+// converting the arguments in the types of the parameters.
+func wrapGoFunc(fn interface{}) builtinFunc {
+	rv := reflect.ValueOf(fn)
+	rt := rv.Type()
+
+	return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("keyword arguments are not supported")
+		}
+
+		nIn := rt.NumIn()
+		if (!rt.IsVariadic() && len(args) != nIn) ||
+			(rt.IsVariadic() && len(args) < nIn-1) {
+			return nil, fmt.Errorf("expected %d arguments but got %d", nIn, len(args))
+		}
+
+		in := []reflect.Value{}
+		for idx, arg := range args {
+			var pt reflect.Type
+			if rt.IsVariadic() && idx >= nIn-1 {
+				pt = rt.In(nIn - 1).Elem()
+			} else {
+				pt = rt.In(idx)
+			}
+
+			v, err := convertArg(arg, pt)
+			if err != nil {
+				return nil, fmt.Errorf("invalid argument %d: %s", idx+1, err.Error())
+			}
+			in = append(in, v)
+		}
+
+		out := rv.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+		if len(out) == 0 {
+			return nil, nil
+		}
+
+		return toValue(out[0].Interface()), nil
+	}
+}
+
+func convertArg(arg interface{}, t reflect.Type) (reflect.Value, error) {
+	if u, ok := arg.(*Undefined); ok {
+		return reflect.Value{}, u
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(toString(arg)).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(arg)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(i).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat(arg)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+	case reflect.Bool:
+		return reflect.ValueOf(truthy(arg)), nil
+	}
+
+	v := toGo(arg)
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.Type().AssignableTo(t) {
+		return reflect.Value{}, fmt.Errorf("unexpected %s", typeName(arg))
+	}
+	return rv, nil
+}
+
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case int:
+		return val != 0
+	case float64:
+		return val != 0
+	case string:
+		return val != ""
+	case *List:
+		return len(val.Items) > 0
+	case *Dict:
+		return val.Len() > 0
+	case *Undefined:
+		return false
+	default:
+		return true
+	}
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, float64, bool:
+		return true
+	}
+	return false
+}
+
+func toInt(v interface{}) (int, error) {
+	switch val := v.(type) {
+	case int:
+		return val, nil
+	case float64:
+		return int(val), nil
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			return 0, fmt.Errorf("invalid literal for int(): '%s'", val)
+		}
+		return i, nil
+	case *Undefined:
+		return 0, val
+	}
+	return 0, fmt.Errorf("unexpected %s, expected a number", typeName(v))
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return 0, fmt.Errorf("could not convert string to float: '%s'", val)
+		}
+		return f, nil
+	case *Undefined:
+		return 0, val
+	}
+	return 0, fmt.Errorf("unexpected %s, expected a number", typeName(v))
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == math.Trunc(f) && math.Abs(f) < 1e16:
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	case math.Abs(f) >= 1e16 || math.Abs(f) < 1e-4:
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	default:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+}
+
+// toString returns the string of the value like the str() of python.
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case *Undefined:
+		return ""
+	default:
+		return repr(v)
+	}
+}
+
+// repr returns the string of the value like the repr() of python.
+func repr(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "None"
+	case bool:
+		if val {
+			return "True"
+		}
+		return "False"
+	case int:
+		return strconv.Itoa(val)
+	case float64:
+		return formatFloat(val)
+	case string:
+		return quoteString(val)
+	case *List:
+		parts := make([]string, 0, len(val.Items))
+		for _, item := range val.Items {
+			parts = append(parts, repr(item))
+		}
+		if val.Tuple {
+			if len(parts) == 1 {
+				return "(" + parts[0] + ",)"
+			}
+			return "(" + strings.Join(parts, ", ") + ")"
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case *Dict:
+		parts := make([]string, 0, val.Len())
+		for _, k := range val.Keys {
+			parts = append(parts, quoteString(k)+": "+repr(val.Values[k]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case *Namespace:
+		return "<Namespace " + repr(val.Attrs) + ">"
+	case *Undefined:
+		return "Undefined"
+	case *Macro:
+		return fmt.Sprintf("<Macro '%s'>", val.Name)
+	case builtinFunc:
+		return "<built-in function>"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func quoteString(s string) string {
+	quote := "'"
+	if strings.Contains(s, "'") && !strings.Contains(s, "\"") {
+		quote = "\""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(quote)
+	for _, c := range s {
+		switch c {
+		case '\\':
+			sb.WriteString("\\\\")
+		case '\n':
+			sb.WriteString("\\n")
+		case '\t':
+			sb.WriteString("\\t")
+		case '\r':
+			sb.WriteString("\\r")
+		default:
+			if string(c) == quote {
+				sb.WriteString("\\" + quote)
+			} else {
+				sb.WriteRune(c)
+			}
+		}
+	}
+	sb.WriteString(quote)
+
+	return sb.String()
+}
+
+func equals(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		return fa == fb
+	}
+
+	switch va := a.(type) {
+	case nil:
+		return b == nil
+	case string:
+		vb, ok := b.(string)
+		return ok && va == vb
+	case *List:
+		vb, ok := b.(*List)
+		if !ok || len(va.Items) != len(vb.Items) {
+			return false
+		}
+		for i := range va.Items {
+			if !equals(va.Items[i], vb.Items[i]) {
+				return false
+			}
+		}
+		return true
+	case *Dict:
+		vb, ok := b.(*Dict)
+		if !ok || va.Len() != vb.Len() {
+			return false
+		}
+		for _, k := range va.Keys {
+			v, ok := vb.Values[k]
+			if !ok || !equals(va.Values[k], v) {
+				return false
+			}
+		}
+		return true
+	case *Undefined:
+		_, ok := b.(*Undefined)
+		return ok
+	case builtinFunc:
+		// The functions are not comparable.
+		return false
+	default:
+		if _, ok := b.(builtinFunc); ok {
+			return false
+		}
+		return a == b
+	}
+}
+
+// compare returns -1, 0 or 1 for the ordering comparison.
+func compare(a, b interface{}) (int, error) {
+	if isNumber(a) && isNumber(b) {
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	if okA && okB {
+		return strings.Compare(sa, sb), nil
+	}
+
+	la, okA := a.(*List)
+	lb, okB := b.(*List)
+	if okA && okB {
+		for i := 0; i < len(la.Items) && i < len(lb.Items); i++ {
+			c, err := compare(la.Items[i], lb.Items[i])
+			if err != nil || c != 0 {
+				return c, err
+			}
+		}
+		switch {
+		case len(la.Items) < len(lb.Items):
+			return -1, nil
+		case len(la.Items) > len(lb.Items):
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("'<' not supported between instances of '%s' and '%s'",
+		typeName(a), typeName(b))
+}
+
+// iterate returns the items of an iterable value.
+func iterate(v interface{}) ([]interface{}, error) {
+	switch val := v.(type) {
+	case *List:
+		return val.Items, nil
+	case *Dict:
+		ans := make([]interface{}, 0, val.Len())
+		for _, k := range val.Keys {
+			ans = append(ans, k)
+		}
+		return ans, nil
+	case string:
+		ans := []interface{}{}
+		for _, c := range val {
+			ans = append(ans, string(c))
+		}
+		return ans, nil
+	case *Undefined:
+		return nil, val
+	}
+	return nil, fmt.Errorf("'%s' is not iterable", typeName(v))
+}
+
+func length(v interface{}) (int, error) {
+	switch val := v.(type) {
+	case string:
+		return len([]rune(val)), nil
+	case *List:
+		return len(val.Items), nil
+	case *Dict:
+		return val.Len(), nil
+	case *Namespace:
+		return val.Attrs.Len(), nil
+	case *Undefined:
+		return 0, val
+	}
+	return 0, fmt.Errorf("object of type '%s' has no len()", typeName(v))
+}
+
+func contains(container, item interface{}) (bool, error) {
+	switch val := container.(type) {
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, fmt.Errorf("'in <string>' requires string as left operand")
+		}
+		return strings.Contains(val, s), nil
+	case *List:
+		for _, i := range val.Items {
+			if equals(i, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case *Dict:
+		s, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, ok = val.Values[s]
+		return ok, nil
+	case *Undefined:
+		return false, val
+	}
+	return false, fmt.Errorf("argument of type '%s' is not iterable", typeName(container))
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	ans := make([]string, 0, len(m))
+	for k := range m {
+		ans = append(ans, k)
+	}
+	sort.Strings(ans)
+	return ans
+}
@@ -5,13 +5,67 @@ See AUTHORS and LICENSE for the license details and contributors.
 package tmplengine
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/flosch/pongo2/v6"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
+// pongo2SearchPathLoader resolves the relative paths of the templates
+// with the first directory of the search paths where the file exists.
+type pongo2SearchPathLoader struct {
+	dirs []string
+}
+
+func newPongo2SearchPathLoader(dirs []string) (*pongo2SearchPathLoader, error) {
+	ans := &pongo2SearchPathLoader{
+		dirs: []string{},
+	}
+
+	for _, dir := range dirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("Error on resolve search path %s: %s",
+				dir, err.Error())
+		}
+		ans.dirs = append(ans.dirs, absDir)
+	}
+
+	return ans, nil
+}
+
+func (l *pongo2SearchPathLoader) Abs(base, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	for _, dir := range l.dirs {
+		p := filepath.Join(dir, name)
+		if utils.Exists(p) {
+			return p
+		}
+	}
+
+	// Return the path of the first directory to have
+	// a clear error message.
+	return filepath.Join(l.dirs[0], name)
+}
+
+func (l *pongo2SearchPathLoader) Get(path string) (io.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
 type Pongo2TemplateEngine struct {
 	*CoreTemplateEngine
 }
@@ -29,20 +83,33 @@ func (t *Pongo2TemplateEngine) Render(aspec *specs.AutogenSpec,
 	values := *valref
 	templateFilePath := t.GetTemplateFile(aspec, atom, def)
 
-	// Read the template file
-	data, err := os.ReadFile(templateFilePath)
+	// The loader resolves relative paths from the search paths.
+	templateFilePath, err := filepath.Abs(templateFilePath)
 	if err != nil {
 		return err
 	}
 
+	// Prepare the template set with the loader used to resolve
+	// the templates of include, import and extends tags.
+	loader, err := newPongo2SearchPathLoader(t.GetSearchPaths(aspec, atom, def))
+	if err != nil {
+		return err
+	}
+	set := pongo2.NewSet(atom.Name, loader)
+
 	// Compile the template first (i. e. creating the AST)
-	tpl, err := pongo2.FromBytes(data)
+	tpl, err := set.FromFile(templateFilePath)
 	if err != nil {
 		return err
 	}
 
-	// Render the content
-	content, err := tpl.ExecuteBytes(pongo2.Context(values))
+	// Render the content with the ebuild functions. The values
+	// have the priority over the functions with the same name.
+	ctx := pongo2.Context(helpers.EbuildFuncMap())
+	for k, v := range values {
+		ctx[k] = v
+	}
+	content, err := tpl.ExecuteBytes(ctx)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package helpers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+)
+
+// EbuildFuncMap returns the functions availables on rendering
+// templates with the different engines.
+func EbuildFuncMap() map[string]interface{} {
+	return map[string]interface{}{
+		"gentooVersionCompare": GentooVersionCompare,
+		"slotFromVersion":      SlotFromVersion,
+		"spdxToGentoo":         SpdxToGentoo,
+		"srcUriRename":         SrcUriRename,
+		"bashQuote":            BashQuote,
+	}
+}
+
+// GentooVersionCompare compares two versions with the Gentoo rules and
+// returns -1 if a is lower than b, 0 if equal and 1 if a is greater than b.
+func GentooVersionCompare(a, b string) (int, error) {
+	pa, err := gentoo.ParsePackageStr(fmt.Sprintf("mark/version-%s", a))
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %s: %s", a, err.Error())
+	}
+	pb, err := gentoo.ParsePackageStr(fmt.Sprintf("mark/version-%s", b))
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %s: %s", b, err.Error())
+	}
+
+	if equal, _ := pa.Equal(pb); equal {
+		return 0, nil
+	}
+
+	greater, err := pa.GreaterThan(pb)
+	if err != nil {
+		return 0, err
+	}
+	if greater {
+		return 1, nil
+	}
+
+	return -1, nil
+}
+
+// SlotFromVersion returns the SLOT composed by the first n components
+// of the version (the major version by default). For example with
+// version 3.12.1 and n 2 the SLOT is 3.12.
+func SlotFromVersion(version string, n ...int) string {
+	parts := 1
+	if len(n) > 0 && n[0] > 0 {
+		parts = n[0]
+	}
+
+	// Drop suffixes and revision: 1.2.3_rc1-r1 => 1.2.3
+	if idx := strings.IndexAny(version, "_-"); idx >= 0 {
+		version = version[:idx]
+	}
+
+	components := strings.Split(version, ".")
+	if len(components) > parts {
+		components = components[:parts]
+	}
+
+	return strings.Join(components, ".")
+}
+
+// SrcUriRename returns the SRC_URI entry of the url with the
+// rename of the distfile when the name differs from the url basename.
+func SrcUriRename(url, name string) string {
+	if name == "" || path.Base(url) == name {
+		return url
+	}
+	return fmt.Sprintf("%s -> %s", url, name)
+}
+
+// BashQuote returns the string quoted to be used safely as a bash word.
+func BashQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
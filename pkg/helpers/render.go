@@ -7,8 +7,10 @@ package helpers
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -17,7 +19,6 @@ import (
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
-	"helm.sh/helm/v3/pkg/engine"
 )
 
 func GetTemplates(templateDirs []string) ([]*chart.File, error) {
@@ -58,11 +59,66 @@ func GetTemplates(templateDirs []string) ([]*chart.File, error) {
 	return ans, nil
 }
 
+// GetTemplatesLibrary returns the files of the templates library
+// directories as Helm partials. Every file is loaded with a name
+// prefixed with _ in order to avoid his rendering, but the `define`
+// blocks are availables to the other templates through `include`.
+func GetTemplatesLibrary(libraryDirs []string) ([]*chart.File, error) {
+	ans := []*chart.File{}
+
+	for idx, ldir := range libraryDirs {
+		err := filepath.WalkDir(ldir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return fmt.Errorf(
+					"Error on read library file %s: %s", p, err.Error())
+			}
+
+			rel, _ := filepath.Rel(ldir, p)
+
+			ans = append(ans, &chart.File{
+				// The index of the directory avoids collisions between
+				// files with the same name in different directories.
+				Name: path.Join("library", fmt.Sprintf("%d", idx),
+					filepath.ToSlash(filepath.Dir(rel)), "_"+d.Name()),
+				Data: content,
+			})
+
+			return nil
+		})
+		if err != nil {
+			return ans, fmt.Errorf(
+				"Error on read templates library %s: %s", ldir, err.Error())
+		}
+	}
+
+	return ans, nil
+}
+
 func RenderContentWithTemplates(
 	raw, valuesFile, defaultFile, originFile string,
 	overrideValues map[string]interface{},
 	templateDirs []string) (string, error) {
 
+	return RenderContentWithLibrary(raw, valuesFile, defaultFile, originFile,
+		overrideValues, templateDirs, []string{})
+}
+
+// RenderContentWithLibrary renders the content like RenderContentWithTemplates
+// and permits to use the partials defined in the templates library directories.
+func RenderContentWithLibrary(
+	raw, valuesFile, defaultFile, originFile string,
+	overrideValues map[string]interface{},
+	templateDirs, libraryDirs []string) (string, error) {
+
 	var err error
 
 	values := make(map[string]interface{}, 0)
@@ -109,34 +165,33 @@ func RenderContentWithTemplates(
 		}
 	}
 
-	charts := []*chart.File{}
+	templates := map[string]string{}
 	if len(templateDirs) > 0 {
-		charts, err = GetTemplates(templateDirs)
+		charts, err := GetTemplates(templateDirs)
 		if err != nil {
 			return "", err
 		}
+		for _, f := range charts {
+			templates[f.Name] = string(f.Data)
+		}
 	}
 
-	charts = append(charts, &chart.File{
-		Name: "templates",
-		Data: []byte(raw),
-	})
-
-	c := &chart.Chart{
-		Metadata: &chart.Metadata{
-			Name:    "tpl",
-			Version: "",
-		},
-		Templates: charts,
-		Values:    map[string]interface{}{"Values": d},
+	if len(libraryDirs) > 0 {
+		partials, err := GetTemplatesLibrary(libraryDirs)
+		if err != nil {
+			return "", err
+		}
+		for _, f := range partials {
+			templates[f.Name] = string(f.Data)
+		}
 	}
 
-	v, err := chartutil.CoalesceValues(c, map[string]interface{}{"Values": values})
-	if err != nil {
-		return "", errors.New(fmt.Sprintf(
-			"Error on coalesce values for file %s: %s", originFile, err.Error()))
-	}
-	out, err := engine.Render(c, v)
+	templates["templates"] = raw
+
+	// The values have priority over the defaults.
+	v := chartutil.CoalesceTables(values, d)
+	out, err := renderTemplates(templates, "templates",
+		map[string]interface{}{"Values": v})
 	if err != nil {
 		return "", errors.New(fmt.Sprintf(
 			"Error on rendering file %s: %s", originFile, err.Error()))
@@ -144,10 +199,10 @@ func RenderContentWithTemplates(
 
 	debugHelmTemplate := os.Getenv("MARKDEVKIT_HELM_DEBUG")
 	if debugHelmTemplate == "1" {
-		fmt.Println(out["tpl/templates"])
+		fmt.Println(out)
 	}
 
-	return out["tpl/templates"], nil
+	return out, nil
 }
 
 func RenderContent(raw, valuesFile, defaultFile, originFile string,
@@ -0,0 +1,215 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package helpers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/pelletier/go-toml/v2"
+	goyaml "gopkg.in/yaml.v3"
+	"sigs.k8s.io/yaml"
+)
+
+// Max depth of the include calls of the same template.
+const renderRecursionMaxNums = 1000
+
+// newRenderTemplate returns the Go template used to render the contents.
+// The helm engine doesn't permit to register additional functions, so
+// the template is created with the sprig functions, the ebuild functions
+// and the include, tpl and required functions availables with helm.
+func newRenderTemplate() *template.Template {
+	t := template.New("gotpl")
+	t.Option("missingkey=zero")
+	t.Funcs(renderFuncMap(t, make(map[string]int)))
+	return t
+}
+
+// renderTemplates parses the templates and renders the main template
+// with the values in input. The others templates are used as partials.
+func renderTemplates(templates map[string]string, main string,
+	values map[string]interface{}) (string, error) {
+
+	t := newRenderTemplate()
+
+	// Parse the templates in a predictable order. The main template
+	// is parsed as last so its defines override the others.
+	names := []string{}
+	for name := range templates {
+		if name != main {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append(names, main)
+
+	for _, name := range names {
+		if _, err := t.New(name).Parse(templates[name]); err != nil {
+			return "", fmt.Errorf("parse error in %s: %s", name, err.Error())
+		}
+	}
+
+	var buf strings.Builder
+	if err := t.ExecuteTemplate(&buf, main, values); err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+func renderFuncMap(t *template.Template, includedNames map[string]int) template.FuncMap {
+	funcMap := sprig.TxtFuncMap()
+	delete(funcMap, "env")
+	delete(funcMap, "expandenv")
+
+	extra := template.FuncMap{
+		"toToml":        toTOML,
+		"fromToml":      fromTOML,
+		"toYaml":        toYAML,
+		"toYamlPretty":  toYAMLPretty,
+		"fromYaml":      fromYAML,
+		"fromYamlArray": fromYAMLArray,
+		"toJson":        toJSON,
+		"fromJson":      fromJSON,
+		"fromJsonArray": fromJSONArray,
+
+		"include": func(name string, data interface{}) (string, error) {
+			var buf strings.Builder
+			if includedNames[name] > renderRecursionMaxNums {
+				return "", fmt.Errorf(
+					"rendering template has a nested reference name: %s", name)
+			}
+			includedNames[name]++
+			err := t.ExecuteTemplate(&buf, name, data)
+			includedNames[name]--
+			return buf.String(), err
+		},
+
+		"tpl": func(tpl string, vals interface{}) (string, error) {
+			nt, err := t.Clone()
+			if err != nil {
+				return "", fmt.Errorf("cannot clone template: %s", err.Error())
+			}
+			// Re-inject include and tpl in order to use the defines
+			// of the tpl string.
+			nt.Funcs(renderFuncMap(nt, includedNames))
+
+			nt, err = nt.New(t.Name()).Parse(tpl)
+			if err != nil {
+				return "", fmt.Errorf("cannot parse template %q: %s", tpl, err.Error())
+			}
+
+			var buf strings.Builder
+			if err := nt.Execute(&buf, vals); err != nil {
+				return "", fmt.Errorf("error during tpl function execution for %q: %s",
+					tpl, err.Error())
+			}
+
+			return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+		},
+
+		"required": func(warn string, val interface{}) (interface{}, error) {
+			if val == nil {
+				return val, fmt.Errorf("%s", warn)
+			} else if s, ok := val.(string); ok && s == "" {
+				return val, fmt.Errorf("%s", warn)
+			}
+			return val, nil
+		},
+	}
+
+	for k, v := range extra {
+		funcMap[k] = v
+	}
+
+	for k, v := range EbuildFuncMap() {
+		funcMap[k] = v
+	}
+
+	return funcMap
+}
+
+// The functions below are the same of the helm engine. Like with helm,
+// the errors are swallowed inside the templates: the from* functions
+// return the error message in the Error key or as the only item.
+
+func toYAML(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+func toYAMLPretty(v interface{}) string {
+	var data bytes.Buffer
+	encoder := goyaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(data.String(), "\n")
+}
+
+func fromYAML(str string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+func fromYAMLArray(str string) []interface{} {
+	a := []interface{}{}
+	if err := yaml.Unmarshal([]byte(str), &a); err != nil {
+		a = []interface{}{err.Error()}
+	}
+	return a
+}
+
+func toTOML(v interface{}) string {
+	data, err := toml.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+func fromTOML(str string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := toml.Unmarshal([]byte(str), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func fromJSON(str string) map[string]interface{} {
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(str), &m); err != nil {
+		m["Error"] = err.Error()
+	}
+	return m
+}
+
+func fromJSONArray(str string) []interface{} {
+	a := []interface{}{}
+	if err := json.Unmarshal([]byte(str), &a); err != nil {
+		a = []interface{}{err.Error()}
+	}
+	return a
+}
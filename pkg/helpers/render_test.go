@@ -0,0 +1,110 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package helpers_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/helpers"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Render Test", func() {
+
+	Context("Ebuild functions", func() {
+		It("Render with values", func() {
+			out, err := RenderContent(
+				`SLOT="{{ slotFromVersion .Values.version 2 }}" LICENSE="{{ spdxToGentoo .Values.license }}"`,
+				"", "", "test",
+				map[string]interface{}{
+					"version": "3.12.1",
+					"license": "MIT OR Apache-2.0",
+				})
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal(`SLOT="3.12" LICENSE="|| ( MIT Apache-2.0 )"`))
+		})
+
+		It("Compare versions", func() {
+			out, err := RenderContent(
+				`{{ gentooVersionCompare "1.9.2" "1.10" }}`,
+				"", "", "test", map[string]interface{}{})
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal("-1"))
+		})
+	})
+
+	Context("Defaults and library", func() {
+		It("Values override defaults", func() {
+			dir := GinkgoT().TempDir()
+			defaults := filepath.Join(dir, "defaults.yaml")
+			Expect(os.WriteFile(defaults, []byte("a: 1\nb: 2\n"), 0644)).Should(BeNil())
+
+			out, err := RenderContent(`{{ .Values.a }}-{{ .Values.b }}`,
+				"", defaults, "test", map[string]interface{}{"a": 3})
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal("3-2"))
+		})
+
+		It("Include partial of library", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "common.tmpl"),
+				[]byte(`{{- define "hello" }}hello {{ . }}{{ end -}}`), 0644)).Should(BeNil())
+
+			out, err := RenderContentWithLibrary(`{{ include "hello" .Values.name | upper }}`,
+				"", "", "test", map[string]interface{}{"name": "mark"},
+				[]string{}, []string{dir})
+			Expect(err).Should(BeNil())
+			Expect(out).To(Equal("HELLO MARK"))
+		})
+
+		It("Required value", func() {
+			_, err := RenderContent(`{{ required "missing name" .Values.name }}`,
+				"", "", "test", map[string]interface{}{})
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Helm functions", func() {
+		DescribeTable("Render",
+			func(tmpl string, values map[string]interface{}, expected string) {
+				out, err := RenderContent(tmpl, "", "", "test", values)
+				Expect(err).Should(BeNil())
+				Expect(out).To(Equal(expected))
+			},
+			Entry("toYaml", `{{ toYaml .Values.m }}`,
+				map[string]interface{}{"m": map[string]interface{}{"b": 1, "a": "x"}},
+				"a: x\nb: 1"),
+			Entry("toYamlPretty", `{{ toYamlPretty .Values.m }}`,
+				map[string]interface{}{"m": map[string]interface{}{"a": []string{"x", "z"}}},
+				"a:\n  - x\n  - z"),
+			Entry("fromYaml", `{{ (fromYaml "a: x\nb: 1").a }}`,
+				map[string]interface{}{}, "x"),
+			Entry("fromYaml with error", `{{ (fromYaml "- a").Error | empty | not }}`,
+				map[string]interface{}{}, "true"),
+			Entry("fromYamlArray", `{{ range fromYamlArray "- a\n- b" }}{{ . }}{{ end }}`,
+				map[string]interface{}{}, "ab"),
+			Entry("toJson", `{{ toJson .Values.m }}`,
+				map[string]interface{}{"m": map[string]interface{}{"b": 1, "a": "x"}},
+				`{"a":"x","b":1}`),
+			Entry("fromJson", `{{ (fromJson "{\"a\": \"x\"}").a }}`,
+				map[string]interface{}{}, "x"),
+			Entry("fromJson with error", `{{ (fromJson "[1]").Error | empty | not }}`,
+				map[string]interface{}{}, "true"),
+			Entry("fromJsonArray", `{{ range fromJsonArray "[\"x\", \"y\"]" }}{{ . }}{{ end }}`,
+				map[string]interface{}{}, "xy"),
+			Entry("toToml", `{{ toToml .Values.m }}`,
+				map[string]interface{}{"m": map[string]interface{}{"a": "x"}},
+				"a = 'x'\n"),
+			Entry("fromToml", `{{ (fromToml "a = \"x\"").a }}`,
+				map[string]interface{}{}, "x"),
+			Entry("fromToml with error", `{{ (fromToml "a = ").Error | empty | not }}`,
+				map[string]interface{}{}, "true"),
+		)
+	})
+
+})
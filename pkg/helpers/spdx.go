@@ -0,0 +1,316 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type spdxNode struct {
+	Op       string
+	Id       string
+	Children []*spdxNode
+}
+
+type spdxParser struct {
+	tokens []string
+	pos    int
+}
+
+var (
+	spdxGplRegex = regexp.MustCompile(`^(A|L)?GPL-([0-9]+)\.([0-9]+)(-only|-or-later|\+)?$`)
+
+	// Map of the SPDX identifiers with a different name in the
+	// Gentoo licenses directory.
+	spdx2GentooLicenses = map[string]string{
+		"0BSD":             "0BSD",
+		"Apache-1.1":       "Apache-1.1",
+		"Apache-2.0":       "Apache-2.0",
+		"Artistic-2.0":     "Artistic-2",
+		"BSD-1-Clause":     "BSD-1",
+		"BSD-2-Clause":     "BSD-2",
+		"BSD-3-Clause":     "BSD",
+		"BSD-4-Clause":     "BSD-4",
+		"BSL-1.0":          "Boost-1.0",
+		"bzip2-1.0.6":      "BZIP2",
+		"CC-BY-4.0":        "CC-BY-4.0",
+		"CC0-1.0":          "CC0-1.0",
+		"EPL-1.0":          "EPL-1.0",
+		"EPL-2.0":          "EPL-2.0",
+		"ISC":              "ISC",
+		"MIT":              "MIT",
+		"MIT-0":            "MIT-0",
+		"MPL-1.1":          "MPL-1.1",
+		"MPL-2.0":          "MPL-2.0",
+		"NCSA":             "UoI-NCSA",
+		"OFL-1.1":          "OFL-1.1",
+		"OpenSSL":          "openssl",
+		"PSF-2.0":          "PSF-2",
+		"Python-2.0":       "PSF-2",
+		"Unicode-3.0":      "Unicode-3.0",
+		"Unicode-DFS-2016": "Unicode-DFS-2016",
+		"Unlicense":        "Unlicense",
+		"WTFPL":            "WTFPL-2",
+		"Zlib":             "ZLIB",
+	}
+
+	// Map of the SPDX expressions with exception that have
+	// a specific license in Gentoo.
+	spdxExceptions2GentooLicenses = map[string]string{
+		"Apache-2.0 WITH LLVM-exception":            "Apache-2.0-with-LLVM-exceptions",
+		"GPL-2.0 WITH Classpath-exception-2.0":      "GPL-2-with-classpath-exception",
+		"GPL-2.0-only WITH Classpath-exception-2.0": "GPL-2-with-classpath-exception",
+	}
+)
+
+// SpdxIdToGentoo returns the name of the Gentoo license of the
+// SPDX identifier. Unknown identifiers are returned as is.
+func SpdxIdToGentoo(id string) string {
+	if name, ok := spdx2GentooLicenses[id]; ok {
+		return name
+	}
+
+	if m := spdxGplRegex.FindStringSubmatch(id); m != nil {
+		ans := fmt.Sprintf("%sGPL-%s", m[1], m[2])
+		if m[3] != "0" {
+			ans += "." + m[3]
+		}
+		if m[4] == "-or-later" || m[4] == "+" {
+			ans += "+"
+		}
+		return ans
+	}
+
+	if strings.HasSuffix(id, "+") {
+		if name, ok := spdx2GentooLicenses[strings.TrimSuffix(id, "+")]; ok {
+			return name + "+"
+		}
+	}
+
+	return id
+}
+
+// SpdxToGentoo converts a SPDX license expression to the
+// Gentoo LICENSE syntax. For example:
+// "MIT OR (Apache-2.0 AND BSD-3-Clause)" => "|| ( MIT ( Apache-2.0 BSD ) )".
+func SpdxToGentoo(expr string) (string, error) {
+	node, err := parseSpdx(expr)
+	if err != nil || node == nil {
+		return "", err
+	}
+
+	return node.gentoo(false), nil
+}
+
+// SpdxToGentooLicenses converts a SPDX license expression to the
+// list of the licenses in AND with the Gentoo LICENSE syntax. For example:
+// "MIT AND (Apache-2.0 OR Zlib)" => ["MIT", "|| ( Apache-2.0 ZLIB )"].
+func SpdxToGentooLicenses(expr string) ([]string, error) {
+	ans := []string{}
+
+	node, err := parseSpdx(expr)
+	if err != nil || node == nil {
+		return ans, err
+	}
+
+	if node.Op == "AND" {
+		for _, c := range node.Children {
+			ans = append(ans, c.gentoo(false))
+		}
+	} else {
+		ans = append(ans, node.gentoo(false))
+	}
+
+	return ans, nil
+}
+
+func parseSpdx(expr string) (*spdxNode, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	p := &spdxParser{tokens: tokenizeSpdx(expr)}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid SPDX expression '%s': %s", expr, err.Error())
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid SPDX expression '%s': unexpected token %s",
+			expr, p.tokens[p.pos])
+	}
+
+	return node, nil
+}
+
+func tokenizeSpdx(expr string) []string {
+	ans := []string{}
+	curr := ""
+
+	flush := func() {
+		if curr != "" {
+			ans = append(ans, curr)
+			curr = ""
+		}
+	}
+
+	for _, c := range expr {
+		switch c {
+		case '(', ')', '/':
+			flush()
+			ans = append(ans, string(c))
+		case ' ', '\t', '\n', ',':
+			flush()
+		default:
+			curr += string(c)
+		}
+	}
+	flush()
+
+	return ans
+}
+
+func (p *spdxParser) next() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *spdxParser) parseOr() (*spdxNode, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &spdxNode{Op: "OR", Children: []*spdxNode{node}}
+	for strings.EqualFold(p.next(), "OR") || p.next() == "/" {
+		p.pos++
+		node, err = p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		ans.addChild(node)
+	}
+
+	if len(ans.Children) == 1 {
+		return ans.Children[0], nil
+	}
+	return ans, nil
+}
+
+func (p *spdxParser) parseAnd() (*spdxNode, error) {
+	node, err := p.parseWith()
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &spdxNode{Op: "AND", Children: []*spdxNode{node}}
+	for strings.EqualFold(p.next(), "AND") {
+		p.pos++
+		node, err = p.parseWith()
+		if err != nil {
+			return nil, err
+		}
+		ans.addChild(node)
+	}
+
+	if len(ans.Children) == 1 {
+		return ans.Children[0], nil
+	}
+	return ans, nil
+}
+
+func (p *spdxParser) parseWith() (*spdxNode, error) {
+	node, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(p.next(), "WITH") {
+		p.pos++
+		exception := p.next()
+		if exception == "" || exception == "(" || exception == ")" {
+			return nil, fmt.Errorf("missing exception after WITH")
+		}
+		p.pos++
+
+		if node.Op != "" {
+			return nil, fmt.Errorf("WITH is valid only after a license identifier")
+		}
+
+		if name, ok := spdxExceptions2GentooLicenses[node.Id+" WITH "+exception]; ok {
+			node.Id = name
+		} else {
+			// The exceptions extend the permissions of the license.
+			// Without a specific Gentoo license we use the main license.
+			node.Id = SpdxIdToGentoo(node.Id)
+		}
+		node.Op = "ID"
+	}
+
+	return node, nil
+}
+
+func (p *spdxParser) parseAtom() (*spdxNode, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing close parenthesis")
+		}
+		p.pos++
+		return node, nil
+	case token == ")" || token == "/" ||
+		strings.EqualFold(token, "AND") || strings.EqualFold(token, "OR") ||
+		strings.EqualFold(token, "WITH"):
+		return nil, fmt.Errorf("unexpected token %s", token)
+	default:
+		p.pos++
+		return &spdxNode{Id: token}, nil
+	}
+}
+
+func (n *spdxNode) addChild(c *spdxNode) {
+	// Flatten nested nodes with the same operator.
+	if c.Op == n.Op {
+		n.Children = append(n.Children, c.Children...)
+	} else {
+		n.Children = append(n.Children, c)
+	}
+}
+
+func (n *spdxNode) gentoo(parentOr bool) string {
+	switch n.Op {
+	case "OR":
+		parts := []string{}
+		for _, c := range n.Children {
+			parts = append(parts, c.gentoo(true))
+		}
+		return fmt.Sprintf("|| ( %s )", strings.Join(parts, " "))
+	case "AND":
+		parts := []string{}
+		for _, c := range n.Children {
+			parts = append(parts, c.gentoo(false))
+		}
+		if parentOr {
+			return fmt.Sprintf("( %s )", strings.Join(parts, " "))
+		}
+		return strings.Join(parts, " ")
+	case "ID":
+		// Already converted by a WITH expression.
+		return n.Id
+	default:
+		return SpdxIdToGentoo(n.Id)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package helpers_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/helpers"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SPDX helpers", func() {
+
+	DescribeTable("SpdxToGentoo",
+		func(expr, expected string) {
+			ans, err := SpdxToGentoo(expr)
+			Expect(err).Should(BeNil())
+			Expect(ans).To(Equal(expected))
+		},
+		Entry("empty", "", ""),
+		Entry("single", "MIT", "MIT"),
+		Entry("renamed", "BSD-3-Clause", "BSD"),
+		Entry("artistic", "Artistic-2.0", "Artistic-2"),
+		Entry("ncsa", "NCSA", "UoI-NCSA"),
+		Entry("gpl or later", "GPL-2.0-or-later", "GPL-2+"),
+		Entry("agpl", "AGPL-3.0-only", "AGPL-3"),
+		Entry("plus", "MPL-2.0+", "MPL-2.0+"),
+		Entry("or", "MIT OR Apache-2.0", "|| ( MIT Apache-2.0 )"),
+		Entry("slash", "MIT/Apache-2.0", "|| ( MIT Apache-2.0 )"),
+		Entry("nested", "MIT OR (Apache-2.0 AND BSD-3-Clause)",
+			"|| ( MIT ( Apache-2.0 BSD ) )"),
+		Entry("with exception", "Apache-2.0 WITH LLVM-exception OR MIT",
+			"|| ( Apache-2.0-with-LLVM-exceptions MIT )"),
+		Entry("unknown exception", "GPL-3.0 WITH foo-exception", "GPL-3"),
+	)
+
+	DescribeTable("SpdxToGentooLicenses",
+		func(expr string, expected []string) {
+			ans, err := SpdxToGentooLicenses(expr)
+			Expect(err).Should(BeNil())
+			Expect(ans).To(Equal(expected))
+		},
+		Entry("empty", "", []string{}),
+		Entry("single", "Zlib", []string{"ZLIB"}),
+		Entry("and", "MIT AND (Apache-2.0 OR Zlib)",
+			[]string{"MIT", "|| ( Apache-2.0 ZLIB )"}),
+		Entry("or", "MIT OR Unicode-DFS-2016",
+			[]string{"|| ( MIT Unicode-DFS-2016 )"}),
+	)
+
+	DescribeTable("Invalid expressions",
+		func(expr string) {
+			_, err := SpdxToGentoo(expr)
+			Expect(err).ShouldNot(BeNil())
+		},
+		Entry("missing close parenthesis", "(MIT OR Zlib"),
+		Entry("dangling operator", "MIT OR"),
+		Entry("unexpected token", "MIT )"),
+	)
+
+})
@@ -0,0 +1,63 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"fmt"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// getGitConfig returns the config used by the git operations to retrieve
+// the credentials of the remotes or an empty config if not defined.
+func getGitConfig(c *specs.MarkDevkitConfig) *specs.MarkDevkitConfig {
+	if c != nil {
+		return c
+	}
+	return specs.NewMarkDevkitConfig(nil)
+}
+
+// getPushAuth returns the authentication method used to push on the
+// remote url. Over HTTP the token of the forge is retrieved from the
+// environment if not available in the config. The token of a forge is
+// never used with the hosts of another forge.
+func getPushAuth(remoteUrl string, opts *PushOptions) (transport.AuthMethod, error) {
+	c := getGitConfig(opts.Config)
+
+	auth, err := c.GetAuthentication().GetGitAuth(remoteUrl, opts.Token)
+	if err != nil || auth != nil {
+		return auth, err
+	}
+
+	r, err := specs.ParseGitRemote(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+	if r.Protocol != specs.GitProtocolHttp {
+		return nil, nil
+	}
+
+	forgeType, err := GetForgeType(c, r.Host)
+	if err != nil {
+		return nil, fmt.Errorf("no credentials for the remote %s: %s",
+			r.Host, err.Error())
+	}
+
+	token := getForgeToken(c, r.Host, forgeType)
+	if token == "" {
+		return nil, fmt.Errorf("no credentials for the remote %s. Push interrupted!",
+			r.Host)
+	}
+
+	return c.GetAuthentication().GetGitAuth(remoteUrl, token)
+}
+
+// getFetchAuth returns the authentication method used to clone or fetch
+// from the remote url.
+func getFetchAuth(c *specs.MarkDevkitConfig, remoteUrl string) (transport.AuthMethod, error) {
+	return getGitConfig(c).GetAuthentication().GetGitAuth(remoteUrl, "")
+}
@@ -0,0 +1,70 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Push authentication", func() {
+
+	var opts *PushOptions
+
+	BeforeEach(func() {
+		c := specs.NewMarkDevkitConfig(nil)
+		c.GetAuthentication().Remotes = map[string]*specs.MarkDevkitRemoteAuth{
+			"git.example.org": {Forge: "forgejo"},
+			"git.company.com": {Username: "bot", Password: "secret"},
+		}
+		opts = NewPushOptions(c)
+
+		GinkgoT().Setenv("GITHUB_TOKEN", "github-token")
+		GinkgoT().Setenv("FORGEJO_TOKEN", "")
+		GinkgoT().Setenv("GITEA_TOKEN", "")
+		GinkgoT().Setenv("GITLAB_TOKEN", "")
+	})
+
+	It("uses the github token with github", func() {
+		auth, err := GetPushAuth("https://github.com/macaroni-os/core-kit.git", opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.(*http.BasicAuth).Password).To(Equal("github-token"))
+	})
+
+	It("uses the credentials of the remote", func() {
+		auth, err := GetPushAuth("https://git.company.com/kits/core-kit.git", opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.(*http.BasicAuth).Username).To(Equal("bot"))
+		Expect(auth.(*http.BasicAuth).Password).To(Equal("secret"))
+	})
+
+	It("uses the token of the forge", func() {
+		GinkgoT().Setenv("FORGEJO_TOKEN", "forgejo-token")
+		auth, err := GetPushAuth("https://git.example.org/kits/core-kit.git", opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(auth.(*http.BasicAuth).Password).To(Equal("forgejo-token"))
+	})
+
+	It("doesn't use the github token with another forge", func() {
+		auth, err := GetPushAuth("https://git.example.org/kits/core-kit.git", opts)
+		Expect(err).To(HaveOccurred())
+		Expect(auth).To(BeNil())
+
+		auth, err = GetPushAuth("https://gitlab.com/kits/core-kit.git", opts)
+		Expect(err).To(HaveOccurred())
+		Expect(auth).To(BeNil())
+	})
+
+	It("doesn't use the github token with an unknown host", func() {
+		auth, err := GetPushAuth("https://git.unknown.org/kits/core-kit.git", opts)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("no credentials for the remote git.unknown.org"))
+		Expect(auth).To(BeNil())
+	})
+})
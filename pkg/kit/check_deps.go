@@ -0,0 +1,323 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DepIssueInvalid       = "invalid"
+	DepIssueUnsatisfiable = "unsatisfiable"
+	DepIssueAmbiguous     = "ambiguous"
+	DepIssueBlocker       = "blocker"
+)
+
+// The slot operators are not relevant for the check.
+var depSlotOperatorRegex = regexp.MustCompile(`:([^:/=*\[]*)(/[^=\[]*)?[=*]?`)
+
+type DepsCheckOpts struct {
+	Kinds []string
+	Atoms []string
+}
+
+// DepIssue describes a dependency of an atom that can't be
+// satisfied correctly by the kits.
+type DepIssue struct {
+	Atom       string   `json:"atom" yaml:"atom"`
+	Kit        string   `json:"kit" yaml:"kit"`
+	Kind       string   `json:"kind" yaml:"kind"`
+	Dependency string   `json:"dependency" yaml:"dependency"`
+	Type       string   `json:"type" yaml:"type"`
+	Message    string   `json:"message" yaml:"message"`
+	Candidates []string `json:"candidates,omitempty" yaml:"candidates,omitempty"`
+}
+
+type DepsCheckStats struct {
+	TotAtoms  int `json:"tot_atoms" yaml:"tot_atoms"`
+	TotDeps   int `json:"tot_deps" yaml:"tot_deps"`
+	TotIssues int `json:"tot_issues" yaml:"tot_issues"`
+}
+
+type DepsCheckReport struct {
+	Stats  *DepsCheckStats `json:"stats" yaml:"stats"`
+	Issues []*DepIssue     `json:"issues,omitempty" yaml:"issues,omitempty"`
+}
+
+type depEntry struct {
+	Kind       string
+	Dependency string
+	Package    *gentoo.GentooPackage
+	Blocker    bool
+	Candidates []*specs.RepoScanAtom
+}
+
+func NewDepsCheckOpts() *DepsCheckOpts {
+	return &DepsCheckOpts{
+		Kinds: []string{"RDEPEND", "DEPEND", "BDEPEND", "PDEPEND"},
+		Atoms: []string{},
+	}
+}
+
+func (o *DepsCheckOpts) AtomInFilter(catpkg string) bool {
+	if len(o.Atoms) == 0 {
+		return true
+	}
+	for _, a := range o.Atoms {
+		if a == catpkg {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *DepsCheckReport) Yaml() ([]byte, error) {
+	return yaml.Marshal(r)
+}
+
+func (r *DepsCheckReport) Json() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *DepsCheckReport) WriteJsonFile(f string) error {
+	data, err := r.Json()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, data, 0644)
+}
+
+func (r *DepsCheckReport) WriteYamlFile(f string) error {
+	data, err := r.Yaml()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(f, data, 0644)
+}
+
+// CheckDependencies verifies that all the dependencies of the atoms
+// loaded in the resolver are satisfied by the atoms of the resolver.
+func (r *RepoScanResolver) CheckDependencies(opts *DepsCheckOpts) (*DepsCheckReport, error) {
+	ans := &DepsCheckReport{
+		Stats:  &DepsCheckStats{},
+		Issues: []*DepIssue{},
+	}
+
+	// Sort the packages for a stable report.
+	catpkgs := []string{}
+	for catpkg := range r.Map {
+		if opts.AtomInFilter(catpkg) {
+			catpkgs = append(catpkgs, catpkg)
+		}
+	}
+	sort.Strings(catpkgs)
+
+	for _, catpkg := range catpkgs {
+		atoms := make([]specs.RepoScanAtom, len(r.Map[catpkg]))
+		copy(atoms, r.Map[catpkg])
+		sort.Slice(atoms, func(i, j int) bool {
+			return atoms[i].Atom+atoms[i].Kit < atoms[j].Atom+atoms[j].Kit
+		})
+
+		for idx := range atoms {
+			ans.Stats.TotAtoms++
+			issues := r.checkAtomDependencies(&atoms[idx], opts, ans.Stats)
+			ans.Issues = append(ans.Issues, issues...)
+		}
+	}
+
+	ans.Stats.TotIssues = len(ans.Issues)
+
+	return ans, nil
+}
+
+func (r *RepoScanResolver) checkAtomDependencies(atom *specs.RepoScanAtom,
+	opts *DepsCheckOpts, stats *DepsCheckStats) []*DepIssue {
+	ans := []*DepIssue{}
+	deps := []*depEntry{}
+
+	newIssue := func(kind, dep, t, msg string) *DepIssue {
+		return &DepIssue{
+			Atom:       atom.Atom,
+			Kit:        atom.Kit,
+			Kind:       kind,
+			Dependency: dep,
+			Type:       t,
+			Message:    msg,
+		}
+	}
+
+	for _, kind := range opts.Kinds {
+		for _, dep := range atom.RelationsByKind[kind] {
+			stats.TotDeps++
+
+			entry, err := parseDependency(kind, dep)
+			if err != nil {
+				ans = append(ans, newIssue(kind, dep, DepIssueInvalid, err.Error()))
+				continue
+			}
+
+			// Ignore the dependencies to the same package.
+			if entry.Package.GetPackageName() == atom.CatPkg {
+				continue
+			}
+
+			if entry.Blocker {
+				deps = append(deps, entry)
+				continue
+			}
+
+			if !r.IsPresentPackage(entry.Package.GetPackageName()) {
+				ans = append(ans, newIssue(kind, dep, DepIssueUnsatisfiable,
+					fmt.Sprintf("package %s not available in the kits",
+						entry.Package.GetPackageName())))
+				continue
+			}
+
+			candidates, err := r.GetValidPackages(sanitizeDependency(dep), NewPortageResolverOpts())
+			if err != nil {
+				ans = append(ans, newIssue(kind, dep, DepIssueInvalid, err.Error()))
+				continue
+			}
+
+			if len(candidates) == 0 {
+				ans = append(ans, newIssue(kind, dep, DepIssueUnsatisfiable,
+					"no versions available match the dependency"))
+				continue
+			}
+
+			candidates = r.getKitsCandidates(candidates)
+			entry.Candidates = candidates
+			deps = append(deps, entry)
+
+			kits := make(map[string]bool, 0)
+			for _, c := range candidates {
+				kits[c.Kit] = true
+			}
+			if len(kits) > 1 {
+				issue := newIssue(kind, dep, DepIssueAmbiguous,
+					fmt.Sprintf("dependency satisfied by %d kits", len(kits)))
+				for _, c := range candidates {
+					issue.Candidates = append(issue.Candidates,
+						fmt.Sprintf("%s::%s", c.Atom, c.Kit))
+				}
+				ans = append(ans, issue)
+			}
+		}
+	}
+
+	// Check if a blocker excludes all the candidates of a dependency
+	// of the same atom.
+	for _, blocker := range deps {
+		if !blocker.Blocker {
+			continue
+		}
+
+		for _, dep := range deps {
+			if dep.Blocker || dep.Package.GetPackageName() != blocker.Package.GetPackageName() {
+				continue
+			}
+
+			blocked := []string{}
+			for _, c := range dep.Candidates {
+				cpkg, err := c.ToGentooPackage()
+				if err != nil {
+					continue
+				}
+				if admit, _ := blocker.Package.Admit(cpkg); admit {
+					blocked = append(blocked, fmt.Sprintf("%s::%s", c.Atom, c.Kit))
+				}
+			}
+
+			if len(blocked) == len(dep.Candidates) {
+				issue := newIssue(dep.Kind, dep.Dependency, DepIssueBlocker,
+					fmt.Sprintf("all the candidates are blocked by %s (%s)",
+						blocker.Dependency, blocker.Kind))
+				issue.Candidates = blocked
+				ans = append(ans, issue)
+			}
+		}
+	}
+
+	return ans
+}
+
+// getKitsCandidates returns the candidates with the same versions
+// available in all the kits. The resolver returns only one atom
+// for every version of the package.
+func (r *RepoScanResolver) getKitsCandidates(candidates []*specs.RepoScanAtom) []*specs.RepoScanAtom {
+	ans := []*specs.RepoScanAtom{}
+	visited := make(map[string]bool, 0)
+
+	for _, c := range candidates {
+		atoms := r.Map[c.CatPkg]
+		for idx := range atoms {
+			key := atoms[idx].Atom + "::" + atoms[idx].Kit
+			if atoms[idx].Atom != c.Atom || visited[key] {
+				continue
+			}
+			p, err := atoms[idx].ToGentooPackage()
+			if err != nil {
+				continue
+			}
+			if valid, _ := r.KeywordsIsAdmit(&atoms[idx], p); valid {
+				visited[key] = true
+				ans = append(ans, &atoms[idx])
+			}
+		}
+	}
+
+	return ans
+}
+
+// sanitizeDependency drops the slot operators and the sub slot
+// of the dependency.
+func sanitizeDependency(dep string) string {
+	ans := depSlotOperatorRegex.ReplaceAllStringFunc(dep, func(s string) string {
+		m := depSlotOperatorRegex.FindStringSubmatch(s)
+		if m[1] == "" {
+			return ""
+		}
+		return ":" + m[1]
+	})
+	return ans
+}
+
+func parseDependency(kind, dep string) (*depEntry, error) {
+	ans := &depEntry{
+		Kind:       kind,
+		Dependency: dep,
+	}
+
+	pkg := dep
+	if strings.HasPrefix(pkg, "!") {
+		ans.Blocker = true
+		pkg = strings.TrimPrefix(strings.TrimPrefix(pkg, "!"), "!")
+	}
+	pkg = sanitizeDependency(pkg)
+
+	gp, err := gentoo.ParsePackageStr(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(pkg, ":") {
+		gp.Slot = ""
+	}
+	ans.Package = gp
+
+	return ans, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newTestResolver(atoms ...*specs.RepoScanAtom) *RepoScanResolver {
+	r := NewRepoScanResolver(specs.NewMarkDevkitConfig(nil))
+	for _, atom := range atoms {
+		r.AddPackageAtom(atom.CatPkg, atom)
+	}
+	return r
+}
+
+var _ = Describe("Check dependencies", func() {
+
+	// getIssues returns the issues in the format:
+	// atom::kit kind type dependency [candidates]
+	getIssues := func(report *DepsCheckReport) []string {
+		ans := []string{}
+		for _, i := range report.Issues {
+			row := fmt.Sprintf("%s::%s %s %s %s", i.Atom, i.Kit, i.Kind, i.Type, i.Dependency)
+			if len(i.Candidates) > 0 {
+				row += " [" + strings.Join(i.Candidates, " ") + "]"
+			}
+			ans = append(ans, row)
+		}
+		return ans
+	}
+
+	DescribeTable("CheckDependencies",
+		func(deps []string, atoms []*specs.RepoScanAtom, expected []string) {
+			r := newTestResolver(append(atoms,
+				newTestAtom("app-misc/root-1.0", "0", "core-kit", deps...))...)
+
+			report, err := r.CheckDependencies(NewDepsCheckOpts())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(getIssues(report)).To(Equal(expected))
+			Expect(report.Stats.TotIssues).To(Equal(len(expected)))
+		},
+		Entry("satisfied dependencies",
+			[]string{"dev-libs/foo", ">=dev-libs/bar-2:2=", "dev-libs/bar:2/2.1="},
+			[]*specs.RepoScanAtom{
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/bar-2.1", "2/2.1", "core-kit"),
+			},
+			[]string{}),
+		Entry("package not available",
+			[]string{"dev-libs/missing"},
+			[]*specs.RepoScanAtom{},
+			[]string{"app-misc/root-1.0::core-kit RDEPEND unsatisfiable dev-libs/missing"}),
+		Entry("version not available",
+			[]string{">=dev-libs/foo-2", "dev-libs/foo:3"},
+			[]*specs.RepoScanAtom{
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+			},
+			[]string{
+				"app-misc/root-1.0::core-kit RDEPEND unsatisfiable >=dev-libs/foo-2",
+				"app-misc/root-1.0::core-kit RDEPEND unsatisfiable dev-libs/foo:3",
+			}),
+		Entry("dependency satisfied by more kits",
+			[]string{"dev-libs/foo"},
+			[]*specs.RepoScanAtom{
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-1.0", "0", "dev-kit"),
+			},
+			[]string{
+				"app-misc/root-1.0::core-kit RDEPEND ambiguous dev-libs/foo" +
+					" [dev-libs/foo-1.0::core-kit dev-libs/foo-1.0::dev-kit]",
+			}),
+		Entry("all the candidates blocked",
+			[]string{"dev-libs/foo", "!<dev-libs/foo-3"},
+			[]*specs.RepoScanAtom{
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "0", "core-kit"),
+			},
+			[]string{
+				"app-misc/root-1.0::core-kit RDEPEND blocker dev-libs/foo" +
+					" [dev-libs/foo-1.0::core-kit dev-libs/foo-2.0::core-kit]",
+			}),
+		Entry("candidates blocked partially",
+			[]string{"dev-libs/foo", "!!<dev-libs/foo-2"},
+			[]*specs.RepoScanAtom{
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "0", "core-kit"),
+			},
+			[]string{}),
+		Entry("dependency to the same package",
+			[]string{"app-misc/root", "!app-misc/root"},
+			[]*specs.RepoScanAtom{},
+			[]string{}),
+	)
+
+	It("checks only the atoms and the kinds in input", func() {
+		root := newTestAtom("app-misc/root-1.0", "0", "core-kit", "dev-libs/missing")
+		root.RelationsByKind["BDEPEND"] = []string{"dev-util/missing"}
+		r := newTestResolver(root,
+			newTestAtom("app-misc/other-1.0", "0", "core-kit", "dev-libs/missing"))
+
+		opts := NewDepsCheckOpts()
+		opts.Kinds = []string{"BDEPEND"}
+		opts.Atoms = []string{"app-misc/root"}
+
+		report, err := r.CheckDependencies(opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getIssues(report)).To(Equal([]string{
+			"app-misc/root-1.0::core-kit BDEPEND unsatisfiable dev-util/missing",
+		}))
+		Expect(report.Stats).To(Equal(&DepsCheckStats{TotAtoms: 1, TotDeps: 1, TotIssues: 1}))
+	})
+
+	It("reports the atoms sorted by package and kit", func() {
+		r := newTestResolver(
+			newTestAtom("dev-libs/b-1.0", "0", "dev-kit", "dev-libs/missing"),
+			newTestAtom("dev-libs/b-1.0", "0", "core-kit", "dev-libs/missing"),
+			newTestAtom("dev-libs/a-1.0", "0", "core-kit", "dev-libs/missing"),
+		)
+
+		report, err := r.CheckDependencies(NewDepsCheckOpts())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(getIssues(report)).To(Equal([]string{
+			"dev-libs/a-1.0::core-kit RDEPEND unsatisfiable dev-libs/missing",
+			"dev-libs/b-1.0::core-kit RDEPEND unsatisfiable dev-libs/missing",
+			"dev-libs/b-1.0::dev-kit RDEPEND unsatisfiable dev-libs/missing",
+		}))
+		Expect(report.Stats).To(Equal(&DepsCheckStats{TotAtoms: 3, TotDeps: 3, TotIssues: 3}))
+	})
+})
@@ -88,6 +88,25 @@ func (m *MergeBot) Clean(specfile string, opts *MergeBotOpts) error {
 			return err
 		}
 
+		// Generate the source kits used to check the reverse dependencies.
+		if opts.CheckReverseDeps && opts.DepsSourcesKits {
+			for _, source := range mkit.Sources {
+				if source.Name == targetKit.Name {
+					continue
+				}
+				sourceDir := filepath.Join(m.GetSourcesDir(), source.Name)
+				if !utils.Exists(sourceDir) {
+					continue
+				}
+				targetFile := filepath.Join(m.GetReposcanDir(), source.Name+"-"+source.Branch)
+				err = m.GenerateKitCacheFile(sourceDir, source.Name, source.Branch,
+					targetFile, eclassDirs, opts.Concurrency, true)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
 	}
 
 	// Setup target resolver
@@ -102,6 +121,22 @@ func (m *MergeBot) Clean(specfile string, opts *MergeBotOpts) error {
 		return err
 	}
 
+	if opts.CheckReverseDeps && len(*candidates) > 0 {
+		m.Logger.InfoC(m.Logger.Aurora.Bold(
+			fmt.Sprintf(":brain:[%s] Checking reverse dependencies...",
+				targetKit.Name)))
+
+		err = m.SetupDepsResolver(mkit, opts, targetKit)
+		if err != nil {
+			return err
+		}
+
+		err = m.keepRequiredAtoms(targetKit, candidates)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(*candidates) > 0 {
 		m.Logger.Info(fmt.Sprintf(":dart:Found %d candidates.",
 			len(*candidates)))
@@ -277,7 +312,7 @@ func (m *MergeBot) cleanAtom(mkit *specs.MergeKit, opts *MergeBotOpts,
 				"/", "_"),
 		)
 
-		prBranchExists, err := BranchExists(kit.Url, prBranchName)
+		prBranchExists, err := BranchExists(m.Config, kit.Url, prBranchName)
 		if err != nil {
 			return err
 		}
@@ -366,12 +401,12 @@ func (m *MergeBot) PushRemoves(mkit *specs.MergeKit, opts *MergeBotOpts,
 	var err error
 	targetKit, _ := mkit.GetTargetKit()
 	kitDir := filepath.Join(m.GetTargetDir(), targetKit.Name)
-	pushOpts := NewPushOptions()
+	pushOpts := NewPushOptions(m.Config)
 	ctx := context.Background()
 
 	if opts.PullRequest {
-		// Setup github client for PR
-		err = m.SetupGithubClient(ctx)
+		// Setup the forge client for PR
+		err = m.SetupForge(ctx, targetKit, opts)
 		if err != nil {
 			return err
 		}
@@ -408,7 +443,7 @@ func (m *MergeBot) PushRemoves(mkit *specs.MergeKit, opts *MergeBotOpts,
 					" * v%s\n", gp.GetPVR())
 			}
 
-			pr, err := CreatePullRequest(m.GithubClient, ctx,
+			pr, err := m.createPullRequest(ctx, opts,
 				// title
 				fmt.Sprintf("mark-devkit: [%s] Purge %s", targetKit.Branch, catpkg),
 				// source branch
@@ -417,10 +452,6 @@ func (m *MergeBot) PushRemoves(mkit *specs.MergeKit, opts *MergeBotOpts,
 				targetKit.Branch,
 				// body
 				body,
-				// github User
-				opts.GithubUser,
-				// github target repository
-				targetKit.Name,
 			)
 
 			if err != nil {
@@ -428,7 +459,7 @@ func (m *MergeBot) PushRemoves(mkit *specs.MergeKit, opts *MergeBotOpts,
 			}
 
 			m.Logger.Info(fmt.Sprintf("[%s] Created correctly PR: %s",
-				catpkg, pr.GetHTMLURL()))
+				catpkg, pr.Url))
 		}
 
 	} else {
@@ -503,26 +534,43 @@ func (m *MergeBot) searchAtom2Clean(atom *specs.MergeKitAtom, mkit *specs.MergeK
 		return ans, err
 	}
 
-	permittedVersions := 5
-	if mkit.Target.AtomDefaults != nil {
-		permittedVersions = *mkit.Target.AtomDefaults.MaxVersions
+	permittedVersions := mkit.GetAtomMaxVersions(atom)
+
+	for _, pkg := range availablesPkgs4Conditions {
+		m.Logger.Debug(fmt.Sprintf(":lollipop:[%s] Found %s.",
+			atom.Package, pkg.Atom))
 	}
 
-	if atom.MaxVersions != nil {
-		permittedVersions = *atom.MaxVersions
+	// The retention window is the same used by the merge
+	// and it's applied for every SLOT if group_by_slot is enabled.
+	groups, err := groupAtomsBySlot(availablesPkgs4Conditions, mkit.AtomGroupBySlot(atom))
+	if err != nil {
+		return ans, err
 	}
-	if permittedVersions < 1 {
-		permittedVersions = 1
+
+	for _, group := range groups {
+		ans = append(ans, m.getGroupAtoms2Clean(atom, group, permittedVersions)...)
 	}
 
-	for _, pkg := range availablesPkgs4Conditions {
-		fmt.Println(pkg.Atom)
+	keepNewerThan, err := mkit.GetAtomKeepNewerThan(atom)
+	if err != nil {
+		return ans, err
 	}
+	if keepNewerThan > 0 && len(ans) > 0 {
+		ans, err = m.filterNewerAtoms(mkit, atom, ans, keepNewerThan)
+	}
+
+	return ans, err
+}
+
+func (m *MergeBot) getGroupAtoms2Clean(atom *specs.MergeKitAtom,
+	availablesPkgs4Conditions []*specs.RepoScanAtom,
+	permittedVersions int) []*specs.RepoScanAtom {
+	ans := []*specs.RepoScanAtom{}
 
 	availablesPkgs := len(availablesPkgs4Conditions)
 
 	if availablesPkgs > permittedVersions {
-
 		packages2Remove := availablesPkgs - permittedVersions
 
 		aidx := 0
@@ -567,5 +615,5 @@ func (m *MergeBot) searchAtom2Clean(atom *specs.MergeKitAtom, mkit *specs.MergeK
 
 	} // else nothing to do
 
-	return ans, nil
+	return ans
 }
@@ -0,0 +1,410 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+const (
+	// The version is required by other packages.
+	CleanKeepDependency = "dependency"
+	// The version is added to the kit recently.
+	CleanKeepNewerThan = "keep_newer_than"
+)
+
+// CleanKeptAtom describes a version outside the retention window
+// that is not removed by the clean.
+type CleanKeptAtom struct {
+	Atom       string   `json:"atom" yaml:"atom"`
+	Reason     string   `json:"reason" yaml:"reason"`
+	RequiredBy []string `json:"required_by,omitempty" yaml:"required_by,omitempty"`
+	Date       string   `json:"date,omitempty" yaml:"date,omitempty"`
+}
+
+// GetKeptAtoms returns the versions not removed by the clean.
+func (m *MergeBot) GetKeptAtoms() map[string]*CleanKeptAtom { return m.keptAtoms }
+
+// SetupDepsResolver prepares the resolver with the atoms of the target
+// kit and of the other kits used to check the reverse dependencies.
+func (m *MergeBot) SetupDepsResolver(mkit *specs.MergeKit,
+	opts *MergeBotOpts, targetKit *specs.ReposcanKit) error {
+
+	var err error
+
+	m.DepsResolver = NewRepoScanResolver(m.Config)
+	m.DepsResolver.JsonSources, err = m.getDepsKitCacheFiles(mkit, opts, targetKit)
+	if err != nil {
+		return err
+	}
+
+	err = m.DepsResolver.LoadJsonFiles(opts.Verbose)
+	if err != nil {
+		return err
+	}
+
+	// The target kit is loaded from the kit-cache file generated
+	// from the cloned kit. The others files of the target kit
+	// could be not updated.
+	sources := []specs.RepoScanSpec{m.DepsResolver.Sources[0]}
+	for _, source := range m.DepsResolver.Sources[1:] {
+		isTarget := false
+		for _, atom := range source.Atoms {
+			isTarget = atom.Kit == targetKit.Name
+			break
+		}
+
+		if isTarget {
+			m.Logger.Debug(fmt.Sprintf(
+				"Skipping kit-cache file %s of the target kit.", source.File))
+			continue
+		}
+		sources = append(sources, source)
+	}
+	m.DepsResolver.Sources = sources
+
+	err = m.DepsResolver.BuildMap()
+	if err != nil {
+		return err
+	}
+
+	return m.DepsResolver.BuildReverseIndex()
+}
+
+// getDepsKitCacheFiles returns the kit-cache files used to check the
+// reverse dependencies. The first file is always the target kit. By default
+// the source kits of the specfile and the sibling kits, the kit-cache files
+// available on the reposcan dir, are used too.
+func (m *MergeBot) getDepsKitCacheFiles(mkit *specs.MergeKit,
+	opts *MergeBotOpts, targetKit *specs.ReposcanKit) ([]string, error) {
+
+	reposcanDir := m.GetReposcanDir()
+	ans := []string{
+		filepath.Join(reposcanDir, "target-"+targetKit.Name+"-"+targetKit.Branch),
+	}
+	mFiles := map[string]bool{filepath.Clean(ans[0]): true}
+
+	addFile := func(f string) {
+		if _, present := mFiles[filepath.Clean(f)]; present {
+			return
+		}
+		mFiles[filepath.Clean(f)] = true
+		ans = append(ans, f)
+	}
+
+	if opts.DepsSourcesKits {
+		for _, source := range mkit.Sources {
+			if source.Name == targetKit.Name {
+				continue
+			}
+			f := filepath.Join(reposcanDir, source.Name+"-"+source.Branch)
+			if !utils.Exists(f) {
+				m.Logger.Warning(fmt.Sprintf(
+					"Kit-cache file %s of the source kit %s not found. Skipped.",
+					f, source.Name))
+				continue
+			}
+			addFile(f)
+		}
+
+		entries, err := os.ReadDir(reposcanDir)
+		if err != nil {
+			return nil, fmt.Errorf("error on reading dir %s: %s",
+				reposcanDir, err.Error())
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), "target-") {
+				continue
+			}
+			addFile(filepath.Join(reposcanDir, entry.Name()))
+		}
+	}
+
+	for _, f := range opts.DepsKitCacheFiles {
+		addFile(f)
+	}
+
+	return ans, nil
+}
+
+// keepRequiredAtoms removes from the candidates the versions that are
+// the only versions that satisfy the dependencies of other packages.
+func (m *MergeBot) keepRequiredAtoms(targetKit *specs.ReposcanKit,
+	candidates *map[string]*specs.RepoScanAtom) error {
+
+	// A kept version could require versions of other packages.
+	// The check is repeated until nothing changes.
+	for changed := true; changed; {
+		changed = false
+
+		candidates4PN := make(map[string][]*specs.RepoScanAtom, 0)
+		for _, candidate := range *candidates {
+			candidates4PN[candidate.CatPkg] = append(
+				candidates4PN[candidate.CatPkg], candidate)
+		}
+
+		catpkgs := []string{}
+		for catpkg := range candidates4PN {
+			catpkgs = append(catpkgs, catpkg)
+		}
+		sort.Strings(catpkgs)
+
+		for _, catpkg := range catpkgs {
+			kept, err := m.keepRequiredVersions(targetKit, catpkg,
+				candidates4PN[catpkg], candidates)
+			if err != nil {
+				return err
+			}
+			changed = changed || kept
+		}
+	}
+
+	return nil
+}
+
+func (m *MergeBot) keepRequiredVersions(targetKit *specs.ReposcanKit,
+	catpkg string, pkgCandidates []*specs.RepoScanAtom,
+	candidates *map[string]*specs.RepoScanAtom) (bool, error) {
+	ans := false
+
+	// The versions that will be available after the clean.
+	remaining := []*gentoo.GentooPackage{}
+	for _, atom := range m.TargetResolver.Map[catpkg] {
+		if _, toRemove := (*candidates)[atom.Atom]; toRemove {
+			continue
+		}
+		gp, err := atom.ToGentooPackage()
+		if err != nil {
+			return false, err
+		}
+		remaining = append(remaining, gp)
+	}
+
+	for _, rdep := range m.DepsResolver.ReverseMap[catpkg] {
+		if rdep.Kit == targetKit.Name {
+			if _, toRemove := (*candidates)[rdep.Atom]; toRemove {
+				// The package that requires the version is removed too.
+				continue
+			}
+		}
+		requiredBy := fmt.Sprintf("%s::%s (%s %s)",
+			rdep.Atom, rdep.Kit, rdep.Kind, rdep.Dependency)
+
+		satisfied := false
+		for _, gp := range remaining {
+			if admit, _ := rdep.Package.Admit(gp); admit {
+				satisfied = true
+				if kept, present := m.keptAtoms[fmt.Sprintf("%s/%s", gp.Category, gp.GetPF())]; present &&
+					kept.Reason == CleanKeepDependency {
+					kept.RequiredBy = append(kept.RequiredBy, requiredBy)
+				}
+				break
+			}
+		}
+		if satisfied {
+			continue
+		}
+
+		// Keep the last version that satisfies the dependency.
+		var best *specs.RepoScanAtom
+		var bestGp *gentoo.GentooPackage
+		for _, candidate := range pkgCandidates {
+			if _, toRemove := (*candidates)[candidate.Atom]; !toRemove {
+				continue
+			}
+			gp, err := candidate.ToGentooPackage()
+			if err != nil {
+				return false, err
+			}
+			if admit, _ := rdep.Package.Admit(gp); !admit {
+				continue
+			}
+			if bestGp != nil {
+				if greater, _ := gp.GreaterThan(bestGp); !greater {
+					continue
+				}
+			}
+			best = candidate
+			bestGp = gp
+		}
+
+		if best == nil {
+			// The dependency is already broken.
+			continue
+		}
+
+		delete(*candidates, best.Atom)
+		remaining = append(remaining, bestGp)
+		m.keptAtoms[best.Atom] = &CleanKeptAtom{
+			Atom:       best.Atom,
+			Reason:     CleanKeepDependency,
+			RequiredBy: []string{requiredBy},
+		}
+		ans = true
+
+		m.Logger.InfoC(fmt.Sprintf(
+			":link:[%s] Version %s required by %s. It will not be removed.",
+			catpkg, bestGp.GetPVR(), requiredBy))
+	}
+
+	return ans, nil
+}
+
+// filterNewerAtoms removes from the candidates the versions added
+// to the target kit after the age defined in input.
+func (m *MergeBot) filterNewerAtoms(mkit *specs.MergeKit, atom *specs.MergeKitAtom,
+	candidates []*specs.RepoScanAtom, age time.Duration) ([]*specs.RepoScanAtom, error) {
+	ans := []*specs.RepoScanAtom{}
+
+	kit, _ := mkit.GetTargetKit()
+	kitDir := filepath.Join(m.GetTargetDir(), kit.Name)
+
+	if m.ebuildsDates == nil {
+		repo, err := git.PlainOpen(kitDir)
+		if err != nil {
+			return nil, fmt.Errorf("error on open repository %s: %s",
+				kitDir, err.Error())
+		}
+
+		shallows, err := repo.Storer.Shallow()
+		if err != nil {
+			return nil, fmt.Errorf("error on read shallow commits of %s: %s",
+				kitDir, err.Error())
+		}
+		if len(shallows) > 0 {
+			m.Logger.Warning(fmt.Sprintf(
+				"The repository %s is a shallow clone. The versions added before the first commit available are not kept by keep_newer_than.",
+				kitDir))
+		}
+
+		m.ebuildsDates, err = getEbuildsAddDate(repo)
+		if err != nil {
+			return nil, fmt.Errorf("error on read history of %s: %s",
+				kitDir, err.Error())
+		}
+	}
+
+	for _, candidate := range candidates {
+		gp, err := candidate.ToGentooPackage()
+		if err != nil {
+			return nil, err
+		}
+
+		ebuild := path.Join(candidate.CatPkg, gp.GetPF()+".ebuild")
+		date := m.ebuildsDates[ebuild]
+
+		if !date.IsZero() && time.Since(date) < age {
+			m.keptAtoms[candidate.Atom] = &CleanKeptAtom{
+				Atom:   candidate.Atom,
+				Reason: CleanKeepNewerThan,
+				Date:   date.Format(time.RFC3339),
+			}
+
+			m.Logger.InfoC(fmt.Sprintf(
+				":hatching_chick:[%s] Version %s added on %s. It will not be removed.",
+				atom.Package, gp.GetPVR(), date.Format(time.DateOnly)))
+			continue
+		}
+
+		ans = append(ans, candidate)
+	}
+
+	return ans, nil
+}
+
+// getEbuildsAddDate walks the history of the repository once and returns
+// the date of the oldest commit available that adds every ebuild.
+// The merge commits are compared only with the first parent. On shallow
+// repositories the commits before the shallow boundary are not available
+// and the ebuilds added before the boundary are not present: the ebuilds
+// only modified after the boundary are not reported as recent.
+func getEbuildsAddDate(repo *git.Repository) (map[string]time.Time, error) {
+	ans := make(map[string]time.Time, 0)
+
+	shallows, err := repo.Storer.Shallow()
+	if err != nil {
+		return ans, err
+	}
+	mShallows := make(map[plumbing.Hash]bool, len(shallows))
+	for _, h := range shallows {
+		mShallows[h] = true
+	}
+
+	iter, err := repo.Log(&git.LogOptions{Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return ans, err
+	}
+	defer iter.Close()
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if _, isShallow := mShallows[c.Hash]; isShallow {
+			return nil
+		}
+
+		tree, err := c.Tree()
+		if err != nil {
+			return err
+		}
+
+		var parentTree *object.Tree
+		if c.NumParents() > 0 {
+			parent, err := c.Parent(0)
+			if err != nil {
+				return err
+			}
+			parentTree, err = parent.Tree()
+			if err != nil {
+				return err
+			}
+		}
+
+		changes, err := object.DiffTree(parentTree, tree)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			action, err := change.Action()
+			if err != nil {
+				return err
+			}
+			if action != merkletrie.Insert {
+				continue
+			}
+			name := change.To.Name
+			if !strings.HasSuffix(name, ".ebuild") {
+				continue
+			}
+			// The merged branches could contain older commits.
+			if date, present := ans[name]; !present || c.Committer.When.Before(date) {
+				ans[name] = c.Committer.When
+			}
+		}
+
+		return nil
+	})
+	// The errors on reach the shallow boundary are ignored.
+	if err != nil && !(len(shallows) > 0 && errors.Is(err, plumbing.ErrObjectNotFound)) {
+		return ans, err
+	}
+
+	return ans, nil
+}
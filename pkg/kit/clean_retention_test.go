@@ -0,0 +1,73 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clean retention", func() {
+
+	var repo *git.Repository
+	var commits []plumbing.Hash
+
+	day := func(d int) time.Time {
+		return time.Date(2026, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		var err error
+		repo, err = git.PlainInit(dir, false)
+		Expect(err).ToNot(HaveOccurred())
+		wt, err := repo.Worktree()
+		Expect(err).ToNot(HaveOccurred())
+
+		commits = []plumbing.Hash{}
+		commit := func(d int, files map[string]string) {
+			for f, content := range files {
+				Expect(os.MkdirAll(filepath.Join(dir, filepath.Dir(f)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, f), []byte(content), 0644)).To(Succeed())
+				_, err := wt.Add(f)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			sig := &object.Signature{Name: "test", Email: "test@example.org", When: day(d)}
+			h, err := wt.Commit("commit", &git.CommitOptions{Author: sig, Committer: sig})
+			Expect(err).ToNot(HaveOccurred())
+			commits = append(commits, h)
+		}
+
+		commit(1, map[string]string{"dev-libs/foo/foo-1.0.ebuild": "1"})
+		commit(2, map[string]string{"dev-libs/foo/foo-1.0.ebuild": "2"})
+		commit(3, map[string]string{"dev-libs/foo/foo-2.0.ebuild": "1"})
+	})
+
+	It("reads the date of the commit that adds the ebuilds", func() {
+		dates, err := GetEbuildsAddDate(repo)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dates).To(HaveLen(2))
+		Expect(dates["dev-libs/foo/foo-1.0.ebuild"].Equal(day(1))).To(BeTrue())
+		Expect(dates["dev-libs/foo/foo-2.0.ebuild"].Equal(day(3))).To(BeTrue())
+	})
+
+	It("ignores the ebuilds only modified after the shallow boundary", func() {
+		Expect(repo.Storer.SetShallow(commits[:1])).To(Succeed())
+
+		dates, err := GetEbuildsAddDate(repo)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dates).To(HaveLen(1))
+		Expect(dates["dev-libs/foo/foo-2.0.ebuild"].Equal(day(3))).To(BeTrue())
+	})
+})
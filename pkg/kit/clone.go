@@ -9,12 +9,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/macaroni-os/mark-devkit/pkg/logger"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/go-git/go-git/v5"
+	git_config "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/storer"
@@ -24,6 +26,8 @@ import (
 )
 
 type CloneOptions struct {
+	// The config with the credentials of the remotes.
+	Config          *specs.MarkDevkitConfig
 	GitCloneOptions *git.CloneOptions
 	Verbose         bool
 	Summary         bool
@@ -101,6 +105,12 @@ func Clone(k *specs.ReposcanKit, targetdir string, o *CloneOptions) error {
 	opts := *o.GitCloneOptions
 
 	opts.URL = k.Url
+	if opts.Auth == nil {
+		opts.Auth, err = getFetchAuth(o.Config, k.Url)
+		if err != nil {
+			return err
+		}
+	}
 	if k.Branch != "" {
 		branchRefName := plumbing.NewBranchReferenceName(k.Branch)
 		opts.ReferenceName = plumbing.ReferenceName(branchRefName)
@@ -126,6 +136,7 @@ func Clone(k *specs.ReposcanKit, targetdir string, o *CloneOptions) error {
 			RemoteName:    opts.RemoteName,
 			ReferenceName: opts.ReferenceName,
 			Depth:         opts.Depth,
+			Auth:          opts.Auth,
 		}
 
 		r, err = git.PlainOpen(targetdir)
@@ -236,9 +247,15 @@ func Clone(k *specs.ReposcanKit, targetdir string, o *CloneOptions) error {
 	return nil
 }
 
-func BranchExists(remoteUrl, branchName string) (bool, error) {
+func BranchExists(c *specs.MarkDevkitConfig, remoteUrl, branchName string) (bool, error) {
+	auth, err := getFetchAuth(c, remoteUrl)
+	if err != nil {
+		return false, err
+	}
+
 	repo, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
 		URL:          remoteUrl,
+		Auth:         auth,
 		SingleBranch: false,
 		NoCheckout:   true,
 		Depth:        1,
@@ -271,6 +288,43 @@ func BranchExists(remoteUrl, branchName string) (bool, error) {
 	return present, err
 }
 
+// ListRemoteBranches returns the branches of the remote repository
+// with the prefix in input and the hash of the last commit.
+func ListRemoteBranches(c *specs.MarkDevkitConfig, remoteUrl, prefix string) (map[string]plumbing.Hash, error) {
+	ans := make(map[string]plumbing.Hash, 0)
+
+	remote := git.NewRemote(memory.NewStorage(), &git_config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{remoteUrl},
+	})
+
+	auth, err := getFetchAuth(c, remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := remote.List(&git.ListOptions{Auth: auth})
+	if err != nil {
+		if err == transport.ErrEmptyRemoteRepository {
+			return ans, nil
+		}
+		return nil, fmt.Errorf("error on list branches of %s: %s",
+			remoteUrl, err.Error())
+	}
+
+	for _, ref := range refs {
+		if !ref.Name().IsBranch() {
+			continue
+		}
+		branch := ref.Name().Short()
+		if strings.HasPrefix(branch, prefix) {
+			ans[branch] = ref.Hash()
+		}
+	}
+
+	return ans, nil
+}
+
 func CloneAndCreateBranch(k *specs.ReposcanKit,
 	targetdir string, o *CloneOptions) error {
 	var r *git.Repository
@@ -283,6 +337,12 @@ func CloneAndCreateBranch(k *specs.ReposcanKit,
 	if k.Branch == "" {
 		return fmt.Errorf("No branch specified for cloning and create")
 	}
+	if opts.Auth == nil {
+		opts.Auth, err = getFetchAuth(o.Config, k.Url)
+		if err != nil {
+			return err
+		}
+	}
 
 	if utils.Exists(targetdir) {
 		err := os.RemoveAll(targetdir)
@@ -0,0 +1,77 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"time"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// SearchDependencies exposes the dependencies walk to the tests
+// using the candidates as roots.
+func (m *MergeBot) SearchDependencies(mkit *specs.MergeKit,
+	candidates []*specs.RepoScanAtom,
+	withDeps *specs.MergeKitWithDeps) ([]*specs.RepoScanAtom, error) {
+	roots := []*mergeDepNode{}
+	for _, candidate := range candidates {
+		roots = append(roots, &mergeDepNode{
+			Atom:     candidate,
+			WithDeps: withDeps,
+			Depth:    0,
+		})
+	}
+	return m.searchDependencies(mkit, roots, candidates)
+}
+
+// MergeAutoEclasses exposes the auto eclasses merge to the tests.
+func (m *MergeBot) MergeAutoEclasses(mkit *specs.MergeKit,
+	candidates []*specs.RepoScanAtom, targetKitDir string,
+	auto *specs.MergeKitEclassesAuto) (map[string]string, error) {
+	ans := make(map[string]string, 0)
+	err := m.mergeAutoEclasses(mkit, candidates, targetKitDir, auto, &ans)
+	return ans, err
+}
+
+// GetPushAuth exposes the push authentication to the tests.
+func GetPushAuth(remoteUrl string, opts *PushOptions) (transport.AuthMethod, error) {
+	return getPushAuth(remoteUrl, opts)
+}
+
+// IsCandidate2Merge exposes the check of the candidates to the tests.
+func (m *MergeBot) IsCandidate2Merge(atom *specs.MergeKitAtom,
+	candidate *specs.RepoScanAtom, onlyNewer bool) (bool, error) {
+	return m.isCandidate2Merge(atom, candidate, onlyNewer)
+}
+
+// AddAtom4Commit registers the files of the merged atom as done
+// by the merge.
+func (m *MergeBot) AddAtom4Commit(atom *specs.RepoScanAtom, files ...string) {
+	m.files4Commit[atom.Atom] = files
+	m.atoms4Commit[atom.Atom] = atom
+}
+
+// GetBumpBranches exposes the PR branches of the bumps to the tests.
+func (m *MergeBot) GetBumpBranches(kit *specs.ReposcanKit,
+	opts *MergeBotOpts) map[string][]string {
+	return m.getBumpBranches(kit, opts)
+}
+
+// GetAggregatedPrBody exposes the body of the aggregated PRs to the tests.
+func (m *MergeBot) GetAggregatedPrBody(mkit *specs.MergeKit, kit *specs.ReposcanKit,
+	atoms, updates []string) (string, error) {
+	return m.getAggregatedPrBody(mkit, kit, &aggregatedPr{
+		Atoms:   atoms,
+		Updates: updates,
+	})
+}
+
+// GetEbuildsAddDate exposes the dates of the ebuilds to the tests.
+func GetEbuildsAddDate(repo *git.Repository) (map[string]time.Time, error) {
+	return getEbuildsAddDate(repo)
+}
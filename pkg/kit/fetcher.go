@@ -21,6 +21,8 @@ type Fetcher interface {
 	GetDownloadDir() string
 	GetFilePath(f string) string
 	GetFilesList() ([]string, error)
+	GetFileMetadata(target string) (*specs.RepoScanFile, error)
+	GetFile(target, to string) error
 	GetType() string
 	GetStats() *AtomsStats
 	GetAtomsInError() *[]*AtomError
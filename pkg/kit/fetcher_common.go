@@ -152,6 +152,7 @@ func (f *FetcherCommon) GetAtomsInError() *[]*AtomError {
 
 func (f *FetcherCommon) PrepareSourcesKits(mkit *specs.DistfilesSpec, opts *FetchOpts) error {
 	gitOpts := &CloneOptions{
+		Config: f.Config,
 		GitCloneOptions: &git.CloneOptions{
 			SingleBranch: true,
 			RemoteName:   "origin",
@@ -56,6 +56,26 @@ func (f *FetcherDir) GetFilesList() ([]string, error) {
 	return ans, nil
 }
 
+func (f *FetcherDir) GetFile(target, to string) error {
+	return fmt.Errorf("Not implemented for this backend")
+}
+
+func (f *FetcherDir) GetFileMetadata(target string) (*specs.RepoScanFile, error) {
+	hashes, err := helpers.GetFileHashes(f.GetFilePath(target))
+	if err != nil {
+		return nil, err
+	}
+
+	return &specs.RepoScanFile{
+		Name: target,
+		Size: fmt.Sprintf("%d", hashes.Size()),
+		Hashes: map[string]string{
+			"sha512":  hashes.Sha512(),
+			"blake2b": hashes.Blake2b(),
+		},
+	}, nil
+}
+
 func (f *FetcherDir) GetFilePath(target string) string {
 	return filepath.Join(f.GetDownloadDir(), target)
 }
@@ -511,6 +511,40 @@ func (f *FetcherS3) GetFilePath(target string) string {
 	return ans
 }
 
+func (f *FetcherS3) GetFile(target, to string) error {
+	return f.GetFileFromObjectStorage(f.GetFilePath(target), to)
+}
+
+// GetFileMetadata returns the size and the hashes stored as
+// user metadata of the object.
+func (f *FetcherS3) GetFileMetadata(target string) (*specs.RepoScanFile, error) {
+	objectInfo, err := f.MinioClient.StatObject(
+		context.Background(), f.Bucket, f.GetFilePath(target),
+		minio.StatObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	ans := &specs.RepoScanFile{
+		Name:   target,
+		Size:   fmt.Sprintf("%d", objectInfo.Size),
+		Hashes: make(map[string]string, 0),
+	}
+	// S3 Object add upper case to the first char of the key.
+	for k, v := range objectInfo.UserMetadata {
+		switch strings.ToLower(k) {
+		case "sha512", "blake2b":
+			ans.Hashes[strings.ToLower(k)] = v
+		}
+	}
+
+	if len(ans.Hashes) == 0 {
+		return nil, fmt.Errorf("file %s without hashes metadata", target)
+	}
+
+	return ans, nil
+}
+
 func (f *FetcherS3) SyncFile(name, source, target string, hashes *map[string]string) error {
 	atom := &specs.RepoScanAtom{
 		Atom: name,
@@ -0,0 +1,193 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+)
+
+const (
+	ForgeGithub  = "github"
+	ForgeForgejo = "forgejo"
+	ForgeGitea   = "gitea"
+	ForgeGitlab  = "gitlab"
+)
+
+// ForgePullRequest describes a pull request of GitHub and Forgejo/Gitea
+// or a merge request of GitLab.
+type ForgePullRequest struct {
+	Number       int64
+	Url          string
+	SourceBranch string
+	TargetBranch string
+}
+
+type ForgePullRequestOpts struct {
+	Title        string
+	Body         string
+	SourceBranch string
+	TargetBranch string
+	Labels       []string
+}
+
+// Forge is the interface of the git services used to open the pull
+// requests on the repository of a kit.
+type Forge interface {
+	GetType() string
+	// GetPullRequest returns the open pull request between the branches
+	// or nil if not present.
+	GetPullRequest(ctx context.Context, sourceBranch, targetBranch string) (*ForgePullRequest, error)
+	CreatePullRequest(ctx context.Context, opts *ForgePullRequestOpts) (*ForgePullRequest, error)
+	// UpdatePullRequest updates the title and the body of the pull request.
+	UpdatePullRequest(ctx context.Context, pr *ForgePullRequest, opts *ForgePullRequestOpts) error
+	// ClosePullRequest closes the pull request with the comment in input.
+	ClosePullRequest(ctx context.Context, pr *ForgePullRequest, comment string) error
+}
+
+// ParseRemoteUrl returns the host, the owner and the name of the
+// repository of a git url in the HTTP or in the SCP-like format.
+func ParseRemoteUrl(remoteUrl string) (string, string, string, error) {
+	var host, path string
+
+	if strings.Contains(remoteUrl, "://") {
+		uri, err := url.Parse(remoteUrl)
+		if err != nil {
+			return "", "", "", fmt.Errorf("error on parse url %s: %s",
+				remoteUrl, err.Error())
+		}
+		host = uri.Host
+		path = uri.Path
+	} else if idx := strings.Index(remoteUrl, ":"); idx > 0 {
+		// Ex: git@github.com:macaroni-os/core-kit.git
+		host = remoteUrl[0:idx]
+		if at := strings.Index(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path = remoteUrl[idx+1:]
+	} else {
+		return "", "", "", fmt.Errorf("invalid git url %s", remoteUrl)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	idx := strings.LastIndex(path, "/")
+	if idx < 0 || host == "" {
+		return "", "", "", fmt.Errorf("invalid git url %s", remoteUrl)
+	}
+
+	return host, path[0:idx], path[idx+1:], nil
+}
+
+// GetForgeType returns the type of the forge of the host from the
+// authentication remote or from the well-known hosts.
+func GetForgeType(c *specs.MarkDevkitConfig, host string) (string, error) {
+	remote, present := c.GetAuthentication().GetRemote(host)
+	if present && remote.Forge != "" {
+		switch remote.Forge {
+		case ForgeGithub, ForgeGitlab, ForgeForgejo:
+			return remote.Forge, nil
+		case ForgeGitea:
+			// The Forgejo API is compatible with Gitea.
+			return ForgeForgejo, nil
+		default:
+			return "", fmt.Errorf("invalid forge %s for the remote %s",
+				remote.Forge, host)
+		}
+	}
+
+	switch host {
+	case "github.com":
+		return ForgeGithub, nil
+	case "gitlab.com":
+		return ForgeGitlab, nil
+	case "codeberg.org":
+		return ForgeForgejo, nil
+	}
+
+	return "", fmt.Errorf(
+		"unable to detect the forge of the host %s. Define the forge of the remote.",
+		host)
+}
+
+// GetKitForgeType returns the type of the forge of the git url of a kit.
+func GetKitForgeType(c *specs.MarkDevkitConfig, kitUrl string) (string, error) {
+	host, _, _, err := ParseRemoteUrl(kitUrl)
+	if err != nil {
+		return "", err
+	}
+	return GetForgeType(c, host)
+}
+
+// getForgeToken returns the token of the remote or the token
+// defined in the environment variable of the forge.
+func getForgeToken(c *specs.MarkDevkitConfig, host, forgeType string) string {
+	remote, present := c.GetAuthentication().GetRemote(host)
+	if present && remote.Token != "" {
+		return remote.Token
+	}
+
+	switch forgeType {
+	case ForgeGitlab:
+		return os.Getenv("GITLAB_TOKEN")
+	case ForgeForgejo:
+		if token := os.Getenv("FORGEJO_TOKEN"); token != "" {
+			return token
+		}
+		return os.Getenv("GITEA_TOKEN")
+	case ForgeGithub:
+		return os.Getenv("GITHUB_TOKEN")
+	default:
+		return ""
+	}
+}
+
+// getForgeApiUrl returns the API url of the forge of the host.
+func getForgeApiUrl(c *specs.MarkDevkitConfig, host string) string {
+	remote, present := c.GetAuthentication().GetRemote(host)
+	if present && remote.Url != "" {
+		return strings.TrimSuffix(remote.Url, "/")
+	}
+	return "https://" + host
+}
+
+// NewForge returns the forge of the repository of the url in input.
+// The owner overrides the owner of the repository if not empty.
+func NewForge(ctx context.Context, c *specs.MarkDevkitConfig,
+	remoteUrl, owner string) (Forge, error) {
+
+	host, repoOwner, repo, err := ParseRemoteUrl(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" {
+		owner = repoOwner
+	}
+
+	forgeType, err := GetForgeType(c, host)
+	if err != nil {
+		return nil, err
+	}
+
+	token := getForgeToken(c, host, forgeType)
+
+	switch forgeType {
+	case ForgeGitlab:
+		apiVersion := "4"
+		if remote, present := c.GetAuthentication().GetRemote(host); present &&
+			remote.ApiVersion != "" {
+			apiVersion = remote.ApiVersion
+		}
+		return NewGitlabForge(getForgeApiUrl(c, host), apiVersion, token, owner, repo)
+	case ForgeForgejo:
+		return NewForgejoForge(getForgeApiUrl(c, host), token, owner, repo)
+	default:
+		return NewGithubForge(ctx, token, owner, repo)
+	}
+}
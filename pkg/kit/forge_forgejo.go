@@ -0,0 +1,206 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/macaroni-os/mark-devkit/pkg/logger"
+
+	client "code.forgejo.org/f3/gof3/v3/forges/forgejo/sdk"
+)
+
+// ForgejoForge supports the pull requests of Forgejo and Gitea.
+type ForgejoForge struct {
+	Client *client.Client
+	Owner  string
+	Repo   string
+}
+
+func NewForgejoForge(host, token, owner, repo string) (*ForgejoForge, error) {
+	// Skip the check of the server version not needed for the
+	// pull requests API.
+	opts := []client.ClientOption{client.SetGiteaVersion("")}
+	if token != "" {
+		opts = append(opts, client.SetToken(token))
+	}
+
+	c, err := client.NewClient(host, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error on setup forgejo client for host %s: %s",
+			host, err.Error())
+	}
+
+	return &ForgejoForge{
+		Client: c,
+		Owner:  owner,
+		Repo:   repo,
+	}, nil
+}
+
+func (f *ForgejoForge) GetType() string { return ForgeForgejo }
+
+func (f *ForgejoForge) GetPullRequest(ctx context.Context,
+	sourceBranch, targetBranch string) (*ForgePullRequest, error) {
+
+	f.Client.SetContext(ctx)
+
+	opts := client.ListPullRequestsOptions{
+		ListOptions: client.ListOptions{Page: 1, PageSize: 50},
+		State:       client.StateOpen,
+	}
+
+	for {
+		prs, _, err := f.Client.ListRepoPullRequests(f.Owner, f.Repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error on retrieve pull requests of %s/%s: %s",
+				f.Owner, f.Repo, err.Error())
+		}
+
+		for _, pr := range prs {
+			if pr.Head != nil && pr.Head.Ref == sourceBranch &&
+				pr.Base != nil && pr.Base.Ref == targetBranch {
+				return forgejoPr2ForgePr(pr), nil
+			}
+		}
+
+		if len(prs) < opts.PageSize {
+			break
+		}
+		opts.Page++
+	}
+
+	return nil, nil
+}
+
+func (f *ForgejoForge) CreatePullRequest(ctx context.Context,
+	opts *ForgePullRequestOpts) (*ForgePullRequest, error) {
+
+	f.Client.SetContext(ctx)
+
+	labels, err := f.getLabelsIds(opts.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, _, err := f.Client.CreatePullRequest(f.Owner, f.Repo,
+		client.CreatePullRequestOption{
+			Head:   opts.SourceBranch,
+			Base:   opts.TargetBranch,
+			Title:  opts.Title,
+			Body:   opts.Body,
+			Labels: labels,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return forgejoPr2ForgePr(pr), nil
+}
+
+func (f *ForgejoForge) UpdatePullRequest(ctx context.Context,
+	pr *ForgePullRequest, opts *ForgePullRequestOpts) error {
+
+	f.Client.SetContext(ctx)
+
+	// The pull requests are issues for the API.
+	_, _, err := f.Client.EditIssue(f.Owner, f.Repo, pr.Number,
+		client.EditIssueOption{
+			Title: opts.Title,
+			Body:  &opts.Body,
+		})
+	if err != nil {
+		return fmt.Errorf("error on update PR %s: %s", pr.Url, err.Error())
+	}
+
+	return nil
+}
+
+func (f *ForgejoForge) ClosePullRequest(ctx context.Context,
+	pr *ForgePullRequest, comment string) error {
+
+	f.Client.SetContext(ctx)
+
+	if comment != "" {
+		_, _, err := f.Client.CreateIssueComment(f.Owner, f.Repo, pr.Number,
+			client.CreateIssueCommentOption{Body: comment})
+		if err != nil {
+			return fmt.Errorf("error on add comment to PR %s: %s",
+				pr.Url, err.Error())
+		}
+	}
+
+	state := client.StateClosed
+	_, _, err := f.Client.EditIssue(f.Owner, f.Repo, pr.Number,
+		client.EditIssueOption{State: &state})
+	if err != nil {
+		return fmt.Errorf("error on close PR %s: %s", pr.Url, err.Error())
+	}
+
+	return nil
+}
+
+// getLabelsIds returns the ids of the labels of the repository.
+// The labels not available are skipped.
+func (f *ForgejoForge) getLabelsIds(names []string) ([]int64, error) {
+	ans := []int64{}
+
+	if len(names) == 0 {
+		return ans, nil
+	}
+
+	labels := []*client.Label{}
+	opts := client.ListLabelsOptions{
+		ListOptions: client.ListOptions{Page: 1, PageSize: 50},
+	}
+
+	for {
+		page, _, err := f.Client.ListRepoLabels(f.Owner, f.Repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("error on retrieve labels of %s/%s: %s",
+				f.Owner, f.Repo, err.Error())
+		}
+		labels = append(labels, page...)
+
+		if len(page) < opts.PageSize {
+			break
+		}
+		opts.Page++
+	}
+
+	for _, name := range names {
+		found := false
+		for _, label := range labels {
+			if label.Name == name {
+				ans = append(ans, label.ID)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			logger.GetDefaultLogger().Warning(fmt.Sprintf(
+				"Label %s not available on %s/%s. Skipped.",
+				name, f.Owner, f.Repo))
+		}
+	}
+
+	return ans, nil
+}
+
+func forgejoPr2ForgePr(pr *client.PullRequest) *ForgePullRequest {
+	ans := &ForgePullRequest{
+		Number: pr.Index,
+		Url:    pr.HTMLURL,
+	}
+	if pr.Head != nil {
+		ans.SourceBranch = pr.Head.Ref
+	}
+	if pr.Base != nil {
+		ans.TargetBranch = pr.Base.Ref
+	}
+	return ans
+}
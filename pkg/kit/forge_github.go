@@ -0,0 +1,136 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v74/github"
+	"golang.org/x/oauth2"
+)
+
+type GithubForge struct {
+	Client *github.Client
+	Owner  string
+	Repo   string
+}
+
+func NewGithubForge(ctx context.Context, token, owner, repo string) (*GithubForge, error) {
+	if token == "" {
+		return nil, fmt.Errorf("Missing github token! Pull request interrupted!")
+	}
+
+	ts := oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: token,
+	})
+	tc := oauth2.NewClient(ctx, ts)
+
+	return NewGithubForgeWithClient(github.NewClient(tc), owner, repo), nil
+}
+
+func NewGithubForgeWithClient(client *github.Client, owner, repo string) *GithubForge {
+	return &GithubForge{
+		Client: client,
+		Owner:  owner,
+		Repo:   repo,
+	}
+}
+
+func (f *GithubForge) GetType() string { return ForgeGithub }
+
+func (f *GithubForge) GetPullRequest(ctx context.Context,
+	sourceBranch, targetBranch string) (*ForgePullRequest, error) {
+
+	prs, _, err := f.Client.PullRequests.List(ctx, f.Owner, f.Repo,
+		&github.PullRequestListOptions{
+			State: "open",
+			Head:  fmt.Sprintf("%s:%s", f.Owner, sourceBranch),
+			Base:  targetBranch,
+		})
+	if err != nil {
+		return nil, fmt.Errorf("error on retrieve pull requests of %s/%s: %s",
+			f.Owner, f.Repo, err.Error())
+	}
+
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	return githubPr2ForgePr(prs[0]), nil
+}
+
+func (f *GithubForge) CreatePullRequest(ctx context.Context,
+	opts *ForgePullRequestOpts) (*ForgePullRequest, error) {
+
+	newPR := &github.NewPullRequest{
+		Title:               github.String(opts.Title),
+		Head:                github.String(opts.SourceBranch),
+		Base:                github.String(opts.TargetBranch),
+		Body:                github.String(opts.Body),
+		MaintainerCanModify: github.Bool(true),
+	}
+
+	pr, _, err := f.Client.PullRequests.Create(ctx, f.Owner, f.Repo, newPR)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.Labels) > 0 {
+		_, _, err = f.Client.Issues.AddLabelsToIssue(ctx, f.Owner, f.Repo,
+			pr.GetNumber(), opts.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("error on add labels to PR %s: %s",
+				pr.GetHTMLURL(), err.Error())
+		}
+	}
+
+	return githubPr2ForgePr(pr), nil
+}
+
+func (f *GithubForge) UpdatePullRequest(ctx context.Context,
+	pr *ForgePullRequest, opts *ForgePullRequestOpts) error {
+
+	_, _, err := f.Client.PullRequests.Edit(ctx, f.Owner, f.Repo, int(pr.Number),
+		&github.PullRequest{
+			Title: github.String(opts.Title),
+			Body:  github.String(opts.Body),
+		})
+	if err != nil {
+		return fmt.Errorf("error on update PR %s: %s", pr.Url, err.Error())
+	}
+
+	return nil
+}
+
+func (f *GithubForge) ClosePullRequest(ctx context.Context,
+	pr *ForgePullRequest, comment string) error {
+
+	if comment != "" {
+		_, _, err := f.Client.Issues.CreateComment(ctx, f.Owner, f.Repo,
+			int(pr.Number), &github.IssueComment{Body: github.String(comment)})
+		if err != nil {
+			return fmt.Errorf("error on add comment to PR %s: %s",
+				pr.Url, err.Error())
+		}
+	}
+
+	_, _, err := f.Client.PullRequests.Edit(ctx, f.Owner, f.Repo, int(pr.Number),
+		&github.PullRequest{State: github.String("closed")})
+	if err != nil {
+		return fmt.Errorf("error on close PR %s: %s", pr.Url, err.Error())
+	}
+
+	return nil
+}
+
+func githubPr2ForgePr(pr *github.PullRequest) *ForgePullRequest {
+	return &ForgePullRequest{
+		Number:       int64(pr.GetNumber()),
+		Url:          pr.GetHTMLURL(),
+		SourceBranch: pr.GetHead().GetRef(),
+		TargetBranch: pr.GetBase().GetRef(),
+	}
+}
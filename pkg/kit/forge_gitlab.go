@@ -0,0 +1,134 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"context"
+	"fmt"
+
+	client "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GitlabForge supports the merge requests of GitLab.
+type GitlabForge struct {
+	Client *client.Client
+	Owner  string
+	Repo   string
+}
+
+func NewGitlabForge(host, apiVersion, token, owner, repo string) (*GitlabForge, error) {
+	// We need to pass the api path
+	apiUrl := fmt.Sprintf("%s/api/v%s", host, apiVersion)
+
+	c, err := client.NewClient(token, client.WithBaseURL(apiUrl))
+	if err != nil {
+		return nil, fmt.Errorf("error on setup gitlab client for host %s: %s",
+			host, err.Error())
+	}
+
+	return &GitlabForge{
+		Client: c,
+		Owner:  owner,
+		Repo:   repo,
+	}, nil
+}
+
+func (f *GitlabForge) GetType() string { return ForgeGitlab }
+
+func (f *GitlabForge) getProject() string {
+	return fmt.Sprintf("%s/%s", f.Owner, f.Repo)
+}
+
+func (f *GitlabForge) GetPullRequest(ctx context.Context,
+	sourceBranch, targetBranch string) (*ForgePullRequest, error) {
+
+	mrs, _, err := f.Client.MergeRequests.ListProjectMergeRequests(f.getProject(),
+		&client.ListProjectMergeRequestsOptions{
+			State:        client.Ptr("opened"),
+			SourceBranch: client.Ptr(sourceBranch),
+			TargetBranch: client.Ptr(targetBranch),
+		}, client.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error on retrieve merge requests of %s: %s",
+			f.getProject(), err.Error())
+	}
+
+	if len(mrs) == 0 {
+		return nil, nil
+	}
+
+	return &ForgePullRequest{
+		Number:       mrs[0].IID,
+		Url:          mrs[0].WebURL,
+		SourceBranch: mrs[0].SourceBranch,
+		TargetBranch: mrs[0].TargetBranch,
+	}, nil
+}
+
+func (f *GitlabForge) CreatePullRequest(ctx context.Context,
+	opts *ForgePullRequestOpts) (*ForgePullRequest, error) {
+
+	mrOpts := &client.CreateMergeRequestOptions{
+		Title:        client.Ptr(opts.Title),
+		Description:  client.Ptr(opts.Body),
+		SourceBranch: client.Ptr(opts.SourceBranch),
+		TargetBranch: client.Ptr(opts.TargetBranch),
+	}
+	if len(opts.Labels) > 0 {
+		labels := client.LabelOptions(opts.Labels)
+		mrOpts.Labels = &labels
+	}
+
+	mr, _, err := f.Client.MergeRequests.CreateMergeRequest(f.getProject(),
+		mrOpts, client.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ForgePullRequest{
+		Number:       mr.IID,
+		Url:          mr.WebURL,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+	}, nil
+}
+
+func (f *GitlabForge) UpdatePullRequest(ctx context.Context,
+	pr *ForgePullRequest, opts *ForgePullRequestOpts) error {
+
+	_, _, err := f.Client.MergeRequests.UpdateMergeRequest(f.getProject(), pr.Number,
+		&client.UpdateMergeRequestOptions{
+			Title:       client.Ptr(opts.Title),
+			Description: client.Ptr(opts.Body),
+		}, client.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error on update MR %s: %s", pr.Url, err.Error())
+	}
+
+	return nil
+}
+
+func (f *GitlabForge) ClosePullRequest(ctx context.Context,
+	pr *ForgePullRequest, comment string) error {
+
+	if comment != "" {
+		_, _, err := f.Client.Notes.CreateMergeRequestNote(f.getProject(), pr.Number,
+			&client.CreateMergeRequestNoteOptions{Body: client.Ptr(comment)},
+			client.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("error on add comment to MR %s: %s",
+				pr.Url, err.Error())
+		}
+	}
+
+	_, _, err := f.Client.MergeRequests.UpdateMergeRequest(f.getProject(), pr.Number,
+		&client.UpdateMergeRequestOptions{StateEvent: client.Ptr("close")},
+		client.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error on close MR %s: %s", pr.Url, err.Error())
+	}
+
+	return nil
+}
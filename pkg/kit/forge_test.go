@@ -0,0 +1,447 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/google/go-github/v74/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type forgeRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   interface{}
+}
+
+// fakeForge is an HTTP server that returns the responses of the
+// routes ("METHOD /path" or "METHOD /path?page=N") and records
+// the requests received.
+type fakeForge struct {
+	Server   *httptest.Server
+	Routes   map[string]string
+	Requests []*forgeRequest
+}
+
+func newFakeForge(routes map[string]string) *fakeForge {
+	ans := &fakeForge{
+		Routes:   routes,
+		Requests: []*forgeRequest{},
+	}
+
+	ans.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			req := &forgeRequest{
+				Method: r.Method,
+				Path:   r.URL.Path,
+				Query:  r.URL.Query(),
+			}
+			data, _ := io.ReadAll(r.Body)
+			if len(data) > 0 {
+				_ = json.Unmarshal(data, &req.Body)
+			}
+			ans.Requests = append(ans.Requests, req)
+
+			key := r.Method + " " + r.URL.Path
+			resp, present := ans.Routes[key+"?page="+req.Query.Get("page")]
+			if !present {
+				resp, present = ans.Routes[key]
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if !present {
+				w.WriteHeader(http.StatusNotFound)
+				io.WriteString(w, `{"message": "not found"}`)
+				return
+			}
+			if r.Method == http.MethodPost {
+				w.WriteHeader(http.StatusCreated)
+			}
+			io.WriteString(w, resp)
+		}))
+
+	return ans
+}
+
+func (f *fakeForge) Close() { f.Server.Close() }
+
+// GetRequests returns the requests received with the method and path in input.
+func (f *fakeForge) GetRequests(method, path string) []*forgeRequest {
+	ans := []*forgeRequest{}
+	for _, r := range f.Requests {
+		if r.Method == method && r.Path == path {
+			ans = append(ans, r)
+		}
+	}
+	return ans
+}
+
+func newForgeTestConfig(remotes map[string]*specs.MarkDevkitRemoteAuth) *specs.MarkDevkitConfig {
+	c := specs.NewMarkDevkitConfig(nil)
+	c.GetAuthentication().Remotes = remotes
+	return c
+}
+
+func newPrOpts(labels ...string) *ForgePullRequestOpts {
+	return &ForgePullRequestOpts{
+		Title:        "Bump dev-libs/foo-1.0",
+		Body:         "New version",
+		SourceBranch: "bump-dev-libs-foo",
+		TargetBranch: "master",
+		Labels:       labels,
+	}
+}
+
+var _ = Describe("Forge", func() {
+	ctx := context.Background()
+
+	Context("ParseRemoteUrl", func() {
+
+		DescribeTable("parse the git url",
+			func(remoteUrl, host, owner, repo string) {
+				h, o, r, err := ParseRemoteUrl(remoteUrl)
+				Expect(err).Should(BeNil())
+				Expect([]string{h, o, r}).To(Equal([]string{host, owner, repo}))
+			},
+			Entry("https", "https://github.com/macaroni-os/core-kit.git",
+				"github.com", "macaroni-os", "core-kit"),
+			Entry("https without suffix", "https://codeberg.org/macaroni/core-kit/",
+				"codeberg.org", "macaroni", "core-kit"),
+			Entry("https with port and subgroups", "https://git.example.org:8443/group/sub/core-kit.git",
+				"git.example.org:8443", "group/sub", "core-kit"),
+			Entry("ssh", "ssh://git@gitlab.com/macaroni/core-kit.git",
+				"gitlab.com", "macaroni", "core-kit"),
+			Entry("scp-like", "git@github.com:macaroni-os/core-kit.git",
+				"github.com", "macaroni-os", "core-kit"),
+			Entry("scp-like without user", "github.com:macaroni-os/core-kit",
+				"github.com", "macaroni-os", "core-kit"),
+		)
+
+		DescribeTable("fails with an invalid url",
+			func(remoteUrl string) {
+				_, _, _, err := ParseRemoteUrl(remoteUrl)
+				Expect(err).ShouldNot(BeNil())
+			},
+			Entry("without owner", "https://github.com/core-kit.git"),
+			Entry("without host", "/srv/git/core-kit"),
+			Entry("scp-like without owner", "git@github.com:core-kit.git"),
+		)
+	})
+
+	Context("GetForgeType", func() {
+		c := newForgeTestConfig(map[string]*specs.MarkDevkitRemoteAuth{
+			"git.example.org":    {Forge: "gitlab"},
+			"gitea.example.org":  {Forge: "gitea"},
+			"github.com":         {Forge: "forgejo"},
+			"bad.example.org":    {Forge: "svn"},
+			"nouser.example.org": {Token: "xxx"},
+		})
+
+		DescribeTable("detect the forge of the host",
+			func(host, forge string) {
+				f, err := GetForgeType(c, host)
+				Expect(err).Should(BeNil())
+				Expect(f).To(Equal(forge))
+			},
+			Entry("from the remote", "git.example.org", ForgeGitlab),
+			Entry("gitea as forgejo", "gitea.example.org", ForgeForgejo),
+			Entry("remote before the well-known hosts", "github.com", ForgeForgejo),
+			Entry("gitlab.com", "gitlab.com", ForgeGitlab),
+			Entry("codeberg.org", "codeberg.org", ForgeForgejo),
+		)
+
+		DescribeTable("fails",
+			func(host string) {
+				_, err := GetForgeType(c, host)
+				Expect(err).ShouldNot(BeNil())
+			},
+			Entry("with an invalid forge", "bad.example.org"),
+			Entry("with a remote without forge", "nouser.example.org"),
+			Entry("with an unknown host", "unknown.example.org"),
+		)
+
+		It("returns github for github.com without remotes", func() {
+			f, err := GetForgeType(newForgeTestConfig(nil), "github.com")
+			Expect(err).Should(BeNil())
+			Expect(f).To(Equal(ForgeGithub))
+		})
+	})
+
+	Context("GitHub", func() {
+		var fake *fakeForge
+		var forge Forge
+
+		BeforeEach(func() {
+			fake = newFakeForge(map[string]string{
+				"GET /repos/o/r/pulls": `[{"number": 3, "html_url": "https://github.com/o/r/pull/3",
+					"head": {"ref": "bump-dev-libs-foo"}, "base": {"ref": "master"}}]`,
+				"POST /repos/o/r/pulls": `{"number": 4, "html_url": "https://github.com/o/r/pull/4",
+					"head": {"ref": "bump-dev-libs-foo"}, "base": {"ref": "master"}}`,
+				"POST /repos/o/r/issues/4/labels":   `[{"id": 1, "name": "mark-bot"}]`,
+				"PATCH /repos/o/r/pulls/3":          `{"number": 3}`,
+				"POST /repos/o/r/issues/3/comments": `{"id": 1}`,
+			})
+			client := github.NewClient(nil)
+			client.BaseURL, _ = url.Parse(fake.Server.URL + "/")
+			forge = NewGithubForgeWithClient(client, "o", "r")
+		})
+
+		AfterEach(func() { fake.Close() })
+
+		It("GetPullRequest", func() {
+			pr, err := forge.GetPullRequest(ctx, "bump-dev-libs-foo", "master")
+			Expect(err).Should(BeNil())
+			Expect(pr).To(Equal(&ForgePullRequest{
+				Number:       3,
+				Url:          "https://github.com/o/r/pull/3",
+				SourceBranch: "bump-dev-libs-foo",
+				TargetBranch: "master",
+			}))
+
+			reqs := fake.GetRequests("GET", "/repos/o/r/pulls")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Query.Get("state")).To(Equal("open"))
+			Expect(reqs[0].Query.Get("head")).To(Equal("o:bump-dev-libs-foo"))
+			Expect(reqs[0].Query.Get("base")).To(Equal("master"))
+		})
+
+		It("CreatePullRequest with labels", func() {
+			pr, err := forge.CreatePullRequest(ctx, newPrOpts("mark-bot"))
+			Expect(err).Should(BeNil())
+			Expect(pr.Number).To(Equal(int64(4)))
+			Expect(pr.Url).To(Equal("https://github.com/o/r/pull/4"))
+
+			reqs := fake.GetRequests("POST", "/repos/o/r/pulls")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("title", "Bump dev-libs/foo-1.0"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("head", "bump-dev-libs-foo"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("base", "master"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("body", "New version"))
+
+			reqs = fake.GetRequests("POST", "/repos/o/r/issues/4/labels")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(Equal([]interface{}{"mark-bot"}))
+		})
+
+		It("UpdatePullRequest", func() {
+			err := forge.UpdatePullRequest(ctx, &ForgePullRequest{Number: 3},
+				newPrOpts())
+			Expect(err).Should(BeNil())
+
+			reqs := fake.GetRequests("PATCH", "/repos/o/r/pulls/3")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("title", "Bump dev-libs/foo-1.0"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("body", "New version"))
+		})
+
+		It("ClosePullRequest", func() {
+			err := forge.ClosePullRequest(ctx, &ForgePullRequest{Number: 3},
+				"Superseded")
+			Expect(err).Should(BeNil())
+
+			reqs := fake.GetRequests("POST", "/repos/o/r/issues/3/comments")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("body", "Superseded"))
+
+			reqs = fake.GetRequests("PATCH", "/repos/o/r/pulls/3")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("state", "closed"))
+		})
+
+		It("ClosePullRequest fails on error", func() {
+			err := forge.ClosePullRequest(ctx, &ForgePullRequest{Number: 5}, "")
+			Expect(err).ShouldNot(BeNil())
+		})
+	})
+
+	Context("Forgejo", func() {
+		var fake *fakeForge
+		var forge Forge
+
+		BeforeEach(func() {
+			// The first page of the labels is full.
+			labels := []string{}
+			for i := 1; i <= 50; i++ {
+				labels = append(labels, fmt.Sprintf(`{"id": %d, "name": "label%d"}`, i, i))
+			}
+
+			fake = newFakeForge(map[string]string{
+				"GET /api/v1/repos/o/r/pulls?page=1": `[{"number": 2, "html_url": "https://codeberg.org/o/r/pulls/2",
+					"head": {"ref": "other"}, "base": {"ref": "master"}}]`,
+				"GET /api/v1/repos/o/r/labels?page=1": "[" + strings.Join(labels, ",") + "]",
+				"GET /api/v1/repos/o/r/labels?page=2": `[{"id": 51, "name": "mark-bot"}]`,
+				"POST /api/v1/repos/o/r/pulls": `{"number": 4, "html_url": "https://codeberg.org/o/r/pulls/4",
+					"head": {"ref": "bump-dev-libs-foo"}, "base": {"ref": "master"}}`,
+				"PATCH /api/v1/repos/o/r/issues/3":         `{"number": 3}`,
+				"POST /api/v1/repos/o/r/issues/3/comments": `{"id": 1}`,
+			})
+
+			var err error
+			forge, err = NewForgejoForge(fake.Server.URL, "token", "o", "r")
+			Expect(err).Should(BeNil())
+		})
+
+		AfterEach(func() { fake.Close() })
+
+		It("GetPullRequest", func() {
+			fake.Routes["GET /api/v1/repos/o/r/pulls?page=1"] = `[{"number": 2,
+				"head": {"ref": "other"}, "base": {"ref": "master"}},
+				{"number": 3, "html_url": "https://codeberg.org/o/r/pulls/3",
+				"head": {"ref": "bump-dev-libs-foo"}, "base": {"ref": "master"}}]`
+
+			pr, err := forge.GetPullRequest(ctx, "bump-dev-libs-foo", "master")
+			Expect(err).Should(BeNil())
+			Expect(pr).To(Equal(&ForgePullRequest{
+				Number:       3,
+				Url:          "https://codeberg.org/o/r/pulls/3",
+				SourceBranch: "bump-dev-libs-foo",
+				TargetBranch: "master",
+			}))
+
+			reqs := fake.GetRequests("GET", "/api/v1/repos/o/r/pulls")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Query.Get("state")).To(Equal("open"))
+		})
+
+		It("GetPullRequest without pull requests", func() {
+			pr, err := forge.GetPullRequest(ctx, "bump-dev-libs-foo", "master")
+			Expect(err).Should(BeNil())
+			Expect(pr).To(BeNil())
+		})
+
+		It("CreatePullRequest with labels", func() {
+			pr, err := forge.CreatePullRequest(ctx, newPrOpts("label2", "mark-bot", "missing"))
+			Expect(err).Should(BeNil())
+			Expect(pr.Number).To(Equal(int64(4)))
+			Expect(pr.SourceBranch).To(Equal("bump-dev-libs-foo"))
+
+			Expect(fake.GetRequests("GET", "/api/v1/repos/o/r/labels")).To(HaveLen(2))
+
+			reqs := fake.GetRequests("POST", "/api/v1/repos/o/r/pulls")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("head", "bump-dev-libs-foo"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("base", "master"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("title", "Bump dev-libs/foo-1.0"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("labels",
+				[]interface{}{float64(2), float64(51)}))
+		})
+
+		It("UpdatePullRequest", func() {
+			err := forge.UpdatePullRequest(ctx, &ForgePullRequest{Number: 3},
+				newPrOpts())
+			Expect(err).Should(BeNil())
+
+			reqs := fake.GetRequests("PATCH", "/api/v1/repos/o/r/issues/3")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("title", "Bump dev-libs/foo-1.0"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("body", "New version"))
+		})
+
+		It("ClosePullRequest", func() {
+			err := forge.ClosePullRequest(ctx, &ForgePullRequest{Number: 3},
+				"Superseded")
+			Expect(err).Should(BeNil())
+
+			reqs := fake.GetRequests("POST", "/api/v1/repos/o/r/issues/3/comments")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("body", "Superseded"))
+
+			reqs = fake.GetRequests("PATCH", "/api/v1/repos/o/r/issues/3")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("state", "closed"))
+		})
+	})
+
+	Context("GitLab", func() {
+		var fake *fakeForge
+		var forge Forge
+
+		BeforeEach(func() {
+			fake = newFakeForge(map[string]string{
+				"GET /api/v4/projects/o/r/merge_requests": `[{"iid": 3, "web_url": "https://gitlab.com/o/r/-/merge_requests/3",
+					"source_branch": "bump-dev-libs-foo", "target_branch": "master"}]`,
+				"POST /api/v4/projects/o/r/merge_requests": `{"iid": 4, "web_url": "https://gitlab.com/o/r/-/merge_requests/4",
+					"source_branch": "bump-dev-libs-foo", "target_branch": "master"}`,
+				"PUT /api/v4/projects/o/r/merge_requests/3":        `{"iid": 3}`,
+				"POST /api/v4/projects/o/r/merge_requests/3/notes": `{"id": 1}`,
+			})
+
+			var err error
+			forge, err = NewGitlabForge(fake.Server.URL, "4", "token", "o", "r")
+			Expect(err).Should(BeNil())
+		})
+
+		AfterEach(func() { fake.Close() })
+
+		It("GetPullRequest", func() {
+			pr, err := forge.GetPullRequest(ctx, "bump-dev-libs-foo", "master")
+			Expect(err).Should(BeNil())
+			Expect(pr).To(Equal(&ForgePullRequest{
+				Number:       3,
+				Url:          "https://gitlab.com/o/r/-/merge_requests/3",
+				SourceBranch: "bump-dev-libs-foo",
+				TargetBranch: "master",
+			}))
+
+			reqs := fake.GetRequests("GET", "/api/v4/projects/o/r/merge_requests")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Query.Get("state")).To(Equal("opened"))
+			Expect(reqs[0].Query.Get("source_branch")).To(Equal("bump-dev-libs-foo"))
+			Expect(reqs[0].Query.Get("target_branch")).To(Equal("master"))
+		})
+
+		It("CreatePullRequest with labels", func() {
+			pr, err := forge.CreatePullRequest(ctx, newPrOpts("mark-bot", "kit"))
+			Expect(err).Should(BeNil())
+			Expect(pr.Number).To(Equal(int64(4)))
+
+			reqs := fake.GetRequests("POST", "/api/v4/projects/o/r/merge_requests")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("title", "Bump dev-libs/foo-1.0"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("description", "New version"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("source_branch", "bump-dev-libs-foo"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("target_branch", "master"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("labels", "mark-bot,kit"))
+		})
+
+		It("UpdatePullRequest", func() {
+			err := forge.UpdatePullRequest(ctx, &ForgePullRequest{Number: 3},
+				newPrOpts())
+			Expect(err).Should(BeNil())
+
+			reqs := fake.GetRequests("PUT", "/api/v4/projects/o/r/merge_requests/3")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("title", "Bump dev-libs/foo-1.0"))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("description", "New version"))
+		})
+
+		It("ClosePullRequest", func() {
+			err := forge.ClosePullRequest(ctx, &ForgePullRequest{Number: 3},
+				"Superseded")
+			Expect(err).Should(BeNil())
+
+			reqs := fake.GetRequests("POST", "/api/v4/projects/o/r/merge_requests/3/notes")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("body", "Superseded"))
+
+			reqs = fake.GetRequests("PUT", "/api/v4/projects/o/r/merge_requests/3")
+			Expect(reqs).To(HaveLen(1))
+			Expect(reqs[0].Body).To(HaveKeyWithValue("state_event", "close"))
+		})
+	})
+})
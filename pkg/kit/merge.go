@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	log "github.com/macaroni-os/mark-devkit/pkg/logger"
@@ -18,9 +19,9 @@ import (
 	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/google/go-github/v74/github"
 	"github.com/macaroni-os/macaronictl/pkg/utils"
-	"golang.org/x/oauth2"
 )
 
 type MergeBot struct {
@@ -29,21 +30,30 @@ type MergeBot struct {
 
 	Resolver       *RepoScanResolver
 	TargetResolver *RepoScanResolver
+	DepsResolver   *RepoScanResolver
 
 	IsANewBranch bool
 	WorkDir      string
 
 	hasCommit      bool
 	files4Commit   map[string][]string
+	atoms4Commit   map[string]*specs.RepoScanAtom
 	manifestFiles  map[string][]specs.RepoScanFile
+	pulledDeps     map[string]*MergeDependency
+	keptAtoms      map[string]*CleanKeptAtom
+	ebuildsDates   map[string]time.Time
 	fixupBranches  map[string]*specs.MergeKitFixupInclude
+	eclassesReport *MergeEclassesReport
 	eclassUpdate   bool
 	profilesUpdate bool
 	metadataUpdate bool
 
 	GithubClient *github.Client
+	Forge        Forge
 
-	branches2Skip map[string]bool
+	branches2Skip   map[string]bool
+	branches2Update map[string]bool
+	prBranches      map[string]plumbing.Hash
 }
 
 type MergeBotOpts struct {
@@ -62,10 +72,21 @@ type MergeBotOpts struct {
 	SignatureEmail string
 
 	// Pull Request data
-	GithubUser string
+	GithubUser        string
+	PullRequestLabels []string
+	// The mode used to create the pull requests: package, single or category.
+	PullRequestMode string
 
 	// Kit Clone specs to merge
 	KitCloneSummaryFile string
+
+	// Clean: keep the versions required by other packages.
+	CheckReverseDeps bool
+	// Clean: the kit-cache files of the other kits to check.
+	DepsKitCacheFiles []string
+	// Clean: check also the source kits of the specfile and the
+	// kit-cache files of the other kits available on the reposcan dir.
+	DepsSourcesKits bool
 }
 
 func NewMergeBotOpts() *MergeBotOpts {
@@ -77,8 +98,15 @@ func NewMergeBotOpts() *MergeBotOpts {
 		GitDeepFetch:    10,
 		Concurrency:     10,
 		CleanWorkingDir: true,
-		GithubUser:      "macaroni-os",
+		GithubUser:      "",
 		Atoms:           []string{},
+
+		PullRequestLabels: []string{},
+		PullRequestMode:   PullRequestModePackage,
+
+		CheckReverseDeps:  true,
+		DepsKitCacheFiles: []string{},
+		DepsSourcesKits:   true,
 	}
 }
 
@@ -103,17 +131,25 @@ func NewMergeBot(c *specs.MarkDevkitConfig) *MergeBot {
 		Logger:         resolver.Logger,
 		Resolver:       resolver,
 		TargetResolver: targetResolver,
+		DepsResolver:   nil,
 		IsANewBranch:   false,
 		WorkDir:        "./workdir",
 		hasCommit:      false,
 		files4Commit:   make(map[string][]string, 0),
+		atoms4Commit:   make(map[string]*specs.RepoScanAtom, 0),
 		manifestFiles:  make(map[string][]specs.RepoScanFile, 0),
+		pulledDeps:     make(map[string]*MergeDependency, 0),
+		keptAtoms:      make(map[string]*CleanKeptAtom, 0),
 		fixupBranches:  make(map[string]*specs.MergeKitFixupInclude, 0),
 		GithubClient:   nil,
+		Forge:          nil,
 		eclassUpdate:   false,
 		profilesUpdate: false,
 		metadataUpdate: false,
 		branches2Skip:  make(map[string]bool, 0),
+
+		branches2Update: make(map[string]bool, 0),
+		prBranches:      nil,
 	}
 }
 
@@ -143,28 +179,31 @@ func (m *MergeBot) HasUpdates(opts *MergeBotOpts) bool {
 func (m *MergeBot) GetResolver() *RepoScanResolver { return m.Resolver }
 func (m *MergeBot) SetWorkDir(d string)            { m.WorkDir = d }
 
-func (m *MergeBot) SetupGithubClient(ctx context.Context) error {
-	if m.GithubClient == nil {
-		pushOpts := NewPushOptions()
+// SetupForge prepares the forge used to create the pull requests
+// on the repository of the target kit.
+func (m *MergeBot) SetupForge(ctx context.Context, targetKit *specs.ReposcanKit,
+	opts *MergeBotOpts) error {
+	if m.Forge != nil {
+		return nil
+	}
 
-		remote, available := m.Config.GetAuthentication().GetRemote("github.com")
-		if available {
-			pushOpts.Token = remote.Token
-		}
+	forgeType, err := GetKitForgeType(m.Config, targetKit.Url)
+	if err != nil {
+		return err
+	}
 
-		auth, err := GetGithubAuth(pushOpts)
-		if err != nil {
-			return err
+	if forgeType == ForgeGithub && m.GithubClient != nil {
+		// Reuse the client configured by the caller.
+		_, owner, repo, _ := ParseRemoteUrl(targetKit.Url)
+		if opts.GithubUser != "" {
+			owner = opts.GithubUser
 		}
-
-		ts := oauth2.StaticTokenSource(&oauth2.Token{
-			AccessToken: auth.Password,
-		})
-		tc := oauth2.NewClient(ctx, ts)
-		m.GithubClient = github.NewClient(tc)
+		m.Forge = NewGithubForgeWithClient(m.GithubClient, owner, repo)
+		return nil
 	}
 
-	return nil
+	m.Forge, err = NewForge(ctx, m.Config, targetKit.Url, opts.GithubUser)
+	return err
 }
 
 func (m *MergeBot) Run(specfile string, opts *MergeBotOpts) error {
@@ -266,8 +305,13 @@ func (m *MergeBot) ElaborateMerge(mkit *specs.MergeKit,
 			len(candidates)))
 
 		for _, candidate := range candidates {
-			m.Logger.Info(fmt.Sprintf(":pizza:[%s] %s",
-				candidate.Kit, candidate.Atom))
+			if dep, isDep := m.pulledDeps[candidate.Atom]; isDep {
+				m.Logger.Info(fmt.Sprintf(":pizza:[%s] %s (%s)",
+					candidate.Kit, candidate.Atom, dep))
+			} else {
+				m.Logger.Info(fmt.Sprintf(":pizza:[%s] %s",
+					candidate.Kit, candidate.Atom))
+			}
 		}
 
 		// Merge Atoms
@@ -296,7 +340,7 @@ func (m *MergeBot) ElaborateMerge(mkit *specs.MergeKit,
 	}
 
 	// Copy eclasses
-	err = m.MergeEclasses(mkit, opts)
+	err = m.MergeEclasses(mkit, candidates, opts)
 	if err != nil {
 		return err
 	}
@@ -321,6 +365,13 @@ func (m *MergeBot) ElaborateMerge(mkit *specs.MergeKit,
 
 	if opts.Push && m.HasUpdates(opts) {
 		err = m.Push(mkit, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Push && opts.PullRequest {
+		err = m.CloseObsoletePullRequests(mkit, candidates, opts)
 	}
 
 	return err
@@ -376,19 +427,22 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 	var repo *git.Repository
 	targetKit, _ := mkit.GetTargetKit()
 	kitDir := filepath.Join(m.GetTargetDir(), targetKit.Name)
-	pushOpts := NewPushOptions()
+	pushOpts := NewPushOptions(m.Config)
 	ctx := context.Background()
 
 	if opts.PullRequest {
-		// Setup github client for PR
-		err = m.SetupGithubClient(ctx)
+		// Setup the forge client for PR
+		err = m.SetupForge(ctx, targetKit, opts)
 		if err != nil {
 			return err
 		}
 
+		if opts.IsAggregatedPR() {
+			return m.pushAggregatedPrs(ctx, mkit, kitDir, pushOpts, opts)
+		}
+
 		// Push bump branches
-		for pkg := range m.files4Commit {
-			prBranchName := GetPrBranchNameForPkgBump(pkg, targetKit.Branch)
+		for prBranchName, atoms := range m.getBumpBranches(targetKit, opts) {
 			if _, present := m.branches2Skip[prBranchName]; present {
 				// Skip elaboration of the branches already present
 				// on remote.
@@ -400,7 +454,13 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				break
 			}
 
-			pr, err := CreatePullRequest(m.GithubClient, ctx,
+			pkg := strings.Join(atoms, ", ")
+			packages := "package"
+			if len(atoms) > 1 {
+				packages = "packages"
+			}
+
+			pr, err := m.createPullRequest(ctx, opts,
 				// title
 				fmt.Sprintf("mark-devkit: [%s] Bump %s", targetKit.Branch, pkg),
 				// source branch
@@ -409,12 +469,8 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				targetKit.Branch,
 				// body
 				fmt.Sprintf(
-					"Automatic bump of package %s for branch %s by mark-bot",
-					pkg, targetKit.Branch),
-				// github User
-				opts.GithubUser,
-				// github target repository
-				targetKit.Name,
+					"Automatic bump of %s %s for branch %s by mark-bot",
+					packages, pkg, targetKit.Branch),
 			)
 
 			if err != nil {
@@ -422,7 +478,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 			}
 
 			m.Logger.Info(fmt.Sprintf("[%s] Created correctly PR: %s",
-				pkg, pr.GetHTMLURL()))
+				pkg, pr.Url))
 		}
 
 		// Create PR for fixups if available
@@ -434,7 +490,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 					return err
 				}
 
-				pr, err := CreatePullRequest(m.GithubClient, ctx,
+				pr, err := m.createPullRequest(ctx, opts,
 					// title
 					fmt.Sprintf("mark-devkit: [%s] Update %s %s", targetKit.Branch,
 						include.GetType(), name),
@@ -446,10 +502,6 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 					fmt.Sprintf(
 						"Automatic update for fixup %s for branch %s for specfile %s by mark-bot",
 						name, targetKit.Branch, mkit.File),
-					// github User
-					opts.GithubUser,
-					// github target repository
-					targetKit.Name,
 				)
 
 				if err != nil {
@@ -457,7 +509,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				}
 
 				m.Logger.Info(fmt.Sprintf("[%s] Created correctly PR for fixup: %s",
-					name, pr.GetHTMLURL()))
+					name, pr.Url))
 			}
 		}
 
@@ -470,7 +522,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				return err
 			}
 
-			pr, err := CreatePullRequest(m.GithubClient, ctx,
+			pr, err := m.createPullRequest(ctx, opts,
 				// title
 				fmt.Sprintf("mark-devkit: [%s] Update/Add eclasses", targetKit.Branch),
 				// source branch
@@ -479,12 +531,8 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				targetKit.Branch,
 				// body
 				fmt.Sprintf(
-					"Automatic update for add/update eclasses to branch %s for specfile %s by mark-bot",
-					targetKit.Branch, mkit.File),
-				// github User
-				opts.GithubUser,
-				// github target repository
-				targetKit.Name,
+					"Automatic update for add/update eclasses to branch %s for specfile %s by mark-bot%s",
+					targetKit.Branch, mkit.File, m.getEclassesReportBody()),
 			)
 
 			if err != nil {
@@ -492,7 +540,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 			}
 
 			m.Logger.Info(fmt.Sprintf("[%s] Created correctly PR for eclasses: %s",
-				targetKit.Name, pr.GetHTMLURL()))
+				targetKit.Name, pr.Url))
 		}
 
 		if m.profilesUpdate {
@@ -504,7 +552,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				return err
 			}
 
-			pr, err := CreatePullRequest(m.GithubClient, ctx,
+			pr, err := m.createPullRequest(ctx, opts,
 				// title
 				fmt.Sprintf("mark-devkit: [%s] Update/Add Profiles", targetKit.Branch),
 				// source branch
@@ -515,10 +563,6 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				fmt.Sprintf(
 					"Automatic update for add/update profiles to branch %s for specfile %s by mark-bot",
 					targetKit.Branch, mkit.File),
-				// github User
-				opts.GithubUser,
-				// github target repository
-				targetKit.Name,
 			)
 
 			if err != nil {
@@ -526,7 +570,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 			}
 
 			m.Logger.Info(fmt.Sprintf("[%s] Created correctly PR for profiles: %s",
-				targetKit.Name, pr.GetHTMLURL()))
+				targetKit.Name, pr.Url))
 		}
 
 		if m.metadataUpdate {
@@ -537,7 +581,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				return err
 			}
 
-			pr, err := CreatePullRequest(m.GithubClient, ctx,
+			pr, err := m.createPullRequest(ctx, opts,
 				// title
 				fmt.Sprintf("mark-devkit: [%s] Update/Add Metadata", targetKit.Branch),
 				// source branch
@@ -548,10 +592,6 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				fmt.Sprintf(
 					"Automatic update for add/update metadata to branch %s for specfile %s by mark-bot",
 					targetKit.Branch, mkit.File),
-				// github User
-				opts.GithubUser,
-				// github target repository
-				targetKit.Name,
 			)
 
 			if err != nil {
@@ -559,7 +599,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 			}
 
 			m.Logger.Info(fmt.Sprintf("[%s] Created correctly PR for profiles: %s",
-				targetKit.Name, pr.GetHTMLURL()))
+				targetKit.Name, pr.Url))
 		}
 
 	} else {
@@ -606,6 +646,7 @@ func (m *MergeBot) Push(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 
 func (m *MergeBot) SearchAtoms(mkit *specs.MergeKit, opts *MergeBotOpts) ([]*specs.RepoScanAtom, error) {
 	ans := []*specs.RepoScanAtom{}
+	depsRoots := []*mergeDepNode{}
 
 	atomFiltered := opts.HasAtoms()
 
@@ -634,26 +675,46 @@ func (m *MergeBot) SearchAtoms(mkit *specs.MergeKit, opts *MergeBotOpts) ([]*spe
 			continue
 		}
 
-		candidate, err := m.searchAtom(atom, mkit, opts)
+		candidates, err := m.searchAtom(atom, mkit, opts)
 		if err != nil {
 			m.Logger.Info(fmt.Sprintf(":warning:[%s] error on search atoms: %s. Skipped.",
 				atom.Package, err.Error()))
 			continue
 		}
 
-		if candidate != nil {
-			ans = append(ans, candidate)
+		if len(candidates) > 0 {
+			ans = append(ans, candidates...)
+
+			if withDeps := mkit.GetAtomWithDeps(atom); withDeps != nil {
+				for _, candidate := range candidates {
+					depsRoots = append(depsRoots, &mergeDepNode{
+						Atom:     candidate,
+						WithDeps: withDeps,
+						Depth:    0,
+					})
+				}
+			}
 		} else {
 			m.Logger.DebugC(fmt.Sprintf(":eyes:[%s] No candidates found.",
 				atom.Package))
 		}
 	}
 
+	if len(depsRoots) > 0 {
+		// Pull in the dependencies missing on target kit.
+		deps, err := m.searchDependencies(mkit, depsRoots, ans)
+		if err != nil {
+			return ans, err
+		}
+		ans = append(ans, deps...)
+	}
+
 	return ans, nil
 }
 
 func (m *MergeBot) searchAtom(atom *specs.MergeKitAtom, mkit *specs.MergeKit,
-	opts *MergeBotOpts) (*specs.RepoScanAtom, error) {
+	opts *MergeBotOpts) ([]*specs.RepoScanAtom, error) {
+	ans := []*specs.RepoScanAtom{}
 
 	pOpts := NewPortageResolverOpts()
 	pOpts.Conditions = atom.Conditions
@@ -661,40 +722,92 @@ func (m *MergeBot) searchAtom(atom *specs.MergeKitAtom, mkit *specs.MergeKit,
 		pOpts.IgnoreSlot = *atom.CondIgnoreSlot
 	}
 
-	// Retrieve the last version package that matches the
-	// conditions.
-	ans, err := m.Resolver.GetLastPackage(atom.Package, pOpts)
-	if err != nil {
-		return ans, err
+	candidates := []*specs.RepoScanAtom{}
+	mergeVersions := mkit.GetAtomMergeVersions(atom)
+
+	switch mergeVersions {
+	case specs.MergeVersionsLast:
+		// Retrieve the last version package that matches the
+		// conditions.
+		last, err := m.Resolver.GetLastPackage(atom.Package, pOpts)
+		if err != nil {
+			return ans, err
+		}
+
+		if last == nil {
+			// POST: no packages found for atom.
+			return ans, nil
+		}
+		candidates = append(candidates, last)
+
+	case specs.MergeVersionsAll:
+		// Retrieve all the versions that match the conditions
+		// in the same retention window used by the clean.
+		atoms, err := m.Resolver.GetValidPackages(atom.Package, pOpts)
+		if err != nil {
+			return ans, err
+		}
+
+		if len(atoms) == 0 {
+			return ans, fmt.Errorf("No packages found matching %s.", atom.Package)
+		}
+
+		candidates, err = getAtomsWindow(atoms,
+			mkit.AtomGroupBySlot(atom), mkit.GetAtomMaxVersions(atom))
+		if err != nil {
+			return ans, err
+		}
+
+	default:
+		return ans, fmt.Errorf("invalid merge_versions value %s", mergeVersions)
 	}
 
-	if ans == nil {
-		// POST: no packages found for atom.
-		return nil, nil
+	for _, candidate := range candidates {
+		// With multiple versions the older versions are
+		// merged also when a newer version is present.
+		toAdd, err := m.isCandidate2Merge(atom, candidate,
+			mergeVersions == specs.MergeVersionsLast)
+		if err != nil {
+			return ans, err
+		}
+
+		if toAdd {
+			ans = append(ans, candidate)
+		}
 	}
 
+	return ans, nil
+}
+
+// isCandidate2Merge checks if the candidate is already present on
+// target kit. With onlyNewer the candidate is ignored if there is
+// a newer version on target kit. The candidate is always ignored if
+// the target kit has the same version with a greater revision.
+func (m *MergeBot) isCandidate2Merge(atom *specs.MergeKitAtom,
+	candidate *specs.RepoScanAtom, onlyNewer bool) (bool, error) {
+
 	// Check if the selected package is with slot
 	gatom, err := gentoo.ParsePackageStr(atom.Package)
 	if err != nil {
-		return ans, err
+		return false, err
 	}
 	if !strings.Contains(atom.Package, ":") {
 		// POST: force empty string when SLOT is not defined.
 		gatom.Slot = ""
 	}
 
-	gpkg, err := ans.ToGentooPackage()
+	gpkg, err := candidate.ToGentooPackage()
 	if err != nil {
-		return ans, err
+		return false, err
 	}
 
 	toAdd := true
-	existingAtoms, _ := m.TargetResolver.GetPackageVersions(ans.CatPkg)
+	existingAtoms, _ := m.TargetResolver.GetPackageVersions(candidate.CatPkg)
 	if len(existingAtoms) > 0 {
 		for _, a := range existingAtoms {
 			epkg, err := a.ToGentooPackage()
 			if err != nil {
-				return ans, err
+				return false, err
 			}
 
 			m.Logger.Debug(fmt.Sprintf(
@@ -718,12 +831,12 @@ func (m *MergeBot) searchAtom(atom *specs.MergeKitAtom, mkit *specs.MergeKit,
 				for _, cond := range atom.Conditions {
 					gcond, err := gentoo.ParsePackageStr(cond)
 					if err != nil {
-						return ans, err
+						return false, err
 					}
 
 					admit, err := gcond.Admit(epkg)
 					if err != nil {
-						return ans, err
+						return false, err
 					}
 
 					if !admit {
@@ -741,20 +854,29 @@ func (m *MergeBot) searchAtom(atom *specs.MergeKitAtom, mkit *specs.MergeKit,
 
 			equal, err := epkg.Equal(gpkg)
 			if err != nil {
-				return ans, err
+				return false, err
 			}
 
 			if equal {
 				// POST: The package is the same. Checking md5
-				if a.Md5 == ans.Md5 {
+				if a.Md5 == candidate.Md5 {
 					toAdd = false
 					break
 				}
-			} else {
+			} else if epkg.GetPV() == gpkg.GetPV() &&
+				epkg.GetRevision() > gpkg.GetRevision() {
+				// POST: The target kit has a revision bump of the
+				//       same version. Ex: foo-1.0-r1 for foo-1.0.
+				m.Logger.Debug(fmt.Sprintf(
+					"[%s] Ignoring %s already bumped as %s.",
+					gpkg.GetPackageName(), gpkg.GetPVR(), epkg.GetPVR()))
+				toAdd = false
+				break
+			} else if onlyNewer {
 
 				lessThen, err := gpkg.LessThan(epkg)
 				if err != nil {
-					return ans, err
+					return false, err
 				}
 
 				if lessThen {
@@ -771,15 +893,12 @@ func (m *MergeBot) searchAtom(atom *specs.MergeKitAtom, mkit *specs.MergeKit,
 
 	} // else is a new package to add
 
-	if !toAdd {
-		ans = nil
-	}
-
-	return ans, nil
+	return toAdd, nil
 }
 
 func (m *MergeBot) CloneSourcesKits(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 	gitOpts := &CloneOptions{
+		Config: m.Config,
 		GitCloneOptions: &git.CloneOptions{
 			SingleBranch: true,
 			RemoteName:   "origin",
@@ -855,12 +974,13 @@ func (m *MergeBot) CloneTargetKit(mkit *specs.MergeKit, opts *MergeBotOpts) erro
 	kitDir := filepath.Join(m.GetTargetDir(), kit.Name)
 
 	// Check if the repository branch exists.
-	existsBranch, err := BranchExists(kit.Url, kit.Branch)
+	existsBranch, err := BranchExists(m.Config, kit.Url, kit.Branch)
 	if err != nil {
 		return err
 	}
 
 	gitOpts := &CloneOptions{
+		Config: m.Config,
 		GitCloneOptions: &git.CloneOptions{
 			RemoteName: "origin",
 		},
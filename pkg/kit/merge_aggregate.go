@@ -0,0 +1,335 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+const (
+	// A PR for every bumped package and for every update.
+	PullRequestModePackage = "package"
+	// A PR with all changes of the run.
+	PullRequestModeSingle = "single"
+	// A PR for every category of the bumped packages and
+	// a PR for the other updates.
+	PullRequestModeCategory = "category"
+)
+
+type aggregatedPr struct {
+	Category string
+	Atoms    []string
+	Updates  []string
+}
+
+func (m *MergeBotOpts) IsAggregatedPR() bool {
+	return m.PullRequestMode == PullRequestModeSingle ||
+		m.PullRequestMode == PullRequestModeCategory
+}
+
+func ValidatePullRequestMode(mode string) error {
+	switch mode {
+	case PullRequestModePackage, PullRequestModeSingle, PullRequestModeCategory:
+		return nil
+	}
+	return fmt.Errorf("invalid pull request mode %s", mode)
+}
+
+// getPrBranchName returns the branch where the changes are committed.
+// The atom is used to retrieve the category of the bumped package.
+func (m *MergeBot) getPrBranchName(opts *MergeBotOpts, kit *specs.ReposcanKit,
+	prBranchName, atom string) string {
+
+	switch opts.PullRequestMode {
+	case PullRequestModeSingle:
+		return GetPrBranchNameForAggregate(kit.Branch, "")
+	case PullRequestModeCategory:
+		if atom != "" {
+			return GetPrBranchNameForAggregate(kit.Branch,
+				atom[0:strings.Index(atom, "/")])
+		}
+		return GetPrBranchNameForAggregate(kit.Branch, "")
+	default:
+		return prBranchName
+	}
+}
+
+// prBranchExists checks if the PR branch is already present on the
+// remote repository. The aggregated branches are always rebuilt.
+func (m *MergeBot) prBranchExists(opts *MergeBotOpts, kit *specs.ReposcanKit,
+	prBranchName string) (bool, error) {
+	if opts.IsAggregatedPR() {
+		return false, nil
+	}
+	return BranchExists(m.Config, kit.Url, prBranchName)
+}
+
+// checkoutPrBranch creates the PR branch from the HEAD of the target
+// branch. With the aggregated PRs the branch already created
+// is reused to collect all commits.
+func (m *MergeBot) checkoutPrBranch(repo *git.Repository, worktree *git.Worktree,
+	headRef *plumbing.Reference, prBranchName string, opts *MergeBotOpts) error {
+
+	branchRef := plumbing.NewBranchReferenceName(prBranchName)
+
+	if opts.IsAggregatedPR() {
+		ref, err := repo.Reference(branchRef, false)
+		if err == nil {
+			// Move HEAD to the aggregated branch without touch the
+			// working tree. The index is aligned to the last commit
+			// of the branch in order to commit only the new changes.
+			err = repo.Storer.SetReference(
+				plumbing.NewSymbolicReference(plumbing.HEAD, branchRef))
+			if err != nil {
+				return err
+			}
+
+			return worktree.Reset(&git.ResetOptions{
+				Commit: ref.Hash(),
+				Mode:   git.MixedReset,
+			})
+		}
+
+		// Drop from the index the files of the others aggregated
+		// branches.
+		err = worktree.Reset(&git.ResetOptions{
+			Commit: headRef.Hash(),
+			Mode:   git.MixedReset,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	ref := plumbing.NewHashReference(branchRef, headRef.Hash())
+	// The created reference is saved in the storage.
+	err := repo.Storer.SetReference(ref)
+	if err != nil {
+		return err
+	}
+
+	// Creating the new branch for the PR.
+	branchCoOpts := git.CheckoutOptions{
+		Branch: plumbing.ReferenceName(branchRef),
+		Create: false,
+		Keep:   true,
+	}
+
+	return worktree.Checkout(&branchCoOpts)
+}
+
+// getAggregatedPrs returns the aggregated PRs to push.
+func (m *MergeBot) getAggregatedPrs(kit *specs.ReposcanKit,
+	opts *MergeBotOpts) map[string]*aggregatedPr {
+	ans := make(map[string]*aggregatedPr, 0)
+
+	for atom := range m.files4Commit {
+		prBranchName := m.getPrBranchName(opts, kit, "", atom)
+		pr, present := ans[prBranchName]
+		if !present {
+			pr = &aggregatedPr{Atoms: []string{}, Updates: []string{}}
+			if opts.PullRequestMode == PullRequestModeCategory {
+				pr.Category = atom[0:strings.Index(atom, "/")]
+			}
+			ans[prBranchName] = pr
+		}
+		pr.Atoms = append(pr.Atoms, atom)
+	}
+
+	updates := []string{}
+	if m.eclassUpdate {
+		updates = append(updates, "eclasses")
+	}
+	if m.metadataUpdate {
+		updates = append(updates, "metadata")
+	}
+	if m.profilesUpdate {
+		updates = append(updates, "profiles")
+	}
+	fixups := []string{}
+	for name, include := range m.fixupBranches {
+		fixups = append(fixups, fmt.Sprintf("%s %s", include.GetType(), name))
+	}
+	sort.Strings(fixups)
+	updates = append(updates, fixups...)
+
+	if len(updates) > 0 {
+		prBranchName := GetPrBranchNameForAggregate(kit.Branch, "")
+		pr, present := ans[prBranchName]
+		if !present {
+			pr = &aggregatedPr{Atoms: []string{}}
+			ans[prBranchName] = pr
+		}
+		pr.Updates = updates
+	}
+
+	for _, pr := range ans {
+		sort.Strings(pr.Atoms)
+	}
+
+	return ans
+}
+
+// pushAggregatedPrs pushes the aggregated branches and creates or
+// updates the PRs.
+func (m *MergeBot) pushAggregatedPrs(ctx context.Context, mkit *specs.MergeKit,
+	kitDir string, pushOpts *PushOptions, opts *MergeBotOpts) error {
+
+	targetKit, _ := mkit.GetTargetKit()
+	prs := m.getAggregatedPrs(targetKit, opts)
+
+	branches := []string{}
+	for branch := range prs {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+
+	for _, prBranchName := range branches {
+		aggregated := prs[prBranchName]
+
+		// The branch is rebuilt from the HEAD of the target branch
+		// with all pending changes. The branch of an already open
+		// PR is replaced and the PR updated.
+		err := PushBranch(kitDir, prBranchName, pushOpts)
+		if err != nil {
+			return err
+		}
+		m.branches2Update[prBranchName] = true
+
+		body, err := m.getAggregatedPrBody(mkit, targetKit, aggregated)
+		if err != nil {
+			return err
+		}
+
+		pr, err := m.createPullRequest(ctx, opts,
+			m.getAggregatedPrTitle(targetKit, aggregated),
+			prBranchName,
+			targetKit.Branch,
+			body,
+		)
+		if err != nil {
+			return err
+		}
+
+		m.Logger.Info(fmt.Sprintf("[%s] Created correctly aggregated PR: %s",
+			prBranchName, pr.Url))
+	}
+
+	return nil
+}
+
+func (m *MergeBot) getAggregatedPrTitle(kit *specs.ReposcanKit, pr *aggregatedPr) string {
+	parts := []string{}
+	if len(pr.Atoms) > 0 {
+		packages := "packages"
+		if len(pr.Atoms) == 1 {
+			packages = "package"
+		}
+		part := fmt.Sprintf("bump %d %s", len(pr.Atoms), packages)
+		if pr.Category != "" {
+			part += " of " + pr.Category
+		}
+		parts = append(parts, part)
+	}
+	if len(pr.Updates) > 0 {
+		parts = append(parts, "update "+strings.Join(pr.Updates, ", "))
+	}
+
+	title := strings.Join(parts, " and ")
+
+	return fmt.Sprintf("mark-devkit: [%s] %s%s", kit.Branch,
+		strings.ToUpper(title[0:1]), title[1:])
+}
+
+func (m *MergeBot) getAggregatedPrBody(mkit *specs.MergeKit,
+	kit *specs.ReposcanKit, pr *aggregatedPr) (string, error) {
+
+	body := fmt.Sprintf(
+		"Automatic update of the branch %s for specfile %s by mark-bot\n",
+		kit.Branch, mkit.File)
+
+	if len(pr.Atoms) > 0 {
+		body += "\n| Package | Old version | New version | Homepage |\n"
+		body += "|---------|-------------|-------------|----------|\n"
+
+		for _, atom := range pr.Atoms {
+			row, err := m.getAggregatedPrRow(atom)
+			if err != nil {
+				return "", err
+			}
+			body += row
+		}
+	}
+
+	if len(pr.Updates) > 0 {
+		body += "\nOther updates:\n\n"
+		for _, update := range pr.Updates {
+			body += fmt.Sprintf(" * %s\n", update)
+		}
+	}
+
+	return body, nil
+}
+
+func (m *MergeBot) getAggregatedPrRow(atom string) (string, error) {
+	gp, err := gentoo.ParsePackageStr(atom)
+	if err != nil {
+		return "", err
+	}
+	catpkg := fmt.Sprintf("%s/%s", gp.Category, gp.Name)
+
+	// The merged atom has the metadata also when the atom
+	// is a revision bump of the source ebuild.
+	homepage := ""
+	if candidate := m.getAtom4Commit(atom); candidate != nil {
+		gp, err = candidate.ToGentooPackage()
+		if err != nil {
+			return "", err
+		}
+		if fields := strings.Fields(candidate.Metadata["HOMEPAGE"]); len(fields) > 0 {
+			homepage = fields[0]
+		}
+	}
+
+	// The old version is the last version of the same slot
+	// available on the target kit.
+	oldVersion := "-"
+	var oldGp *gentoo.GentooPackage
+	for idx := range m.TargetResolver.Map[catpkg] {
+		tgp, err := m.TargetResolver.Map[catpkg][idx].ToGentooPackage()
+		if err != nil {
+			return "", err
+		}
+		if tgp.Slot != gp.Slot {
+			continue
+		}
+		if oldGp != nil {
+			if greater, _ := tgp.GreaterThan(oldGp); !greater {
+				continue
+			}
+		}
+		oldGp = tgp
+	}
+	if oldGp != nil {
+		oldVersion = oldGp.GetPVR()
+	}
+
+	name := catpkg
+	if dep, isDep := m.pulledDeps[atom]; isDep {
+		name += fmt.Sprintf(" (%s)", dep)
+	}
+
+	return fmt.Sprintf("| %s | %s | %s | %s |\n",
+		name, oldVersion, gp.GetPVR(), homepage), nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Aggregated pull requests", func() {
+
+	mkit := &specs.MergeKit{File: "core-kit.yml"}
+	kit := &specs.ReposcanKit{Name: "core-kit", Branch: "master"}
+
+	It("reports the revision bumps with the data of the merged atom", func() {
+		bot := newTestMergeBot(newTestAtom("dev-libs/foo-1.0", "2", "core-kit"))
+		for _, t := range []string{"dev-libs/foo-0.9", "dev-libs/foo-3.0"} {
+			slot := "2"
+			if t == "dev-libs/foo-3.0" {
+				slot = "3"
+			}
+			a := newTestAtom(t, slot, "core-kit")
+			bot.TargetResolver.AddPackageAtom(a.CatPkg, a)
+		}
+
+		bumped := newTestAtom("dev-libs/foo-1.0", "2", "core-kit")
+		bumped.Atom = "dev-libs/foo-1.0-r1"
+		bumped.Metadata["HOMEPAGE"] = "https://foo.org https://github.com/foo/foo"
+		bot.AddAtom4Commit(bumped, "dev-libs/foo/foo-1.0-r1.ebuild")
+
+		body, err := bot.GetAggregatedPrBody(mkit, kit,
+			[]string{"dev-libs/foo-1.0-r1"}, []string{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(ContainSubstring(
+			"| dev-libs/foo | 0.9 | 1.0-r1 | https://foo.org |\n"))
+	})
+
+	DescribeTable("body of the pull request",
+		func(atoms, updates []string, expected string) {
+			bot := newTestMergeBot()
+			for _, t := range []string{"dev-libs/foo-1.0", "dev-libs/foo-1.1", "dev-libs/bar-2.0"} {
+				a := newTestAtom(t, "0", "core-kit")
+				bot.TargetResolver.AddPackageAtom(a.CatPkg, a)
+			}
+
+			foo := newTestAtom("dev-libs/foo-1.2", "0", "core-kit")
+			foo.Metadata["HOMEPAGE"] = "https://foo.org"
+			bot.AddAtom4Commit(foo, "dev-libs/foo/foo-1.2.ebuild")
+			bot.AddAtom4Commit(newTestAtom("dev-libs/new-1.0", "0", "core-kit"),
+				"dev-libs/new/new-1.0.ebuild")
+			bot.GetPulledDeps()["dev-libs/new-1.0"] = &MergeDependency{
+				Parent: "dev-libs/foo-1.2",
+				Kind:   "RDEPEND",
+			}
+
+			body, err := bot.GetAggregatedPrBody(mkit, kit, atoms, updates)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(Equal(expected))
+		},
+		Entry("only updates", []string{}, []string{"eclass/foo.eclass", "metadata"},
+			"Automatic update of the branch master for specfile core-kit.yml by mark-bot\n"+
+				"\nOther updates:\n\n"+
+				" * eclass/foo.eclass\n"+
+				" * metadata\n"),
+		Entry("more atoms", []string{"dev-libs/foo-1.2", "dev-libs/new-1.0", "dev-libs/bar-2.1"},
+			[]string{"eclass/foo.eclass"},
+			"Automatic update of the branch master for specfile core-kit.yml by mark-bot\n"+
+				"\n| Package | Old version | New version | Homepage |\n"+
+				"|---------|-------------|-------------|----------|\n"+
+				"| dev-libs/foo | 1.1 | 1.2 | https://foo.org |\n"+
+				"| dev-libs/new (RDEPEND of dev-libs/foo-1.2) | - | 1.0 |  |\n"+
+				"| dev-libs/bar | 2.0 | 2.1 |  |\n"+
+				"\nOther updates:\n\n"+
+				" * eclass/foo.eclass\n"),
+	)
+
+	It("fails with an invalid atom", func() {
+		bot := newTestMergeBot()
+		_, err := bot.GetAggregatedPrBody(mkit, kit, []string{"foo"}, []string{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -13,6 +13,7 @@ import (
 	"github.com/macaroni-os/mark-devkit/pkg/helpers"
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
@@ -59,11 +60,16 @@ func (m *MergeBot) mergeAtom(atom *specs.RepoScanAtom,
 		"Processing atom %s:\n%s",
 		atom.Atom, yamlAtom))
 
-	// Create ebuild
+	// Create ebuild. The atom is updated with the revision bump
+	// of the ebuild if the package is changed.
+	sourceAtom := atom.Atom
 	ebuildFile, oldEbuildFile, err := m.copyEbuild(sourcePkgDir, targetPkgDir, atom)
 	if err != nil {
 		return err
 	}
+	if dep, isDep := m.pulledDeps[sourceAtom]; isDep && sourceAtom != atom.Atom {
+		m.pulledDeps[atom.Atom] = dep
+	}
 
 	// Create manifest
 	manifestFile, err = m.createManifest(targetPkgDir, atom)
@@ -92,6 +98,29 @@ func (m *MergeBot) mergeAtom(atom *specs.RepoScanAtom,
 	}
 
 	m.files4Commit[atom.Atom] = files4commit
+	m.atoms4Commit[atom.Atom] = atom
+
+	return nil
+}
+
+// getAtom4Commit returns the merged atom of the files to commit.
+// The atom of the files could be a revision bump not available
+// on the resolver.
+func (m *MergeBot) getAtom4Commit(atom string) *specs.RepoScanAtom {
+	if a, present := m.atoms4Commit[atom]; present {
+		return a
+	}
+
+	gp, err := gentoo.ParsePackageStr(atom)
+	if err != nil {
+		return nil
+	}
+	catpkg := gp.GetPackageName()
+	for idx := range m.Resolver.Map[catpkg] {
+		if m.Resolver.Map[catpkg][idx].Atom == atom {
+			return &m.Resolver.Map[catpkg][idx]
+		}
+	}
 
 	return nil
 }
@@ -7,10 +7,13 @@ package kit
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
@@ -42,69 +45,61 @@ func (m *MergeBot) BumpAtoms(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 		return err
 	}
 
-	for pkg, files := range m.files4Commit {
+	var prBranches map[string]plumbing.Hash
+	if opts.PullRequest {
+		prBranches, err = m.getRemoteBumpBranches(kit)
+		if err != nil {
+			return err
+		}
+	}
 
-		if opts.PullRequest {
+	bumpBranches := m.getBumpBranches(kit, opts)
+	branches := []string{}
+	for branch := range bumpBranches {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
 
-			prBranchName := GetPrBranchNameForPkgBump(pkg, kit.Branch)
-			prBranchExists, err := BranchExists(kit.Url, prBranchName)
-			if err != nil {
-				return err
-			}
+	for _, prBranchName := range branches {
+		atoms := bumpBranches[prBranchName]
+		name := strings.Join(atoms, ", ")
+		var prBranchHash plumbing.Hash
+		prBranchExists := false
 
-			if prBranchExists {
-				// PR is already been pushed.
-				m.Logger.InfoC(fmt.Sprintf(
-					"[%s] PR branch already present. Nothing to do.",
-					pkg))
-				m.branches2Skip[prBranchName] = true
+		if opts.PullRequest {
 
-				// Restore committed files in order to avoid
-				// that the same changes will be added in new commit.
-				err = m.restoreFiles(kitDir, files, opts, worktree)
-				if err != nil {
-					return err
-				}
-				continue
-			}
+			prBranchHash, prBranchExists = prBranches[prBranchName]
 
-			branchRef := plumbing.NewBranchReferenceName(prBranchName)
-			ref := plumbing.NewHashReference(branchRef, headRef.Hash())
-			// The created reference is saved in the storage.
-			err = repo.Storer.SetReference(ref)
+			err = m.checkoutPrBranch(repo, worktree, headRef, prBranchName, opts)
 			if err != nil {
 				return err
 			}
 
-			// Creating the new branch for the PR.
-			branchCoOpts := git.CheckoutOptions{
-				Branch: plumbing.ReferenceName(branchRef),
-				Create: false,
-				Keep:   true,
-			}
-
-			if err := worktree.Checkout(&branchCoOpts); err != nil {
-				return err
-			}
-
 			m.Logger.Info(fmt.Sprintf(":factory:[%s] Created branch %s.",
-				pkg, prBranchName))
+				name, prBranchName))
 
 		}
 
-		cMsg := fmt.Sprintf("Bump %s", pkg)
-		commitHash, err := m.commitFiles(kitDir, files, cMsg, opts, worktree)
-		if err != nil {
-			return err
-		}
+		// The versions of the same slot are committed on the same branch.
+		files := []string{}
+		var commitHash plumbing.Hash
+		for _, pkg := range atoms {
+			cMsg := fmt.Sprintf("Bump %s", pkg)
+			if dep, isDep := m.pulledDeps[pkg]; isDep {
+				cMsg += fmt.Sprintf("\n\nPulled in as %s.", dep)
+			}
+			commitHash, err = m.commitFiles(kitDir, m.files4Commit[pkg], cMsg, opts, worktree)
+			if err != nil {
+				return err
+			}
+			files = append(files, m.files4Commit[pkg]...)
 
-		if opts.Verbose {
-			commit, _ := repo.CommitObject(commitHash)
-			m.Logger.InfoC(fmt.Sprintf("%s", commit))
+			if opts.Verbose {
+				commit, _ := repo.CommitObject(commitHash)
+				m.Logger.InfoC(fmt.Sprintf("%s", commit))
+			}
 		}
 
-		m.hasCommit = true
-
 		if opts.PullRequest {
 
 			// Return to working branch
@@ -126,12 +121,81 @@ func (m *MergeBot) BumpAtoms(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				return err
 			}
 
+			if prBranchExists {
+				// The new commit is based on the current HEAD of
+				// the target branch. If the changes are different,
+				// for example for a new version of the slot, the PR
+				// branch is force-pushed with the new commits.
+				updated, err := m.prBranchIsUpdated(repo, kitDir, prBranchName,
+					prBranchHash, commitHash, files)
+				if err != nil {
+					return err
+				}
+
+				if updated {
+					// PR is already been pushed.
+					m.Logger.InfoC(fmt.Sprintf(
+						"[%s] PR branch already present. Nothing to do.",
+						name))
+					m.branches2Skip[prBranchName] = true
+					continue
+				}
+
+				m.Logger.InfoC(fmt.Sprintf(
+					":recycle:[%s] PR branch already present but outdated. It will be updated.",
+					name))
+				m.branches2Update[prBranchName] = true
+			}
+
 		}
+
+		m.hasCommit = true
 	}
 
 	return nil
 }
 
+// getBumpBranches returns the atoms to commit grouped by PR branch. In
+// the package mode the branch is shared by the versions of the same slot
+// of the package. Without pull requests all the atoms are committed on
+// the target branch (the empty key).
+func (m *MergeBot) getBumpBranches(kit *specs.ReposcanKit,
+	opts *MergeBotOpts) map[string][]string {
+	ans := make(map[string][]string, 0)
+
+	for pkg := range m.files4Commit {
+		prBranchName := ""
+		if opts.PullRequest {
+			prBranchName = m.getPrBranchName(opts, kit,
+				m.getSlotBumpBranchName(kit, pkg), pkg)
+		}
+		ans[prBranchName] = append(ans[prBranchName], pkg)
+	}
+
+	for _, atoms := range ans {
+		sort.Strings(atoms)
+	}
+
+	return ans
+}
+
+// getSlotBumpBranchName returns the PR branch of the slot of the atom.
+func (m *MergeBot) getSlotBumpBranchName(kit *specs.ReposcanKit, atom string) string {
+	catpkg := atom
+	slot := ""
+
+	gp, err := gentoo.ParsePackageStr(atom)
+	if err == nil {
+		catpkg = gp.GetPackageName()
+	}
+
+	if a := m.getAtom4Commit(atom); a != nil {
+		slot = a.GetMetadataValue("SLOT")
+	}
+
+	return GetPrBranchNameForSlotBump(catpkg, slot, kit.Branch)
+}
+
 func (m *MergeBot) restoreFiles(kitDir string, files []string,
 	opts *MergeBotOpts, worktree *git.Worktree) error {
 
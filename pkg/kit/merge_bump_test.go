@@ -0,0 +1,50 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge bump", func() {
+
+	kit := &specs.ReposcanKit{Name: "core-kit", Branch: "master"}
+
+	It("groups the revision bumps by the slot of the package", func() {
+		bot := newTestMergeBot(
+			newTestAtom("dev-libs/foo-1.0", "2", "core-kit"),
+			newTestAtom("dev-libs/foo-3.0", "3", "core-kit"),
+		)
+
+		// The atom merged as revision bump is not available on resolver.
+		bumped := newTestAtom("dev-libs/foo-1.0", "2", "core-kit")
+		bumped.Atom = "dev-libs/foo-1.0-r1"
+		bot.AddAtom4Commit(bumped, "dev-libs/foo/foo-1.0-r1.ebuild")
+		bot.AddAtom4Commit(newTestAtom("dev-libs/foo-3.0", "3/3.0", "core-kit"),
+			"dev-libs/foo/foo-3.0.ebuild")
+
+		opts := NewMergeBotOpts()
+		opts.PullRequest = true
+
+		Expect(bot.GetBumpBranches(kit, opts)).To(Equal(map[string][]string{
+			"merge/master/bump-dev-libs_foo-slot-2": {"dev-libs/foo-1.0-r1"},
+			"merge/master/bump-dev-libs_foo-slot-3": {"dev-libs/foo-3.0"},
+		}))
+	})
+
+	It("uses a single branch without pull requests", func() {
+		bot := newTestMergeBot()
+		bot.AddAtom4Commit(newTestAtom("dev-libs/foo-1.0", "2", "core-kit"))
+		bot.AddAtom4Commit(newTestAtom("dev-libs/bar-1.0", "0", "core-kit"))
+
+		Expect(bot.GetBumpBranches(kit, NewMergeBotOpts())).To(Equal(map[string][]string{
+			"": {"dev-libs/bar-1.0", "dev-libs/foo-1.0"},
+		}))
+	})
+})
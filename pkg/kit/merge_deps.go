@@ -0,0 +1,152 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"fmt"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+)
+
+// MergeDependency describes a package pulled in as dependency
+// of a merged atom because missing on target kit.
+type MergeDependency struct {
+	Atom   *specs.RepoScanAtom
+	Parent string
+	Kind   string
+	Depth  int
+}
+
+type mergeDepNode struct {
+	Atom     *specs.RepoScanAtom
+	WithDeps *specs.MergeKitWithDeps
+	Depth    int
+}
+
+func (d *MergeDependency) String() string {
+	return fmt.Sprintf("%s of %s", d.Kind, d.Parent)
+}
+
+// GetPulledDeps returns the dependencies pulled in by the merge.
+func (m *MergeBot) GetPulledDeps() map[string]*MergeDependency { return m.pulledDeps }
+
+// searchDependencies walks the dependencies graph of the candidates
+// and returns the packages missing on target kit that are available
+// on the source kits.
+func (m *MergeBot) searchDependencies(mkit *specs.MergeKit,
+	roots []*mergeDepNode, candidates []*specs.RepoScanAtom) ([]*specs.RepoScanAtom, error) {
+	ans := []*specs.RepoScanAtom{}
+
+	// The packages already merged or defined on the kit file
+	// are never pulled in as dependencies.
+	visited := make(map[string]bool, 0)
+	for _, candidate := range candidates {
+		visited[candidate.CatPkg] = true
+	}
+	for _, atom := range mkit.Target.Atoms {
+		gp, err := gentoo.ParsePackageStr(atom.Package)
+		if err != nil {
+			continue
+		}
+		visited[gp.GetPackageName()] = true
+	}
+
+	queue := roots
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if node.Depth >= node.WithDeps.GetDepth() {
+			continue
+		}
+
+		for _, kind := range node.WithDeps.GetKinds() {
+			for _, dep := range node.Atom.RelationsByKind[kind] {
+				entry, err := parseDependency(kind, dep)
+				if err != nil {
+					m.Logger.Warning(fmt.Sprintf(
+						"[%s] Invalid dependency %s: %s. Skipped.",
+						node.Atom.Atom, dep, err.Error()))
+					continue
+				}
+
+				// The blockers never pull in packages.
+				if entry.Blocker {
+					continue
+				}
+
+				gp := entry.Package
+				catpkg := gp.GetPackageName()
+				if _, present := visited[catpkg]; present {
+					continue
+				}
+
+				if !node.WithDeps.IsCategoryAdmitted(gp.Category) {
+					m.Logger.Debug(fmt.Sprintf(
+						"[%s] Dependency %s with category not admitted. Skipped.",
+						node.Atom.Atom, catpkg))
+					continue
+				}
+				visited[catpkg] = true
+
+				if m.TargetResolver.IsPresentPackage(catpkg) {
+					continue
+				}
+
+				candidate, err := m.searchDependency(sanitizeDependency(dep), node.Atom.Kit)
+				if err != nil {
+					m.Logger.Warning(fmt.Sprintf(
+						":warning:[%s] Dependency %s not found on source kits: %s",
+						node.Atom.Atom, dep, err.Error()))
+					continue
+				}
+
+				mdep := &MergeDependency{
+					Atom:   candidate,
+					Parent: node.Atom.Atom,
+					Kind:   kind,
+					Depth:  node.Depth + 1,
+				}
+				m.pulledDeps[candidate.Atom] = mdep
+
+				m.Logger.InfoC(fmt.Sprintf(":link:[%s] Pulled in %s from kit %s (%s).",
+					node.Atom.CatPkg, candidate.Atom, candidate.Kit, mdep))
+
+				ans = append(ans, candidate)
+				queue = append(queue, &mergeDepNode{
+					Atom:     candidate,
+					WithDeps: node.WithDeps,
+					Depth:    node.Depth + 1,
+				})
+			}
+		}
+	}
+
+	return ans, nil
+}
+
+// searchDependency returns the last version of the package that matches
+// the dependency (with the version conditions and the slot) with priority
+// to the kit of the parent.
+func (m *MergeBot) searchDependency(dep, kit string) (*specs.RepoScanAtom, error) {
+	atoms, err := m.Resolver.GetValidPackages(dep, NewPortageResolverOpts())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(atoms) == 0 {
+		return nil, fmt.Errorf("No packages found matching %s.", dep)
+	}
+
+	for idx := len(atoms) - 1; idx >= 0; idx-- {
+		if atoms[idx].Kit == kit {
+			return atoms[idx], nil
+		}
+	}
+
+	return atoms[len(atoms)-1], nil
+}
@@ -0,0 +1,224 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"strings"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newTestAtom(atom, slot, kit string, deps ...string) *specs.RepoScanAtom {
+	pf := atom[strings.Index(atom, "/")+1:]
+	ans := &specs.RepoScanAtom{
+		Atom:     atom,
+		Category: atom[0:strings.Index(atom, "/")],
+		Kit:      kit,
+		Metadata: map[string]string{
+			"KEYWORDS": "*",
+			"SLOT":     slot,
+		},
+		RelationsByKind: map[string][]string{},
+	}
+	// Ex: foo-bar-1.2.3 => foo-bar
+	idx := strings.LastIndex(pf, "-")
+	if strings.HasPrefix(pf[idx+1:], "r") {
+		idx = strings.LastIndex(pf[0:idx], "-")
+	}
+	ans.Package = pf[0:idx]
+	ans.CatPkg = ans.Category + "/" + ans.Package
+	if len(deps) > 0 {
+		ans.RelationsByKind["RDEPEND"] = deps
+	}
+	return ans
+}
+
+func newTestMergeBot(atoms ...*specs.RepoScanAtom) *MergeBot {
+	bot := NewMergeBot(specs.NewMarkDevkitConfig(nil))
+	for _, atom := range atoms {
+		bot.Resolver.AddPackageAtom(atom.CatPkg, atom)
+	}
+	return bot
+}
+
+func getAtoms(atoms []*specs.RepoScanAtom) []string {
+	ans := []string{}
+	for _, atom := range atoms {
+		ans = append(ans, atom.Atom)
+	}
+	return ans
+}
+
+var _ = Describe("Merge dependencies", func() {
+
+	mkit := &specs.MergeKit{
+		Target: specs.MergeKitTarget{
+			Name: "core-kit",
+			Atoms: []*specs.MergeKitAtom{
+				{Package: "app-misc/root"},
+			},
+		},
+	}
+	withDeps := &specs.MergeKitWithDeps{
+		Enable: true,
+		Kinds:  []string{"RDEPEND"},
+	}
+
+	Context("Pull in", func() {
+
+		It("the last version of the dependency", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"dev-libs/foo")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{"dev-libs/foo-2.0"}))
+			Expect(bot.GetPulledDeps()).To(HaveKey("dev-libs/foo-2.0"))
+		})
+
+		It("the version admitted by the condition", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"<dev-libs/foo-2.0")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-1.5", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{"dev-libs/foo-1.5"}))
+		})
+
+		It("the exact version", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"=dev-libs/foo-1.0")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{"dev-libs/foo-1.0"}))
+		})
+
+		It("the version of the slot without the slot operators", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"dev-libs/foo:1=")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "1", "core-kit"),
+				newTestAtom("dev-libs/foo-1.1", "1/1.1", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "2", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{"dev-libs/foo-1.1"}))
+		})
+
+		It("the version of the parent kit", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"dev-libs/foo")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/foo-2.0", "0", "dev-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{"dev-libs/foo-1.0"}))
+		})
+
+		It("the dependencies of the dependencies up to the depth", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"dev-libs/foo")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit", "dev-libs/bar"),
+				newTestAtom("dev-libs/bar-1.0", "0", "core-kit", "dev-libs/baz"),
+				newTestAtom("dev-libs/baz-1.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root},
+				&specs.MergeKitWithDeps{
+					Enable: true,
+					Depth:  2,
+					Kinds:  []string{"RDEPEND"},
+				})
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{
+				"dev-libs/foo-1.0", "dev-libs/bar-1.0",
+			}))
+		})
+	})
+
+	Context("Skip", func() {
+
+		It("the blockers", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"!dev-libs/foo", "!!<dev-libs/bar-2.0")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("dev-libs/bar-1.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(deps).To(BeEmpty())
+		})
+
+		It("the packages present on target kit", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"dev-libs/foo")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+			)
+			bot.TargetResolver.AddPackageAtom("dev-libs/foo",
+				newTestAtom("dev-libs/foo-0.9", "0", "core-kit"))
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(deps).To(BeEmpty())
+		})
+
+		It("the categories not admitted", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				"dev-libs/foo", "sys-libs/bar")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+				newTestAtom("sys-libs/bar-1.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root},
+				&specs.MergeKitWithDeps{
+					Enable:     true,
+					Kinds:      []string{"RDEPEND"},
+					Categories: []string{"dev-*"},
+				})
+			Expect(err).Should(BeNil())
+			Expect(getAtoms(deps)).To(Equal([]string{"dev-libs/foo-1.0"}))
+		})
+
+		It("the dependencies without versions admitted", func() {
+			root := newTestAtom("app-misc/root-1.0", "0", "core-kit",
+				">=dev-libs/foo-3.0")
+			bot := newTestMergeBot(root,
+				newTestAtom("dev-libs/foo-1.0", "0", "core-kit"),
+			)
+
+			deps, err := bot.SearchDependencies(mkit, []*specs.RepoScanAtom{root}, withDeps)
+			Expect(err).Should(BeNil())
+			Expect(deps).To(BeEmpty())
+		})
+	})
+})
@@ -18,7 +18,8 @@ import (
 	"github.com/macaroni-os/macaronictl/pkg/utils"
 )
 
-func (m *MergeBot) MergeEclasses(mkit *specs.MergeKit, opts *MergeBotOpts) error {
+func (m *MergeBot) MergeEclasses(mkit *specs.MergeKit,
+	candidates []*specs.RepoScanAtom, opts *MergeBotOpts) error {
 	eMap := mkit.GetEclassesInclude()
 	if eMap == nil {
 		return nil
@@ -53,6 +54,13 @@ func (m *MergeBot) MergeEclasses(mkit *specs.MergeKit, opts *MergeBotOpts) error
 		}
 	}
 
+	if auto := mkit.GetEclassesAuto(); auto != nil {
+		err := m.mergeAutoEclasses(mkit, candidates, kitDir, auto, &eclassMap4Commit)
+		if err != nil {
+			return err
+		}
+	}
+
 	m.Logger.Info(fmt.Sprintf(":dart:Found %d eclasses to add/updates.",
 		len(eclassMap4Commit)))
 
@@ -89,13 +97,14 @@ func (m *MergeBot) MergeEclasses(mkit *specs.MergeKit, opts *MergeBotOpts) error
 		if opts.PullRequest {
 			// NOTE: pull request for a new branch it doesn't make sense
 			// Probably we need to add a check.
-			prBranchName := GetPrBranchNameForEclasses(kit.Branch)
+			prBranchName := m.getPrBranchName(opts, kit,
+				GetPrBranchNameForEclasses(kit.Branch), "")
 
 			// Restore committed files in order to avoid
 			// that the same changes will be added in new commit.
 			defer m.restoreFiles(kitDir, files, opts, worktree)
 
-			prBranchExists, err := BranchExists(kit.Url, prBranchName)
+			prBranchExists, err := m.prBranchExists(opts, kit, prBranchName)
 			if err != nil {
 				return err
 			}
@@ -108,24 +117,10 @@ func (m *MergeBot) MergeEclasses(mkit *specs.MergeKit, opts *MergeBotOpts) error
 				return nil
 			}
 
-			branchRef := plumbing.NewBranchReferenceName(prBranchName)
-			ref := plumbing.NewHashReference(branchRef, headRef.Hash())
-			// The created reference is saved in the storage.
-			err = repo.Storer.SetReference(ref)
+			err = m.checkoutPrBranch(repo, worktree, headRef, prBranchName, opts)
 			if err != nil {
 				return err
 			}
-
-			// Creating the new branch for the PR.
-			branchCoOpts := git.CheckoutOptions{
-				Branch: plumbing.ReferenceName(branchRef),
-				Create: false,
-				Keep:   true,
-			}
-
-			if err := worktree.Checkout(&branchCoOpts); err != nil {
-				return err
-			}
 		}
 
 		commitHash, err := m.commitFiles(kitDir, files, cMsg, opts, worktree)
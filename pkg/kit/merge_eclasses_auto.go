@@ -0,0 +1,419 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/helpers"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/macaroni-os/macaronictl/pkg/utils"
+)
+
+var eclassNameRegex = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+-]*$`)
+
+type requiredEclass struct {
+	// The atom or the eclass that inherits the eclass.
+	RequiredBy string
+	// The source kit of the atom. Empty for the atoms of the
+	// target kit.
+	Kit string
+}
+
+// MergeEclassesReport describes the eclasses required by the packages
+// of the target kit and how the missing eclasses are been resolved.
+type MergeEclassesReport struct {
+	// The masters of the target kit defined on metadata/layout.conf.
+	Masters []string `json:"masters,omitempty" yaml:"masters,omitempty"`
+	// The number of the eclasses required by the packages.
+	Required int `json:"required" yaml:"required"`
+	// The eclasses copied from the source kits.
+	Added []*MergeEclassEntry `json:"added,omitempty" yaml:"added,omitempty"`
+	// The eclasses already provided by the masters of the kit.
+	Provided []*MergeEclassEntry `json:"provided,omitempty" yaml:"provided,omitempty"`
+	// The eclasses not available on the source kits.
+	Missing []*MergeEclassEntry `json:"missing,omitempty" yaml:"missing,omitempty"`
+	// The eclasses excluded by the allow/deny rules.
+	Denied []*MergeEclassEntry `json:"denied,omitempty" yaml:"denied,omitempty"`
+}
+
+type MergeEclassEntry struct {
+	Eclass     string `json:"eclass" yaml:"eclass"`
+	RequiredBy string `json:"required_by" yaml:"required_by"`
+	// The source kit of the eclass or the master that provides it.
+	Kit string `json:"kit,omitempty" yaml:"kit,omitempty"`
+}
+
+// GetEclassesReport returns the report of the auto eclasses merge.
+// It's nil when the auto eclasses are disabled.
+func (m *MergeBot) GetEclassesReport() *MergeEclassesReport { return m.eclassesReport }
+
+// mergeAutoEclasses copies from the source kits the eclasses inherited,
+// directly or through other eclasses, by the packages of the target kit
+// and missing on target kit and on its masters.
+func (m *MergeBot) mergeAutoEclasses(mkit *specs.MergeKit,
+	candidates []*specs.RepoScanAtom, targetKitDir string,
+	auto *specs.MergeKitEclassesAuto, eclassMap *map[string]string) error {
+
+	me := *eclassMap
+	targetEclassdir := filepath.Join(targetKitDir, "eclass")
+
+	allow, err := compileEclassesRules(auto.Allow)
+	if err != nil {
+		return err
+	}
+	deny, err := compileEclassesRules(auto.Deny)
+	if err != nil {
+		return err
+	}
+
+	masters, err := getLayoutMasters(mkit, targetKitDir)
+	if err != nil {
+		return err
+	}
+	mastersDirs := m.getMastersEclassDirs(mkit, masters)
+
+	required := m.getRequiredEclasses(candidates)
+
+	queue := []string{}
+	for name := range required {
+		queue = append(queue, name)
+	}
+	sort.Strings(queue)
+
+	report := &MergeEclassesReport{
+		Masters:  masters,
+		Added:    []*MergeEclassEntry{},
+		Provided: []*MergeEclassEntry{},
+		Missing:  []*MergeEclassEntry{},
+		Denied:   []*MergeEclassEntry{},
+	}
+	m.eclassesReport = report
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		req := required[name]
+		file := name + ".eclass"
+		entry := &MergeEclassEntry{
+			Eclass:     name,
+			RequiredBy: req.RequiredBy,
+		}
+
+		targetEclass := filepath.Join(targetEclassdir, file)
+		if !utils.Exists(targetEclass) {
+			if master := findEclassOnMasters(mastersDirs, masters, file); master != "" {
+				// The eclasses inherited by the eclass are
+				// resolved by the master too.
+				entry.Kit = master
+				report.Provided = append(report.Provided, entry)
+				continue
+			}
+
+			if !eclassAdmitted(file, allow, deny) {
+				report.Denied = append(report.Denied, entry)
+				continue
+			}
+
+			sourceKit, sourceEclass := m.findEclassSource(mkit, file, req.Kit)
+			if sourceEclass == "" {
+				m.Logger.Warning(fmt.Sprintf(
+					"[%s] Eclass inherited by %s not available on source kits.",
+					name, req.RequiredBy))
+				report.Missing = append(report.Missing, entry)
+				continue
+			}
+
+			err = helpers.CopyFile(sourceEclass, targetEclass)
+			if err != nil {
+				return err
+			}
+			me[file] = targetEclass
+
+			m.Logger.InfoC(fmt.Sprintf(
+				":magic_wand:[%s] Added eclass from kit %s (inherited by %s).",
+				name, sourceKit, req.RequiredBy))
+			entry.Kit = sourceKit
+			report.Added = append(report.Added, entry)
+		}
+
+		// Check the eclasses inherited by the eclass.
+		inherits, err := getEclassInherits(targetEclass)
+		if err != nil {
+			return err
+		}
+		for _, e := range inherits {
+			if _, present := required[e]; present {
+				continue
+			}
+			required[e] = &requiredEclass{
+				RequiredBy: file,
+				Kit:        req.Kit,
+			}
+			queue = append(queue, e)
+		}
+	}
+	report.Required = len(required)
+
+	m.Logger.Info(fmt.Sprintf(
+		":dart:Eclasses required: %d, auto added: %d, provided by masters: %d, missing: %d, denied: %d.",
+		report.Required, len(report.Added), len(report.Provided),
+		len(report.Missing), len(report.Denied)))
+	if len(report.Missing) > 0 {
+		m.Logger.Warning(fmt.Sprintf("Missing eclasses: %s",
+			strings.Join(report.GetNames(report.Missing), ", ")))
+	}
+	if len(report.Denied) > 0 {
+		m.Logger.Debug(fmt.Sprintf("Denied eclasses: %s",
+			strings.Join(report.GetNames(report.Denied), ", ")))
+	}
+
+	return nil
+}
+
+// GetNames returns the names of the eclasses of the entries.
+func (r *MergeEclassesReport) GetNames(entries []*MergeEclassEntry) []string {
+	ans := []string{}
+	for _, e := range entries {
+		ans = append(ans, e.Eclass)
+	}
+	return ans
+}
+
+// getEclassesReportBody returns the list of the eclasses added
+// automatically for the body of the pull request.
+func (m *MergeBot) getEclassesReportBody() string {
+	if m.eclassesReport == nil || len(m.eclassesReport.Added) == 0 {
+		return ""
+	}
+
+	ans := "\n\nEclasses added automatically:\n"
+	for _, e := range m.eclassesReport.Added {
+		ans += fmt.Sprintf("- `%s` from %s (inherited by %s)\n",
+			e.Eclass, e.Kit, e.RequiredBy)
+	}
+	if len(m.eclassesReport.Missing) > 0 {
+		ans += fmt.Sprintf("\nMissing eclasses: %s\n",
+			strings.Join(m.eclassesReport.GetNames(m.eclassesReport.Missing), ", "))
+	}
+
+	return ans
+}
+
+// getLayoutMasters returns the masters defined on the metadata/layout.conf
+// of the target kit. If the file is not present are used the masters
+// defined on the specfile that will be written on layout.conf.
+func getLayoutMasters(mkit *specs.MergeKit, targetKitDir string) ([]string, error) {
+	ans := []string{}
+	masters := ""
+
+	layoutConf := filepath.Join(targetKitDir, "metadata", "layout.conf")
+	if utils.Exists(layoutConf) {
+		f, err := os.Open(layoutConf)
+		if err != nil {
+			return nil, fmt.Errorf("error on open %s: %s",
+				layoutConf, err.Error())
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			key, value, found := strings.Cut(scanner.Text(), "=")
+			if found && strings.TrimSpace(key) == "masters" {
+				masters = value
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error on read %s: %s",
+				layoutConf, err.Error())
+		}
+	} else if mkit.GetMetadata().GetLayoutMasters() != mkit.Target.Name {
+		masters = mkit.GetMetadata().GetLayoutMasters()
+	}
+
+	for _, master := range strings.Fields(masters) {
+		if master != mkit.Target.Name {
+			ans = append(ans, master)
+		}
+	}
+
+	return ans, nil
+}
+
+// getMastersEclassDirs returns the eclass directories of the masters
+// available on the sources or on the target directory.
+func (m *MergeBot) getMastersEclassDirs(mkit *specs.MergeKit, masters []string) map[string]string {
+	ans := make(map[string]string, 0)
+
+	for _, master := range masters {
+		for _, dir := range []string{m.GetSourcesDir(), m.GetTargetDir()} {
+			eclassDir := filepath.Join(dir, master, "eclass")
+			if utils.Exists(eclassDir) {
+				ans[master] = eclassDir
+				break
+			}
+		}
+
+		if _, present := ans[master]; !present {
+			m.Logger.Warning(fmt.Sprintf(
+				"[%s] Master of the kit %s not available. Its eclasses are not checked.",
+				master, mkit.Target.Name))
+		}
+	}
+
+	return ans
+}
+
+// findEclassOnMasters returns the first master that provides the eclass.
+func findEclassOnMasters(mastersDirs map[string]string, masters []string, file string) string {
+	for _, master := range masters {
+		dir, present := mastersDirs[master]
+		if present && utils.Exists(filepath.Join(dir, file)) {
+			return master
+		}
+	}
+	return ""
+}
+
+// getRequiredEclasses returns the eclasses inherited by the candidates
+// and by the packages already available on target kit.
+func (m *MergeBot) getRequiredEclasses(candidates []*specs.RepoScanAtom) map[string]*requiredEclass {
+	ans := make(map[string]*requiredEclass, 0)
+
+	addEclasses := func(atom *specs.RepoScanAtom, kit string) {
+		for _, eclass := range atom.Eclasses {
+			if len(eclass) == 0 {
+				continue
+			}
+			if _, present := ans[eclass[0]]; present {
+				continue
+			}
+			ans[eclass[0]] = &requiredEclass{
+				RequiredBy: atom.Atom,
+				Kit:        kit,
+			}
+		}
+	}
+
+	// The eclasses of the candidates have priority in order to
+	// use the kit that provides the atom.
+	for _, candidate := range candidates {
+		addEclasses(candidate, candidate.Kit)
+	}
+
+	for _, atoms := range m.TargetResolver.Map {
+		for idx := range atoms {
+			addEclasses(&atoms[idx], "")
+		}
+	}
+
+	return ans
+}
+
+// findEclassSource returns the source kit and the path of the eclass.
+// The kit in input is checked before the others source kits.
+func (m *MergeBot) findEclassSource(mkit *specs.MergeKit, file, kit string) (string, string) {
+	kits := []string{}
+	if kit != "" && mkit.GetSourceKit(kit) != nil {
+		kits = append(kits, kit)
+	}
+	for _, source := range mkit.Sources {
+		if source.Name != kit {
+			kits = append(kits, source.Name)
+		}
+	}
+
+	for _, k := range kits {
+		sourceEclass := filepath.Join(m.GetSourcesDir(), k, "eclass", file)
+		if utils.Exists(sourceEclass) {
+			return k, sourceEclass
+		}
+	}
+
+	return "", ""
+}
+
+func compileEclassesRules(rules []string) ([]*regexp.Regexp, error) {
+	ans := []*regexp.Regexp{}
+	for _, rule := range rules {
+		r, err := regexp.Compile(rule)
+		if err != nil {
+			return nil, fmt.Errorf("error on parse eclasses rule %s: %s",
+				rule, err.Error())
+		}
+		ans = append(ans, r)
+	}
+	return ans, nil
+}
+
+func eclassAdmitted(file string, allow, deny []*regexp.Regexp) bool {
+	for _, r := range deny {
+		if r.MatchString(file) {
+			return false
+		}
+	}
+
+	if len(allow) == 0 {
+		return true
+	}
+
+	for _, r := range allow {
+		if r.MatchString(file) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getEclassInherits returns the eclasses defined in the inherit
+// statements of the eclass. The eclasses defined with variables
+// are ignored.
+func getEclassInherits(eclassFile string) ([]string, error) {
+	ans := []string{}
+
+	f, err := os.Open(eclassFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "inherit ") {
+			continue
+		}
+
+		if idx := strings.Index(line, "#"); idx > 0 {
+			line = line[0:idx]
+		}
+
+		for _, name := range strings.Fields(line)[1:] {
+			if strings.Contains(name, "$") {
+				continue
+			}
+			if !eclassNameRegex.MatchString(name) {
+				// Ex: inherit foo || die
+				break
+			}
+			ans = append(ans, name)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error on read eclass %s: %s",
+			eclassFile, err.Error())
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeTestFile(file, content string) {
+	Expect(os.MkdirAll(filepath.Dir(file), 0755)).To(Succeed())
+	Expect(os.WriteFile(file, []byte(content), 0644)).To(Succeed())
+}
+
+func getEntries(entries []*MergeEclassEntry) []string {
+	ans := []string{}
+	for _, e := range entries {
+		ans = append(ans, e.Eclass+"@"+e.Kit)
+	}
+	return ans
+}
+
+var _ = Describe("Merge auto eclasses", func() {
+
+	var workdir, targetKitDir string
+	var bot *MergeBot
+
+	mkit := &specs.MergeKit{
+		Sources: []*specs.ReposcanKit{
+			{Name: "core-kit"},
+			{Name: "gentoo-kit"},
+		},
+		Target: specs.MergeKitTarget{
+			Name: "dev-kit",
+		},
+	}
+	candidates := []*specs.RepoScanAtom{
+		{
+			Atom:     "dev-libs/foo-1.0",
+			Kit:      "gentoo-kit",
+			Eclasses: [][]string{{"meson", "x"}, {"multilib", "x"}, {"bar", "x"}},
+		},
+	}
+
+	BeforeEach(func() {
+		workdir = GinkgoT().TempDir()
+		bot = NewMergeBot(specs.NewMarkDevkitConfig(nil))
+		bot.SetWorkDir(workdir)
+
+		coreDir := filepath.Join(bot.GetSourcesDir(), "core-kit", "eclass")
+		writeTestFile(filepath.Join(coreDir, "multilib.eclass"), "")
+		writeTestFile(filepath.Join(coreDir, "toolchain-funcs.eclass"), "")
+
+		gentooDir := filepath.Join(bot.GetSourcesDir(), "gentoo-kit", "eclass")
+		writeTestFile(filepath.Join(gentooDir, "meson.eclass"),
+			"inherit toolchain-funcs python-utils-r1\n")
+		writeTestFile(filepath.Join(gentooDir, "multilib.eclass"), "")
+		writeTestFile(filepath.Join(gentooDir, "toolchain-funcs.eclass"), "")
+		writeTestFile(filepath.Join(gentooDir, "python-utils-r1.eclass"), "")
+
+		targetKitDir = filepath.Join(bot.GetTargetDir(), "dev-kit")
+		Expect(os.MkdirAll(filepath.Join(targetKitDir, "eclass"), 0755)).To(Succeed())
+	})
+
+	It("skips the eclasses of the masters of layout.conf", func() {
+		writeTestFile(filepath.Join(targetKitDir, "metadata", "layout.conf"),
+			"repo-name = dev-kit\nmasters = core-kit\n")
+
+		added, err := bot.MergeAutoEclasses(mkit, candidates, targetKitDir,
+			&specs.MergeKitEclassesAuto{Enable: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(added).To(HaveLen(2))
+		Expect(added).To(HaveKey("meson.eclass"))
+		Expect(added).To(HaveKey("python-utils-r1.eclass"))
+		Expect(filepath.Join(targetKitDir, "eclass", "multilib.eclass")).ToNot(BeAnExistingFile())
+
+		report := bot.GetEclassesReport()
+		Expect(report.Masters).To(Equal([]string{"core-kit"}))
+		Expect(report.Required).To(Equal(5))
+		Expect(getEntries(report.Added)).To(Equal([]string{
+			"meson@gentoo-kit", "python-utils-r1@gentoo-kit",
+		}))
+		Expect(getEntries(report.Provided)).To(Equal([]string{
+			"multilib@core-kit", "toolchain-funcs@core-kit",
+		}))
+		Expect(report.Provided[1].RequiredBy).To(Equal("meson.eclass"))
+		Expect(getEntries(report.Missing)).To(Equal([]string{"bar@"}))
+	})
+
+	It("uses the masters of the specfile without layout.conf", func() {
+		_, err := bot.MergeAutoEclasses(mkit, candidates, targetKitDir,
+			&specs.MergeKitEclassesAuto{Enable: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		report := bot.GetEclassesReport()
+		Expect(report.Masters).To(Equal([]string{"core-kit"}))
+		Expect(getEntries(report.Provided)).To(Equal([]string{
+			"multilib@core-kit", "toolchain-funcs@core-kit",
+		}))
+	})
+
+	It("copies the eclasses of a kit without masters", func() {
+		writeTestFile(filepath.Join(targetKitDir, "metadata", "layout.conf"),
+			"repo-name = dev-kit\n")
+
+		_, err := bot.MergeAutoEclasses(mkit, candidates, targetKitDir,
+			&specs.MergeKitEclassesAuto{
+				Enable: true,
+				Deny:   []string{"^python-"},
+			})
+		Expect(err).ToNot(HaveOccurred())
+
+		report := bot.GetEclassesReport()
+		Expect(report.Masters).To(BeEmpty())
+		Expect(report.Provided).To(BeEmpty())
+		Expect(getEntries(report.Added)).To(Equal([]string{
+			"meson@gentoo-kit", "multilib@gentoo-kit", "toolchain-funcs@gentoo-kit",
+		}))
+		Expect(getEntries(report.Denied)).To(Equal([]string{"python-utils-r1@"}))
+	})
+})
@@ -67,9 +67,10 @@ func (m *MergeBot) MergeFixups(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 		if opts.PullRequest {
 			// NOTE: pull request for a new branch it doesn't make sense
 			// Probably we need to add a check.
-			prBranchName := GetPrBranchNameForFixup(name, kit.Branch)
+			prBranchName := m.getPrBranchName(opts, kit,
+				GetPrBranchNameForFixup(name, kit.Branch), "")
 
-			prBranchExists, err := BranchExists(kit.Url, prBranchName)
+			prBranchExists, err := m.prBranchExists(opts, kit, prBranchName)
 			if err != nil {
 				return err
 			}
@@ -86,25 +87,11 @@ func (m *MergeBot) MergeFixups(mkit *specs.MergeKit, opts *MergeBotOpts) error {
 				continue
 			}
 
-			branchRef := plumbing.NewBranchReferenceName(prBranchName)
-			ref := plumbing.NewHashReference(branchRef, headRef.Hash())
-			// The created reference is saved in the storage.
-			err = repo.Storer.SetReference(ref)
+			err = m.checkoutPrBranch(repo, worktree, headRef, prBranchName, opts)
 			if err != nil {
 				return err
 			}
 
-			// Creating the new branch for the PR.
-			branchCoOpts := git.CheckoutOptions{
-				Branch: plumbing.ReferenceName(branchRef),
-				Create: false,
-				Keep:   true,
-			}
-
-			if err := worktree.Checkout(&branchCoOpts); err != nil {
-				return err
-			}
-
 			m.fixupBranches[name] = include
 		}
 
@@ -100,7 +100,8 @@ func (m *MergeBot) PrepareMetadataDir(mkit *specs.MergeKit,
 			return err
 		}
 
-		prBranchName := GetPrBranchNameForMetadata(kit.Branch)
+		prBranchName := m.getPrBranchName(opts, kit,
+			GetPrBranchNameForMetadata(kit.Branch), "")
 
 		// Restore committed files in order to avoid
 		// that the same changes will be added in new commit.
@@ -110,7 +111,7 @@ func (m *MergeBot) PrepareMetadataDir(mkit *specs.MergeKit,
 		if opts.PullRequest {
 			// NOTE: pull request for a new branch it doesn't make sense
 			// Probably we need to add a check.
-			prBranchExists, err := BranchExists(kit.Url, prBranchName)
+			prBranchExists, err := m.prBranchExists(opts, kit, prBranchName)
 			if err != nil {
 				return err
 			}
@@ -123,24 +124,10 @@ func (m *MergeBot) PrepareMetadataDir(mkit *specs.MergeKit,
 				return nil
 			}
 
-			branchRef := plumbing.NewBranchReferenceName(prBranchName)
-			ref := plumbing.NewHashReference(branchRef, headRef.Hash())
-			// The created reference is saved in the storage.
-			err = repo.Storer.SetReference(ref)
+			err = m.checkoutPrBranch(repo, worktree, headRef, prBranchName, opts)
 			if err != nil {
 				return err
 			}
-
-			// Creating the new branch for the PR.
-			branchCoOpts := git.CheckoutOptions{
-				Branch: plumbing.ReferenceName(branchRef),
-				Create: false,
-				Keep:   true,
-			}
-
-			if err := worktree.Checkout(&branchCoOpts); err != nil {
-				return err
-			}
 		}
 
 		commitHash, err := m.commitFiles(kitDir, files, cMsg, opts, worktree)
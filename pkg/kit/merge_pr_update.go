@@ -0,0 +1,291 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+	"github.com/go-git/go-git/v5"
+	git_config "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// getRemoteBumpBranches returns the bump branches of the target kit
+// already pushed on the remote repository.
+func (m *MergeBot) getRemoteBumpBranches(kit *specs.ReposcanKit) (map[string]plumbing.Hash, error) {
+	if m.prBranches == nil {
+		prBranches, err := ListRemoteBranches(m.Config, kit.Url,
+			fmt.Sprintf("%s%s/bump-", prBranchPrefix, kit.Branch))
+		if err != nil {
+			return nil, err
+		}
+		m.prBranches = prBranches
+	}
+	return m.prBranches, nil
+}
+
+// prBranchIsUpdated checks if the files of the new commit are equal to
+// the files of the PR branch already present on the remote repository.
+func (m *MergeBot) prBranchIsUpdated(repo *git.Repository, kitDir, prBranchName string,
+	remoteHash, commitHash plumbing.Hash, files []string) (bool, error) {
+
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return false, err
+	}
+	auth, err := getFetchAuth(m.Config, remote.Config().URLs[0])
+	if err != nil {
+		return false, err
+	}
+
+	refSpec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s",
+		prBranchName, prBranchName)
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		RefSpecs:   []git_config.RefSpec{git_config.RefSpec(refSpec)},
+		Depth:      1,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return false, fmt.Errorf("error on fetch branch %s: %s",
+			prBranchName, err.Error())
+	}
+
+	remoteTree, err := getCommitTree(repo, remoteHash)
+	if err != nil {
+		return false, err
+	}
+	newTree, err := getCommitTree(repo, commitHash)
+	if err != nil {
+		return false, err
+	}
+
+	for _, file := range files {
+		// Drop kitDir prefix
+		f := file[len(kitDir)+1:]
+		if getTreeFileHash(remoteTree, f) != getTreeFileHash(newTree, f) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func getCommitTree(repo *git.Repository, hash plumbing.Hash) (*object.Tree, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("error on retrieve commit %s: %s",
+			hash.String(), err.Error())
+	}
+	return commit.Tree()
+}
+
+// getTreeFileHash returns the hash of the file or the zero hash
+// if the file is not present.
+func getTreeFileHash(tree *object.Tree, file string) plumbing.Hash {
+	entry, err := tree.FindEntry(file)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return entry.Hash
+}
+
+// CloseObsoletePullRequests closes the bump PRs of the slots without
+// new versions to merge and the PRs of the single versions, opened before
+// the slot branches, already available on the target branch or replaced
+// by a new version of the same slot.
+func (m *MergeBot) CloseObsoletePullRequests(mkit *specs.MergeKit,
+	candidates []*specs.RepoScanAtom, opts *MergeBotOpts) error {
+
+	if !opts.PullRequest || m.IsANewBranch {
+		return nil
+	}
+
+	targetKit, _ := mkit.GetTargetKit()
+	ctx := context.Background()
+
+	prBranches, err := m.getRemoteBumpBranches(targetKit)
+	if err != nil {
+		return err
+	}
+	if len(prBranches) == 0 {
+		return nil
+	}
+
+	// The branch names are not reversible. The atoms are
+	// searched between the atoms of the sources and the target kits.
+	// The branches of the single versions are created by the previous
+	// releases.
+	mBranches := make(map[string]*specs.RepoScanAtom, 0)
+	mSlotBranches := make(map[string]*specs.RepoScanAtom, 0)
+	for _, resolver := range []*RepoScanResolver{m.Resolver, m.TargetResolver} {
+		for _, atoms := range resolver.Map {
+			for idx := range atoms {
+				mBranches[GetPrBranchNameForPkgBump(atoms[idx].Atom, targetKit.Branch)] = &atoms[idx]
+				mSlotBranches[GetPrBranchNameForSlotBump(atoms[idx].CatPkg,
+					atoms[idx].GetMetadataValue("SLOT"), targetKit.Branch)] = &atoms[idx]
+			}
+		}
+	}
+
+	// The packages of the specfile.
+	mPkgs := make(map[string]bool, 0)
+	for _, atom := range mkit.Target.Atoms {
+		gp, err := gentoo.ParsePackageStr(atom.Package)
+		if err != nil {
+			continue
+		}
+		mPkgs[gp.GetPackageName()] = true
+	}
+
+	bumpBranches := m.getBumpBranches(targetKit, opts)
+
+	branches := []string{}
+	for branch := range prBranches {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+
+	for _, branch := range branches {
+		reason := ""
+
+		if atom, present := mBranches[branch]; present {
+			if opts.HasAtoms() && !opts.AtomInFilter(atom.CatPkg) {
+				continue
+			}
+
+			if _, proposed := m.files4Commit[atom.Atom]; proposed {
+				// The bump is replaced by the branch of the slot
+				// or by the aggregated PR.
+				reason = fmt.Sprintf("%s is included in the PR of the branch %s",
+					atom.Atom, m.getPrBranchName(opts, targetKit,
+						m.getSlotBumpBranchName(targetKit, atom.Atom), atom.Atom))
+			} else {
+				reason, err = m.getObsoleteReason(atom, candidates)
+				if err != nil {
+					return err
+				}
+				if reason == "" {
+					continue
+				}
+			}
+
+		} else if atom, present := mSlotBranches[branch]; present {
+			if opts.HasAtoms() && !opts.AtomInFilter(atom.CatPkg) {
+				continue
+			}
+
+			slot := atom.GetMetadataValue("SLOT")
+			if idx := strings.Index(slot, "/"); idx >= 0 {
+				slot = slot[0:idx]
+			}
+
+			atoms, proposed := bumpBranches[m.getPrBranchName(
+				opts, targetKit, branch, atom.Atom)]
+			if proposed {
+				if !opts.IsAggregatedPR() {
+					// The PR of the slot is updated by the bump.
+					continue
+				}
+				reason = fmt.Sprintf("%s is included in the PR of the branch %s",
+					strings.Join(atoms, ", "), m.getPrBranchName(opts, targetKit, branch, atom.Atom))
+			} else {
+				if _, present := mPkgs[atom.CatPkg]; !present {
+					// The package is not of the specfile. For example
+					// a pulled in dependency or a package of another
+					// specfile of the same kit.
+					continue
+				}
+				// Without new versions to merge the last version
+				// of the slot is already available on the target branch.
+				reason = fmt.Sprintf("there are no new versions of %s:%s to merge",
+					atom.CatPkg, slot)
+			}
+
+		} else {
+			continue
+		}
+
+		err = m.SetupForge(ctx, targetKit, opts)
+		if err != nil {
+			return err
+		}
+
+		pr, err := m.Forge.GetPullRequest(ctx, branch, targetKit.Branch)
+		if err != nil {
+			return err
+		}
+		if pr == nil {
+			continue
+		}
+
+		err = m.Forge.ClosePullRequest(ctx, pr, fmt.Sprintf(
+			"Closed by mark-bot: %s.", reason))
+		if err != nil {
+			return err
+		}
+
+		m.Logger.InfoC(fmt.Sprintf(
+			":wastebasket:[%s] Closed obsolete PR %s: %s.",
+			branch, pr.Url, reason))
+	}
+
+	return nil
+}
+
+// getObsoleteReason returns the reason why the PR of the atom is
+// obsolete or an empty string.
+func (m *MergeBot) getObsoleteReason(atom *specs.RepoScanAtom,
+	candidates []*specs.RepoScanAtom) (string, error) {
+
+	gp, err := atom.ToGentooPackage()
+	if err != nil {
+		return "", err
+	}
+
+	for _, targetAtom := range m.TargetResolver.Map[atom.CatPkg] {
+		if targetAtom.Atom == atom.Atom {
+			return fmt.Sprintf("%s is already available on the target branch",
+				atom.Atom), nil
+		}
+
+		tgp, err := targetAtom.ToGentooPackage()
+		if err != nil {
+			return "", err
+		}
+		if tgp.Slot != gp.Slot {
+			continue
+		}
+		if greater, _ := tgp.GreaterThan(gp); greater {
+			return fmt.Sprintf("%s is already available on the target branch",
+				targetAtom.Atom), nil
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate.CatPkg != atom.CatPkg {
+			continue
+		}
+		cgp, err := candidate.ToGentooPackage()
+		if err != nil {
+			return "", err
+		}
+		if cgp.Slot != gp.Slot {
+			continue
+		}
+		if greater, _ := cgp.GreaterThan(gp); greater {
+			return fmt.Sprintf("replaced by %s", candidate.Atom), nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+)
+
+// groupAtomsBySlot splits the sorted atoms for SLOT. Every group keeps
+// the order of the atoms in input. Without bySlot all the atoms are
+// returned in a single group.
+func groupAtomsBySlot(atoms []*specs.RepoScanAtom, bySlot bool) ([][]*specs.RepoScanAtom, error) {
+	if !bySlot {
+		return [][]*specs.RepoScanAtom{atoms}, nil
+	}
+
+	ans := [][]*specs.RepoScanAtom{}
+	mSlots := make(map[string]int, 0)
+
+	for _, atom := range atoms {
+		gpkg, err := atom.ToGentooPackage()
+		if err != nil {
+			return nil, err
+		}
+
+		slot := gpkg.Slot
+		if slot == "" {
+			slot = "0"
+		}
+
+		idx, present := mSlots[slot]
+		if !present {
+			idx = len(ans)
+			mSlots[slot] = idx
+			ans = append(ans, []*specs.RepoScanAtom{})
+		}
+		ans[idx] = append(ans[idx], atom)
+	}
+
+	return ans, nil
+}
+
+// getAtomsWindow returns the last maxVersions atoms of every group
+// of the sorted atoms in input.
+func getAtomsWindow(atoms []*specs.RepoScanAtom, bySlot bool,
+	maxVersions int) ([]*specs.RepoScanAtom, error) {
+	ans := []*specs.RepoScanAtom{}
+
+	groups, err := groupAtomsBySlot(atoms, bySlot)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		start := 0
+		if len(group) > maxVersions {
+			start = len(group) - maxVersions
+		}
+		ans = append(ans, group[start:]...)
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge versions", func() {
+
+	atom := &specs.MergeKitAtom{Package: "dev-libs/foo"}
+
+	DescribeTable("candidate to merge",
+		func(candidate string, target []string, onlyNewer, expected bool) {
+			bot := newTestMergeBot()
+			for _, t := range target {
+				a := newTestAtom(t, "0", "core-kit")
+				a.Md5 = "target"
+				bot.TargetResolver.AddPackageAtom(a.CatPkg, a)
+			}
+			c := newTestAtom(candidate, "0", "core-kit")
+			c.Md5 = "source"
+
+			toAdd, err := bot.IsCandidate2Merge(atom, c, onlyNewer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(toAdd).To(Equal(expected))
+		},
+		Entry("new package", "dev-libs/foo-1.0", []string{}, false, true),
+		Entry("older version in all mode", "dev-libs/foo-1.0",
+			[]string{"dev-libs/foo-2.0"}, false, true),
+		Entry("older version in last mode", "dev-libs/foo-1.0",
+			[]string{"dev-libs/foo-2.0"}, true, false),
+		Entry("version bumped on target in all mode", "dev-libs/foo-1.0",
+			[]string{"dev-libs/foo-1.0-r1"}, false, false),
+		Entry("version bumped on target in last mode", "dev-libs/foo-1.0",
+			[]string{"dev-libs/foo-1.0-r1"}, true, false),
+		Entry("revision of the source greater", "dev-libs/foo-1.0-r2",
+			[]string{"dev-libs/foo-1.0-r1"}, false, true),
+		Entry("same version with a different ebuild", "dev-libs/foo-1.0",
+			[]string{"dev-libs/foo-1.0"}, false, true),
+	)
+})
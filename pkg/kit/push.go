@@ -8,21 +8,27 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/macaroni-os/mark-devkit/pkg/specs"
 
 	"github.com/go-git/go-git/v5"
 	git_config "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
-	"github.com/google/go-github/v74/github"
 )
 
 type PushOptions struct {
+	// The config with the credentials of the remotes.
+	Config     *specs.MarkDevkitConfig
 	Token      string
 	RemoteName string
 }
 
-func NewPushOptions() *PushOptions {
+func NewPushOptions(c *specs.MarkDevkitConfig) *PushOptions {
 	return &PushOptions{
+		Config:     c,
 		Token:      "",
 		RemoteName: "origin",
 	}
@@ -46,6 +52,18 @@ func GetGithubAuth(opts *PushOptions) (*http.BasicAuth, error) {
 	return auth, nil
 }
 
+// getRepoPushAuth returns the authentication method of the remote
+// of the repository used for the push.
+func getRepoPushAuth(repo *git.Repository, opts *PushOptions) (transport.AuthMethod, error) {
+	remote, err := repo.Remote(opts.RemoteName)
+	if err != nil {
+		return nil, fmt.Errorf("error on retrieve remote %s: %s",
+			opts.RemoteName, err.Error())
+	}
+
+	return getPushAuth(remote.Config().URLs[0], opts)
+}
+
 func Push(repoDir string, opts *PushOptions) error {
 	// Open the repository
 	repo, err := git.PlainOpen(repoDir)
@@ -53,7 +71,7 @@ func Push(repoDir string, opts *PushOptions) error {
 		return err
 	}
 
-	auth, err := GetGithubAuth(opts)
+	auth, err := getRepoPushAuth(repo, opts)
 	if err != nil {
 		return err
 	}
@@ -100,7 +118,7 @@ func PushBranch(repoDir, branch string, opts *PushOptions) error {
 		}
 	}
 
-	auth, err := GetGithubAuth(opts)
+	auth, err := getRepoPushAuth(repo, opts)
 	if err != nil {
 		return err
 	}
@@ -134,19 +152,40 @@ func PushBranch(repoDir, branch string, opts *PushOptions) error {
 	return nil
 }
 
-func CreatePullRequest(client *github.Client, ctx context.Context,
-	title, srcBranch, targetBranch, body,
-	githubUser, githubRepo string) (*github.PullRequest, error) {
+// createPullRequest opens the pull request for the branch or returns
+// the pull request already open.
+func (m *MergeBot) createPullRequest(ctx context.Context, opts *MergeBotOpts,
+	title, srcBranch, targetBranch, body string) (*ForgePullRequest, error) {
 
-	newPR := &github.NewPullRequest{
-		Title:               github.String(title),
-		Head:                github.String(srcBranch),
-		Base:                github.String(targetBranch),
-		Body:                github.String(body),
-		MaintainerCanModify: github.Bool(true),
+	pr, err := m.Forge.GetPullRequest(ctx, srcBranch, targetBranch)
+	if err != nil {
+		return nil, err
 	}
 
-	pr, _, err := client.PullRequests.Create(ctx,
-		githubUser, githubRepo, newPR)
-	return pr, err
+	if pr != nil {
+		if _, toUpdate := m.branches2Update[srcBranch]; toUpdate {
+			err = m.Forge.UpdatePullRequest(ctx, pr, &ForgePullRequestOpts{
+				Title: title,
+				Body: fmt.Sprintf("%s\n\nBranch updated by mark-bot on %s.",
+					body, time.Now().UTC().Format(time.RFC3339)),
+			})
+			if err != nil {
+				return nil, err
+			}
+			m.Logger.Info(fmt.Sprintf("[%s] Updated PR: %s",
+				srcBranch, pr.Url))
+		} else {
+			m.Logger.Info(fmt.Sprintf("[%s] PR already present: %s",
+				srcBranch, pr.Url))
+		}
+		return pr, nil
+	}
+
+	return m.Forge.CreatePullRequest(ctx, &ForgePullRequestOpts{
+		Title:        title,
+		Body:         body,
+		SourceBranch: srcBranch,
+		TargetBranch: targetBranch,
+		Labels:       opts.PullRequestLabels,
+	})
 }
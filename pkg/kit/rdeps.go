@@ -0,0 +1,186 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gentoo "github.com/geaaru/pkgs-checker/pkg/gentoo"
+)
+
+// ReverseDependency describes an atom that requires a package.
+type ReverseDependency struct {
+	Atom       string `json:"atom" yaml:"atom"`
+	Kit        string `json:"kit" yaml:"kit"`
+	Kind       string `json:"kind" yaml:"kind"`
+	Dependency string `json:"dependency" yaml:"dependency"`
+	// The versions of the required package that satisfy the dependency.
+	Versions []string `json:"versions,omitempty" yaml:"versions,omitempty"`
+
+	Package *gentoo.GentooPackage `json:"-" yaml:"-"`
+}
+
+type ReverseDepsOpts struct {
+	Kinds []string
+}
+
+func NewReverseDepsOpts() *ReverseDepsOpts {
+	return &ReverseDepsOpts{
+		Kinds: []string{},
+	}
+}
+
+func (o *ReverseDepsOpts) KindInFilter(kind string) bool {
+	if len(o.Kinds) == 0 {
+		return true
+	}
+	for _, k := range o.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildReverseIndex builds the map of the reverse dependencies from the
+// relations of the atoms available in the map. The blockers are ignored.
+func (r *RepoScanResolver) BuildReverseIndex() error {
+	r.ReverseMap = make(map[string][]*ReverseDependency, 0)
+
+	for _, atoms := range r.Map {
+		for _, atom := range atoms {
+			for kind, deps := range atom.RelationsByKind {
+				for _, dep := range deps {
+					entry, err := parseDependency(kind, dep)
+					if err != nil {
+						r.Logger.Debug(fmt.Sprintf(
+							"[%s::%s] Invalid dependency %s: %s. Skipped.",
+							atom.Atom, atom.Kit, dep, err.Error()))
+						continue
+					}
+
+					catpkg := entry.Package.GetPackageName()
+					if entry.Blocker || catpkg == atom.CatPkg {
+						continue
+					}
+
+					r.ReverseMap[catpkg] = append(r.ReverseMap[catpkg],
+						&ReverseDependency{
+							Atom:       atom.Atom,
+							Kit:        atom.Kit,
+							Kind:       kind,
+							Dependency: dep,
+							Package:    entry.Package,
+						})
+				}
+			}
+		}
+	}
+
+	for catpkg := range r.ReverseMap {
+		rdeps := r.ReverseMap[catpkg]
+		sort.Slice(rdeps, func(i, j int) bool {
+			if rdeps[i].Atom != rdeps[j].Atom {
+				return rdeps[i].Atom < rdeps[j].Atom
+			}
+			if rdeps[i].Kit != rdeps[j].Kit {
+				return rdeps[i].Kit < rdeps[j].Kit
+			}
+			return rdeps[i].Kind < rdeps[j].Kind
+		})
+	}
+
+	return nil
+}
+
+// GetReverseDependencies returns the atoms that require the package
+// or the versions of the package matching the condition in input.
+// The reverse index is built on the first call.
+func (r *RepoScanResolver) GetReverseDependencies(pkg string,
+	opts *ReverseDepsOpts) ([]*ReverseDependency, error) {
+	ans := []*ReverseDependency{}
+
+	if r.ReverseMap == nil {
+		err := r.BuildReverseIndex()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gp, err := gentoo.ParsePackageStr(pkg)
+	if err != nil {
+		return nil, err
+	}
+	// Reset slot if not in input
+	if !strings.Contains(pkg, ":") {
+		gp.Slot = ""
+	}
+	// Ignore sub slot
+	if strings.Contains(gp.Slot, "/") {
+		gp.Slot = gp.Slot[0:strings.Index(gp.Slot, "/")]
+	}
+
+	// Without version and slot all the dependencies are returned.
+	filterVersions := gp.Version != "" || gp.Slot != ""
+
+	targets := []*gentoo.GentooPackage{}
+	if filterVersions {
+		atoms, ok := r.Map[gp.GetPackageName()]
+		if !ok {
+			return nil, fmt.Errorf("Package %s not found in map.",
+				gp.GetPackageName())
+		}
+
+		for _, atom := range atoms {
+			p, err := atom.ToGentooPackage()
+			if err != nil {
+				continue
+			}
+			if valid, _ := gp.Admit(p); valid {
+				targets = append(targets, p)
+			}
+		}
+
+		if len(targets) == 0 {
+			return ans, nil
+		}
+	}
+
+	for _, rdep := range r.ReverseMap[gp.GetPackageName()] {
+		if !opts.KindInFilter(rdep.Kind) {
+			continue
+		}
+
+		entry := *rdep
+		entry.Versions = []string{}
+
+		if filterVersions {
+			for _, p := range targets {
+				valid, err := rdep.Package.Admit(p)
+				if err != nil {
+					r.Logger.Debug(fmt.Sprintf(
+						"[%s::%s] Error on check dependency %s: %s",
+						rdep.Atom, rdep.Kit, rdep.Dependency, err.Error()))
+					continue
+				}
+				if valid {
+					entry.Versions = append(entry.Versions,
+						fmt.Sprintf("%s/%s::%s", p.Category, p.GetPF(), p.Repository))
+				}
+			}
+
+			if len(entry.Versions) == 0 {
+				continue
+			}
+			sort.Strings(entry.Versions)
+		}
+
+		ans = append(ans, &entry)
+	}
+
+	return ans, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reverse dependencies", func() {
+
+	newResolver := func() *RepoScanResolver {
+		app := newTestAtom("app-misc/app-1.0", "0", "core-kit",
+			">=dev-libs/foo-2", "!<dev-libs/foo-1.5")
+		app.RelationsByKind["BDEPEND"] = []string{"dev-libs/foo"}
+		tool := newTestAtom("dev-util/tool-1.0", "0", "dev-kit", "dev-libs/foo:1=")
+
+		return newTestResolver(app, tool,
+			newTestAtom("dev-libs/foo-1.0", "1/1.0", "core-kit", "dev-libs/foo-data"),
+			newTestAtom("dev-libs/foo-2.0", "2", "core-kit"),
+			newTestAtom("dev-libs/foo-2.0", "2", "dev-kit"),
+			newTestAtom("dev-libs/bar-1.0", "0", "core-kit", "dev-libs/bar-data", "dev-libs/bar"),
+		)
+	}
+
+	// getRdeps returns the reverse dependencies in the format:
+	// atom::kit kind dependency [versions]
+	getRdeps := func(rdeps []*ReverseDependency) []string {
+		ans := []string{}
+		for _, d := range rdeps {
+			row := fmt.Sprintf("%s::%s %s %s", d.Atom, d.Kit, d.Kind, d.Dependency)
+			if len(d.Versions) > 0 {
+				row += " [" + strings.Join(d.Versions, " ") + "]"
+			}
+			ans = append(ans, row)
+		}
+		return ans
+	}
+
+	DescribeTable("GetReverseDependencies",
+		func(pkg string, kinds []string, expected []string) {
+			opts := NewReverseDepsOpts()
+			opts.Kinds = kinds
+
+			rdeps, err := newResolver().GetReverseDependencies(pkg, opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(getRdeps(rdeps)).To(Equal(expected))
+		},
+		Entry("all the dependencies of the package", "dev-libs/foo", []string{}, []string{
+			"app-misc/app-1.0::core-kit BDEPEND dev-libs/foo",
+			"app-misc/app-1.0::core-kit RDEPEND >=dev-libs/foo-2",
+			"dev-util/tool-1.0::dev-kit RDEPEND dev-libs/foo:1=",
+		}),
+		Entry("filtered by kind", "dev-libs/foo", []string{"RDEPEND"}, []string{
+			"app-misc/app-1.0::core-kit RDEPEND >=dev-libs/foo-2",
+			"dev-util/tool-1.0::dev-kit RDEPEND dev-libs/foo:1=",
+		}),
+		Entry("version", "=dev-libs/foo-1.0", []string{}, []string{
+			"app-misc/app-1.0::core-kit BDEPEND dev-libs/foo [dev-libs/foo-1.0::core-kit]",
+			"dev-util/tool-1.0::dev-kit RDEPEND dev-libs/foo:1= [dev-libs/foo-1.0::core-kit]",
+		}),
+		Entry("version of more kits", "=dev-libs/foo-2.0", []string{}, []string{
+			"app-misc/app-1.0::core-kit BDEPEND dev-libs/foo" +
+				" [dev-libs/foo-2.0::core-kit dev-libs/foo-2.0::dev-kit]",
+			"app-misc/app-1.0::core-kit RDEPEND >=dev-libs/foo-2" +
+				" [dev-libs/foo-2.0::core-kit dev-libs/foo-2.0::dev-kit]",
+		}),
+		Entry("slot with sub slot", "dev-libs/foo:1/1.0", []string{}, []string{
+			"app-misc/app-1.0::core-kit BDEPEND dev-libs/foo [dev-libs/foo-1.0::core-kit]",
+			"dev-util/tool-1.0::dev-kit RDEPEND dev-libs/foo:1= [dev-libs/foo-1.0::core-kit]",
+		}),
+		Entry("version without matching atoms", ">=dev-libs/foo-3", []string{}, []string{}),
+		Entry("package without reverse dependencies", "app-misc/app", []string{}, []string{}),
+		Entry("dependency to the same package", "dev-libs/bar", []string{}, []string{}),
+		Entry("package not in the kits", "dev-libs/foo-data", []string{}, []string{
+			"dev-libs/foo-1.0::core-kit RDEPEND dev-libs/foo-data",
+		}),
+	)
+
+	It("fails with a version of a package not in the kits", func() {
+		_, err := newResolver().GetReverseDependencies("=dev-libs/foo-data-1.0",
+			NewReverseDepsOpts())
+		Expect(err).To(HaveOccurred())
+	})
+})
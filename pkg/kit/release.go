@@ -133,7 +133,7 @@ func (r *ReleaseBot) Run(specfile string, opts *ReleaseOpts) error {
 		kit := &release.Release.Target
 		repoDir := filepath.Join(r.GetTargetDir(), kit.Name)
 
-		pushOpts := NewPushOptions()
+		pushOpts := NewPushOptions(r.Config)
 		err = Push(repoDir, pushOpts)
 		if err != nil {
 			return err
@@ -447,6 +447,7 @@ func (r *ReleaseBot) prepareMetadataDir(release *specs.KitReleaseSpec,
 func (r *ReleaseBot) cloneSourcesKit(release *specs.KitReleaseSpec,
 	opts *ReleaseOpts) error {
 	gitOpts := &CloneOptions{
+		Config: r.Config,
 		GitCloneOptions: &git.CloneOptions{
 			SingleBranch: true,
 			RemoteName:   "origin",
@@ -492,12 +493,13 @@ func (r *ReleaseBot) cloneTargetKit(release *specs.KitReleaseSpec, opts *Release
 	kitDir := filepath.Join(r.GetTargetDir(), kit.Name)
 
 	// Check if the repository branch exists.
-	existsBranch, err := BranchExists(kit.Url, kit.Branch)
+	existsBranch, err := BranchExists(r.Config, kit.Url, kit.Branch)
 	if err != nil {
 		return err
 	}
 
 	gitOpts := &CloneOptions{
+		Config: r.Config,
 		GitCloneOptions: &git.CloneOptions{
 			RemoteName: "origin",
 			Depth:      opts.GitDeepFetch,
@@ -28,6 +28,7 @@ type RepoScanResolver struct {
 	Constraints        []string
 	MapConstraints     map[string]([]gentoo.GentooPackage)
 	Map                map[string]([]specs.RepoScanAtom)
+	ReverseMap         map[string]([]*ReverseDependency)
 	IgnoreMissingDeps  bool
 	ContinueWithError  bool
 	DepsWithSlot       bool
@@ -23,6 +23,8 @@ func GetPrBranchNameForMetadata(branch string) string {
 	)
 }
 
+// GetPrBranchNameForPkgBump returns the branch of the bump of a single
+// version. It's used to close the PRs opened before the slot branches.
 func GetPrBranchNameForPkgBump(pkg, branch string) string {
 	return fmt.Sprintf(
 		"%s%s/%s-%s",
@@ -32,6 +34,25 @@ func GetPrBranchNameForPkgBump(pkg, branch string) string {
 	)
 }
 
+// GetPrBranchNameForSlotBump returns the branch of the bumps of a slot
+// of the package. The new versions of the same slot update the same branch.
+func GetPrBranchNameForSlotBump(catpkg, slot, branch string) string {
+	// Ignore the sub slot.
+	if idx := strings.Index(slot, "/"); idx >= 0 {
+		slot = slot[0:idx]
+	}
+	if slot == "" {
+		slot = "0"
+	}
+	return fmt.Sprintf(
+		"%s%s/%s-%s-slot-%s",
+		prBranchPrefix, branch, "bump",
+		strings.ReplaceAll(strings.ReplaceAll(catpkg, ".", "_"),
+			"/", "_"),
+		strings.ReplaceAll(slot, ".", "_"),
+	)
+}
+
 func GetPrBranchNameForFixup(name, branch string) string {
 	return fmt.Sprintf(
 		"%s%s/%s-%s",
@@ -47,3 +68,14 @@ func GetPrBranchNameForProfile(branch string) string {
 		prBranchPrefix, branch, "profiles-update",
 	)
 }
+
+func GetPrBranchNameForAggregate(branch, category string) string {
+	if category == "" {
+		return fmt.Sprintf("%s%s/%s", prBranchPrefix, branch, "aggregate")
+	}
+	return fmt.Sprintf(
+		"%s%s/%s-%s",
+		prBranchPrefix, branch, "aggregate",
+		strings.ReplaceAll(category, ".", "_"),
+	)
+}
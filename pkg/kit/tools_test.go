@@ -0,0 +1,27 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package kit_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/kit"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tools", func() {
+
+	DescribeTable("GetPrBranchNameForSlotBump",
+		func(catpkg, slot, branch string) {
+			Expect(GetPrBranchNameForSlotBump(catpkg, slot, "master")).To(Equal(branch))
+		},
+		Entry("without slot", "dev-libs/foo", "",
+			"merge/master/bump-dev-libs_foo-slot-0"),
+		Entry("with slot", "dev-lang/python", "3.12",
+			"merge/master/bump-dev-lang_python-slot-3_12"),
+		Entry("with sub slot", "dev-libs/openssl", "0/3",
+			"merge/master/bump-dev-libs_openssl-slot-0"),
+	)
+})
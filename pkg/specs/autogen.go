@@ -7,6 +7,7 @@ package specs
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
@@ -58,6 +59,24 @@ func (a *AutogenSpec) HasExtensionsDefs() bool {
 	return ans
 }
 
+// GetTemplatesLibraryDirs returns the templates library directories
+// of the definition resolved from the specfile directory.
+func (a *AutogenSpec) GetTemplatesLibraryDirs(def *AutogenDefinition) []string {
+	ans := []string{}
+	if def.TemplateEngine == nil {
+		return ans
+	}
+
+	for _, dir := range def.TemplateEngine.Library {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(a.File), dir)
+		}
+		ans = append(ans, dir)
+	}
+
+	return ans
+}
+
 func (a *AutogenSpec) Prepare() {
 	for idx := range a.Definitions {
 		if len(a.Definitions[idx].Packages) > 0 {
@@ -81,12 +100,14 @@ func (e *AutogenExtension) GetName() string               { return e.Name }
 func (e *AutogenExtension) GetOptions() map[string]string { return e.Options }
 func (e *AutogenExtension) Clone() *AutogenExtension {
 	ans := &AutogenExtension{
-		Name:    e.Name,
-		Options: make(map[string]string, 0),
+		Name:     e.Name,
+		Options:  make(map[string]string, 0),
+		Requires: []string{},
 	}
 	for k, v := range e.Options {
 		ans.Options[k] = v
 	}
+	ans.Requires = append(ans.Requires, e.Requires...)
 	return ans
 }
 
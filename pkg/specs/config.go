@@ -30,6 +30,9 @@ type MarkDevkitConfig struct {
 
 type MarkDevkitGeneral struct {
 	Debug bool `mapstructure:"debug,omitempty" json:"debug,omitempty" yaml:"debug,omitempty"`
+	// Directories where search the generator and extension plugins
+	// before the PATH.
+	PluginsDirs []string `mapstructure:"plugins_dirs,omitempty" json:"plugins_dirs,omitempty" yaml:"plugins_dirs,omitempty"`
 }
 
 type MarkDevkitTarflowsConfig struct {
@@ -77,6 +80,18 @@ type MarkDevkitRemoteAuth struct {
 	Token      string `mapstructure:"token,omitempty" json:"token,omitempty" yaml:"token,omitempty"`
 	ApiVersion string `mapstructure:"api_version,omitempty" json:"api_version,omitempty" yaml:"api_version,omitempty"`
 	Url        string `mapstructure:"url,omitempty" json:"url,omitempty" yaml:"url,omitempty"`
+	// The type of the forge used for the pull requests:
+	// github, forgejo, gitea or gitlab.
+	Forge string `mapstructure:"forge,omitempty" json:"forge,omitempty" yaml:"forge,omitempty"`
+
+	// The SSH private key used for the git operations over SSH.
+	SshKey           string `mapstructure:"ssh_key,omitempty" json:"ssh_key,omitempty" yaml:"ssh_key,omitempty"`
+	SshKeyPassphrase string `mapstructure:"ssh_key_passphrase,omitempty" json:"ssh_key_passphrase,omitempty" yaml:"ssh_key_passphrase,omitempty"`
+	// Use the keys of the ssh-agent available through SSH_AUTH_SOCK.
+	SshAgent bool `mapstructure:"ssh_agent,omitempty" json:"ssh_agent,omitempty" yaml:"ssh_agent,omitempty"`
+	// The known_hosts files used to verify the host key.
+	// If not defined are used SSH_KNOWN_HOSTS or ~/.ssh/known_hosts.
+	KnownHosts []string `mapstructure:"known_hosts,omitempty" json:"known_hosts,omitempty" yaml:"known_hosts,omitempty"`
 }
 
 func (a *MarkDevkitAuthentication) GetRemote(r string) (*MarkDevkitRemoteAuth, bool) {
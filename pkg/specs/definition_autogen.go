@@ -18,11 +18,18 @@ const (
 	TmplEngineHelm   = "helm"
 	TmplEnginePongo2 = "pongo2"
 	TmplEngineJ2cli  = "j2cli"
+	TmplEngineJinja2 = "jinja2"
 
 	ExtensionCustom        = "custom"
 	ExtensionGolang        = "golang"
 	ExtensionRust          = "rust"
 	ExtensionGitSubmodules = "git-submodules"
+	ExtensionNode          = "node"
+	ExtensionPython        = "python"
+	ExtensionComposer      = "composer"
+	ExtensionNuget         = "nuget"
+	ExtensionGitSnapshot   = "git-snapshot"
+	ExtensionPatch         = "patch"
 
 	NotifyDiscord = "discord"
 )
@@ -46,8 +53,9 @@ type AutogenDefinition struct {
 }
 
 type AutogenExtension struct {
-	Name    string            `json:"name" yaml:"name"`
-	Options map[string]string `json:"opts,omitempty" yaml:"opts,omitempty"`
+	Name     string            `json:"name" yaml:"name"`
+	Options  map[string]string `json:"opts,omitempty" yaml:"opts,omitempty"`
+	Requires []string          `json:"requires,omitempty" yaml:"requires,omitempty"`
 }
 
 type AutogenArtefact struct {
@@ -113,6 +121,9 @@ type AutogenTransform struct {
 type AutogenTemplateEngine struct {
 	Engine string   `json:"engine,omitempty" yaml:"engine,omitempty"`
 	Opts   []string `json:"opts,omitempty" yaml:"opts,omitempty"`
+	// Directories with the partials shared between templates.
+	// The paths are relative to the specfile directory.
+	Library []string `json:"library,omitempty" yaml:"library,omitempty"`
 }
 
 type AutogenGithubProps struct {
@@ -40,14 +40,57 @@ type MergeKitThirdPartyMirror struct {
 
 type MergeKitEclasses struct {
 	Include map[string][]string `yaml:"include,omitempty" json:"include,omitempty"`
+
+	Auto *MergeKitEclassesAuto `yaml:"auto,omitempty" json:"auto,omitempty"`
+}
+
+// MergeKitEclassesAuto defines how to copy the eclasses inherited by the
+// packages of the target kit and missing on target kit.
+type MergeKitEclassesAuto struct {
+	Enable bool `yaml:"enable" json:"enable"`
+	// The regexes of the eclasses admitted. If empty all the
+	// eclasses are admitted.
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+	// The regexes of the eclasses never copied. The eclasses
+	// available from the masters of the kit are always skipped.
+	Deny []string `yaml:"deny,omitempty" json:"deny,omitempty"`
 }
 
+const (
+	// Merge only the last version admitted by the conditions.
+	MergeVersionsLast = "last"
+	// Merge all the versions admitted by the conditions
+	// up to max_versions.
+	MergeVersionsAll = "all"
+)
+
 type MergeKitAtom struct {
 	Package        string   `yaml:"pkg,omitempty" json:"pkg,omitempty"`
 	MaxVersions    *int     `yaml:"max_versions,omitempty" json:"max_versions,omitempty"`
 	Conditions     []string `yaml:"conditions,omitempty" json:"conditions,omitempty"`
 	CondIgnoreSlot *bool    `yaml:"cond_ignore_slot,omitempty" json:"cond_ignore_slot,omitempty"`
 	Versions       []string `yaml:"versions,omitempty" json:"versions,omitempty"`
+	MergeVersions  string   `yaml:"merge_versions,omitempty" json:"merge_versions,omitempty"`
+	GroupBySlot    *bool    `yaml:"group_by_slot,omitempty" json:"group_by_slot,omitempty"`
+	// The versions added to the kit more recently than the
+	// defined age are never removed. Ex: 30d, 2w, 72h.
+	KeepNewerThan string `yaml:"keep_newer_than,omitempty" json:"keep_newer_than,omitempty"`
+
+	WithDeps *MergeKitWithDeps `yaml:"with_deps,omitempty" json:"with_deps,omitempty"`
+}
+
+// MergeKitWithDeps defines how to pull in the dependencies of the atom
+// missing on target kit.
+type MergeKitWithDeps struct {
+	Enable bool `yaml:"enable" json:"enable"`
+	// The max depth of the dependencies graph. Default 1: only
+	// the direct dependencies.
+	Depth int `yaml:"depth,omitempty" json:"depth,omitempty"`
+	// The kinds of the dependencies to follow: RDEPEND, DEPEND, BDEPEND.
+	Kinds []string `yaml:"kinds,omitempty" json:"kinds,omitempty"`
+	// The categories of the dependencies admitted. It supports
+	// glob patterns. If empty all the categories are admitted.
+	Categories []string `yaml:"categories,omitempty" json:"categories,omitempty"`
 }
 
 type MergeKitFixups struct {
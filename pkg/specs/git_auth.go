@@ -0,0 +1,178 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+const (
+	GitProtocolLocal = "local"
+	GitProtocolHttp  = "http"
+	GitProtocolSsh   = "ssh"
+)
+
+type GitRemote struct {
+	Protocol string
+	Host     string
+	User     string
+}
+
+// ParseGitRemote returns the protocol, the host and the user of a git
+// url in the HTTP, SSH or SCP-like format.
+func ParseGitRemote(remoteUrl string) (*GitRemote, error) {
+	if strings.Contains(remoteUrl, "://") {
+		uri, err := url.Parse(remoteUrl)
+		if err != nil {
+			return nil, fmt.Errorf("error on parse url %s: %s",
+				remoteUrl, err.Error())
+		}
+
+		ans := &GitRemote{Host: uri.Host}
+		if uri.User != nil {
+			ans.User = uri.User.Username()
+		}
+
+		switch uri.Scheme {
+		case "http", "https":
+			ans.Protocol = GitProtocolHttp
+		case "ssh", "git+ssh", "ssh+git":
+			ans.Protocol = GitProtocolSsh
+		default:
+			ans.Protocol = GitProtocolLocal
+		}
+		return ans, nil
+	}
+
+	// The SCP-like format has the host before the first colon
+	// and not a path. Ex: git@github.com:macaroni-os/core-kit.git
+	idx := strings.Index(remoteUrl, ":")
+	if idx > 0 && !strings.Contains(remoteUrl[0:idx], "/") {
+		ans := &GitRemote{
+			Protocol: GitProtocolSsh,
+			Host:     remoteUrl[0:idx],
+		}
+		if at := strings.Index(ans.Host, "@"); at >= 0 {
+			ans.User = ans.Host[0:at]
+			ans.Host = ans.Host[at+1:]
+		}
+		return ans, nil
+	}
+
+	return &GitRemote{Protocol: GitProtocolLocal}, nil
+}
+
+// GetGitRemote returns the credentials of the host of the git remote.
+// The host without the port is checked as fallback.
+func (a *MarkDevkitAuthentication) GetGitRemote(r *GitRemote) (*MarkDevkitRemoteAuth, bool) {
+	remote, present := a.GetRemote(r.Host)
+	if !present {
+		// Try without the port.
+		if idx := strings.LastIndex(r.Host, ":"); idx > 0 {
+			remote, present = a.GetRemote(r.Host[0:idx])
+		}
+	}
+	return remote, present && remote != nil
+}
+
+// GetGitAuth returns the authentication method used by the git operations
+// with the remote url based on the authentication remotes.
+// The token in input has priority over the credentials of the remote.
+// It returns nil when there aren't credentials for the remote.
+func (a *MarkDevkitAuthentication) GetGitAuth(remoteUrl, token string) (transport.AuthMethod, error) {
+	r, err := ParseGitRemote(remoteUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	remote, present := a.GetGitRemote(r)
+
+	switch r.Protocol {
+	case GitProtocolHttp:
+		if token == "" && present {
+			token = remote.Token
+		}
+		if token != "" {
+			username := "oauth2"
+			if present && remote.Username != "" {
+				username = remote.Username
+			}
+			return &http.BasicAuth{
+				Username: username,
+				Password: token,
+			}, nil
+		}
+
+		if present && remote.Username != "" {
+			return &http.BasicAuth{
+				Username: remote.Username,
+				Password: remote.Password,
+			}, nil
+		}
+
+	case GitProtocolSsh:
+		if !present {
+			// go-git uses the ssh-agent and the default known_hosts.
+			return nil, nil
+		}
+		return getSshAuth(r, remote)
+	}
+
+	return nil, nil
+}
+
+func getSshAuth(r *GitRemote, remote *MarkDevkitRemoteAuth) (transport.AuthMethod, error) {
+	var helper *ssh.HostKeyCallbackHelper
+
+	user := r.User
+	if user == "" {
+		user = remote.Username
+	}
+	if user == "" {
+		user = ssh.DefaultUsername
+	}
+
+	if len(remote.KnownHosts) > 0 {
+		callback, err := ssh.NewKnownHostsCallback(remote.KnownHosts...)
+		if err != nil {
+			return nil, fmt.Errorf("error on load known_hosts for %s: %s",
+				r.Host, err.Error())
+		}
+		helper = &ssh.HostKeyCallbackHelper{HostKeyCallback: callback}
+	}
+
+	if remote.SshKey != "" {
+		auth, err := ssh.NewPublicKeysFromFile(user, remote.SshKey,
+			remote.SshKeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error on load ssh key %s for %s: %s",
+				remote.SshKey, r.Host, err.Error())
+		}
+		if helper != nil {
+			auth.HostKeyCallbackHelper = *helper
+		}
+		return auth, nil
+	}
+
+	if remote.SshAgent || helper != nil {
+		auth, err := ssh.NewSSHAgentAuth(user)
+		if err != nil {
+			return nil, fmt.Errorf("error on setup ssh-agent for %s: %s",
+				r.Host, err.Error())
+		}
+		if helper != nil {
+			auth.HostKeyCallbackHelper = *helper
+		}
+		return auth, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,73 @@
+/*
+Copyright © 2024-2026 Macaroni OS Linux
+See AUTHORS and LICENSE for the license details and contributors.
+*/
+package specs_test
+
+import (
+	. "github.com/macaroni-os/mark-devkit/pkg/specs"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Git authentication", func() {
+
+	DescribeTable("ParseGitRemote",
+		func(remoteUrl, protocol, host, user string) {
+			r, err := ParseGitRemote(remoteUrl)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Protocol).To(Equal(protocol))
+			Expect(r.Host).To(Equal(host))
+			Expect(r.User).To(Equal(user))
+		},
+		Entry("https", "https://github.com/macaroni-os/core-kit.git",
+			GitProtocolHttp, "github.com", ""),
+		Entry("https with port", "https://git.example.org:3000/kits/core-kit.git",
+			GitProtocolHttp, "git.example.org:3000", ""),
+		Entry("ssh", "ssh://git@git.example.org:2222/kits/core-kit.git",
+			GitProtocolSsh, "git.example.org:2222", "git"),
+		Entry("scp-like", "git@github.com:macaroni-os/core-kit.git",
+			GitProtocolSsh, "github.com", "git"),
+		Entry("local path", "/var/lib/kits/core-kit",
+			GitProtocolLocal, "", ""),
+	)
+
+	Context("GetGitAuth", func() {
+		auth := &MarkDevkitAuthentication{
+			Remotes: map[string]*MarkDevkitRemoteAuth{
+				"github.com":      {Token: "mytoken"},
+				"git.example.org": {Username: "bot", Password: "secret"},
+			},
+		}
+
+		It("uses the token of the remote", func() {
+			a, err := auth.GetGitAuth("https://github.com/macaroni-os/core-kit.git", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(a).To(Equal(&http.BasicAuth{Username: "oauth2", Password: "mytoken"}))
+		})
+
+		It("prefers the token in input", func() {
+			a, err := auth.GetGitAuth("https://github.com/macaroni-os/core-kit.git", "other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(a).To(Equal(&http.BasicAuth{Username: "oauth2", Password: "other"}))
+		})
+
+		It("uses the credentials of the host without the port", func() {
+			a, err := auth.GetGitAuth("https://git.example.org:3000/kits/core-kit.git", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(a).To(Equal(&http.BasicAuth{Username: "bot", Password: "secret"}))
+		})
+
+		It("returns nil without credentials", func() {
+			a, err := auth.GetGitAuth("https://gitlab.com/kits/core-kit.git", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(a).To(BeNil())
+
+			a, err = auth.GetGitAuth("git@gitlab.com:kits/core-kit.git", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(a).To(BeNil())
+		})
+	})
+})
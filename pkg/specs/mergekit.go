@@ -7,7 +7,10 @@ package specs
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -76,6 +79,15 @@ func (m *MergeKit) GetEclassesInclude() *map[string][]string {
 	}
 	return ans
 }
+
+func (m *MergeKit) GetEclassesAuto() *MergeKitEclassesAuto {
+	if m.Target.Eclasses != nil && m.Target.Eclasses.Auto != nil &&
+		m.Target.Eclasses.Auto.Enable {
+		return m.Target.Eclasses.Auto
+	}
+	return nil
+}
+
 func (m *MergeKit) GetFixupsInclude() *[]*MergeKitFixupInclude {
 	var ans *[]*MergeKitFixupInclude = nil
 
@@ -98,6 +110,122 @@ func (m *MergeKit) GetMetadata() *MergeKitMetadata {
 	return m.Target.Metadata
 }
 
+// GetAtomMaxVersions returns the number of versions of the atom
+// to keep in the target kit.
+func (m *MergeKit) GetAtomMaxVersions(atom *MergeKitAtom) int {
+	ans := 5
+	if m.Target.AtomDefaults != nil && m.Target.AtomDefaults.MaxVersions != nil {
+		ans = *m.Target.AtomDefaults.MaxVersions
+	}
+	if atom.MaxVersions != nil {
+		ans = *atom.MaxVersions
+	}
+	if ans < 1 {
+		ans = 1
+	}
+	return ans
+}
+
+// GetAtomMergeVersions returns the merge mode of the atom.
+func (m *MergeKit) GetAtomMergeVersions(atom *MergeKitAtom) string {
+	if atom.MergeVersions != "" {
+		return atom.MergeVersions
+	}
+	if m.Target.AtomDefaults != nil && m.Target.AtomDefaults.MergeVersions != "" {
+		return m.Target.AtomDefaults.MergeVersions
+	}
+	return MergeVersionsLast
+}
+
+// AtomGroupBySlot returns true if max_versions is applied
+// for every SLOT of the atom.
+func (m *MergeKit) AtomGroupBySlot(atom *MergeKitAtom) bool {
+	if atom.GroupBySlot != nil {
+		return *atom.GroupBySlot
+	}
+	if m.Target.AtomDefaults != nil && m.Target.AtomDefaults.GroupBySlot != nil {
+		return *m.Target.AtomDefaults.GroupBySlot
+	}
+	return false
+}
+
+// GetAtomKeepNewerThan returns the age of the versions of the atom
+// that are never removed by the clean. Zero means no age rule.
+func (m *MergeKit) GetAtomKeepNewerThan(atom *MergeKitAtom) (time.Duration, error) {
+	age := atom.KeepNewerThan
+	if age == "" && m.Target.AtomDefaults != nil {
+		age = m.Target.AtomDefaults.KeepNewerThan
+	}
+	if age == "" {
+		return 0, nil
+	}
+
+	ans, err := parseAge(age)
+	if err != nil {
+		return 0, fmt.Errorf("invalid keep_newer_than value %s for atom %s: %s",
+			age, atom.Package, err.Error())
+	}
+	return ans, nil
+}
+
+// parseAge parses a duration that supports the days (d) and
+// the weeks (w) units in addition to the units of time.ParseDuration.
+func parseAge(age string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	} {
+		if strings.HasSuffix(age, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(age, suffix))
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(age)
+}
+
+// GetAtomWithDeps returns the dependencies options of the atom
+// or nil if the dependencies must not be pulled in.
+func (m *MergeKit) GetAtomWithDeps(atom *MergeKitAtom) *MergeKitWithDeps {
+	ans := atom.WithDeps
+	if ans == nil && m.Target.AtomDefaults != nil {
+		ans = m.Target.AtomDefaults.WithDeps
+	}
+	if ans == nil || !ans.Enable {
+		return nil
+	}
+	return ans
+}
+
+func (w *MergeKitWithDeps) GetDepth() int {
+	if w.Depth < 1 {
+		return 1
+	}
+	return w.Depth
+}
+
+func (w *MergeKitWithDeps) GetKinds() []string {
+	if len(w.Kinds) == 0 {
+		return []string{"RDEPEND", "DEPEND", "BDEPEND"}
+	}
+	return w.Kinds
+}
+
+func (w *MergeKitWithDeps) IsCategoryAdmitted(cat string) bool {
+	if len(w.Categories) == 0 {
+		return true
+	}
+	for _, pattern := range w.Categories {
+		if matched, _ := filepath.Match(pattern, cat); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MergeKitTarget) GetThirdpartyMirrorsUris(alias string) []string {
 	ans := []string{}
 